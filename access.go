@@ -0,0 +1,88 @@
+package epub
+
+// SectionAccess describes whether a section is available to readers who don't
+// have full access to the book, for storefronts and reading systems that
+// implement partial access.
+type SectionAccess int
+
+const (
+	// AccessDefault leaves the access of the section unspecified
+	AccessDefault SectionAccess = iota
+	// AccessPreview marks the section as freely available, e.g. a sample
+	// chapter
+	AccessPreview
+	// AccessLocked marks the section as only available with full access
+	AccessLocked
+)
+
+const (
+	pkgAccessibleForFreeProperty = "schema:isAccessibleForFree"
+	xhtmlAccessPreview           = "preview"
+	xhtmlAccessLocked            = "locked"
+)
+
+// SetSectionAccess marks a section as a preview or as locked. When the EPUB is
+// written, the access of each marked section is described in the package file
+// with schema.org's isAccessibleForFree property refining the section, and the
+// book itself is marked as not accessible for free if any section is locked.
+// The body of the section also gets a data-access attribute ("preview" or
+// "locked") that styles and scripts can rely on; sections added as complete
+// XHTML documents must include it themselves.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection).
+func (e *Epub) SetSectionAccess(sectionFilename string, access SectionAccess) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	section.access = access
+	switch access {
+	case AccessPreview:
+		section.xhtml.xml.Body.Access = xhtmlAccessPreview
+	case AccessLocked:
+		section.xhtml.xml.Body.Access = xhtmlAccessLocked
+	default:
+		section.xhtml.xml.Body.Access = ""
+	}
+	return nil
+}
+
+// Add the access metadata of the sections to the package file
+func (e *Epub) writeSectionAccess() error {
+	access := make(map[string]string)
+	locked := false
+	var walk func([]*epubSection) error
+	walk = func(sections []*epubSection) error {
+		for _, s := range sections {
+			if s.access == AccessPreview || s.access == AccessLocked {
+				id, err := e.sectionID(s.filename)
+				if err != nil {
+					return err
+				}
+				access[id] = "true"
+				if s.access == AccessLocked {
+					access[id] = "false"
+					locked = true
+				}
+			}
+			if err := walk(s.children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(e.sections); err != nil {
+		return err
+	}
+
+	publication := ""
+	if locked {
+		publication = "false"
+	}
+	e.pkg.setRefinedMetas(pkgAccessibleForFreeProperty, publication, access)
+	return nil
+}
@@ -0,0 +1,70 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetSectionAccess(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{"preview.xhtml", "locked.xhtml", "other.xhtml"} {
+		if _, err := e.AddSection(testSectionBody, testSectionTitle, filename, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.SetSectionAccess("preview.xhtml", AccessPreview); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionAccess("locked.xhtml", AccessLocked); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`<meta property="schema:isAccessibleForFree">false</meta>`,
+		`<meta refines="#preview.xhtml" property="schema:isAccessibleForFree">true</meta>`,
+		`<meta refines="#locked.xhtml" property="schema:isAccessibleForFree">false</meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Package file doesn't contain %s\n%s", expected, opf)
+		}
+	}
+	if strings.Contains(opf, "#other.xhtml") {
+		t.Errorf("Package file shouldn't describe the access of unmarked sections\n%s", opf)
+	}
+	if !strings.Contains(files["EPUB/xhtml/locked.xhtml"], `data-access="locked"`) {
+		t.Errorf("Locked section body isn't marked:\n%s", files["EPUB/xhtml/locked.xhtml"])
+	}
+	if !strings.Contains(files["EPUB/xhtml/preview.xhtml"], `data-access="preview"`) {
+		t.Errorf("Preview section body isn't marked:\n%s", files["EPUB/xhtml/preview.xhtml"])
+	}
+
+	// Unlocking the section removes the publication-level metadata
+	if err := e.SetSectionAccess("locked.xhtml", AccessDefault); err != nil {
+		t.Fatal(err)
+	}
+	opf = writeAndReadPackage(t, e)
+	if strings.Contains(opf, ">false</meta>") {
+		t.Errorf("Package file still marks locked content\n%s", opf)
+	}
+}
+
+func TestSetSectionAccessError(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.SetSectionAccess("missing.xhtml", AccessLocked)
+	if _, ok := err.(*SectionDoesNotExistError); !ok {
+		t.Errorf("Expected error SectionDoesNotExistError not returned. Returned instead: %+v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package epub
+
+import "strings"
+
+// Access modes of the content of the EPUB, see AccessibilityMetadata
+const (
+	AccessModeAuditory = "auditory"
+	AccessModeTactile  = "tactile"
+	AccessModeTextual  = "textual"
+	AccessModeVisual   = "visual"
+)
+
+// Common accessibility features of the EPUB, see AccessibilityMetadata and
+// https://www.w3.org/2021/a11y-discov-vocab/latest/ for the whole vocabulary
+const (
+	AccessibilityFeatureAlternativeText         = "alternativeText"
+	AccessibilityFeatureDisplayTransformability = "displayTransformability"
+	AccessibilityFeatureLongDescription         = "longDescription"
+	AccessibilityFeatureMathML                  = "MathML"
+	AccessibilityFeaturePageNavigation          = "pageNavigation"
+	AccessibilityFeatureReadingOrder            = "readingOrder"
+	AccessibilityFeatureStructuralNavigation    = "structuralNavigation"
+	AccessibilityFeatureSynchronizedAudioText   = "synchronizedAudioText"
+	AccessibilityFeatureTableOfContents         = "tableOfContents"
+	AccessibilityFeatureTranscript              = "transcript"
+)
+
+// Accessibility hazards of the EPUB, see AccessibilityMetadata
+const (
+	AccessibilityHazardNone               = "none"
+	AccessibilityHazardFlashing           = "flashing"
+	AccessibilityHazardMotionSimulation   = "motionSimulation"
+	AccessibilityHazardSound              = "sound"
+	AccessibilityHazardNoFlashing         = "noFlashingHazard"
+	AccessibilityHazardNoMotionSimulation = "noMotionSimulationHazard"
+	AccessibilityHazardNoSound            = "noSoundHazard"
+	AccessibilityHazardUnknown            = "unknown"
+)
+
+// Conformance of the EPUB to the EPUB Accessibility specification, see
+// AccessibilityMetadata
+const (
+	// EPUB Accessibility 1.0, identified by URLs and written as links
+	A11yConformanceEPUB10WCAG20A  = "http://www.idpf.org/epub/a11y/accessibility-20170105.html#wcag-a"
+	A11yConformanceEPUB10WCAG20AA = "http://www.idpf.org/epub/a11y/accessibility-20170105.html#wcag-aa"
+	// EPUB Accessibility 1.1, identified by strings and written as metas
+	A11yConformanceEPUB11WCAG21AA = "EPUB Accessibility 1.1 - WCAG 2.1 Level AA"
+	A11yConformanceEPUB11WCAG22AA = "EPUB Accessibility 1.1 - WCAG 2.2 Level AA"
+)
+
+const (
+	pkgAccessModeProperty           = "schema:accessMode"
+	pkgAccessModeSufficientProperty = "schema:accessModeSufficient"
+	pkgAccessibilityFeatureProperty = "schema:accessibilityFeature"
+	pkgAccessibilityHazardProperty  = "schema:accessibilityHazard"
+	pkgAccessibilitySummaryProperty = "schema:accessibilitySummary"
+	pkgConformsToProperty           = "dcterms:conformsTo"
+	pkgCertifiedByProperty          = "a11y:certifiedBy"
+)
+
+// AccessibilityMetadata describes the accessibility of the EPUB with the
+// schema.org accessibility properties, which many retailers require, see
+// SetAccessibility.
+type AccessibilityMetadata struct {
+	// Access modes needed to understand the whole content, e.g.
+	// AccessModeTextual and AccessModeVisual for a book with illustrations
+	AccessModes []string `json:"accessModes,omitempty"`
+	// Sets of access modes sufficient to understand the whole content, each
+	// one being a comma-separated list of access modes, e.g. "textual" if the
+	// illustrations have text alternatives
+	AccessModesSufficient []string `json:"accessModesSufficient,omitempty"`
+	// Accessibility features of the content, e.g.
+	// AccessibilityFeatureAlternativeText
+	Features []string `json:"features,omitempty"`
+	// Accessibility hazards of the content, e.g. AccessibilityHazardNone
+	Hazards []string `json:"hazards,omitempty"`
+	// Human-readable summary of the accessibility of the EPUB
+	Summary string `json:"summary,omitempty"`
+	// Accessibility specification the EPUB conforms to, e.g.
+	// A11yConformanceEPUB11WCAG21AA. URLs (EPUB Accessibility 1.0) are
+	// written as links, other values as metas.
+	ConformsTo string `json:"conformsTo,omitempty"`
+	// Party that evaluated the conformance of the EPUB
+	CertifiedBy string `json:"certifiedBy,omitempty"`
+}
+
+// Accessibility returns the accessibility metadata of the EPUB, see
+// SetAccessibility.
+func (e *Epub) Accessibility() AccessibilityMetadata {
+	return e.a11y
+}
+
+// SetAccessibility sets the accessibility metadata of the EPUB, replacing any
+// previous ones. They are written to the package file as schema.org metas
+// (schema:accessMode, schema:accessModeSufficient,
+// schema:accessibilityFeature, schema:accessibilityHazard and
+// schema:accessibilitySummary, one meta per value), and the conformance to
+// the EPUB Accessibility specification as a dcterms:conformsTo link or meta,
+// refined by a11y:certifiedBy. Empty fields are left out.
+func (e *Epub) SetAccessibility(a11y AccessibilityMetadata) {
+	e.Lock()
+	defer e.Unlock()
+	a11y.Summary = e.sanitize("", "accessibility summary", a11y.Summary)
+	a11y.CertifiedBy = e.sanitize("", "certifier", a11y.CertifiedBy)
+	e.a11y = a11y
+	e.pkg.setAccessibility(a11y)
+}
+
+func (p *pkg) setAccessibility(a11y AccessibilityMetadata) {
+	p.setMetas(pkgAccessModeProperty, a11y.AccessModes)
+	p.setMetas(pkgAccessModeSufficientProperty, a11y.AccessModesSufficient)
+	p.setMetas(pkgAccessibilityFeatureProperty, a11y.Features)
+	p.setMetas(pkgAccessibilityHazardProperty, a11y.Hazards)
+	p.setMetas(pkgAccessibilitySummaryProperty, nonEmpty(a11y.Summary))
+
+	var conformsTo []string
+	var links []pkgLink
+	if isURL(a11y.ConformsTo) {
+		links = append(links, pkgLink{Rel: pkgConformsToProperty, Href: a11y.ConformsTo})
+	} else {
+		conformsTo = nonEmpty(a11y.ConformsTo)
+	}
+	p.setMetas(pkgConformsToProperty, conformsTo)
+	p.setLinks(pkgConformsToProperty, links)
+	p.setMetas(pkgCertifiedByProperty, nonEmpty(a11y.CertifiedBy))
+}
+
+// Return a slice holding s, or nil if s is empty
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// Report whether s is an absolute http or https URL
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// Return the accessibility metadata of a package file
+func readAccessibility(p *readPackage) AccessibilityMetadata {
+	var a11y AccessibilityMetadata
+	for _, m := range p.Metadata.Metas {
+		if m.Refines != "" {
+			continue
+		}
+		value := strings.TrimSpace(m.Value)
+		switch m.Property {
+		case pkgAccessModeProperty:
+			a11y.AccessModes = append(a11y.AccessModes, value)
+		case pkgAccessModeSufficientProperty:
+			a11y.AccessModesSufficient = append(a11y.AccessModesSufficient, value)
+		case pkgAccessibilityFeatureProperty:
+			a11y.Features = append(a11y.Features, value)
+		case pkgAccessibilityHazardProperty:
+			a11y.Hazards = append(a11y.Hazards, value)
+		case pkgAccessibilitySummaryProperty:
+			a11y.Summary = value
+		case pkgConformsToProperty:
+			a11y.ConformsTo = value
+		case pkgCertifiedByProperty:
+			a11y.CertifiedBy = value
+		}
+	}
+	for _, l := range p.Metadata.Links {
+		if l.Rel == pkgConformsToProperty {
+			a11y.ConformsTo = strings.TrimSpace(l.Href)
+		}
+	}
+	return a11y
+}
@@ -0,0 +1,72 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetAccessibility(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	a11y := AccessibilityMetadata{
+		AccessModes:           []string{AccessModeTextual, AccessModeVisual},
+		AccessModesSufficient: []string{"textual"},
+		Features:              []string{AccessibilityFeatureAlternativeText, AccessibilityFeatureTableOfContents},
+		Hazards:               []string{AccessibilityHazardNone},
+		Summary:               "All images have text alternatives.",
+		ConformsTo:            A11yConformanceEPUB10WCAG20AA,
+		CertifiedBy:           "Example Press",
+	}
+	e.SetAccessibility(AccessibilityMetadata{Summary: "Replaced"})
+	e.SetAccessibility(a11y)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`<meta property="schema:accessMode">textual</meta>`,
+		`<meta property="schema:accessMode">visual</meta>`,
+		`<meta property="schema:accessModeSufficient">textual</meta>`,
+		`<meta property="schema:accessibilityFeature">alternativeText</meta>`,
+		`<meta property="schema:accessibilityFeature">tableOfContents</meta>`,
+		`<meta property="schema:accessibilityHazard">none</meta>`,
+		`<meta property="schema:accessibilitySummary">All images have text alternatives.</meta>`,
+		`<meta property="a11y:certifiedBy">Example Press</meta>`,
+		`<link rel="dcterms:conformsTo" href="http://www.idpf.org/epub/a11y/accessibility-20170105.html#wcag-aa"></link>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+	if strings.Contains(opf, "Replaced") {
+		t.Errorf("Expected the previous metadata to be replaced, got:\n%s", opf)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := opened.Accessibility(); !reflect.DeepEqual(got, a11y) {
+		t.Errorf("Expected the metadata to be read back, got %+v", got)
+	}
+
+	// EPUB Accessibility 1.1 conformance is a meta
+	e.SetAccessibility(AccessibilityMetadata{ConformsTo: A11yConformanceEPUB11WCAG21AA})
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf = readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if !strings.Contains(opf, `<meta property="dcterms:conformsTo">EPUB Accessibility 1.1 - WCAG 2.1 Level AA</meta>`) || strings.Contains(opf, "<link") {
+		t.Errorf("Unexpected conformance metadata:\n%s", opf)
+	}
+}
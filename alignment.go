@@ -0,0 +1,141 @@
+package epub
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const (
+	smilHeader   = `<smil xmlns="http://www.w3.org/ns/SMIL" xmlns:epub="http://www.idpf.org/2007/ops" version="3.0">`
+	smilFileExt  = ".smil"
+	smilSeqIDFmt = "seq%d"
+	smilParIDFmt = "par%d"
+)
+
+// AlignmentFragment is a fragment of a section (identified by the id of an
+// element of the section) aligned with a clip of an audio file.
+type AlignmentFragment struct {
+	// ID of the element of the section, without the leading #
+	ID    string
+	Begin time.Duration
+	End   time.Duration
+}
+
+// ParseAeneasAlignment parses the JSON sync map produced by aeneas
+// (https://www.readbeyond.it/aeneas/) and returns its fragments in order.
+// Nested fragments are flattened.
+func ParseAeneasAlignment(r io.Reader) ([]AlignmentFragment, error) {
+	type aeneasFragment struct {
+		ID       string           `json:"id"`
+		Begin    string           `json:"begin"`
+		End      string           `json:"end"`
+		Children []aeneasFragment `json:"children"`
+	}
+	var syncMap struct {
+		Fragments []aeneasFragment `json:"fragments"`
+	}
+	if err := json.NewDecoder(r).Decode(&syncMap); err != nil {
+		return nil, fmt.Errorf("unable to parse aeneas sync map: %w", err)
+	}
+
+	var fragments []AlignmentFragment
+	var flatten func([]aeneasFragment) error
+	flatten = func(afs []aeneasFragment) error {
+		for _, af := range afs {
+			if len(af.Children) > 0 {
+				if err := flatten(af.Children); err != nil {
+					return err
+				}
+				continue
+			}
+			begin, err := parseClockValue(af.Begin)
+			if err != nil {
+				return fmt.Errorf("fragment %s: %w", af.ID, err)
+			}
+			end, err := parseClockValue(af.End)
+			if err != nil {
+				return fmt.Errorf("fragment %s: %w", af.ID, err)
+			}
+			fragments = append(fragments, AlignmentFragment{
+				ID:    af.ID,
+				Begin: begin,
+				End:   end,
+			})
+		}
+		return nil
+	}
+	if err := flatten(syncMap.Fragments); err != nil {
+		return nil, err
+	}
+	return fragments, nil
+}
+
+// AddMediaOverlayFromAlignment generates a SMIL media overlay from alignment
+// data and links it to an existing section, like AddMediaOverlay. It returns a
+// relative path to the overlay file.
+//
+// The internal path to an already-added audio file (as returned by AddAudio)
+// is required. Each fragment must reference the id of an element of the
+// section.
+//
+// The internal filename is optional; if no filename is provided, one will be
+// derived from the section filename.
+func (e *Epub) AddMediaOverlayFromAlignment(sectionFilename string, internalAudioPath string, fragments []AlignmentFragment, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	if len(fragments) == 0 {
+		return "", fmt.Errorf("can't generate a media overlay without fragments")
+	}
+	if _, ok := e.audios[path.Base(internalAudioPath)]; !ok {
+		return "", fmt.Errorf("audio file %s has not been added", internalAudioPath)
+	}
+	if internalFilename == "" {
+		internalFilename = strings.TrimSuffix(sectionFilename, path.Ext(sectionFilename)) + smilFileExt
+		if _, ok := e.overlays[internalFilename]; ok {
+			internalFilename = fmt.Sprintf(mediaOverlayFileFormat, len(e.overlays)+1, smilFileExt)
+		}
+	}
+
+	smil, err := alignmentToSmil(sectionFilename, internalAudioPath, fragments)
+	if err != nil {
+		return "", err
+	}
+	return e.addMediaOverlay(dataurl.EncodeBytes(smil), sectionFilename, internalFilename)
+}
+
+// Generate the SMIL document. Overlays are stored in their own folder, so the
+// section and audio paths are relative to it.
+func alignmentToSmil(sectionFilename string, internalAudioPath string, fragments []AlignmentFragment) ([]byte, error) {
+	textRef := path.Join("..", xhtmlFolderName, sectionFilename)
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(smilHeader + "\n  <body>\n")
+	fmt.Fprintf(&b, "    <seq id=\"%s\" epub:textref=\"%s\">\n", fmt.Sprintf(smilSeqIDFmt, 1), html.EscapeString(textRef))
+	for i, f := range fragments {
+		if f.ID == "" {
+			return nil, fmt.Errorf("fragment %d has no id", i+1)
+		}
+		if f.End < f.Begin {
+			return nil, fmt.Errorf("fragment %s ends before it begins", f.ID)
+		}
+		fmt.Fprintf(&b, "      <par id=\"%s\">\n", fmt.Sprintf(smilParIDFmt, i+1))
+		fmt.Fprintf(&b, "        <text src=\"%s#%s\"/>\n", html.EscapeString(textRef), html.EscapeString(f.ID))
+		fmt.Fprintf(&b, "        <audio src=\"%s\" clipBegin=\"%s\" clipEnd=\"%s\"/>\n",
+			html.EscapeString(internalAudioPath),
+			formatClockValue(f.Begin),
+			formatClockValue(f.End))
+		b.WriteString("      </par>\n")
+	}
+	b.WriteString("    </seq>\n  </body>\n</smil>\n")
+	return []byte(b.String()), nil
+}
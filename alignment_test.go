@@ -0,0 +1,88 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const testAeneasSyncMap = `{
+  "fragments": [
+    {"begin": "0.000", "end": "1.520", "id": "f000001", "language": "eng", "lines": ["One"]},
+    {"begin": "1.520", "end": "3.000", "id": "f000002", "language": "eng", "lines": ["Two"]},
+    {"begin": "3.000", "end": "4.250", "id": "parent", "children": [
+      {"begin": "3.000", "end": "4.250", "id": "f000003", "lines": ["Three"]}
+    ]}
+  ]
+}`
+
+func TestParseAeneasAlignment(t *testing.T) {
+	fragments, err := ParseAeneasAlignment(strings.NewReader(testAeneasSyncMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []AlignmentFragment{
+		{ID: "f000001", Begin: 0, End: 1520 * time.Millisecond},
+		{ID: "f000002", Begin: 1520 * time.Millisecond, End: 3 * time.Second},
+		{ID: "f000003", Begin: 3 * time.Second, End: 4250 * time.Millisecond},
+	}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+	for i := range expected {
+		if fragments[i] != expected[i] {
+			t.Errorf("Expected fragment %+v, got %+v", expected[i], fragments[i])
+		}
+	}
+}
+
+func TestAddMediaOverlayFromAlignment(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(`<p id="f000001">One</p><p id="f000002">Two</p>`, testSectionTitle, "chapter1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioPath, err := e.AddAudio(testAudioFromFileSource, testAudioFromFileFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments := []AlignmentFragment{
+		{ID: "f000001", Begin: 0, End: 1520 * time.Millisecond},
+		{ID: "f000002", Begin: 1520 * time.Millisecond, End: 3 * time.Second},
+	}
+
+	if _, err := e.AddMediaOverlayFromAlignment(sectionPath, "../audios/missing.mp3", fragments, ""); err == nil {
+		t.Error("Expected an error for an audio file that hasn't been added")
+	}
+	overlayPath, err := e.AddMediaOverlayFromAlignment(sectionPath, audioPath, fragments, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overlayPath != "../overlays/chapter1.smil" {
+		t.Errorf("Unexpected overlay path %s", overlayPath)
+	}
+
+	data, err := dataurl.DecodeString(e.overlays["chapter1.smil"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<seq id="seq1" epub:textref="../xhtml/chapter1.xhtml">`,
+		`<text src="../xhtml/chapter1.xhtml#f000002"/>`,
+		`<audio src="../audios/sample_audio.wav" clipBegin="0:00:01.520" clipEnd="0:00:03.000"/>`,
+	} {
+		if !strings.Contains(string(data.Data), expected) {
+			t.Errorf("Overlay doesn't contain %s\nGot: %s", expected, data.Data)
+		}
+	}
+
+	var b strings.Builder
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	appleBooksDisplayOptionsFilename = "com.apple.ibooks.display-options.xml"
+	appleBooksPrefix                 = "ibooks"
+	appleBooksPrefixURI              = "http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/"
+	pkgAppleBooksSpecifiedFonts      = "ibooks:specified-fonts"
+	pkgAppleBooksVersion             = "ibooks:version"
+	pkgAppleBooksScrollAxis          = "ibooks:scroll-axis"
+)
+
+// Scroll axes of Apple Books, see AppleBooksOptions
+const (
+	ScrollAxisDefault    = "default"
+	ScrollAxisVertical   = "vertical"
+	ScrollAxisHorizontal = "horizontal"
+)
+
+// AppleBooksOptions are the display options and metadata specific to Apple
+// Books, see SetAppleBooksOptions.
+type AppleBooksOptions struct {
+	// Use the fonts embedded in the EPUB rather than the system fonts, which
+	// Apple Books uses otherwise
+	SpecifiedFonts bool
+	// Version of the EPUB shown by Apple Books, e.g. "1.2"
+	Version string
+	// Direction of scrolling when the reader chooses the scrolling view:
+	// ScrollAxisVertical, ScrollAxisHorizontal or ScrollAxisDefault (empty is
+	// the same)
+	ScrollAxis string
+}
+
+// The META-INF/com.apple.ibooks.display-options.xml file
+type appleBooksDisplayOptions struct {
+	XMLName  xml.Name `xml:"display_options"`
+	Platform struct {
+		Name    string             `xml:"name,attr"`
+		Options []appleBooksOption `xml:"option"`
+	} `xml:"platform"`
+}
+
+// <option> elements of the Apple Books display options
+// Ex: <option name="specified-fonts">true</option>
+type appleBooksOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// AppleBooksOptions returns the Apple Books options of the EPUB, or nil if
+// none were set, see SetAppleBooksOptions.
+func (e *Epub) AppleBooksOptions() *AppleBooksOptions {
+	if e.appleBooks == nil {
+		return nil
+	}
+	opts := *e.appleBooks
+	return &opts
+}
+
+// SetAppleBooksOptions sets the display options and metadata of the EPUB
+// specific to Apple Books. When the EPUB is written, the options are written
+// to META-INF/com.apple.ibooks.display-options.xml and as ibooks: metas of
+// the package file (the ibooks prefix being declared). Apple Books renders
+// EPUBs with its system fonts unless SpecifiedFonts is set, even if the EPUB
+// embeds fonts. Setting nil options removes them.
+func (e *Epub) SetAppleBooksOptions(opts *AppleBooksOptions) error {
+	e.Lock()
+	defer e.Unlock()
+	if opts != nil {
+		switch opts.ScrollAxis {
+		case "", ScrollAxisDefault, ScrollAxisVertical, ScrollAxisHorizontal:
+		default:
+			return fmt.Errorf("invalid Apple Books scroll axis %q", opts.ScrollAxis)
+		}
+		copied := *opts
+		copied.Version = e.sanitize("", "version", copied.Version)
+		opts = &copied
+		if _, ok := e.prefixes[appleBooksPrefix]; !ok {
+			if e.prefixes == nil {
+				e.prefixes = make(map[string]string)
+			}
+			e.prefixes[appleBooksPrefix] = appleBooksPrefixURI
+			e.pkg.setPrefixes(e.prefixes)
+		}
+	}
+	e.appleBooks = opts
+	e.pkg.setAppleBooksMetas(opts)
+	return nil
+}
+
+// Set the ibooks: metas of the package file
+func (p *pkg) setAppleBooksMetas(opts *AppleBooksOptions) {
+	if opts == nil {
+		opts = &AppleBooksOptions{}
+	}
+	specifiedFonts := ""
+	if opts.SpecifiedFonts {
+		specifiedFonts = "true"
+	}
+	p.setRefinedMetas(pkgAppleBooksSpecifiedFonts, specifiedFonts, nil)
+	p.setRefinedMetas(pkgAppleBooksVersion, opts.Version, nil)
+	scrollAxis := opts.ScrollAxis
+	if scrollAxis == ScrollAxisDefault {
+		scrollAxis = ""
+	}
+	p.setRefinedMetas(pkgAppleBooksScrollAxis, scrollAxis, nil)
+}
+
+// Write the Apple Books display options file, if the EPUB has Apple Books
+// options
+func (e *Epub) writeAppleBooksDisplayOptions(a *archive) error {
+	if e.appleBooks == nil {
+		return nil
+	}
+	var options appleBooksDisplayOptions
+	options.Platform.Name = "*"
+	options.Platform.Options = []appleBooksOption{
+		{Name: "specified-fonts", Value: strconv.FormatBool(e.appleBooks.SpecifiedFonts)},
+	}
+	output, err := xml.MarshalIndent(options, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error marshalling Apple Books display options: %w", err)
+	}
+	output = append([]byte(xml.Header), output...)
+	if err := a.add(path.Join(metaInfFolderName, appleBooksDisplayOptionsFilename), output); err != nil {
+		return fmt.Errorf("Error writing Apple Books display options: %w", err)
+	}
+	return nil
+}
+
+// Return the Apple Books options of a package file, or nil if it has none
+func readAppleBooksOptions(p *readPackage) *AppleBooksOptions {
+	var opts *AppleBooksOptions
+	for _, m := range p.Metadata.Metas {
+		switch m.Property {
+		case pkgAppleBooksSpecifiedFonts, pkgAppleBooksVersion, pkgAppleBooksScrollAxis:
+		default:
+			continue
+		}
+		if m.Refines != "" {
+			continue
+		}
+		if opts == nil {
+			opts = &AppleBooksOptions{}
+		}
+		value := strings.TrimSpace(m.Value)
+		switch m.Property {
+		case pkgAppleBooksSpecifiedFonts:
+			opts.SpecifiedFonts = value == "true"
+		case pkgAppleBooksVersion:
+			opts.Version = value
+		case pkgAppleBooksScrollAxis:
+			opts.ScrollAxis = value
+		}
+	}
+	return opts
+}
@@ -0,0 +1,65 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetAppleBooksOptions(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetAppleBooksOptions(&AppleBooksOptions{ScrollAxis: "diagonal"}); err == nil {
+		t.Error("Expected an error for an invalid scroll axis")
+	}
+	opts := &AppleBooksOptions{SpecifiedFonts: true, Version: "1.2", ScrollAxis: ScrollAxisVertical}
+	if err := e.SetAppleBooksOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	displayOptions := files["META-INF/com.apple.ibooks.display-options.xml"]
+	if !strings.Contains(displayOptions, `<platform name="*">`) || !strings.Contains(displayOptions, `<option name="specified-fonts">true</option>`) {
+		t.Errorf("Unexpected display options:\n%s", displayOptions)
+	}
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`prefix="ibooks: http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/"`,
+		`<meta property="ibooks:specified-fonts">true</meta>`,
+		`<meta property="ibooks:version">1.2</meta>`,
+		`<meta property="ibooks:scroll-axis">vertical</meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := opened.AppleBooksOptions(); got == nil || *got != *opts {
+		t.Errorf("Expected the options to be read back, got %+v", got)
+	}
+
+	if err := e.SetAppleBooksOptions(nil); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files = readZipFiles(t, b.Bytes())
+	if _, ok := files["META-INF/com.apple.ibooks.display-options.xml"]; ok || strings.Contains(files["EPUB/package.opf"], "ibooks:version") {
+		t.Error("Expected the options to be removed")
+	}
+}
@@ -0,0 +1,203 @@
+package epub
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// archive writes the files of the EPUB straight into the zip container as
+// they are produced, so that nothing is staged on the filesystem. Zip64
+// records are written when the container exceeds the limits of classic zip
+// files (4GB, 65535 entries).
+type archive struct {
+	// Context of the write, which cancels it when done
+	ctx context.Context
+	e   *Epub
+	z   *zip.Writer
+	// Time of the write, see SetReproducible
+	modified time.Time
+	// Paths of the resources encrypted by the resource encrypter
+	encrypted []string
+	// Hashes of the files added to the archive, for the provenance statement
+	hashes provenanceHasher
+	// Size of the files added to the archive, by path in the container
+	sizes map[string]int64
+	// Durations of the WAV files added to the archive, by path relative to
+	// the content folder, for the media overlays
+	wavDurations map[string]time.Duration
+	// Images kept for the chapter thumbnails: the key is the image filename,
+	// the value is its content once added, nil before
+	images map[string][]byte
+	// Content of the CSS files added to the archive, by filename, to check
+	// the use of the fonts
+	css map[string][]byte
+	// Content of the media overlays added to the archive, by filename, to
+	// compute their durations
+	overlays map[string][]byte
+	// XHTML documents of the sections added to the archive, before they are
+	// transformed, by filename, to check their links
+	sections map[string]string
+	// Sections left out of the EPUB because they couldn't be written, in
+	// lenient mode
+	skippedSections map[string]bool
+	// Progress of the write, see SetProgressFunc
+	mediaDone, mediaTotal       int
+	sectionsDone, sectionsTotal int
+}
+
+// Constructor for archive
+func newArchive(ctx context.Context, e *Epub, dst io.Writer) *archive {
+	a := &archive{
+		ctx:             ctx,
+		e:               e,
+		z:               zip.NewWriter(dst),
+		modified:        e.buildTime(),
+		sizes:           make(map[string]int64),
+		wavDurations:    make(map[string]time.Duration),
+		images:          make(map[string][]byte),
+		css:             make(map[string][]byte),
+		sections:        make(map[string]string),
+		skippedSections: make(map[string]bool),
+		mediaTotal:      len(e.css) + len(e.fonts) + len(e.images) + len(e.videos) + len(e.audios) + len(e.overlays) + len(e.scripts) + len(e.fontLicenses) + len(e.captions) + len(e.files),
+	}
+	e.compression.register(a.z)
+	if e.provenanceKey != nil {
+		a.hashes = provenanceHasher{}
+	}
+	return a
+}
+
+// Add a file to the archive. The name is the path of the file in the
+// container.
+func (a *archive) add(name string, data []byte) error {
+	return a.addMedia(name, "", data)
+}
+
+// Add a media file to the archive, compressed or not depending on its media
+// type, see SetCompression
+func (a *archive) addMedia(name string, mediaType string, data []byte) error {
+	size := int64(len(data))
+	// Encrypt the file before creating its entry, so that a failure doesn't
+	// leave a partial entry in the archive
+	encrypt := a.e.shouldEncrypt(name)
+	if encrypt {
+		var err error
+		data, err = a.e.encrypter.Encrypt(name, data)
+		if err != nil {
+			return fmt.Errorf("error encrypting file %v being added to EPUB: %w", name, err)
+		}
+	}
+	w, err := a.create(name, mediaType)
+	if err != nil {
+		return err
+	}
+	a.sizes[name] = size
+	if encrypt {
+		a.encrypted = append(a.encrypted, name)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error writing file %v being added to EPUB: %w", name, err)
+	}
+	return nil
+}
+
+// Add a media file to the archive from a reader, copying it to the archive
+// without holding it in memory. It can't be encrypted, see addMedia.
+func (a *archive) addMediaStream(name string, mediaType string, r io.Reader) error {
+	w, err := a.create(name, mediaType)
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(w, contextReader{ctx: a.ctx, r: r})
+	if err != nil {
+		return fmt.Errorf("error writing file %v being added to EPUB: %w", name, err)
+	}
+	a.sizes[name] = size
+	return nil
+}
+
+// Create the entry of a file in the archive, compressed or not depending on
+// its media type
+func (a *archive) create(name string, mediaType string) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:   name,
+		Method: a.e.compression.method(mediaType),
+	}
+	if name == mimetypeFilename {
+		// The mimetype file must be uncompressed according to the EPUB spec
+		header.Method = zip.Store
+	}
+	if a.e.reproducible {
+		setModTime(header, a.modified)
+	}
+	w, err := a.z.CreateHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("error creating zip writer: %w", err)
+	}
+	return a.hashes.wrap(name, w), nil
+}
+
+// A reader that stops reading once its context is done, so that the copy of a
+// large file is cancelled with the write
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Report that a media file has been handled, written or left out
+func (a *archive) mediaWritten() {
+	a.mediaDone++
+	a.e.reportProgress(ProgressMedia, a.mediaDone, a.mediaTotal)
+}
+
+// Report that a section has been handled
+func (a *archive) sectionWritten() {
+	a.sectionsDone++
+	a.e.reportProgress(ProgressSections, a.sectionsDone, a.sectionsTotal)
+}
+
+// Set the modification time of a zip entry in the MS-DOS fields of its
+// header only: setting Modified would add an extended timestamp extra field,
+// which the mimetype file must not have
+func setModTime(header *zip.FileHeader, t time.Time) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	header.ModifiedDate = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	header.ModifiedTime = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+}
+
+// Write the encryption and provenance files, if any, and close the archive
+func (a *archive) close() error {
+	if len(a.encrypted) > 0 {
+		if err := writeEncryptionFile(a.z, a.hashes, a.e.encrypter.Algorithm(), a.encrypted); err != nil {
+			a.abort()
+			return err
+		}
+	}
+	if a.hashes != nil {
+		if err := a.hashes.write(a.z, a.e.provenanceKey, a.modified); err != nil {
+			a.abort()
+			return err
+		}
+	}
+	return a.z.Close()
+}
+
+// Close the archive after an error
+func (a *archive) abort() {
+	if err := a.z.Close(); err != nil {
+		a.e.report.add(SeverityWarning, RuleWriteFailure, "", "unable to close the EPUB: %v", err)
+	}
+}
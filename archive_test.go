@@ -0,0 +1,177 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestWriteToStreamsArchive(t *testing.T) {
+	if err := Use(MemoryFS); err != nil {
+		t.Fatal(err)
+	}
+	defer Use(OsFS)
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, testImageFromFileFilename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is staged on the filesystem
+	entries, err := fs.ReadDir(filesystem, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing to be written to the filesystem, got %d entries", len(entries))
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(z.File) == 0 || z.File[0].Name != mimetypeFilename || z.File[0].Method != zip.Store {
+		t.Errorf("Expected the mimetype file to be the first entry, uncompressed")
+	}
+	files := readZipFiles(t, b.Bytes())
+	for _, name := range []string{
+		"META-INF/container.xml",
+		"EPUB/images/" + testImageFromFileFilename,
+		"EPUB/xhtml/section.xhtml",
+		"EPUB/nav.xhtml",
+		"EPUB/package.opf",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the archive", name)
+		}
+	}
+}
+
+func TestArchiveZip64(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes more than 65535 entries")
+	}
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// More entries than the classic end of central directory record can
+	// count, which requires the Zip64 records like archives larger than 4GB
+	const entries = 1 << 16
+	var b bytes.Buffer
+	a := newArchive(context.Background(), e, &b)
+	if err := writeMimetype(a); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		if err := a.add(fmt.Sprintf("EPUB/files/%05d.txt", i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signature of the Zip64 end of central directory record
+	if !bytes.Contains(b.Bytes(), []byte{0x50, 0x4b, 0x06, 0x06}) {
+		t.Error("Expected a Zip64 end of central directory record")
+	}
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(z.File) != entries+1 || z.File[0].Name != mimetypeFilename {
+		t.Errorf("Expected %d entries starting with the mimetype file, got %d", entries+1, len(z.File))
+	}
+}
+
+// Reader of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestArchiveZip64LargeEntry(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams a file larger than 4GB")
+	}
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single file larger than the 4GB a classic header can give, streamed
+	// and compressed so that the archive stays small
+	const size = 1<<32 + 1<<20
+	const filename = "EPUB/audio/large.bin"
+	var b bytes.Buffer
+	a := newArchive(context.Background(), e, &b)
+	if err := writeMimetype(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.addMediaStream(filename, "", io.LimitReader(zeroReader{}, size)); err != nil {
+		t.Fatal(err)
+	}
+	if a.sizes[filename] != size {
+		t.Errorf("Expected a size of %d, got %d", size, a.sizes[filename])
+	}
+	if err := a.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(z.File) != 2 || z.File[1].Name != filename {
+		t.Fatalf("Expected the mimetype file and %s, got %d entries", filename, len(z.File))
+	}
+	f := z.File[1]
+	if f.UncompressedSize64 != size || f.UncompressedSize != 0xffffffff {
+		t.Errorf("Expected the Zip64 size %d, got %d (classic size %#x)", size, f.UncompressedSize64, f.UncompressedSize)
+	}
+	// Reading the whole entry checks its size and checksum
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n, err := io.Copy(io.Discard, r); err != nil || n != size {
+		t.Errorf("Expected to read %d bytes, got %d: %v", size, n, err)
+	}
+}
+
+func TestArchiveMimetypeExtraField(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixed timestamps of the reproducible mode don't add an extra field
+	e.SetReproducible(true)
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := z.File[0]; f.Name != mimetypeFilename || len(f.Extra) != 0 || !f.Modified.Equal(canonicalModTime) {
+		t.Errorf("Expected the mimetype file without extra field, got %q modified %v", f.Extra, f.Modified)
+	}
+}
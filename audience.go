@@ -0,0 +1,211 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interactivity types of educational content, see EducationalMetadata
+const (
+	InteractivityActive     = "active"
+	InteractivityExpositive = "expositive"
+	InteractivityMixed      = "mixed"
+)
+
+// Common alignment types of the educational alignments, see
+// EducationalAlignment
+const (
+	AlignmentTypeAssesses           = "assesses"
+	AlignmentTypeEducationalLevel   = "educationalLevel"
+	AlignmentTypeEducationalSubject = "educationalSubject"
+	AlignmentTypeReadingLevel       = "readingLevel"
+	AlignmentTypeRequires           = "requires"
+	AlignmentTypeTeaches            = "teaches"
+)
+
+const (
+	pkgTypicalAgeRangeProperty      = "schema:typicalAgeRange"
+	pkgAudienceProperty             = "dcterms:audience"
+	pkgEducationalUseProperty       = "schema:educationalUse"
+	pkgLearningResourceTypeProperty = "schema:learningResourceType"
+	pkgInteractivityTypeProperty    = "schema:interactivityType"
+	pkgTimeRequiredProperty         = "schema:timeRequired"
+	pkgEducationalAlignmentProperty = "schema:educationalAlignment"
+	pkgAlignmentTypeProperty        = "schema:alignmentType"
+	pkgEducationalFrameworkProperty = "schema:educationalFramework"
+	pkgTargetURLProperty            = "schema:targetUrl"
+	pkgAlignmentIDFormat            = "alignment-%d"
+)
+
+// EducationalMetadata describes the educational content of the EPUB (e.g. an
+// EDUPUB textbook) with the schema.org learning resource properties, see
+// SetEducationalMetadata.
+type EducationalMetadata struct {
+	// Purposes of the content, e.g. "assignment" or "curriculum/instruction"
+	EducationalUses []string `json:"educationalUses,omitempty"`
+	// Kinds of content, e.g. "textbook" or "workbook"
+	LearningResourceTypes []string `json:"learningResourceTypes,omitempty"`
+	// Mode of learning, e.g. InteractivityExpositive
+	InteractivityType string `json:"interactivityType,omitempty"`
+	// Time it takes to work through the content, as an ISO 8601 duration,
+	// e.g. PT30M
+	TimeRequired string                 `json:"timeRequired,omitempty"`
+	Alignments   []EducationalAlignment `json:"alignments,omitempty"`
+}
+
+// EducationalAlignment aligns the EPUB to a node of an established
+// educational framework, e.g. a grade level or a curriculum standard.
+type EducationalAlignment struct {
+	// Name of the node of the framework, e.g. "Grade 5"
+	TargetName string `json:"targetName"`
+	// Kind of alignment, e.g. AlignmentTypeEducationalLevel
+	AlignmentType string `json:"alignmentType,omitempty"`
+	// Framework the node belongs to, e.g. "Common Core State Standards"
+	EducationalFramework string `json:"educationalFramework,omitempty"`
+	// URL of the node of the framework
+	TargetURL string `json:"targetUrl,omitempty"`
+}
+
+// TypicalAgeRange returns the typical age range of the readers of the EPUB,
+// see SetTypicalAgeRange.
+func (e *Epub) TypicalAgeRange() string {
+	return e.typicalAgeRange
+}
+
+// SetTypicalAgeRange sets the typical age range of the readers of the EPUB
+// (schema:typicalAgeRange), which stores use for the age gating of children's
+// books, e.g. "8-12" for 8 to 12 years old or "14-" for 14 and older. An empty
+// range removes it.
+func (e *Epub) SetTypicalAgeRange(ageRange string) {
+	e.Lock()
+	defer e.Unlock()
+	e.typicalAgeRange = e.sanitize("", "typical age range", ageRange)
+	e.pkg.setMetas(pkgTypicalAgeRangeProperty, nonEmpty(e.typicalAgeRange))
+}
+
+// Audience returns the audiences of the EPUB, see SetAudience.
+func (e *Epub) Audience() []string {
+	return append([]string(nil), e.audiences...)
+}
+
+// SetAudience sets the audiences the EPUB is intended for (dcterms:audience),
+// e.g. "children", "young adult", or "teacher" and "student" for educational
+// content, replacing any previous ones. Setting no audience removes them.
+func (e *Epub) SetAudience(audiences ...string) {
+	e.Lock()
+	defer e.Unlock()
+	e.audiences = nil
+	for _, a := range audiences {
+		if a = e.sanitize("", "audience", a); a != "" {
+			e.audiences = append(e.audiences, a)
+		}
+	}
+	e.pkg.setMetas(pkgAudienceProperty, e.audiences)
+}
+
+// EducationalMetadata returns the educational metadata of the EPUB, see
+// SetEducationalMetadata.
+func (e *Epub) EducationalMetadata() EducationalMetadata {
+	return e.education
+}
+
+// SetEducationalMetadata sets the educational metadata of the EPUB, replacing
+// any previous ones, as EDUPUB titles require. They are written to the
+// package file as schema.org metas (schema:educationalUse,
+// schema:learningResourceType, schema:interactivityType and
+// schema:timeRequired), and each alignment as a schema:educationalAlignment
+// meta holding the target name, refined by its alignment type, framework and
+// target URL. Empty fields are left out.
+func (e *Epub) SetEducationalMetadata(m EducationalMetadata) {
+	e.Lock()
+	defer e.Unlock()
+	for i := range m.Alignments {
+		m.Alignments[i].TargetName = e.sanitize("", "educational alignment", m.Alignments[i].TargetName)
+	}
+	e.education = m
+	e.pkg.setEducationalMetadata(m)
+}
+
+func (p *pkg) setEducationalMetadata(m EducationalMetadata) {
+	p.setMetas(pkgEducationalUseProperty, m.EducationalUses)
+	p.setMetas(pkgLearningResourceTypeProperty, m.LearningResourceTypes)
+	p.setMetas(pkgInteractivityTypeProperty, nonEmpty(m.InteractivityType))
+	p.setMetas(pkgTimeRequiredProperty, nonEmpty(m.TimeRequired))
+
+	metas := p.xml.Metadata.Meta[:0]
+	for _, meta := range p.xml.Metadata.Meta {
+		if meta.Property != pkgEducationalAlignmentProperty && !strings.HasPrefix(meta.Refines, "#alignment-") {
+			metas = append(metas, meta)
+		}
+	}
+	for i, a := range m.Alignments {
+		id := fmt.Sprintf(pkgAlignmentIDFormat, i+1)
+		metas = append(metas, pkgMeta{ID: id, Property: pkgEducationalAlignmentProperty, Data: a.TargetName})
+		for _, refinement := range []struct{ property, value string }{
+			{pkgAlignmentTypeProperty, a.AlignmentType},
+			{pkgEducationalFrameworkProperty, a.EducationalFramework},
+			{pkgTargetURLProperty, a.TargetURL},
+		} {
+			if refinement.value != "" {
+				metas = append(metas, pkgMeta{Refines: "#" + id, Property: refinement.property, Data: refinement.value})
+			}
+		}
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Set the audience metadata of a freshly created Epub from the package file
+func (e *Epub) readAudience(p *readPackage) {
+	var audiences []string
+	var education EducationalMetadata
+	var alignments []*EducationalAlignment
+	alignmentIDs := make(map[string]*EducationalAlignment)
+	for _, m := range p.Metadata.Metas {
+		if m.Refines != "" {
+			continue
+		}
+		value := strings.TrimSpace(m.Value)
+		switch m.Property {
+		case pkgTypicalAgeRangeProperty:
+			e.SetTypicalAgeRange(value)
+		case pkgAudienceProperty:
+			audiences = append(audiences, value)
+		case pkgEducationalUseProperty:
+			education.EducationalUses = append(education.EducationalUses, value)
+		case pkgLearningResourceTypeProperty:
+			education.LearningResourceTypes = append(education.LearningResourceTypes, value)
+		case pkgInteractivityTypeProperty:
+			education.InteractivityType = value
+		case pkgTimeRequiredProperty:
+			education.TimeRequired = value
+		case pkgEducationalAlignmentProperty:
+			a := &EducationalAlignment{TargetName: value}
+			alignments = append(alignments, a)
+			if m.ID != "" {
+				alignmentIDs[m.ID] = a
+			}
+		}
+	}
+	for _, m := range p.Metadata.Metas {
+		a, ok := alignmentIDs[strings.TrimPrefix(m.Refines, "#")]
+		if !ok || m.Refines == "" {
+			continue
+		}
+		value := strings.TrimSpace(m.Value)
+		switch m.Property {
+		case pkgAlignmentTypeProperty:
+			a.AlignmentType = value
+		case pkgEducationalFrameworkProperty:
+			a.EducationalFramework = value
+		case pkgTargetURLProperty:
+			a.TargetURL = value
+		}
+	}
+	for _, a := range alignments {
+		education.Alignments = append(education.Alignments, *a)
+	}
+	if len(audiences) > 0 {
+		e.SetAudience(audiences...)
+	}
+	e.SetEducationalMetadata(education)
+}
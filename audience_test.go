@@ -0,0 +1,78 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAudienceMetadata(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetTypicalAgeRange("8-12")
+	e.SetAudience("children", "teacher")
+	education := EducationalMetadata{
+		EducationalUses:       []string{"curriculum/instruction"},
+		LearningResourceTypes: []string{"textbook"},
+		InteractivityType:     InteractivityExpositive,
+		TimeRequired:          "PT30M",
+		Alignments: []EducationalAlignment{
+			{TargetName: "Grade 5", AlignmentType: AlignmentTypeEducationalLevel, EducationalFramework: "US Grade Levels"},
+			{TargetName: "CCSS.ELA-LITERACY.RL.5.1", AlignmentType: AlignmentTypeTeaches, TargetURL: "http://corestandards.org/ELA-Literacy/RL/5/1/"},
+		},
+	}
+	e.SetEducationalMetadata(education)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, element := range []string{
+		`<meta property="schema:typicalAgeRange">8-12</meta>`,
+		`<meta property="dcterms:audience">children</meta>`,
+		`<meta property="dcterms:audience">teacher</meta>`,
+		`<meta property="schema:educationalUse">curriculum/instruction</meta>`,
+		`<meta property="schema:learningResourceType">textbook</meta>`,
+		`<meta property="schema:interactivityType">expositive</meta>`,
+		`<meta property="schema:timeRequired">PT30M</meta>`,
+		`<meta property="schema:educationalAlignment" id="alignment-1">Grade 5</meta>`,
+		`<meta refines="#alignment-1" property="schema:alignmentType">educationalLevel</meta>`,
+		`<meta refines="#alignment-1" property="schema:educationalFramework">US Grade Levels</meta>`,
+		`<meta refines="#alignment-2" property="schema:targetUrl">http://corestandards.org/ELA-Literacy/RL/5/1/</meta>`,
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.TypicalAgeRange() != "8-12" || !reflect.DeepEqual(opened.Audience(), []string{"children", "teacher"}) {
+		t.Errorf("Audience not read back: %q %v", opened.TypicalAgeRange(), opened.Audience())
+	}
+	if !reflect.DeepEqual(opened.EducationalMetadata(), education) {
+		t.Errorf("Educational metadata not read back: %+v", opened.EducationalMetadata())
+	}
+
+	// Replacing the metadata removes the previous metas
+	e.SetAudience()
+	e.SetEducationalMetadata(EducationalMetadata{Alignments: []EducationalAlignment{{TargetName: "Grade 6"}}})
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf = readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if strings.Contains(opf, "dcterms:audience") || strings.Contains(opf, "Grade 5") || strings.Contains(opf, "#alignment-") ||
+		!strings.Contains(opf, `<meta property="schema:educationalAlignment" id="alignment-1">Grade 6</meta>`) {
+		t.Errorf("Expected the previous metadata to be replaced, got:\n%s", opf)
+	}
+}
@@ -0,0 +1,105 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The src attribute of the img elements of a section body, with its value in
+// the second or third group
+var imgSrcRegexp = regexp.MustCompile(`(?i)<img\b[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// SetAutoAddImages sets whether the images referenced by the sections added
+// afterwards, with AddSection, AddSubSection or ReplaceSection, are added to
+// the EPUB automatically: an img element whose src is a remote URL or the path
+// of a local file that wasn't added with AddImage gets the image added and its
+// src rewritten to the internal path. An image referenced several times is
+// added once.
+//
+// Images that can't be added are left as they are and reported as warnings
+// (RuleEmbedImage). Data URLs and internal paths are left as they are.
+func (e *Epub) SetAutoAddImages(enabled bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.autoAddImages = enabled
+}
+
+// Add the images referenced by a section body that aren't in the EPUB yet and
+// return the body with their src rewritten
+func (e *Epub) addInlineImages(sectionFilename string, body string) string {
+	matches := imgSrcRegexp.FindAllStringSubmatchIndex(body, -1)
+	if len(matches) == 0 {
+		return body
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[4], m[5]
+		}
+		source := html.UnescapeString(body[start:end])
+		internalPath, ok := e.addInlineImage(sectionFilename, source)
+		if !ok {
+			continue
+		}
+		b.WriteString(body[last:start])
+		b.WriteString(html.EscapeString(internalPath))
+		last = end
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// Add an image referenced by a section and return its internal path, or false
+// if it must be left as is
+func (e *Epub) addInlineImage(sectionFilename string, source string) (string, bool) {
+	if internalPath, ok := e.autoImages[source]; ok {
+		return internalPath, true
+	}
+	// The image may have been added with AddImage
+	for filename, imageSource := range e.images {
+		if imageSource == source {
+			return path.Join("..", ImageFolderName, filename), true
+		}
+	}
+	var filename string
+	switch detectMediaType(source) {
+	case "DataURL":
+		return "", false
+	case "URL":
+		u, err := url.Parse(source)
+		if err != nil {
+			e.warnings.add(SeverityWarning, RuleEmbedImage, sectionFilename, "can't parse image URL: %s", err)
+			return "", false
+		}
+		filename = path.Base(u.Path)
+	default:
+		// Internal paths and paths that aren't local files
+		if strings.HasPrefix(source, "../") {
+			return "", false
+		}
+		if info, err := os.Stat(source); err != nil || info.IsDir() {
+			return "", false
+		}
+		filename = path.Base(strings.ReplaceAll(source, "\\", "/"))
+	}
+	if _, used := e.images[filename]; used || ValidateFilename(filename) != nil {
+		filename = fmt.Sprintf(imageFileFormat, len(e.images)+1, strings.ToLower(path.Ext(filename)))
+	}
+	internalPath, err := addMedia(e.grabber(), source, filename, imageFileFormat, ImageFolderName, e.images)
+	if err != nil {
+		e.warnings.add(SeverityWarning, RuleEmbedImage, sectionFilename, "can't add image to the epub: %s", err)
+		return "", false
+	}
+	if e.autoImages == nil {
+		e.autoImages = make(map[string]string)
+	}
+	e.autoImages[source] = internalPath
+	return internalPath, true
+}
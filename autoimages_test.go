@@ -0,0 +1,63 @@
+package epub
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetAutoAddImages(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAutoAddImages(true)
+	remote := server.URL + "/gophercolor16x16.png?size=small&amp;v=1"
+	body := `<p><img src="` + testImageFromFileSource + `" alt="local"/><img alt="remote" src='` + remote + `'/></p>` +
+		`<p><img src="` + testImageFromFileSource + `" alt="again"/><img src="missing.png" alt="missing"/><img src="../images/other.png" alt="internal"/></p>`
+	if _, err := e.AddSection(body, testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.images) != 2 {
+		t.Fatalf("Expected 2 images to be added, got %v", e.images)
+	}
+	section := e.sections[0].xhtml.xml.Body.XML
+	for _, s := range []string{
+		`<img src="../images/gophercolor16x16.png" alt="local"/>`,
+		`<img alt="remote" src='../images/image0002.png'/>`,
+		`<img src="../images/gophercolor16x16.png" alt="again"/>`,
+		`<img src="missing.png" alt="missing"/>`,
+		`<img src="../images/other.png" alt="internal"/>`,
+	} {
+		if !strings.Contains(section, s) {
+			t.Errorf("Expected the section to contain %s, got:\n%s", s, section)
+		}
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if _, ok := files["EPUB/images/image0002.png"]; !ok {
+		t.Error("Expected the remote image in the EPUB")
+	}
+
+	// Disabled by default
+	e2, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e2.AddSection(body, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(e2.images) != 0 {
+		t.Errorf("Expected no image to be added, got %v", e2.images)
+	}
+}
@@ -0,0 +1,135 @@
+package epub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// BatchSpec describes an EPUB built by a Batch.
+type BatchSpec struct {
+	// Title of the EPUB, see NewEpub
+	Title string
+	// Build adds the content of the EPUB (sections, media, metadata, etc.).
+	// The EPUB already uses the media cache and the fetch limiter of the
+	// batch.
+	Build func(e *Epub) error
+	// Writer the EPUB is written to, see WriteTo
+	Dest io.Writer
+	// Path of the EPUB file to write, see FileDestination: a failed write
+	// leaves no partial EPUB at this path. Ignored if Dest is set.
+	DestFilePath string
+}
+
+// BatchResult is the outcome of building a BatchSpec.
+type BatchResult struct {
+	// Index of the spec in the specs given to Run
+	Index int
+	// Non-fatal issues found while writing the EPUB, see Warnings
+	Warnings []ValidationIssue
+	// Error returned by Build or while writing the EPUB, nil on success
+	Err error
+}
+
+// Batch builds many EPUBs concurrently, e.g. for a service that renders
+// hundreds of books per hour. The EPUBs of a batch share a media cache, so
+// that the fonts, CSS and images used by several books are only retrieved
+// once, and an optional fetch limiter.
+//
+// The concurrency budget is global to the batch: concurrent calls to Run
+// share it. A Batch is safe for concurrent use.
+type Batch struct {
+	cache   *MediaCache
+	limiter *FetchLimiter
+	// Slots of the EPUBs being built
+	slots chan struct{}
+}
+
+// NewBatch returns a Batch that builds at most concurrency EPUBs at the same
+// time, with a new MediaCache. A concurrency of 0 or less uses the number of
+// CPUs available.
+func NewBatch(concurrency int) *Batch {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return &Batch{
+		cache: NewMediaCache(),
+		slots: make(chan struct{}, concurrency),
+	}
+}
+
+// MediaCache returns the media cache shared by the EPUBs of the batch.
+func (b *Batch) MediaCache() *MediaCache {
+	return b.cache
+}
+
+// SetMediaCache sets the media cache shared by the EPUBs of the batch, e.g. a
+// cache shared with other batches. Setting a nil cache disables caching.
+// Unlike the other methods of a Batch, it must not be called while Run is in
+// progress.
+func (b *Batch) SetMediaCache(cache *MediaCache) {
+	b.cache = cache
+}
+
+// SetFetchLimiter sets the limiter of the requests made by the EPUBs of the
+// batch to retrieve remote media sources, see NewFetchLimiter. It must not be
+// called while Run is in progress.
+func (b *Batch) SetFetchLimiter(limiter *FetchLimiter) {
+	b.limiter = limiter
+}
+
+// Run builds and writes the EPUBs of specs, and returns their results in the
+// same order. A failed EPUB doesn't stop the others. When ctx is done, the
+// EPUBs that haven't been started fail with ctx.Err() and the writes in
+// progress are cancelled.
+func (b *Batch) Run(ctx context.Context, specs []BatchSpec) []BatchResult {
+	results := make([]BatchResult, len(specs))
+	var wg sync.WaitGroup
+	for i := range specs {
+		results[i].Index = i
+		select {
+		case b.slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-b.slots }()
+			results[i].Warnings, results[i].Err = b.build(ctx, specs[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// Build and write the EPUB of a spec
+func (b *Batch) build(ctx context.Context, spec BatchSpec) ([]ValidationIssue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if spec.Dest == nil && spec.DestFilePath == "" {
+		return nil, fmt.Errorf("EPUB %q has no destination", spec.Title)
+	}
+	e, err := NewEpub(spec.Title)
+	if err != nil {
+		return nil, err
+	}
+	e.SetMediaCache(b.cache)
+	e.SetFetchLimiter(b.limiter)
+	if spec.Build != nil {
+		if err := spec.Build(e); err != nil {
+			return nil, err
+		}
+	}
+
+	dst := FileDestination(spec.DestFilePath)
+	if spec.Dest != nil {
+		dst = WriterDestination(spec.Dest)
+	}
+	_, err = e.WriteToDestination(ctx, dst)
+	return e.Warnings(), err
+}
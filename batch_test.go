@@ -0,0 +1,152 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchRun(t *testing.T) {
+	var requests int32
+	files := http.FileServer(http.Dir("testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The images are only checked with HEAD requests when they are added
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&requests, 1)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	imageURL := server.URL + "/gophercolor16x16.png"
+
+	b := NewBatch(2)
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	errBuild := errors.New("no content")
+	const books = 6
+	specs := make([]BatchSpec, books)
+	dests := make([]bytes.Buffer, books)
+	for i := range specs {
+		i := i
+		specs[i] = BatchSpec{
+			Title: fmt.Sprintf("Book %d", i),
+			Build: func(e *Epub) error {
+				mu.Lock()
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				mu.Unlock()
+				defer func() {
+					mu.Lock()
+					active--
+					mu.Unlock()
+				}()
+				if i == 3 {
+					return errBuild
+				}
+				imagePath, err := e.AddImage(imageURL, "gopher.png")
+				if err != nil {
+					return err
+				}
+				_, err = e.AddSection(`<p><img src="`+imagePath+`" alt="Gopher"/></p>`, "Chapter", "", "")
+				return err
+			},
+			Dest: &dests[i],
+		}
+	}
+	results := b.Run(context.Background(), specs)
+
+	if len(results) != books {
+		t.Fatalf("Expected %d results, got %d", books, len(results))
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("Expected result %d to have index %d, got %d", i, i, r.Index)
+		}
+		if i == 3 {
+			if !errors.Is(r.Err, errBuild) {
+				t.Errorf("Expected the build error for book 3, got %v", r.Err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("Unexpected error for book %d: %v", i, r.Err)
+		}
+		if _, ok := readZipFiles(t, dests[i].Bytes())["EPUB/images/gopher.png"]; !ok {
+			t.Errorf("Expected the image in book %d", i)
+		}
+	}
+	if maxActive > 2 {
+		t.Errorf("Expected at most 2 books built at the same time, got %d", maxActive)
+	}
+	// The image is retrieved once for all the books
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected the image to be requested once, got %d requests", n)
+	}
+}
+
+func TestBatchRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var b bytes.Buffer
+	results := NewBatch(1).Run(ctx, []BatchSpec{{Title: "Book", Dest: &b}, {Title: "Other"}})
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Expected book %d to be cancelled, got %v", r.Index, r.Err)
+		}
+	}
+}
+
+func TestBatchRunDestFilePath(t *testing.T) {
+	// The image can be checked but not downloaded, failing the write
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	failed := filepath.Join(dir, "failed.epub")
+	if err := os.WriteFile(failed, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	written := filepath.Join(dir, "written.epub")
+	results := NewBatch(2).Run(context.Background(), []BatchSpec{
+		{
+			Title:        "Failed",
+			DestFilePath: failed,
+			Build: func(e *Epub) error {
+				_, err := e.AddImage(server.URL+"/gopher.png", "gopher.png")
+				return err
+			},
+		},
+		{Title: "Written", DestFilePath: written},
+	})
+	if results[0].Err == nil {
+		t.Error("Expected the write of the first book to fail")
+	}
+	if results[1].Err != nil {
+		t.Errorf("Unexpected error: %v", results[1].Err)
+	}
+	if data, err := os.ReadFile(failed); err != nil || string(data) != "previous" {
+		t.Errorf("Expected the failed write to leave the previous file, got %d bytes (%v)", len(data), err)
+	}
+	if data, err := os.ReadFile(written); err != nil {
+		t.Error(err)
+	} else {
+		readZipFiles(t, data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files to be left, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,115 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const (
+	breakHintsCSSFilename = "break-hints.css"
+	// Class of the wrappers of the blocks that shouldn't be split across pages
+	breakHintsAvoidClass = "epub-break-avoid"
+	breakHintsCSSContent = `h1 {
+  page-break-before: always;
+  break-before: page;
+}
+h1, h2, h3, h4, h5, h6 {
+  page-break-after: avoid;
+  break-after: avoid;
+}
+p {
+  widows: 2;
+  orphans: 2;
+}
+.epub-break-avoid, figure, table, pre {
+  page-break-inside: avoid;
+  break-inside: avoid;
+}
+`
+)
+
+// Elements wrapped so that they aren't split across pages
+var breakHintsWrappedElements = map[string]bool{
+	"figure": true,
+	"pre":    true,
+	"table":  true,
+}
+
+// SetBreakHints enables or disables the break hints of the sections: a
+// generated utility stylesheet, linked before the stylesheet of each section,
+// avoids widows and orphans, page breaks right after headings and inside
+// figures, tables and code blocks, and starts the chapter headings (h1) on a
+// new page. Since some reading systems ignore page-break-inside on tables, the
+// figures, tables and code blocks are also wrapped in a div with the
+// epub-break-avoid class when the EPUB is written. Raw documents (e.g. a
+// custom cover page) are written as is.
+func (e *Epub) SetBreakHints(enabled bool) error {
+	e.Lock()
+	defer e.Unlock()
+	if enabled && e.breakHintsCSSPath == "" {
+		source := dataurl.EncodeBytes([]byte(breakHintsCSSContent))
+		cssPath, err := addMedia(e.generatedGrabber(), source, breakHintsCSSFilename, cssFileFormat, CSSFolderName, e.css)
+		if _, ok := err.(*FilenameAlreadyUsedError); ok {
+			cssPath, err = addMedia(e.generatedGrabber(), source, fmt.Sprintf(cssFileFormat, len(e.css)+1, ".css"), cssFileFormat, CSSFolderName, e.css)
+		}
+		if err != nil {
+			return fmt.Errorf("Error adding break hints CSS file: %w", err)
+		}
+		e.breakHintsCSSPath = cssPath
+	}
+	e.breakHints = enabled
+	return nil
+}
+
+// Link the break hints stylesheet to a section document, before its own
+// stylesheet
+func (e *Epub) linkBreakHints(root *xhtmlRoot) {
+	root.Head.Links = append([]xhtmlLink{{
+		Rel:  xhtmlLinkRel,
+		Type: mediaTypeCSS,
+		Href: e.breakHintsCSSPath,
+	}}, root.Head.Links...)
+}
+
+// Wrap the figures, tables and code blocks of a section body in a div that
+// avoids page breaks. The rest of the body is kept verbatim.
+func wrapBreakAvoidBlocks(body string) string {
+	const prefix = "<body>"
+	d := xml.NewDecoder(strings.NewReader(prefix + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	last := 0
+	for {
+		start := int(d.InputOffset()) - len(prefix)
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || !breakHintsWrappedElements[strings.ToLower(se.Name.Local)] {
+			continue
+		}
+		// The nested blocks are kept together with the outer one
+		if err := d.Skip(); err != nil {
+			break
+		}
+		end := int(d.InputOffset()) - len(prefix)
+		if end > len(body) {
+			end = len(body)
+		}
+		b.WriteString(body[last:start])
+		b.WriteString(`<div class="` + breakHintsAvoidClass + `">` + body[start:end] + "</div>")
+		last = end
+	}
+	if last == 0 {
+		return body
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
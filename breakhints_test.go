@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrapBreakAvoidBlocks(t *testing.T) {
+	body := `<h1>Title</h1><figure><img src="a.png" alt=""/><figcaption>A</figcaption></figure><p>Text</p><TABLE><tr><td><pre>nested</pre></td></tr></TABLE><pre>code</pre>`
+	expected := `<h1>Title</h1><div class="epub-break-avoid"><figure><img src="a.png" alt=""/><figcaption>A</figcaption></figure></div><p>Text</p>` +
+		`<div class="epub-break-avoid"><TABLE><tr><td><pre>nested</pre></td></tr></TABLE></div><div class="epub-break-avoid"><pre>code</pre></div>`
+	if got := wrapBreakAvoidBlocks(body); got != expected {
+		t.Errorf("Unexpected body:\n%s\nexpected:\n%s", got, expected)
+	}
+	if body := `<p>Nothing to wrap</p>`; wrapBreakAvoidBlocks(body) != body {
+		t.Error("Expected the body to be left as is")
+	}
+}
+
+func TestSetBreakHints(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cssPath, err := e.AddCSS(testCoverCSSSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<h1>Chapter</h1><pre>code</pre>`, "Chapter", "chapter.xhtml", cssPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetBreakHints(true); err != nil {
+		t.Fatal(err)
+	}
+	// Enabling the hints again doesn't add the stylesheet twice
+	if err := e.SetBreakHints(true); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/css/break-hints.css"] != breakHintsCSSContent || len(e.css) != 2 {
+		t.Errorf("Expected the break hints stylesheet in the EPUB, got %v", e.css)
+	}
+	section := files["EPUB/xhtml/chapter.xhtml"]
+	hints := strings.Index(section, `href="../css/break-hints.css"`)
+	own := strings.Index(section, `href="`+cssPath+`"`)
+	if hints < 0 || own < hints {
+		t.Errorf("Expected the break hints stylesheet before the section stylesheet, got:\n%s", section)
+	}
+	if !strings.Contains(section, `<div class="epub-break-avoid"><pre>code</pre></div>`) {
+		t.Errorf("Expected the code block to be wrapped, got:\n%s", section)
+	}
+
+	// Disabled hints leave the sections as they are
+	if err := e.SetBreakHints(false); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if section := readZipFiles(t, b.Bytes())["EPUB/xhtml/chapter.xhtml"]; strings.Contains(section, "break-hints.css") || strings.Contains(section, breakHintsAvoidClass) {
+		t.Errorf("Expected no break hints, got:\n%s", section)
+	}
+}
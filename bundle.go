@@ -0,0 +1,120 @@
+package epub
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Media types of the extensions of the files of bundles. The system table of
+// the mime package isn't used, as it differs from one system to another.
+var bundleMediaTypes = map[string]string{
+	".css":   mediaTypeCSS,
+	".gif":   "image/gif",
+	".htm":   "text/html",
+	".html":  "text/html",
+	".jpeg":  "image/jpeg",
+	".jpg":   "image/jpeg",
+	".js":    mediaTypeJavascript,
+	".json":  "application/json",
+	".mjs":   mediaTypeJavascript,
+	".mp4":   "video/mp4",
+	".otf":   "font/otf",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".ttf":   "font/ttf",
+	".txt":   "text/plain",
+	".vtt":   mediaTypeVTT,
+	".wasm":  "application/wasm",
+	".webm":  "video/webm",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".xhtml": mediaTypeXhtml,
+	".xml":   "application/xml",
+}
+
+// Return the media type of a file of a bundle from its extension, or "" if
+// it is unknown, in which case it is detected from its content
+func bundleMediaType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if mediaType, ok := bundleMediaTypes[ext]; ok {
+		return mediaType
+	}
+	return audioMediaTypes[ext]
+}
+
+// AddBundle adds all the files of fsys to the EPUB, preserving their relative
+// structure under internalFolder, and returns a relative path to the folder
+// that can be used from the sections, e.g. to link to the entry point of
+// interactive content exported by authoring tools such as Twine or H5P. A
+// directory can be added with os.DirFS and a zip file with zip.OpenReader or
+// zip.NewReader.
+//
+// Each file is added to the manifest like a file added with AddFile, with the
+// media type of its extension if it is a common one of web content, or else
+// the one detected from its content. Hidden files and folders (starting with a
+// dot, such as .DS_Store) are left out. internalFolder follows the rules of
+// the paths of AddFile; if a file can't be read or added, an error is
+// returned and the EPUB is left as it was: none of the files of the bundle
+// are added, and the files it replaced (see SetDuplicatePolicy) are restored.
+func (e *Epub) AddBundle(fsys fs.FS, internalFolder string) (string, error) {
+	type bundleFile struct {
+		name string
+		data []byte
+	}
+	var files []bundleFile
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &FileRetrievalError{Source: name, Err: err}
+		}
+		if name != "." && d.Name()[0] == '.' {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := readFS(fsys, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, bundleFile{name: name, data: data})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	// All or nothing: the files added are removed and the files replaced
+	// (see SetDuplicatePolicy) restored if a file can't be added
+	var added []string
+	replaced := make(map[string]epubFile)
+	issues := len(e.warnings.Issues)
+	for _, f := range files {
+		internalPath := path.Join(internalFolder, f.name)
+		previous, exists := e.files[internalPath]
+		if _, err := e.addFile(dataurl.EncodeBytes(f.data), internalPath, bundleMediaType(f.name), ""); err != nil {
+			for _, p := range added {
+				delete(e.files, p)
+			}
+			for p, previous := range replaced {
+				e.files[p] = previous
+			}
+			e.warnings.Issues = e.warnings.Issues[:issues]
+			return "", err
+		}
+		if exists {
+			replaced[internalPath] = previous
+		} else {
+			added = append(added, internalPath)
+		}
+	}
+	return path.Join("..", internalFolder), nil
+}
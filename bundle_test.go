@@ -0,0 +1,135 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestAddBundle(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A bundle zipped by an authoring tool
+	var z bytes.Buffer
+	zw := zip.NewWriter(&z)
+	for name, content := range map[string]string{
+		"index.html":      "<html><body><script src=\"js/story.js\"></script></body></html>",
+		"js/story.js":     "window.story = {};",
+		"data/story.json": `{"passages":[]}`,
+		".DS_Store":       "junk",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(z.Bytes()), int64(z.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundlePath, err := e.AddBundle(zr, "interactive/story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundlePath != "../interactive/story" {
+		t.Errorf("Unexpected bundle path %s", bundlePath)
+	}
+
+	// Nothing is added if a file of the bundle can't be
+	var used *FilenameAlreadyUsedError
+	if _, err := e.AddBundle(fstest.MapFS{
+		"a.css":      {Data: []byte("p {}")},
+		"index.html": {Data: []byte("<html></html>")},
+	}, "interactive/story"); !errors.As(err, &used) {
+		t.Errorf("Expected a FilenameAlreadyUsedError, got %v", err)
+	}
+	if _, ok := e.files["interactive/story/a.css"]; ok {
+		t.Error("Expected the files of a bundle that can't be added to be left out")
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/interactive/story/js/story.js"] != "window.story = {};" {
+		t.Errorf("Expected the script of the bundle in the EPUB, got %q", files["EPUB/interactive/story/js/story.js"])
+	}
+	if _, ok := files["EPUB/interactive/story/.DS_Store"]; ok {
+		t.Error("Expected the hidden files of the bundle to be left out")
+	}
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`href="interactive/story/index.html" media-type="text/html"></item>`,
+		`href="interactive/story/js/story.js" media-type="text/javascript"></item>`,
+		`href="interactive/story/data/story.json" media-type="application/json"></item>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+}
+
+func TestAddBundleRollback(t *testing.T) {
+	for name, policy := range map[string]DuplicatePolicy{"Replace": DuplicatesReplace, "Skip": DuplicatesSkip} {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEpub(testEpubTitle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.SetDuplicatePolicy(policy)
+			original := dataurl.EncodeBytes([]byte("body {}"))
+			if _, err := e.AddFile(original, "bundle/a.css", mediaTypeCSS, ""); err != nil {
+				t.Fatal(err)
+			}
+			// The file with a control character in its name can't be added
+			// after the existing one has been handled
+			var invalid *InvalidFilenameError
+			if _, err := e.AddBundle(fstest.MapFS{
+				"a.css":     {Data: []byte("p {}")},
+				"b.js":      {Data: []byte("window.b = {};")},
+				"z\x01.txt": {Data: []byte("z")},
+			}, "bundle"); !errors.As(err, &invalid) {
+				t.Fatalf("Expected an InvalidFilenameError, got %v", err)
+			}
+			if f, ok := e.files["bundle/a.css"]; !ok || f.source != original || f.mediaType != mediaTypeCSS {
+				t.Errorf("Expected the file added before the bundle to be kept, got %+v (%t)", f, ok)
+			}
+			if _, ok := e.files["bundle/b.js"]; ok {
+				t.Error("Expected the files of the bundle to be left out")
+			}
+			if len(e.warnings.Issues) != 0 {
+				t.Errorf("Expected no warnings about the bundle left out, got %v", e.warnings.Issues)
+			}
+		})
+	}
+}
+
+func TestBundleMediaType(t *testing.T) {
+	for name, expected := range map[string]string{
+		"index.HTML":   "text/html",
+		"js/app.mjs":   mediaTypeJavascript,
+		"fonts/a.woff": "font/woff",
+		"audio/a.mp3":  "audio/mpeg",
+		"data.bin":     "",
+	} {
+		if got := bundleMediaType(name); got != expected {
+			t.Errorf("Expected the media type %q for %s, got %q", expected, name, got)
+		}
+	}
+}
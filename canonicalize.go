@@ -0,0 +1,250 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timestamp of the entries of canonicalized EPUBs, the earliest one ZIP files
+// can hold
+var canonicalModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Extensions of the files formatted by Canonicalize
+var canonicalXMLExtensions = map[string]bool{
+	".htm":   true,
+	".html":  true,
+	".ncx":   true,
+	".opf":   true,
+	".smil":  true,
+	".svg":   true,
+	".xhtml": true,
+	".xml":   true,
+}
+
+var (
+	canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	canonicalAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+// Canonicalize rewrites the EPUB read from r to dst in a canonical form, so that
+// EPUBs produced by different tools, or by different versions of a tool, can be
+// compared with a diff of their entries:
+//   - the mimetype file comes first, the other entries are sorted by name and
+//     directory entries are left out
+//   - all the entries have the same timestamp
+//   - the XML files (package file, XHTML documents, NCX, SMIL, SVG...) are
+//     indented, with sorted attributes and whitespace-only text removed where
+//     an element only contains elements; files that aren't well-formed are
+//     left as is
+//
+// Formatting may change the whitespace of XHTML documents, e.g. in pre
+// elements, so the output is meant for comparison rather than distribution.
+func Canonicalize(r io.ReaderAt, size int64, dst io.Writer) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("unable to read EPUB: %w", err)
+	}
+	files := make([]*zip.File, 0, len(z.File))
+	for _, f := range z.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			files = append(files, f)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Name == mimetypeFilename || files[j].Name == mimetypeFilename {
+			return files[i].Name == mimetypeFilename && files[j].Name != mimetypeFilename
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	w := zip.NewWriter(dst)
+	for _, f := range files {
+		if err := canonicalizeEntry(w, f); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("unable to write canonical EPUB: %w", err)
+	}
+	return nil
+}
+
+// Copy an entry to the canonical EPUB
+func canonicalizeEntry(w *zip.Writer, f *zip.File) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", f.Name, err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", f.Name, err)
+	}
+	if canonicalXMLExtensions[strings.ToLower(path.Ext(f.Name))] {
+		if formatted, err := canonicalXML(data); err == nil {
+			data = formatted
+		}
+	}
+
+	header := &zip.FileHeader{
+		Name:   f.Name,
+		Method: zip.Deflate,
+	}
+	setModTime(header, canonicalModTime)
+	if f.Name == mimetypeFilename {
+		header.Method = zip.Store
+	}
+	fw, err := w.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", f.Name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("unable to write %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// A node of an XML document formatted by canonicalXML: an element with its
+// children, or another token
+type canonicalNode struct {
+	start    *xml.StartElement
+	token    xml.Token
+	children []*canonicalNode
+}
+
+// Return whether the node is text made of whitespace only
+func (n *canonicalNode) isBlank() bool {
+	text, ok := n.token.(xml.CharData)
+	return ok && len(bytes.TrimSpace(text)) == 0
+}
+
+// Format an XML document, see Canonicalize
+func canonicalXML(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Entity = xml.HTMLEntity
+	root := &canonicalNode{}
+	stack := []*canonicalNode{root}
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			start := t.Copy()
+			n := &canonicalNode{start: &start}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			parent.children = append(parent.children, &canonicalNode{token: xml.CopyToken(tok)})
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("unclosed element %s", stack[len(stack)-1].start.Name.Local)
+	}
+
+	var b bytes.Buffer
+	for _, n := range root.children {
+		if n.isBlank() {
+			continue
+		}
+		writeCanonicalNode(&b, n, 0, false)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// Write a node at the given depth. The content of inline nodes is written as
+// is, without indentation.
+func writeCanonicalNode(b *bytes.Buffer, n *canonicalNode, depth int, inline bool) {
+	if n.start == nil {
+		switch t := n.token.(type) {
+		case xml.CharData:
+			canonicalTextEscaper.WriteString(b, string(t))
+		case xml.Comment:
+			b.WriteString("<!--")
+			b.Write(t)
+			b.WriteString("-->")
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+			} else {
+				fmt.Fprintf(b, "<?%s %s?>", t.Target, bytes.TrimSpace(t.Inst))
+			}
+		case xml.Directive:
+			b.WriteString("<!")
+			b.Write(t)
+			b.WriteString(">")
+		}
+		return
+	}
+
+	name := canonicalName(n.start.Name)
+	b.WriteString("<" + name)
+	attrs := append([]xml.Attr(nil), n.start.Attr...)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return canonicalName(attrs[i].Name) < canonicalName(attrs[j].Name)
+	})
+	for _, a := range attrs {
+		b.WriteString(" " + canonicalName(a.Name) + `="`)
+		canonicalAttrEscaper.WriteString(b, a.Value)
+		b.WriteString(`"`)
+	}
+	if len(n.children) == 0 {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+
+	// Elements containing text are written inline, as their whitespace may
+	// be significant
+	for _, c := range n.children {
+		if _, ok := c.token.(xml.CharData); ok && !c.isBlank() {
+			inline = true
+			break
+		}
+	}
+	if inline {
+		for _, c := range n.children {
+			writeCanonicalNode(b, c, depth+1, true)
+		}
+	} else {
+		written := false
+		for _, c := range n.children {
+			if c.isBlank() {
+				continue
+			}
+			b.WriteString("\n" + strings.Repeat("  ", depth+1))
+			writeCanonicalNode(b, c, depth+1, false)
+			written = true
+		}
+		if written {
+			b.WriteString("\n" + strings.Repeat("  ", depth))
+		}
+	}
+	b.WriteString("</" + name + ">")
+}
+
+// Return a name with its prefix, as read by RawToken
+func canonicalName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	// The same EPUB zipped by two tools, in a different order and formatting
+	first := zipTestEpub(t, [][2]string{
+		{"EPUB/package.opf", `<?xml version='1.0' encoding='utf-8'?><package version="3.0" xmlns="http://www.idpf.org/2007/opf"><metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Book</dc:title></metadata></package>`},
+		{"mimetype", "application/epub+zip"},
+		{"EPUB/", ""},
+		{"EPUB/xhtml/a.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><body><p class="x" id="p1">Some <em>text</em>&amp; more</p></body></html>`},
+	})
+	second := zipTestEpub(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"EPUB/xhtml/a.xhtml", "<html xmlns=\"http://www.w3.org/1999/xhtml\">\n  <body>\n    <p id=\"p1\" class=\"x\">Some <em>text</em>&amp; more</p>\n  </body>\n</html>\n"},
+		{"EPUB/package.opf", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\">\n\t<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n\t\t<dc:title>Book</dc:title>\n\t</metadata>\n</package>"},
+	})
+
+	canonicalize := func(data []byte) []byte {
+		var b bytes.Buffer
+		if err := Canonicalize(bytes.NewReader(data), int64(len(data)), &b); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+	a, b := canonicalize(first), canonicalize(second)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Expected the canonical EPUBs to be identical:\n%q\n%q", readZipFiles(t, a), readZipFiles(t, b))
+	}
+	if again := canonicalize(a); !bytes.Equal(a, again) {
+		t.Error("Expected canonicalizing a canonical EPUB not to change it")
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(canonicalModTime) {
+			t.Errorf("Unexpected timestamp for %s: %v", f.Name, f.Modified)
+		}
+	}
+	if len(names) != 3 || names[0] != mimetypeFilename || names[1] != "EPUB/package.opf" || names[2] != "EPUB/xhtml/a.xhtml" ||
+		z.File[0].Method != zip.Store {
+		t.Errorf("Unexpected entries: %v", names)
+	}
+
+	files := readZipFiles(t, a)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<package version="3.0" xmlns="http://www.idpf.org/2007/opf">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Book</dc:title>
+  </metadata>
+</package>
+`
+	if files["EPUB/package.opf"] != expected {
+		t.Errorf("Unexpected package file:\n%s", files["EPUB/package.opf"])
+	}
+	if p := `<p class="x" id="p1">Some <em>text</em>&amp; more</p>`; !bytes.Contains([]byte(files["EPUB/xhtml/a.xhtml"]), []byte(p)) {
+		t.Errorf("Expected the text to be kept inline, got:\n%s", files["EPUB/xhtml/a.xhtml"])
+	}
+}
+
+func TestCanonicalizeMalformedXML(t *testing.T) {
+	data := zipTestEpub(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"EPUB/xhtml/broken.xhtml", "<html><body><p>unclosed</body></html>"},
+	})
+	var b bytes.Buffer
+	if err := Canonicalize(bytes.NewReader(data), int64(len(data)), &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := readZipFiles(t, b.Bytes())["EPUB/xhtml/broken.xhtml"]; got != "<html><body><p>unclosed</body></html>" {
+		t.Errorf("Expected the malformed file to be left as is, got %q", got)
+	}
+
+	if err := Canonicalize(bytes.NewReader([]byte("not a zip")), 9, &b); err == nil {
+		t.Error("Expected an error for a file that isn't a zip file")
+	}
+}
@@ -0,0 +1,261 @@
+package epub
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	captionsFileFormat = "captions%04d%s"
+	// Class of the transcript sections, see AddTranscript
+	transcriptClass = "epub-transcript"
+)
+
+// CaptionCue is a cue of WebVTT captions: a text displayed while the media
+// plays from Begin to End.
+type CaptionCue struct {
+	// Optional cue identifier
+	ID    string
+	Begin time.Duration
+	End   time.Duration
+	// Text of the cue, which may contain WebVTT cue tags such as <v Speaker>
+	// or <i>
+	Text string
+}
+
+// Timings line of a cue, e.g. 00:01.000 --> 00:04.000 align:start
+var vttTimingsRegex = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2}\.\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}\.\d{3})(?:\s|$)`)
+
+// Cue tags and timestamps of a cue text, the voice tags being matched
+// separately to keep the name of the speaker
+var (
+	vttVoiceRegex = regexp.MustCompile(`<v(?:\.[^\s>]*)?\s+([^>]*)>`)
+	vttTagRegex   = regexp.MustCompile(`</?[^>]*>`)
+)
+
+// ParseWebVTT parses WebVTT captions (https://www.w3.org/TR/webvtt1/) and
+// returns their cues in order. Comments, styles and regions are ignored.
+func ParseWebVTT(r io.Reader) ([]CaptionCue, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() || !strings.HasPrefix(strings.TrimPrefix(s.Text(), "\ufeff"), "WEBVTT") {
+		return nil, fmt.Errorf("unable to parse WebVTT captions: missing WEBVTT header")
+	}
+
+	// Blocks are separated by blank lines
+	var cues []CaptionCue
+	var block []string
+	line := 1
+	flush := func() error {
+		defer func() { block = block[:0] }()
+		if len(block) == 0 {
+			return nil
+		}
+		var id string
+		timings := block[0]
+		if !strings.Contains(timings, "-->") {
+			if len(block) < 2 || !strings.Contains(block[1], "-->") {
+				// The header, a comment, a style or a region
+				return nil
+			}
+			id, timings, block = block[0], block[1], block[1:]
+		}
+		m := vttTimingsRegex.FindStringSubmatch(timings)
+		if m == nil {
+			return fmt.Errorf("unable to parse WebVTT captions: line %d: invalid cue timings %q", line-len(block), timings)
+		}
+		begin, err := parseVTTTimestamp(m[1])
+		if err != nil {
+			return err
+		}
+		end, err := parseVTTTimestamp(m[2])
+		if err != nil {
+			return err
+		}
+		cues = append(cues, CaptionCue{
+			ID:    id,
+			Begin: begin,
+			End:   end,
+			Text:  strings.Join(block[1:], "\n"),
+		})
+		return nil
+	}
+	for s.Scan() {
+		line++
+		text := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		block = append(block, text)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("unable to parse WebVTT captions: %w", err)
+	}
+	line++
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return cues, nil
+}
+
+// Parse a WebVTT timestamp, e.g. 01:02:03.456 or 02:03.456
+func parseVTTTimestamp(timestamp string) (time.Duration, error) {
+	parts := strings.Split(timestamp, ":")
+	var d time.Duration
+	for i, part := range parts {
+		unit := time.Duration(1)
+		switch len(parts) - i {
+		case 3:
+			unit = time.Hour
+		case 2:
+			unit = time.Minute
+		}
+		if unit != 1 {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid WebVTT timestamp %q", timestamp)
+			}
+			d += time.Duration(n) * unit
+			continue
+		}
+		seconds, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WebVTT timestamp %q", timestamp)
+		}
+		d += time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+	}
+	return d, nil
+}
+
+// AddCaptions adds WebVTT captions of an audio or video file of the EPUB and
+// returns a relative path to the captions file that can be used as the source
+// of a <track> element in EPUB sections, in the format:
+// ../CaptionsFolderName/internalFilename
+//
+// mediaPath is the path to the audio or video file as returned by AddAudio or
+// AddVideo; an error is returned if the EPUB has no such file. The captions
+// source and the internal filename follow the same rules as AddAudio.
+//
+// A readable transcript of the captions can be added as a section with
+// AddTranscript.
+func (e *Epub) AddCaptions(mediaPath string, source string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	if !e.hasTimedMedia(mediaPath) {
+		return "", fmt.Errorf("audio or video file %s has not been added", mediaPath)
+	}
+	captionsPath, err := addMedia(e.grabber(), source, internalFilename, captionsFileFormat, CaptionsFolderName, e.captions)
+	if err != nil {
+		return "", err
+	}
+	e.captionedMedia[filepath.Base(captionsPath)] = mediaPath
+	return captionsPath, nil
+}
+
+// Report whether mediaPath is the path to an audio or video file of the EPUB
+func (e *Epub) hasTimedMedia(mediaPath string) bool {
+	filename := path.Base(mediaPath)
+	switch path.Base(path.Dir(mediaPath)) {
+	case AudioFolderName:
+		_, ok := e.audios[filename]
+		return ok
+	case VideoFolderName:
+		_, ok := e.videos[filename]
+		return ok
+	}
+	return false
+}
+
+// AddTranscript adds a section with a readable transcript of captions added
+// with AddCaptions and returns its relative path, like AddSection.
+// captionsPath is the path returned by AddCaptions; the captions are retrieved
+// right away to generate the section.
+//
+// Each cue of the captions becomes a paragraph starting with its timestamp,
+// which links to the playback position of the cue in the audio or video file
+// (a media fragment, e.g. ../audios/audio0001.mp3#t=12.5,17), and with the
+// name of the speaker for cues with a voice tag. The section has the
+// epub-transcript class so that it can be styled.
+//
+// The section title and internal filename follow the same rules as
+// AddSection.
+func (e *Epub) AddTranscript(captionsPath string, sectionTitle string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	filename := filepath.Base(captionsPath)
+	source, ok := e.captions[filename]
+	if !ok {
+		return "", fmt.Errorf("captions %s have not been added", captionsPath)
+	}
+	data, _, err := e.grabber().fetchMediaData(source, filename)
+	if err != nil {
+		return "", err
+	}
+	cues, err := ParseWebVTT(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return e.addSection("", transcriptBody(sectionTitle, e.captionedMedia[filename], cues), sectionTitle, internalFilename, "")
+}
+
+// Generate the body of a transcript section, see AddTranscript
+func transcriptBody(title string, mediaPath string, cues []CaptionCue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<div class=\"%s\">\n", transcriptClass)
+	if title != "" {
+		fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	}
+	for i, cue := range cues {
+		id := cue.ID
+		if id == "" || strings.ContainsAny(id, " \t") {
+			id = fmt.Sprintf("cue%d", i+1)
+		}
+		fmt.Fprintf(&b, "<p id=\"%s\"><a class=\"%s-time\" href=\"%s#t=%s,%s\">%s</a> ",
+			html.EscapeString(id), transcriptClass, html.EscapeString(mediaPath),
+			formatMediaFragmentTime(cue.Begin), formatMediaFragmentTime(cue.End), formatTranscriptTime(cue.Begin))
+		if m := vttVoiceRegex.FindStringSubmatch(cue.Text); m != nil && strings.TrimSpace(m[1]) != "" {
+			fmt.Fprintf(&b, "<b class=\"%s-speaker\">%s:</b> ", transcriptClass, html.EscapeString(strings.TrimSpace(m[1])))
+		}
+		// The text is escaped once the cue tags are removed, the entities of
+		// the cue text being decoded first
+		text := html.UnescapeString(vttTagRegex.ReplaceAllString(cue.Text, ""))
+		lines := strings.Split(text, "\n")
+		for j, line := range lines {
+			lines[j] = html.EscapeString(strings.TrimSpace(line))
+		}
+		b.WriteString(strings.Join(lines, "<br/>"))
+		b.WriteString("</p>\n")
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
+
+// Format a playback position for a temporal media fragment, in seconds
+func formatMediaFragmentTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// Format the timestamp of a transcript paragraph, e.g. 02:03 or 1:02:03
+func formatTranscriptTime(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// Get captions from their source and write them to the archive
+func (e *Epub) writeCaptions(a *archive) error {
+	return e.writeMedia(a, e.captions, CaptionsFolderName)
+}
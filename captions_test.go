@@ -0,0 +1,108 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const testCaptions = `WEBVTT - Interview
+
+NOTE The first cue has no identifier
+
+00:00.000 --> 00:04.500 align:start
+<v Roger Bingham>We are in New York City</v>
+
+intro
+00:04.500 --> 01:02:03.250
+<i>Applause</i> &amp; cheers
+from the audience
+`
+
+func TestParseWebVTT(t *testing.T) {
+	cues, err := ParseWebVTT(strings.NewReader(testCaptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []CaptionCue{
+		{Begin: 0, End: 4500 * time.Millisecond, Text: "<v Roger Bingham>We are in New York City</v>"},
+		{ID: "intro", Begin: 4500 * time.Millisecond, End: time.Hour + 2*time.Minute + 3250*time.Millisecond, Text: "<i>Applause</i> &amp; cheers\nfrom the audience"},
+	}
+	if len(cues) != len(expected) {
+		t.Fatalf("Expected %d cues, got %+v", len(expected), cues)
+	}
+	for i := range expected {
+		if cues[i] != expected[i] {
+			t.Errorf("Unexpected cue %d: %+v, expected %+v", i, cues[i], expected[i])
+		}
+	}
+
+	for _, invalid := range []string{
+		"00:00.000 --> 00:01.000\nNo header\n",
+		"WEBVTT\n\n00:00.000 --> 1.000\nInvalid end\n",
+	} {
+		if _, err := ParseWebVTT(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestAddTranscript(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioPath, err := e.AddAudio(testAudioFromFileSource, testAudioFromFileFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddCaptions("../audios/missing.mp3", dataurl.EncodeBytes([]byte(testCaptions)), ""); err == nil {
+		t.Error("Expected an error for captions of a missing audio file")
+	}
+	captionsPath, err := e.AddCaptions(audioPath, dataurl.EncodeBytes([]byte(testCaptions)), "interview.vtt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if captionsPath != "../captions/interview.vtt" {
+		t.Errorf("Unexpected captions path %s", captionsPath)
+	}
+	if _, err := e.AddSection(`<audio src="`+audioPath+`" controls="controls"><track kind="captions" src="`+captionsPath+`"/></audio>`, "Interview", "interview.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddTranscript("../captions/missing.vtt", "Transcript", ""); err == nil {
+		t.Error("Expected an error for missing captions")
+	}
+	if _, err := e.AddTranscript(captionsPath, "Transcript", "transcript.xhtml"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			t.Errorf("Unexpected broken link: %s", w)
+		}
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/captions/interview.vtt"] != testCaptions {
+		t.Errorf("Expected the captions in the EPUB, got %q", files["EPUB/captions/interview.vtt"])
+	}
+	if opf := files["EPUB/package.opf"]; !strings.Contains(opf, `href="captions/interview.vtt" media-type="text/vtt"`) {
+		t.Errorf("Expected the captions in the manifest, got:\n%s", opf)
+	}
+	transcript := files["EPUB/xhtml/transcript.xhtml"]
+	for _, expected := range []string{
+		`<div class="epub-transcript">`,
+		`<p id="cue1"><a class="epub-transcript-time" href="../audios/` + testAudioFromFileFilename + `#t=0,4.5">00:00</a> <b class="epub-transcript-speaker">Roger Bingham:</b> We are in New York City</p>`,
+		`<p id="intro"><a class="epub-transcript-time" href="../audios/` + testAudioFromFileFilename + `#t=4.5,3723.25">00:04</a> Applause &amp; cheers<br/>from the audience</p>`,
+	} {
+		if !strings.Contains(transcript, expected) {
+			t.Errorf("Expected %s in the transcript, got:\n%s", expected, transcript)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package epub
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	whatsNewAddedHeading   = "New chapters"
+	whatsNewChangedHeading = "Updated chapters"
+	whatsNewRemovedHeading = "Removed chapters"
+)
+
+// ChecksumManifest records a checksum of every section of a build, in reading
+// order. It can be stored alongside a published EPUB (it marshals to JSON) and
+// compared against a later build with ChangesSince.
+type ChecksumManifest struct {
+	Sections []SectionChecksum `json:"sections"`
+}
+
+// SectionChecksum is the checksum of a single section.
+type SectionChecksum struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title"`
+	Checksum string `json:"checksum"`
+}
+
+// ChapterChanges lists the sections that were added, changed or removed
+// between two builds.
+type ChapterChanges struct {
+	Added   []SectionChecksum
+	Changed []SectionChecksum
+	Removed []SectionChecksum
+}
+
+// HasChanges reports whether any section was added, changed or removed.
+func (c *ChapterChanges) HasChanges() bool {
+	return len(c.Added)+len(c.Changed)+len(c.Removed) > 0
+}
+
+// ChecksumManifest returns the checksums of the sections currently in the
+// EPUB. The checksum covers the section title and body.
+func (e *Epub) ChecksumManifest() *ChecksumManifest {
+	e.Lock()
+	defer e.Unlock()
+	return e.checksumManifest()
+}
+
+func (e *Epub) checksumManifest() *ChecksumManifest {
+	m := &ChecksumManifest{}
+	var walk func(sections []*epubSection)
+	walk = func(sections []*epubSection) {
+		for _, section := range sections {
+			title := section.xhtml.Title()
+			// The cover page title is set to the EPUB title when it is written
+			if section.filename == e.cover.xhtmlFilename {
+				title = e.title
+			}
+			m.Sections = append(m.Sections, SectionChecksum{
+				Filename: section.filename,
+				Title:    title,
+				Checksum: sectionChecksum(title, section.xhtml.xml.Body.XML),
+			})
+			walk(section.children)
+		}
+	}
+	walk(e.sections)
+	return m
+}
+
+// ReadChecksumManifest computes the checksum manifest of a previously written
+// EPUB, so that a new build can be compared against it without keeping the
+// manifest around.
+func ReadChecksumManifest(r io.ReaderAt, size int64) (*ChecksumManifest, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open EPUB: %w", err)
+	}
+
+	var opf struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Itemrefs []struct {
+			Idref string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := unmarshalZipFile(z, path.Join(contentFolderName, pkgFilename), &opf); err != nil {
+		return nil, err
+	}
+	hrefs := make(map[string]string, len(opf.Items))
+	for _, item := range opf.Items {
+		hrefs[item.ID] = item.Href
+	}
+
+	m := &ChecksumManifest{}
+	for _, itemref := range opf.Itemrefs {
+		href, ok := hrefs[itemref.Idref]
+		if !ok {
+			return nil, fmt.Errorf("spine item %q is not in the manifest", itemref.Idref)
+		}
+		x := &xhtmlRoot{}
+		if err := unmarshalZipFile(z, path.Join(contentFolderName, href), x); err != nil {
+			return nil, err
+		}
+		m.Sections = append(m.Sections, SectionChecksum{
+			Filename: path.Base(href),
+			Title:    x.Head.Title.Value,
+			Checksum: sectionChecksum(x.Head.Title.Value, x.Body.XML),
+		})
+	}
+	return m, nil
+}
+
+// ChangesSince compares the sections currently in the EPUB with a previous
+// checksum manifest. Sections are matched by their internal filename.
+func (e *Epub) ChangesSince(previous *ChecksumManifest) *ChapterChanges {
+	e.Lock()
+	defer e.Unlock()
+
+	old := make(map[string]SectionChecksum, len(previous.Sections))
+	for _, s := range previous.Sections {
+		old[s.Filename] = s
+	}
+
+	changes := &ChapterChanges{}
+	for _, s := range e.checksumManifest().Sections {
+		prev, ok := old[s.Filename]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, s)
+		case prev.Checksum != s.Checksum:
+			changes.Changed = append(changes.Changed, s)
+		}
+		delete(old, s.Filename)
+	}
+	// Keep removed sections in their previous reading order
+	for _, s := range previous.Sections {
+		if _, ok := old[s.Filename]; ok {
+			changes.Removed = append(changes.Removed, s)
+		}
+	}
+	return changes
+}
+
+// AddWhatsNewSection adds a section listing the changes between two builds,
+// with links to the added and updated sections. It returns a relative path to
+// the section, like AddSection.
+//
+// The title and internal filename follow the same rules as AddSection.
+func (e *Epub) AddWhatsNewSection(changes *ChapterChanges, sectionTitle string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	var body strings.Builder
+	if sectionTitle != "" {
+		fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(sectionTitle))
+	}
+	writeList := func(heading string, sections []SectionChecksum, link bool) {
+		if len(sections) == 0 {
+			return
+		}
+		fmt.Fprintf(&body, "<h2>%s</h2>\n<ul>\n", heading)
+		for _, s := range sections {
+			title := s.Title
+			if title == "" {
+				title = s.Filename
+			}
+			if link {
+				fmt.Fprintf(&body, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(s.Filename), html.EscapeString(title))
+			} else {
+				fmt.Fprintf(&body, "<li>%s</li>\n", html.EscapeString(title))
+			}
+		}
+		body.WriteString("</ul>\n")
+	}
+	writeList(whatsNewAddedHeading, changes.Added, true)
+	writeList(whatsNewChangedHeading, changes.Changed, true)
+	writeList(whatsNewRemovedHeading, changes.Removed, false)
+
+	return e.addSection("", body.String(), sectionTitle, internalFilename, "")
+}
+
+func sectionChecksum(title string, body string) string {
+	h := sha256.New()
+	io.WriteString(h, title)
+	h.Write([]byte{0})
+	io.WriteString(h, body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Unmarshal the XML file stored at name in the zip archive into v
+func unmarshalZipFile(z *zip.Reader, name string, v interface{}) error {
+	f, err := z.Open(name)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", name, err)
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", name, err)
+	}
+	return nil
+}
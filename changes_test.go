@@ -0,0 +1,77 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChangesSince(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.AddSection(testSectionBody, "Chapter 1", "chapter1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.AddSection(testSectionBody, "Chapter 2", "chapter2.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := ReadChecksumManifest(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.ChecksumManifest(); len(got.Sections) != len(previous.Sections) {
+		t.Fatalf("Expected %d sections, got %d", len(got.Sections), len(previous.Sections))
+	}
+	if changes := e.ChangesSince(previous); changes.HasChanges() {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+
+	// Build the next instalment
+	e2, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e2.AddSection(testSectionBody+"<p>Fixed a typo.</p>", "Chapter 2", "chapter2.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e2.AddSection(testSectionBody, "Chapter 3", "chapter3.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := e2.ChangesSince(previous)
+	if len(changes.Added) != 1 || changes.Added[0].Filename != "chapter3.xhtml" {
+		t.Errorf("Unexpected added sections: %+v", changes.Added)
+	}
+	if len(changes.Changed) != 1 || changes.Changed[0].Filename != "chapter2.xhtml" {
+		t.Errorf("Unexpected changed sections: %+v", changes.Changed)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].Filename != "chapter1.xhtml" {
+		t.Errorf("Unexpected removed sections: %+v", changes.Removed)
+	}
+
+	_, err = e2.AddWhatsNewSection(changes, "What's new", "whatsnew.xhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := e2.sections[len(e2.sections)-1].xhtml.xml.Body.XML
+	for _, expected := range []string{
+		`<a href="chapter3.xhtml">Chapter 3</a>`,
+		`<a href="chapter2.xhtml">Chapter 2</a>`,
+		`<li>Chapter 1</li>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("What's new section doesn't contain %s\nGot: %s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package epub
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+const (
+	xhtmlLinkRelAlternate    = "alternate"
+	defaultChapterAudioTitle = "Narration"
+)
+
+// SetChapterAudio attaches a narration audio file to a section, for books
+// that come with a recording of each chapter but no media overlays (see
+// AddMediaOverlay) to play it in sync with the text. The audio file is linked
+// from the head of the section as an alternate version of it:
+//
+//	<link rel="alternate" type="audio/mpeg" href="../audios/chapter1.mp3" title="Narration"/>
+//
+// audioPath is a path returned by AddAudio, and title the title of the link,
+// "Narration" if empty. An empty audioPath removes the audio file of the
+// section. SectionDoesNotExistError is returned if the EPUB has no section
+// with this filename. Complete documents, such as a cover page set with
+// SetCoverXHTML or the sections of an EPUB read with Open, are written as they
+// are.
+func (e *Epub) SetChapterAudio(sectionFilename string, audioPath string, title string) error {
+	e.Lock()
+	defer e.Unlock()
+	s, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	title = e.sanitize(s.filename, "chapter audio title", title)
+	if title == "" {
+		title = defaultChapterAudioTitle
+	}
+	s.xhtml.setAudioLink(audioPath, audioMediaType(audioPath), title)
+	return nil
+}
+
+// Media types of the common audio file extensions, which the system table of
+// the mime package may not know
+var audioMediaTypes = map[string]string{
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".m4b":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".weba": "audio/webm",
+}
+
+// Return the media type of an audio file from its extension, or "" if it is
+// unknown
+func audioMediaType(audioPath string) string {
+	ext := strings.ToLower(path.Ext(audioPath))
+	if mediaType, ok := audioMediaTypes[ext]; ok {
+		return mediaType
+	}
+	if mediaType := mime.TypeByExtension(ext); strings.HasPrefix(mediaType, "audio/") {
+		return mediaType
+	}
+	return ""
+}
+
+// Set the audio file linked from the head of the document, replacing any
+// previous one; an empty path removes it
+func (x *xhtml) setAudioLink(audioPath string, mediaType string, title string) {
+	var links []xhtmlLink
+	for _, l := range x.xml.Head.Links {
+		if l.Rel != xhtmlLinkRelAlternate {
+			links = append(links, l)
+		}
+	}
+	if audioPath != "" {
+		links = append(links, xhtmlLink{
+			Rel:   xhtmlLinkRelAlternate,
+			Type:  mediaType,
+			Href:  audioPath,
+			Title: title,
+		})
+	}
+	x.xml.Head.Links = links
+}
@@ -0,0 +1,61 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetChapterAudio(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "Two", "two.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	audioPath, err := e.AddAudio(testAudioFromFileSource, "one.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterAudio("one.xhtml", "../audios/old.mp3", ""); err != nil {
+		t.Fatal(err)
+	}
+	// Replaces the previous audio file
+	if err := e.SetChapterAudio("one.xhtml", audioPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterAudio("two.xhtml", audioPath, "Read by the author"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterAudio("two.xhtml", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var notFound *SectionDoesNotExistError
+	if err := e.SetChapterAudio("missing.xhtml", audioPath, ""); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	one := files["EPUB/xhtml/one.xhtml"]
+	if !strings.Contains(one, `<link rel="alternate" type="audio/mpeg" href="../audios/one.mp3" title="Narration"></link>`) ||
+		strings.Contains(one, "old.mp3") {
+		t.Errorf("Expected the audio link in the head of the section:\n%s", one)
+	}
+	if strings.Contains(files["EPUB/xhtml/two.xhtml"], "alternate") {
+		t.Error("Expected the audio link of the second section to be removed")
+	}
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			t.Errorf("Unexpected broken link: %s", w)
+		}
+	}
+}
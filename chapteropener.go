@@ -0,0 +1,127 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// DefaultChapterOpenerTemplate is the template of the chapter openers used by
+// SetChapterOpeners when no template is given.
+const DefaultChapterOpenerTemplate = `<header class="epub-chapter-opener" style="margin: 15% 0 3em 0; text-align: center;">
+{{- if .Image}}
+<p class="epub-chapter-opener-image"><img src="{{.Image}}" alt="{{.ImageAlt}}" style="max-width: 60%;"/></p>
+{{- end}}
+<p class="epub-chapter-opener-number" style="font-size: 1.2em; letter-spacing: 0.2em; margin: 0;">{{.Number}}</p>
+<h1 class="epub-chapter-opener-title" style="margin: 0.5em 0 1em 0;">{{.Title}}</h1>
+{{- if .Epigraph}}
+<blockquote epub:type="epigraph" class="epub-epigraph" style="margin: 1em 10%; font-style: italic;">
+<p>{{.Epigraph}}</p>
+{{- if .EpigraphAttribution}}
+<footer style="text-align: right; font-style: normal;">— {{.EpigraphAttribution}}</footer>
+{{- end}}
+</blockquote>
+{{- end}}
+</header>
+`
+
+// ChapterOpener holds the optional parts of the opener of a chapter, see
+// SetChapterOpener.
+type ChapterOpener struct {
+	// Epigraph and its attribution, plain text
+	Epigraph            string
+	EpigraphAttribution string
+	// Illustration, a path returned by AddImage, and its alternative text
+	Image    string
+	ImageAlt string
+}
+
+// ChapterOpenerData is the data the chapter opener template is executed
+// against.
+type ChapterOpenerData struct {
+	// Number of the chapter, starting at 1
+	Number int
+	// Title of the chapter section
+	Title string
+	ChapterOpener
+	// Author, date and source of the chapter, see SetSectionMetadata
+	Metadata SectionMetadata
+}
+
+// SetChapterOpeners enables generated chapter openers: when the EPUB is
+// written, each chapter (top-level section with a title) starts with an
+// opener rendered from tmpl, a Go template (see html/template) executed
+// against ChapterOpenerData, rather than with heading markup of its own. An
+// empty tmpl uses DefaultChapterOpenerTemplate. The epigraph and illustration
+// of each chapter are set with SetChapterOpener.
+//
+// The cover page and complete documents, such as the sections of an EPUB read
+// with Open, don't get an opener. An error is returned if tmpl isn't a valid
+// template.
+func (e *Epub) SetChapterOpeners(tmpl string) error {
+	e.Lock()
+	defer e.Unlock()
+	if tmpl == "" {
+		tmpl = DefaultChapterOpenerTemplate
+	}
+	t, err := template.New("chapter-opener").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("unable to parse the chapter opener template: %w", err)
+	}
+	e.chapterOpeners = t
+	return nil
+}
+
+// DisableChapterOpeners disables the chapter openers enabled by
+// SetChapterOpeners.
+func (e *Epub) DisableChapterOpeners() {
+	e.Lock()
+	defer e.Unlock()
+	e.chapterOpeners = nil
+}
+
+// SetChapterOpener sets the epigraph and illustration of the opener of a
+// chapter, see SetChapterOpeners. SectionDoesNotExistError is returned if the
+// EPUB has no chapter with this filename.
+func (e *Epub) SetChapterOpener(sectionFilename string, opener ChapterOpener) error {
+	e.Lock()
+	defer e.Unlock()
+	for _, s := range e.sections {
+		if s.filename == sectionFilename {
+			s.opener = opener
+			return nil
+		}
+	}
+	return &SectionDoesNotExistError{Filename: sectionFilename}
+}
+
+// Return the number of a chapter, or 0 if the section isn't a chapter that
+// gets an opener
+func (e *Epub) chapterNumber(s *epubSection) int {
+	number := 0
+	for _, chapter := range e.sections {
+		if chapter.xhtml.raw != "" || chapter.filename == e.cover.xhtmlFilename || chapter.xhtml.Title() == "" {
+			continue
+		}
+		number++
+		if chapter == s {
+			return number
+		}
+	}
+	return 0
+}
+
+// Render the opener of a chapter
+func (e *Epub) chapterOpener(s *epubSection, number int) (string, error) {
+	var b bytes.Buffer
+	data := ChapterOpenerData{
+		Number:        number,
+		Title:         s.xhtml.Title(),
+		ChapterOpener: s.opener,
+		Metadata:      s.metadata,
+	}
+	if err := e.chapterOpeners.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("unable to execute the chapter opener template of %s: %w", s.filename, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,101 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChapterOpeners(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddDedication("For Ada", "", "dedication.xhtml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>It was a dark night.</p>", "The Beginning", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection("one.xhtml", "<p>Part A</p>", "Part A", "one-a.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>The end.</p>", "The End", "two.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterOpeners(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterOpener("two.xhtml", ChapterOpener{
+		Epigraph:            "All things <end>.",
+		EpigraphAttribution: "Anonymous",
+		Image:               imagePath,
+		ImageAlt:            "A sunset",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var notFound *SectionDoesNotExistError
+	if err := e.SetChapterOpener("one-a.xhtml", ChapterOpener{}); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError for a subsection, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	one := files["EPUB/xhtml/one.xhtml"]
+	if !strings.Contains(one, `<p class="epub-chapter-opener-number" style="font-size: 1.2em; letter-spacing: 0.2em; margin: 0;">1</p>`) ||
+		!strings.Contains(one, `<h1 class="epub-chapter-opener-title" style="margin: 0.5em 0 1em 0;">The Beginning</h1>`) ||
+		strings.Contains(one, "epigraph") || strings.Contains(one, "<img") {
+		t.Errorf("Unexpected opener of the first chapter:\n%s", one)
+	}
+	if i := strings.Index(one, "</header>"); i < 0 || i > strings.Index(one, "<p>It was a dark night.</p>") {
+		t.Errorf("Expected the opener before the body of the chapter:\n%s", one)
+	}
+	two := files["EPUB/xhtml/two.xhtml"]
+	for _, expected := range []string{
+		`0.2em; margin: 0;">2</p>`,
+		`<img src="` + imagePath + `" alt="A sunset" style="max-width: 60%;"/>`,
+		`<p>All things &lt;end&gt;.</p>`,
+		`— Anonymous</footer>`,
+	} {
+		if !strings.Contains(two, expected) {
+			t.Errorf("Expected %s in the second chapter:\n%s", expected, two)
+		}
+	}
+	for _, filename := range []string{"dedication.xhtml", "one-a.xhtml"} {
+		if strings.Contains(files["EPUB/xhtml/"+filename], "epub-chapter-opener") {
+			t.Errorf("Expected no opener in %s", filename)
+		}
+	}
+
+	// Custom template
+	if err := e.SetChapterOpeners(`<h1>Chapter {{.Number}}: {{.Title}}</h1>`); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if two := readZipFiles(t, b.Bytes())["EPUB/xhtml/two.xhtml"]; !strings.Contains(two, "<h1>Chapter 2: The End</h1>") {
+		t.Errorf("Expected the custom opener:\n%s", two)
+	}
+
+	if err := e.SetChapterOpeners("{{.Number"); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+	e.DisableChapterOpeners()
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if two := readZipFiles(t, b.Bytes())["EPUB/xhtml/two.xhtml"]; strings.Contains(two, "Chapter 2") {
+		t.Errorf("Expected no opener once disabled:\n%s", two)
+	}
+}
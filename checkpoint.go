@@ -0,0 +1,95 @@
+package epub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+const checkpointFileExt = ".media"
+
+// SetCheckpointDir sets a work directory where the media retrieved from
+// remote sources are checkpointed while the EPUB is written, so that writing
+// a very large EPUB again after an interrupted write (e.g. a crash, a timeout
+// or a cancelled context) resumes from the media already retrieved rather than
+// downloading everything again, even from another process.
+//
+// The checkpoints are removed once the EPUB has been written successfully.
+// Local files and data URLs are never checkpointed, and the sections are
+// rendered again on each write since they are built in memory. An empty
+// directory disables checkpointing.
+func (e *Epub) SetCheckpointDir(dir string) {
+	e.Lock()
+	defer e.Unlock()
+	e.checkpointDir = dir
+}
+
+// Return the path of the checkpoint of a source, or "" if the source isn't
+// checkpointed
+func (g grabber) checkpointPath(mediaSource string) string {
+	if g.checkpointDir == "" || detectMediaType(mediaSource) != "URL" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(mediaSource))
+	return filepath.Join(g.checkpointDir, hex.EncodeToString(h[:])+checkpointFileExt)
+}
+
+// Return the checkpointed content of a source, if any
+func (g grabber) readCheckpoint(mediaSource string) ([]byte, bool) {
+	p := g.checkpointPath(mediaSource)
+	if p == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	logDebug(g.logger, "resuming media from checkpoint", "source", mediaSource, "checkpoint", p)
+	return data, true
+}
+
+// Checkpoint the content of a source. The content is written to a temporary
+// file renamed once complete, so that an interrupted write never leaves a
+// partial checkpoint. Failing to checkpoint doesn't fail the write.
+func (g grabber) writeCheckpoint(mediaSource string, data []byte) {
+	p := g.checkpointPath(mediaSource)
+	if p == "" {
+		return
+	}
+	err := os.MkdirAll(g.checkpointDir, dirPermissions)
+	if err == nil {
+		var f *os.File
+		f, err = os.CreateTemp(g.checkpointDir, tempDirPrefix+"-*")
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err == nil {
+				err = os.Rename(f.Name(), p)
+			}
+			if err != nil {
+				os.Remove(f.Name())
+			}
+		}
+	}
+	if err != nil {
+		logDebug(g.logger, "unable to checkpoint media", "source", mediaSource, "error", err)
+	}
+}
+
+// Remove the checkpoints of the media of the EPUB, once it has been written
+func (e *Epub) removeCheckpoints() {
+	if e.checkpointDir == "" {
+		return
+	}
+	g := e.grabber()
+	for _, media := range []map[string]string{e.css, e.fonts, e.images, e.videos, e.audios, e.overlays, e.scripts, e.fontLicenses, e.captions, e.fileSources()} {
+		for _, source := range media {
+			if p := g.checkpointPath(source); p != "" {
+				os.Remove(p)
+			}
+		}
+	}
+}
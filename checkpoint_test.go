@@ -0,0 +1,78 @@
+package epub
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestSetCheckpointDir(t *testing.T) {
+	var mu sync.Mutex
+	gets := make(map[string]int)
+	failing := true
+	files := http.FileServer(http.Dir("testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			gets[r.URL.Path]++
+			fail := failing && r.URL.Path == "/gophercolor16x16withoutextention"
+			mu.Unlock()
+			if fail {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	build := func() error {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetCheckpointDir(dir)
+		// Media are written in filename order, so a.png is retrieved first
+		if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", "a.png"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddImage(server.URL+"/gophercolor16x16withoutextention", "b.png"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = e.WriteTo(io.Discard)
+		return err
+	}
+
+	if err := build(); err == nil {
+		t.Fatal("Expected the first write to fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected the retrieved image to be checkpointed, got %d files", len(entries))
+	}
+
+	// The write resumes from the checkpoint
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if err := build(); err != nil {
+		t.Fatal(err)
+	}
+	if gets["/gophercolor16x16.png"] != 1 {
+		t.Errorf("Expected the checkpointed image to be retrieved once, got %d requests", gets["/gophercolor16x16.png"])
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the checkpoints to be removed after a successful write, got %d files", len(entries))
+	}
+}
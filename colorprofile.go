@@ -0,0 +1,141 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// SetNormalizeColorProfiles enables or disables the normalization of the
+// color profiles of the images when the EPUB is written: CMYK JPEG images are
+// converted to RGB, since many reading systems, especially e-ink readers,
+// render them black or with inverted colors, and the embedded ICC profiles of
+// JPEG and PNG images are stripped, so that the images are rendered as sRGB
+// everywhere. The converted images are reported with RuleConvertedMedia.
+func (e *Epub) SetNormalizeColorProfiles(normalize bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.normalizeColorProfiles = normalize
+}
+
+// Normalize the color profile of an image, see SetNormalizeColorProfiles.
+// Images that can't be parsed are returned as is.
+func (e *Epub) normalizeColorProfile(mediaFilename string, mediaType string, data []byte) []byte {
+	switch mediaType {
+	case "image/jpeg":
+		if config, err := jpeg.DecodeConfig(bytes.NewReader(data)); err == nil && config.ColorModel == color.CMYKModel {
+			converted, err := convertCMYKJPEG(data)
+			if err == nil {
+				e.report.add(SeverityWarning, RuleConvertedMedia, mediaFilename, "converted from CMYK to RGB")
+				return converted
+			}
+		}
+		if stripped, err := filterJPEGSegments(data, isICCProfileSegment); err == nil {
+			return stripped
+		}
+	case "image/png":
+		if stripped, err := filterPNGChunks(data, func(chunkType string) bool { return chunkType != "iCCP" }); err == nil {
+			return stripped
+		}
+	}
+	return data
+}
+
+// Convert a CMYK JPEG image to an RGB one
+func convertCMYKJPEG(data []byte) ([]byte, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+	converted, _, err := encodeImage(rgba, "jpeg")
+	return converted, err
+}
+
+// Report whether a JPEG segment is an ICC profile (APP2 ICC_PROFILE), see
+// filterJPEGSegments
+func isICCProfileSegment(marker byte, payload []byte) bool {
+	return marker == 0xe2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+}
+
+var errInvalidImage = errors.New("invalid image")
+
+// Remove the marker segments of a JPEG image for which remove returns true,
+// given the marker and the payload of the segment, without decoding the image.
+// The segments after the start of scan are kept as is.
+func filterJPEGSegments(data []byte, remove func(marker byte, payload []byte) bool) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xff {
+			return nil, errInvalidImage
+		}
+		// Fill bytes
+		if i+1 < len(data) && data[i+1] == 0xff {
+			i++
+			continue
+		}
+		if i+1 >= len(data) {
+			return nil, errInvalidImage
+		}
+		marker := data[i+1]
+		// Markers without payload
+		if marker == 0x01 || marker >= 0xd0 && marker <= 0xd9 {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return nil, errInvalidImage
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			return nil, errInvalidImage
+		}
+		if marker == 0xda {
+			// Start of scan: the entropy-coded data follows
+			return append(out, data[i:]...), nil
+		}
+		if !remove(marker, data[i+4:end]) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// Keep the chunks of a PNG image for which keep returns true, given the type
+// of the chunk, without decoding the image
+func filterPNGChunks(data []byte, keep func(chunkType string) bool) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return nil, errInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errInvalidImage
+		}
+		// Length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i+12 {
+			return nil, errInvalidImage
+		}
+		if keep(string(data[i+4 : i+8])) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
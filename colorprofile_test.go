@@ -0,0 +1,108 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Return a JPEG image with an ICC profile segment
+func testICCJPEG(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("ICC_PROFILE\x00\x01\x01"), bytes.Repeat([]byte{0x42}, 32)...)
+	segment := []byte{0xff, 0xe2, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	data := b.Bytes()
+	return append(append(append([]byte(nil), data[:2]...), segment...), data[2:]...)
+}
+
+// Return a PNG image with an iCCP chunk
+func testICCPNG(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("iCCP"), []byte("sRGB\x00\x00profile")...)
+	chunk := make([]byte, 4, len(payload)+8)
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)-4))
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(payload))
+	data := b.Bytes()
+	// After the IHDR chunk, which comes first
+	ihdrEnd := len(pngSignature) + 12 + 13
+	return append(append(append([]byte(nil), data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+}
+
+func TestNormalizeColorProfiles(t *testing.T) {
+	jpegData, pngData := testICCJPEG(t), testICCPNG(t)
+	if _, err := jpeg.Decode(bytes.NewReader(jpegData)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(pngData)); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetNormalizeColorProfiles(true)
+	sources := map[string][]byte{"icc.jpg": jpegData, "icc.png": pngData}
+	cmykPath := filepath.Join(runtime.GOROOT(), "src", "image", "testdata", "video-001.cmyk.jpeg")
+	if cmyk, err := os.ReadFile(cmykPath); err == nil {
+		sources["cmyk.jpg"] = cmyk
+	} else {
+		t.Logf("No CMYK image to test: %v", err)
+	}
+	for filename, data := range sources {
+		if _, err := e.AddImage(dataurl.EncodeBytes(data), filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+
+	if written := []byte(files["EPUB/images/icc.jpg"]); bytes.Contains(written, []byte("ICC_PROFILE")) || len(written) != len(jpegData)-50 {
+		t.Errorf("Expected the ICC profile to be stripped from the JPEG image, got %d bytes from %d", len(written), len(jpegData))
+	}
+	written := []byte(files["EPUB/images/icc.png"])
+	if bytes.Contains(written, []byte("iCCP")) {
+		t.Error("Expected the iCCP chunk to be stripped from the PNG image")
+	}
+	if _, err := png.Decode(bytes.NewReader(written)); err != nil {
+		t.Errorf("Expected a valid PNG image, got %v", err)
+	}
+	if _, ok := sources["cmyk.jpg"]; ok {
+		config, err := jpeg.DecodeConfig(bytes.NewReader([]byte(files["EPUB/images/cmyk.jpg"])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.ColorModel == color.CMYKModel {
+			t.Error("Expected the CMYK image to be converted")
+		}
+		converted := false
+		for _, w := range e.Warnings() {
+			converted = converted || w.Rule == RuleConvertedMedia && w.Filename == "cmyk.jpg"
+		}
+		if !converted {
+			t.Error("Expected the conversion to be reported")
+		}
+	}
+}
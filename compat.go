@@ -0,0 +1,176 @@
+package epub
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Titles of the references of the EPUB 2 guide element
+	guideCoverTitle = "Cover"
+	guideTocTitle   = "Table of Contents"
+	guideTextTitle  = "Start"
+)
+
+// CompatProfile is a set of workarounds for the reading systems of a vendor,
+// set with SetCompatProfile. The predefined profiles can be copied and
+// adjusted.
+type CompatProfile struct {
+	Name string
+	// Write the EPUB 2 guide element, referencing the cover page, the table
+	// of contents and the first section; Kindle conversion uses it to find
+	// the cover and where the book starts
+	Guide bool
+	// Write the EPUB 2 table of contents (toc.ncx), which EPUB 2 reading
+	// systems need
+	NCX bool
+	// Write the EPUB 2 cover meta element, which many reading systems use to
+	// find the cover image
+	CoverMeta bool
+	// CSS properties the reading systems don't support, either a property
+	// name (e.g. "transform") or a property and the beginning of its value
+	// (e.g. "display: flex"). Using them is reported as a warning when the
+	// EPUB is written, the CSS is left as is.
+	UnsupportedCSS []string
+}
+
+// Predefined compatibility profiles
+var (
+	// ProfileGeneric suits most EPUB 3 reading systems while keeping the
+	// EPUB 2 fallbacks. It is the default.
+	ProfileGeneric = CompatProfile{
+		Name:      "generic",
+		NCX:       true,
+		CoverMeta: true,
+	}
+	// ProfileKindle suits EPUBs converted for Kindle devices by Amazon
+	ProfileKindle = CompatProfile{
+		Name:      "kindle",
+		Guide:     true,
+		NCX:       true,
+		CoverMeta: true,
+		UnsupportedCSS: []string{
+			"position: fixed",
+			"position: absolute",
+			"display: grid",
+			"column-count",
+		},
+	}
+	// ProfileAppleBooks suits Apple Books
+	ProfileAppleBooks = CompatProfile{
+		Name:      "apple-books",
+		NCX:       true,
+		CoverMeta: true,
+		UnsupportedCSS: []string{
+			"position: fixed",
+		},
+	}
+	// ProfileKobo suits Kobo e-readers and apps
+	ProfileKobo = CompatProfile{
+		Name:      "kobo",
+		NCX:       true,
+		CoverMeta: true,
+		UnsupportedCSS: []string{
+			"position: fixed",
+			"display: grid",
+		},
+	}
+	// ProfileADE suits Adobe Digital Editions and the reading systems based
+	// on the Adobe RMSDK, whose CSS support is the most limited
+	ProfileADE = CompatProfile{
+		Name:      "ade",
+		Guide:     true,
+		NCX:       true,
+		CoverMeta: true,
+		UnsupportedCSS: []string{
+			"position: fixed",
+			"display: flex",
+			"display: inline-flex",
+			"display: grid",
+			"column-count",
+			"transform",
+			"@media",
+		},
+	}
+)
+
+// SetCompatProfile sets the reading systems the EPUB targets, which toggles
+// the workarounds they need when the EPUB is written. See CompatProfile.
+func (e *Epub) SetCompatProfile(profile CompatProfile) {
+	e.Lock()
+	defer e.Unlock()
+	e.compat = profile
+}
+
+// Set or remove the guide element of the package file, following the
+// compatibility profile
+//
+// Must be called after the sections have been written
+func (e *Epub) writeGuide() {
+	if !e.compat.Guide {
+		e.pkg.setGuide(nil)
+		return
+	}
+	var refs []pkgReference
+	if e.cover.xhtmlFilename != "" {
+		refs = append(refs, pkgReference{
+			Type:  "cover",
+			Title: guideCoverTitle,
+			Href:  filepath.ToSlash(filepath.Join(xhtmlFolderName, e.cover.xhtmlFilename)),
+		})
+	}
+	refs = append(refs, pkgReference{
+		Type:  "toc",
+		Title: guideTocTitle,
+		Href:  tocNavFilename,
+	})
+	for _, s := range e.sections {
+		if s.filename != e.cover.xhtmlFilename {
+			refs = append(refs, pkgReference{
+				Type:  "text",
+				Title: guideTextTitle,
+				Href:  filepath.ToSlash(filepath.Join(xhtmlFolderName, s.filename)),
+			})
+			break
+		}
+	}
+	e.pkg.setGuide(refs)
+}
+
+var (
+	cssCommentRegexp    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssWhitespaceRegexp = regexp.MustCompile(`\s+`)
+	cssColonRegexp      = regexp.MustCompile(`\s*:\s*`)
+)
+
+// Report the properties of a CSS file that the reading systems of the
+// compatibility profile don't support
+func (e *Epub) checkCSSCompat(filename string, data []byte) {
+	for _, property := range unsupportedCSS(string(data), e.compat.UnsupportedCSS) {
+		e.report.add(SeverityWarning, RuleCSSCompat, filename, "%s isn't supported by the %s profile", property, e.compat.Name)
+	}
+}
+
+// Return the unsupported properties used by the CSS stylesheet
+func unsupportedCSS(css string, unsupported []string) []string {
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+	var used []string
+	seen := make(map[string]bool)
+	for _, declaration := range strings.FieldsFunc(css, func(r rune) bool { return r == ';' || r == '{' || r == '}' }) {
+		declaration = strings.ToLower(strings.TrimSpace(cssWhitespaceRegexp.ReplaceAllString(declaration, " ")))
+		declaration = cssColonRegexp.ReplaceAllString(declaration, ": ")
+		for _, property := range unsupported {
+			if seen[property] {
+				continue
+			}
+			if declaration == property || strings.HasPrefix(declaration, property+":") ||
+				strings.Contains(property, ":") && strings.HasPrefix(declaration, property) ||
+				strings.HasPrefix(property, "@") && strings.HasPrefix(declaration, property) {
+				seen[property] = true
+				used = append(used, property)
+			}
+		}
+	}
+	return used
+}
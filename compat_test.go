@@ -0,0 +1,100 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestCompatProfile(t *testing.T) {
+	newEpub := func(profile CompatProfile) map[string]string {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetCompatProfile(profile)
+		imagePath, err := e.AddImage(testImageFromFileSource, "cover.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SetCover(imagePath, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSection("<p>Chapter one</p>", "Chapter 1", "chapter1.xhtml", ""); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return readZipFiles(t, b.Bytes())
+	}
+
+	generic := newEpub(ProfileGeneric)
+	if strings.Contains(generic["EPUB/package.opf"], "<guide>") {
+		t.Error("Expected no guide element with the generic profile")
+	}
+	if _, ok := generic["EPUB/toc.ncx"]; !ok || !strings.Contains(generic["EPUB/package.opf"], `<spine toc="ncx">`) {
+		t.Error("Expected an NCX with the generic profile")
+	}
+
+	kindle := newEpub(ProfileKindle)
+	for _, ref := range []string{
+		`<reference type="cover" title="Cover" href="xhtml/cover.xhtml"></reference>`,
+		`<reference type="toc" title="Table of Contents" href="nav.xhtml"></reference>`,
+		`<reference type="text" title="Start" href="xhtml/chapter1.xhtml"></reference>`,
+	} {
+		if !strings.Contains(kindle["EPUB/package.opf"], ref) {
+			t.Errorf("Expected the guide to contain %s", ref)
+		}
+	}
+
+	custom := ProfileGeneric
+	custom.NCX = false
+	custom.CoverMeta = false
+	files := newEpub(custom)
+	if _, ok := files["EPUB/toc.ncx"]; ok {
+		t.Error("Expected no NCX")
+	}
+	if strings.Contains(files["EPUB/package.opf"], "toc.ncx") || strings.Contains(files["EPUB/package.opf"], `toc="`) {
+		t.Error("Expected the NCX not to be referenced")
+	}
+	if strings.Contains(files["EPUB/package.opf"], `name="cover"`) {
+		t.Error("Expected no cover meta element")
+	}
+}
+
+func TestCompatProfileCSS(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetCompatProfile(ProfileADE)
+	css := "/* display: grid */\n.a { DISPLAY : flex; }\n.b{transform:rotate(3deg)}\n@media print { .c { color: red } }\n.d { display: block }"
+	if _, err := e.AddCSS(dataurl.New([]byte(css), "text/css").String(), "style.css"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>Chapter</p>", "Chapter", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	var reported []string
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleCSSCompat && w.Filename == "style.css" {
+			reported = append(reported, w.Message)
+		}
+	}
+	want := []string{
+		"display: flex isn't supported by the ade profile",
+		"transform isn't supported by the ade profile",
+		"@media isn't supported by the ade profile",
+	}
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("Expected %v, got %v", want, reported)
+	}
+}
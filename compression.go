@@ -0,0 +1,88 @@
+package epub
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultStoredMediaTypes are media types of already compressed files, which
+// gain little from being compressed again.
+var DefaultStoredMediaTypes = []string{
+	"audio/mp4",
+	"audio/mpeg",
+	"font/woff",
+	"font/woff2",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"video/mp4",
+	"video/webm",
+}
+
+// CompressionOptions configures how the files of the EPUB are compressed in
+// the zip container. The mimetype file is always stored uncompressed.
+type CompressionOptions struct {
+	// Level of the deflate compression, from flate.BestSpeed (1) to
+	// flate.BestCompression (9); the default level if zero
+	Level int
+	// Media types of the media files stored without compression, e.g.
+	// DefaultStoredMediaTypes, which speeds up the writing of media-heavy
+	// books
+	StoredMediaTypes []string
+}
+
+// InvalidCompressionLevelError is thrown by SetCompression if the compression
+// level isn't a valid deflate level.
+type InvalidCompressionLevelError struct {
+	Level int // The level that was given
+}
+
+func (e *InvalidCompressionLevelError) Error() string {
+	return fmt.Sprintf("Invalid compression level %d: must be between %d and %d", e.Level, flate.BestSpeed, flate.BestCompression)
+}
+
+// SetCompression sets how the files of the EPUB are compressed when it is
+// written. By default, all the files are compressed with the default deflate
+// level.
+func (e *Epub) SetCompression(opts CompressionOptions) error {
+	if opts.Level != 0 && (opts.Level < flate.BestSpeed || opts.Level > flate.BestCompression) {
+		return &InvalidCompressionLevelError{Level: opts.Level}
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.compression = opts
+	e.compression.StoredMediaTypes = append([]string(nil), opts.StoredMediaTypes...)
+	return nil
+}
+
+// Register the compressor of the compression level, if any
+func (o CompressionOptions) register(z *zip.Writer) {
+	if o.Level == 0 {
+		return
+	}
+	level := o.Level
+	z.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+}
+
+// Return the compression method of a file with the given media type
+func (o CompressionOptions) method(mediaType string) uint16 {
+	if mediaType == "" {
+		return zip.Deflate
+	}
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	for _, stored := range o.StoredMediaTypes {
+		if strings.EqualFold(stored, mediaType) {
+			return zip.Store
+		}
+	}
+	return zip.Deflate
+}
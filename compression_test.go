@@ -0,0 +1,60 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetCompression(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, "image.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(strings.Repeat("<p>Lorem ipsum dolor sit amet.</p>", 100), "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var invalid *InvalidCompressionLevelError
+	if err := e.SetCompression(CompressionOptions{Level: 12}); !errors.As(err, &invalid) {
+		t.Errorf("Expected an invalid level error, got %v", err)
+	}
+
+	write := func(opts CompressionOptions) map[string]*zip.File {
+		if err := e.SetCompression(opts); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]*zip.File)
+		for _, f := range z.File {
+			files[f.Name] = f
+		}
+		return files
+	}
+
+	files := write(CompressionOptions{})
+	if files["EPUB/images/image.png"].Method != zip.Deflate || files[mimetypeFilename].Method != zip.Store {
+		t.Error("Expected all the files but the mimetype file to be compressed by default")
+	}
+	defaultSize := files["EPUB/xhtml/one.xhtml"].CompressedSize64
+
+	files = write(CompressionOptions{Level: flate.BestSpeed, StoredMediaTypes: DefaultStoredMediaTypes})
+	if files["EPUB/images/image.png"].Method != zip.Store {
+		t.Error("Expected the PNG image to be stored")
+	}
+	if f := files["EPUB/xhtml/one.xhtml"]; f.Method != zip.Deflate || f.CompressedSize64 == defaultSize {
+		t.Errorf("Expected the section to be compressed with another level, got %d bytes like the default level", f.CompressedSize64)
+	}
+}
@@ -0,0 +1,90 @@
+package epub
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// BuildTagsAttribute is the attribute marking content of the section bodies
+// that is only written in some editions, see SetBuildTags.
+const BuildTagsAttribute = "data-edition"
+
+// SetBuildTags sets the build tags of the edition written, so that the
+// chapters of several editions can share their sources. Elements of the
+// section bodies with a data-edition attribute (BuildTagsAttribute) are only
+// written if one of the space-separated tags of the attribute is a build tag:
+//
+//	<div data-edition="teacher">Answers...</div>
+//	<p data-edition="print ebook">...</p>
+//
+// Content without the attribute is always written. Setting no build tags, the
+// default, writes all the content. Media only referenced by left out content
+// are still written.
+func (e *Epub) SetBuildTags(tags ...string) {
+	e.Lock()
+	defer e.Unlock()
+	if len(tags) == 0 {
+		e.buildTags = nil
+		return
+	}
+	e.buildTags = append([]string(nil), tags...)
+}
+
+// Remove the elements of a section body whose build tags don't match the given
+// ones. The rest of the body is kept verbatim.
+func filterBuildTags(body string, tags []string) string {
+	const prefix = "<body>"
+	d := xml.NewDecoder(strings.NewReader(prefix + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	last := 0
+	for {
+		start := int(d.InputOffset()) - len(prefix)
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		tagged, matched := false, false
+		for _, a := range se.Attr {
+			if a.Name.Space == "" && a.Name.Local == BuildTagsAttribute {
+				tagged, matched = true, matchesBuildTags(a.Value, tags)
+			}
+		}
+		if !tagged || matched {
+			continue
+		}
+		if err := d.Skip(); err != nil {
+			break
+		}
+		end := int(d.InputOffset()) - len(prefix)
+		if end > len(body) {
+			end = len(body)
+		}
+		b.WriteString(body[last:start])
+		last = end
+	}
+	if last == 0 {
+		return body
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// Report whether one of the space-separated tags of value is a build tag
+func matchesBuildTags(value string, tags []string) bool {
+	for _, v := range strings.Fields(value) {
+		for _, t := range tags {
+			if v == t {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFilterBuildTags(t *testing.T) {
+	body := `<p>Intro</p><div data-edition="teacher"><p>Answers<br/></p></div><p data-edition="student ebook">Exercises &amp; more</p><img data-edition="print" src="a.png" alt=""/>`
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{[]string{"teacher"}, `<p>Intro</p><div data-edition="teacher"><p>Answers<br/></p></div>`},
+		{[]string{"ebook"}, `<p>Intro</p><p data-edition="student ebook">Exercises &amp; more</p>`},
+		{[]string{"print", "teacher"}, `<p>Intro</p><div data-edition="teacher"><p>Answers<br/></p></div><img data-edition="print" src="a.png" alt=""/>`},
+		{[]string{"other"}, `<p>Intro</p>`},
+	}
+	for _, test := range tests {
+		if got := filterBuildTags(body, test.tags); got != test.expected {
+			t.Errorf("filterBuildTags(%v):\ngot      %s\nexpected %s", test.tags, got, test.expected)
+		}
+	}
+}
+
+func TestSetBuildTags(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p>Question</p><p data-edition="teacher">Answer</p>`, testSectionTitle, "quiz.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	write := func() string {
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		return readZipFiles(t, b.Bytes())["EPUB/xhtml/quiz.xhtml"]
+	}
+
+	e.SetBuildTags("student")
+	if section := write(); strings.Contains(section, "Answer") || !strings.Contains(section, "Question") {
+		t.Errorf("Expected the answer to be left out of the student edition:\n%s", section)
+	}
+	e.SetBuildTags("teacher")
+	if section := write(); !strings.Contains(section, "Answer") {
+		t.Errorf("Expected the answer in the teacher edition:\n%s", section)
+	}
+	e.SetBuildTags()
+	if section := write(); !strings.Contains(section, "Answer") {
+		t.Errorf("Expected all the content without build tags:\n%s", section)
+	}
+}
@@ -0,0 +1,100 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MARC relator codes of the roles of the contributors, see AddContributor
+const (
+	ContributorRoleAuthor       = "aut"
+	ContributorRoleContributor  = "ctb"
+	ContributorRoleEditor       = "edt"
+	ContributorRoleIllustrator  = "ill"
+	ContributorRoleNarrator     = "nrt"
+	ContributorRolePhotographer = "pht"
+	ContributorRoleTranslator   = "trl"
+)
+
+const pkgContributorIDFormat = "contributor-%d"
+
+// Contributor is a contributor of the EPUB besides its author
+// (dc:contributor), e.g. its translator or illustrator.
+type Contributor struct {
+	Name string `json:"name"`
+	// MARC relator code of the role of the contributor, e.g.
+	// ContributorRoleTranslator, or "" if unspecified
+	Role string `json:"role,omitempty"`
+}
+
+// The <dc:contributor> element
+type pkgContributor struct {
+	ID   string `xml:"id,attr"`
+	Data string `xml:",chardata"`
+}
+
+// Contributors returns the contributors of the EPUB, in the order they were
+// added.
+func (e *Epub) Contributors() []Contributor {
+	e.Lock()
+	defer e.Unlock()
+	return append([]Contributor(nil), e.contributors...)
+}
+
+// AddContributor adds a contributor to the EPUB besides its author (see
+// SetAuthor), with the MARC relator code of their role, e.g.
+//
+//	e.AddContributor("Jane Doe", ContributorRoleTranslator)
+//
+// The role may be empty.
+func (e *Epub) AddContributor(name string, role string) {
+	e.Lock()
+	defer e.Unlock()
+	e.addContributor(Contributor{Name: name, Role: role})
+}
+
+func (e *Epub) addContributor(c Contributor) {
+	c.Name = e.sanitize("", "contributor", c.Name)
+	c.Role = e.sanitize("", "contributor role", c.Role)
+	e.contributors = append(e.contributors, c)
+	e.pkg.setContributors(e.contributors)
+}
+
+// Set the dc:contributor elements and the role refinements of the
+// contributors
+func (p *pkg) setContributors(contributors []Contributor) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if !(strings.HasPrefix(m.Refines, "#contributor-") && m.Property == pkgAuthorProperty) {
+			metas = append(metas, m)
+		}
+	}
+	p.xml.Metadata.Contributors = nil
+	for i, c := range contributors {
+		contributor := pkgContributor{ID: fmt.Sprintf(pkgContributorIDFormat, i+1), Data: c.Name}
+		if c.Role != "" {
+			metas = append(metas, pkgMeta{Refines: "#" + contributor.ID, Property: pkgAuthorProperty, Scheme: pkgAuthorScheme, Data: c.Role})
+		}
+		p.xml.Metadata.Contributors = append(p.xml.Metadata.Contributors, contributor)
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Set the contributors of a freshly created Epub from the package file
+func (e *Epub) readContributors(p *readPackage) {
+	roles := make(map[string]string)
+	for _, m := range p.Metadata.Metas {
+		if m.Property == pkgAuthorProperty && m.Refines != "" {
+			roles[strings.TrimPrefix(m.Refines, "#")] = strings.TrimSpace(m.Value)
+		}
+	}
+	for _, c := range p.Metadata.Contributors {
+		contributor := Contributor{Name: strings.TrimSpace(c.Value)}
+		if c.ID != "" {
+			contributor.Role = roles[c.ID]
+		}
+		if contributor.Name != "" {
+			e.addContributor(contributor)
+		}
+	}
+}
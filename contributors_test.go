@@ -0,0 +1,55 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddContributor(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetAuthor("Ann Author")
+	e.AddContributor("Tom Translator", ContributorRoleTranslator)
+	e.AddContributor("Nobody", "")
+	expected := []Contributor{
+		{Name: "Tom Translator", Role: ContributorRoleTranslator},
+		{Name: "Nobody"},
+	}
+	if !reflect.DeepEqual(e.Contributors(), expected) {
+		t.Errorf("Unexpected contributors %v", e.Contributors())
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, element := range []string{
+		`<dc:contributor id="contributor-1">Tom Translator</dc:contributor>`,
+		`<dc:contributor id="contributor-2">Nobody</dc:contributor>`,
+		`<meta refines="#contributor-1" property="role" scheme="marc:relators">trl</meta>`,
+		`<meta refines="#creator" property="role" scheme="marc:relators" id="role">aut</meta>`,
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened.Contributors(), expected) {
+		t.Errorf("Contributors not read back: %v", opened.Contributors())
+	}
+	if opened.Author() != "Ann Author" {
+		t.Errorf("Expected the author to be read back, got %q", opened.Author())
+	}
+}
@@ -0,0 +1,98 @@
+package epub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Prefixes reserved by the EPUB specification, which are available without
+// being declared
+var reservedPrefixes = map[string]bool{
+	"a11y": true, "dcterms": true, "marc": true, "media": true, "onix": true,
+	"rendition": true, "schema": true, "xsd": true, "msv": true, "prism": true,
+}
+
+// Valid prefix names (NCName without colons)
+var prefixNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// InvalidPrefixError is returned by AddPrefix if the prefix can't be
+// declared.
+type InvalidPrefixError struct {
+	Prefix string // The prefix that was given
+	Reason string // Why the prefix can't be declared
+}
+
+func (e *InvalidPrefixError) Error() string {
+	return fmt.Sprintf("Prefix %s can't be declared: %s", e.Prefix, e.Reason)
+}
+
+// AddPrefix declares a vocabulary prefix in the package file (the prefix
+// attribute of the package element), so that the properties of this
+// vocabulary can be used with AddMeta, e.g.
+//
+//	e.AddPrefix("ibooks", "http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/")
+//	e.AddMeta("ibooks:version", "1.2", "")
+//
+// Declaring a prefix again replaces its URI. InvalidPrefixError is returned
+// if the prefix isn't a valid name or is one of the prefixes reserved by the
+// EPUB specification (a11y, dcterms, marc, media, onix, rendition, schema,
+// xsd, msv and prism), which are available without being declared.
+func (e *Epub) AddPrefix(prefix string, uri string) error {
+	e.Lock()
+	defer e.Unlock()
+	if !prefixNameRegex.MatchString(prefix) {
+		return &InvalidPrefixError{Prefix: prefix, Reason: "invalid prefix name"}
+	}
+	if reservedPrefixes[prefix] {
+		return &InvalidPrefixError{Prefix: prefix, Reason: "reserved prefix"}
+	}
+	if e.prefixes == nil {
+		e.prefixes = make(map[string]string)
+	}
+	e.prefixes[prefix] = uri
+	e.pkg.setPrefixes(e.prefixes)
+	return nil
+}
+
+// AddMeta adds a meta element to the package file, for the metadata the
+// package doesn't handle, e.g. rendition properties, schema.org accessibility
+// metadata or the properties of reading systems vocabularies:
+//
+//	<meta property="schema:accessMode">textual</meta>
+//
+// refines is the id of the element refined by the meta, with or without its
+// leading #, or "" if the meta applies to the publication. The properties of
+// vocabularies that aren't reserved must be declared with AddPrefix.
+func (e *Epub) AddMeta(property string, value string, refines string) {
+	e.Lock()
+	defer e.Unlock()
+	m := pkgMeta{
+		Property: property,
+		Data:     e.sanitize("", property, value),
+	}
+	if refines != "" {
+		m.Refines = "#" + strings.TrimPrefix(e.sanitize("", property, refines), "#")
+	}
+	e.pkg.addMeta(m)
+}
+
+func (p *pkg) addMeta(m pkgMeta) {
+	p.xml.Metadata.Meta = append(p.xml.Metadata.Meta, m)
+}
+
+// Set the prefix attribute of the package element from the declared
+// prefixes, in prefix order
+func (p *pkg) setPrefixes(prefixes map[string]string) {
+	names := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		names = append(names, prefix)
+	}
+	sort.Strings(names)
+	declarations := make([]string, len(names))
+	for i, prefix := range names {
+		declarations[i] = prefix + ": " + prefixes[prefix]
+	}
+	p.xml.Prefix = strings.Join(declarations, " ")
+}
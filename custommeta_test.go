@@ -0,0 +1,54 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddMeta(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddPrefix("ibooks", "http://example.com/old/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddPrefix("ibooks", "http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddPrefix("calibre", "https://calibre-ebook.com"); err != nil {
+		t.Fatal(err)
+	}
+	var invalid *InvalidPrefixError
+	for _, prefix := range []string{"dcterms", "rendition", "1abc", "a:b", ""} {
+		if err := e.AddPrefix(prefix, "http://example.com/"); !errors.As(err, &invalid) {
+			t.Errorf("Prefix %q: expected an InvalidPrefixError, got %v", prefix, err)
+		}
+	}
+	e.AddMeta("ibooks:version", "1.2", "")
+	e.AddMeta("rendition:layout", "pre-paginated", "")
+	e.AddMeta("schema:accessMode", "textual", "")
+	e.AddMeta("role", "edt", "#creator")
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`prefix="calibre: https://calibre-ebook.com ibooks: http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/"`,
+		`<meta property="ibooks:version">1.2</meta>`,
+		`<meta property="rendition:layout">pre-paginated</meta>`,
+		`<meta property="schema:accessMode">textual</meta>`,
+		`<meta refines="#creator" property="role">edt</meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+}
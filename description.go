@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// HTML elements kept by SetHTMLDescription, the ones the stores accept in
+// descriptions
+var descriptionElements = map[string]bool{
+	"b": true, "blockquote": true, "br": true, "em": true, "i": true,
+	"li": true, "ol": true, "p": true, "s": true, "strong": true,
+	"sub": true, "sup": true, "u": true, "ul": true,
+}
+
+// SetHTMLDescription sets the description of the EPUB from HTML, e.g. the blurb
+// of the book. If stripTags is false, the description keeps the basic
+// formatting stores such as Kobo accept (paragraphs, line breaks, emphasis and
+// lists): the other elements and all the attributes are removed, keeping their
+// text, and the HTML is written escaped in the dc:description element.
+// Otherwise the description is the text of the HTML, one line per paragraph,
+// as SetDescription sets it.
+func (e *Epub) SetHTMLDescription(html string, stripTags bool) {
+	e.Lock()
+	defer e.Unlock()
+	desc := proofText(html)
+	if !stripTags {
+		desc = descriptionHTML(html)
+	}
+	desc = e.sanitize("", "description", desc)
+	e.desc = desc
+	e.pkg.setDescription(desc)
+}
+
+// Return the HTML of a description limited to the elements of
+// descriptionElements, without attributes
+func descriptionHTML(html string) string {
+	d := xml.NewDecoder(strings.NewReader("<body>" + html + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "script" || name == "style":
+				d.Skip()
+			case name == "br":
+				b.WriteString("<br/>")
+			case descriptionElements[name]:
+				b.WriteString("<" + name + ">")
+			}
+		case xml.EndElement:
+			if name := strings.ToLower(t.Name.Local); name != "br" && descriptionElements[name] {
+				b.WriteString("</" + name + ">")
+			}
+		case xml.CharData:
+			canonicalTextEscaper.WriteString(&b, string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,35 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetHTMLDescription(t *testing.T) {
+	blurb := `<p class="blurb">A <b>dragon</b> &amp; a <a href="https://example.com">knight</a>.<br>
+<script>alert(1)</script></p><ul><li><em>Epic</em></li></ul><div>The end.</div>`
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetHTMLDescription(blurb, false)
+	expected := "<p>A <b>dragon</b> &amp; a knight.<br/>\n</p><ul><li><em>Epic</em></li></ul>The end."
+	if e.Description() != expected {
+		t.Errorf("Expected description %q, got %q", expected, e.Description())
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if escaped := "<dc:description>&lt;p&gt;A &lt;b&gt;dragon&lt;/b&gt; &amp;amp; a knight."; !strings.Contains(opf, escaped) {
+		t.Errorf("Expected the escaped HTML description in the package file, got:\n%s", opf)
+	}
+
+	e.SetHTMLDescription(blurb, true)
+	if expected := "A dragon & a knight.\nEpic\nThe end."; e.Description() != expected {
+		t.Errorf("Expected description %q, got %q", expected, e.Description())
+	}
+}
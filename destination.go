@@ -0,0 +1,263 @@
+package epub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPartSize is the size of the parts of a MultipartDestination that
+// doesn't set its own.
+const DefaultPartSize = 8 << 20
+
+// Destination is where WriteToDestination writes an EPUB, e.g. a local file
+// (see FileDestination), an io.Writer (see WriterDestination) or an object
+// storage (see MultipartDestination).
+type Destination interface {
+	// Open starts writing an EPUB and returns the writer it is written to.
+	// The writes stop when ctx is done.
+	Open(ctx context.Context) (DestinationWriter, error)
+}
+
+// DestinationWriter is the writer of a Destination an EPUB is written to.
+type DestinationWriter interface {
+	io.Writer
+	// Commit is called once the whole EPUB has been written, e.g. to
+	// complete an upload
+	Commit() error
+	// Abort is called if the EPUB couldn't be written or committed, to
+	// discard what was written
+	Abort() error
+}
+
+// WriteToDestination is like WriteToContext, but writes the EPUB to a
+// Destination, which is committed once the EPUB has been written or aborted
+// if the write or the commit fails. The return value is the number of bytes
+// written.
+func (e *Epub) WriteToDestination(ctx context.Context, dst Destination) (int64, error) {
+	e.Lock()
+	defer e.Unlock()
+	w, err := dst.Open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := e.writeTo(ctx, w)
+	if err == nil {
+		if err = w.Commit(); err == nil {
+			return n, nil
+		}
+	}
+	if abortErr := w.Abort(); abortErr != nil {
+		return n, errors.Join(err, fmt.Errorf("unable to abort the write: %w", abortErr))
+	}
+	return n, err
+}
+
+// FileDestination returns a Destination writing the EPUB to a local file. The
+// EPUB is written to a temporary file of the same folder first, renamed once
+// complete, so that the file at destFilePath is never a partial EPUB.
+func FileDestination(destFilePath string) Destination {
+	return fileDestination(destFilePath)
+}
+
+type fileDestination string
+
+func (d fileDestination) Open(ctx context.Context) (DestinationWriter, error) {
+	f, err := os.CreateTemp(filepath.Dir(string(d)), "."+filepath.Base(string(d))+"-*")
+	if err != nil {
+		return nil, &UnableToCreateEpubError{
+			Path: string(d),
+			Err:  err,
+		}
+	}
+	return &fileDestinationWriter{File: f, path: string(d)}, nil
+}
+
+type fileDestinationWriter struct {
+	*os.File
+	// Final path of the file
+	path string
+}
+
+func (w *fileDestinationWriter) Commit() error {
+	if err := w.Close(); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	if err := os.Chmod(w.Name(), filePermissions); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	if err := os.Rename(w.Name(), w.path); err != nil {
+		os.Remove(w.Name())
+		return &UnableToCreateEpubError{
+			Path: w.path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (w *fileDestinationWriter) Abort() error {
+	w.Close()
+	// A failed commit has already removed the file
+	if err := os.Remove(w.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WriterDestination returns a Destination writing the EPUB to w. Committing
+// or aborting does nothing: what was written to w is left as is.
+func WriterDestination(w io.Writer) Destination {
+	return writerDestination{w}
+}
+
+type writerDestination struct {
+	io.Writer
+}
+
+func (d writerDestination) Open(ctx context.Context) (DestinationWriter, error) {
+	return d, nil
+}
+
+func (d writerDestination) Commit() error { return nil }
+func (d writerDestination) Abort() error  { return nil }
+
+// PartUploader uploads an EPUB in parts, e.g. with the multipart upload of an
+// object storage such as S3, see MultipartDestination.
+type PartUploader interface {
+	// UploadPart uploads a part of the EPUB. Parts are numbered from 1 and
+	// all have the part size of the destination but the last one. data is
+	// reused for the next part once UploadPart returns, so it must not be
+	// kept.
+	UploadPart(ctx context.Context, number int, data []byte) error
+	// Complete completes the upload of the given number of parts
+	Complete(ctx context.Context, parts int) error
+	// Abort aborts the upload, discarding the uploaded parts
+	Abort(ctx context.Context) error
+}
+
+// ResumablePartUploader is a PartUploader that keeps the parts of an upload
+// that failed, so that writing the EPUB again resumes the upload.
+type ResumablePartUploader interface {
+	PartUploader
+	// UploadedParts returns the checksums (the hexadecimal SHA-256) of the
+	// parts already uploaded, by part number
+	UploadedParts(ctx context.Context) (map[int]string, error)
+}
+
+// MultipartDestination is a Destination uploading the EPUB in parts while it
+// is written, so that it can be streamed to an object storage without being
+// stored locally first.
+//
+// The upload of a part is retried after an error according to Retry (its
+// StatusCodes aren't used). If the uploader is a ResumablePartUploader, the
+// upload isn't aborted when the write fails, and writing the EPUB again only
+// uploads the parts that aren't already uploaded with the same content, which
+// are usually all of them unless the EPUB is written in reproducible mode
+// (see SetReproducible).
+type MultipartDestination struct {
+	Uploader PartUploader
+	// Size of the parts; if 0, DefaultPartSize
+	PartSize int
+	Retry    RetryPolicy
+}
+
+func (d *MultipartDestination) Open(ctx context.Context) (DestinationWriter, error) {
+	w := &multipartWriter{d: d, ctx: ctx, partSize: d.PartSize}
+	if w.partSize <= 0 {
+		w.partSize = DefaultPartSize
+	}
+	if r, ok := d.Uploader.(ResumablePartUploader); ok {
+		uploaded, err := r.UploadedParts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the uploaded parts: %w", err)
+		}
+		w.uploaded = uploaded
+	}
+	return w, nil
+}
+
+type multipartWriter struct {
+	d *MultipartDestination
+	// Context of the write, see Destination.Open
+	ctx      context.Context
+	partSize int
+	// Content of the current part
+	buf []byte
+	// Number of parts sent
+	parts int
+	// Checksums of the parts uploaded by a previous write, by part number
+	uploaded map[int]string
+}
+
+func (w *multipartWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := w.partSize - len(w.buf)
+		if chunk > len(p) {
+			chunk = len(p)
+		}
+		w.buf = append(w.buf, p[:chunk]...)
+		p = p[chunk:]
+		if len(w.buf) == w.partSize {
+			if err := w.flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Upload the current part, unless it was uploaded by a previous write
+func (w *multipartWriter) flush() error {
+	w.parts++
+	number := w.parts
+	sum := sha256.Sum256(w.buf)
+	if checksum, ok := w.uploaded[number]; ok && checksum == hex.EncodeToString(sum[:]) {
+		w.buf = w.buf[:0]
+		return nil
+	}
+	retry := w.d.Retry
+	for attempt := 1; ; attempt++ {
+		err := w.d.Uploader.UploadPart(w.ctx, number, w.buf)
+		if err == nil {
+			w.buf = w.buf[:0]
+			return nil
+		}
+		if attempt >= retry.Attempts || w.ctx.Err() != nil {
+			return fmt.Errorf("unable to upload part %d: %w", number, err)
+		}
+		t := time.NewTimer(retry.delay(attempt, nil))
+		select {
+		case <-t.C:
+		case <-w.ctx.Done():
+			t.Stop()
+			return fmt.Errorf("unable to upload part %d: %w", number, w.ctx.Err())
+		}
+	}
+}
+
+func (w *multipartWriter) Commit() error {
+	if len(w.buf) > 0 || w.parts == 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	return w.d.Uploader.Complete(w.ctx, w.parts)
+}
+
+func (w *multipartWriter) Abort() error {
+	if _, ok := w.d.Uploader.(ResumablePartUploader); ok {
+		return nil
+	}
+	return w.d.Uploader.Abort(context.WithoutCancel(w.ctx))
+}
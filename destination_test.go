@@ -0,0 +1,174 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteToFileDestination(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "book.epub")
+	n, err := e.WriteToDestination(context.Background(), FileDestination(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != n {
+		t.Errorf("Expected %d bytes, got %d", n, info.Size())
+	}
+
+	// A failed write leaves neither the EPUB nor a temporary file
+	e.SetBuildMode(BuildModeStrict)
+	e.SetLang("not a language")
+	failed := filepath.Join(dir, "failed.epub")
+	if _, err := e.WriteToDestination(context.Background(), FileDestination(failed)); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "book.epub" {
+		t.Errorf("Expected only book.epub in the folder, got %v", entries)
+	}
+}
+
+// PartUploader storing the parts in memory, failing the uploads of the parts
+// in failures the given number of times
+type testPartUploader struct {
+	parts     map[int][]byte
+	failures  map[int]int
+	completed int
+	aborted   bool
+	resumable bool
+	// Errors returned by Complete and Abort
+	completeErr, abortErr error
+}
+
+func (u *testPartUploader) UploadPart(ctx context.Context, number int, data []byte) error {
+	if u.failures[number] > 0 {
+		u.failures[number]--
+		return errors.New("connection reset")
+	}
+	u.parts[number] = append([]byte(nil), data...)
+	return nil
+}
+
+func (u *testPartUploader) Complete(ctx context.Context, parts int) error {
+	if u.completeErr != nil {
+		return u.completeErr
+	}
+	u.completed = parts
+	return nil
+}
+
+func (u *testPartUploader) Abort(ctx context.Context) error {
+	u.aborted = true
+	return u.abortErr
+}
+
+func (u *testPartUploader) content() []byte {
+	var b bytes.Buffer
+	for i := 1; i <= u.completed; i++ {
+		b.Write(u.parts[i])
+	}
+	return b.Bytes()
+}
+
+type testResumableUploader struct {
+	*testPartUploader
+	// Parts uploaded by UploadPart, to check which are uploaded again
+	uploads []int
+}
+
+func (u *testResumableUploader) UploadPart(ctx context.Context, number int, data []byte) error {
+	u.uploads = append(u.uploads, number)
+	return u.testPartUploader.UploadPart(ctx, number, data)
+}
+
+func (u *testResumableUploader) UploadedParts(ctx context.Context) (map[int]string, error) {
+	sums := make(map[int]string)
+	for number, data := range u.parts {
+		sum := sha256.Sum256(data)
+		sums[number] = hex.EncodeToString(sum[:])
+	}
+	return sums, nil
+}
+
+func TestWriteToMultipartDestination(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetReproducible(true)
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var expected bytes.Buffer
+	if _, err := e.WriteTo(&expected); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &testPartUploader{parts: make(map[int][]byte), failures: map[int]int{2: 1}}
+	dst := &MultipartDestination{Uploader: u, PartSize: 512, Retry: RetryPolicy{Attempts: 2}}
+	if _, err := e.WriteToDestination(context.Background(), dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(u.content(), expected.Bytes()) {
+		t.Errorf("Expected the uploaded parts to be the EPUB (%d parts)", u.completed)
+	}
+
+	// Without retries, the failed upload is aborted
+	u = &testPartUploader{parts: make(map[int][]byte), failures: map[int]int{3: 1}}
+	dst = &MultipartDestination{Uploader: u, PartSize: 512}
+	if _, err := e.WriteToDestination(context.Background(), dst); err == nil || !u.aborted {
+		t.Errorf("Expected the upload to fail and be aborted, got %v", err)
+	}
+
+	// A failed completion aborts the upload too, and the abort error is
+	// returned along with it
+	completeErr, abortErr := errors.New("complete failed"), errors.New("abort failed")
+	u = &testPartUploader{parts: make(map[int][]byte), completeErr: completeErr, abortErr: abortErr}
+	dst = &MultipartDestination{Uploader: u, PartSize: 512}
+	_, err = e.WriteToDestination(context.Background(), dst)
+	if !u.aborted || !errors.Is(err, completeErr) || !errors.Is(err, abortErr) {
+		t.Errorf("Expected the upload to be aborted after the failed completion, got %v", err)
+	}
+
+	// A resumable upload keeps the uploaded parts, and the next write only
+	// uploads the remaining ones
+	r := &testResumableUploader{testPartUploader: &testPartUploader{parts: make(map[int][]byte), failures: map[int]int{3: 1}}}
+	dst = &MultipartDestination{Uploader: r, PartSize: 512}
+	if _, err := e.WriteToDestination(context.Background(), dst); err == nil || r.aborted {
+		t.Fatalf("Expected the upload to fail without being aborted, got %v", err)
+	}
+	r.uploads = nil
+	if _, err := e.WriteToDestination(context.Background(), dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.uploads) == 0 || r.uploads[0] != 3 {
+		t.Errorf("Expected the upload to resume from part 3, got uploads %v", r.uploads)
+	}
+	if !bytes.Equal(r.content(), expected.Bytes()) {
+		t.Error("Expected the resumed upload to be the EPUB")
+	}
+}
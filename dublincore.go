@@ -0,0 +1,112 @@
+package epub
+
+import "regexp"
+
+// Dates and times of the W3C Date and Time Formats profile of ISO 8601, as
+// required by EPUB for dc:date: a year, optionally followed by the month, the
+// day and a time with its time zone
+var w3cdtfRegex = regexp.MustCompile(`^\d{4}(?:-\d{2}(?:-\d{2}(?:T\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:\d{2}))?)?)?$`)
+
+// Publisher returns the publisher of the EPUB.
+func (e *Epub) Publisher() string {
+	return e.publisher
+}
+
+// Rights returns the rights statement of the EPUB.
+func (e *Epub) Rights() string {
+	return e.rights
+}
+
+// Date returns the publication date of the EPUB.
+func (e *Epub) Date() string {
+	return e.date
+}
+
+// Source returns the source of the EPUB.
+func (e *Epub) Source() string {
+	return e.source
+}
+
+// Type returns the type of the EPUB.
+func (e *Epub) Type() string {
+	return e.dcType
+}
+
+// Coverage returns the coverage of the EPUB.
+func (e *Epub) Coverage() string {
+	return e.coverage
+}
+
+// Relation returns the related resource of the EPUB.
+func (e *Epub) Relation() string {
+	return e.relation
+}
+
+// SetPublisher sets the publisher of the EPUB (dc:publisher).
+func (e *Epub) SetPublisher(publisher string) {
+	e.Lock()
+	defer e.Unlock()
+	publisher = e.sanitize("", "publisher", publisher)
+	e.publisher = publisher
+	e.pkg.setPublisher(publisher)
+}
+
+// SetRights sets the rights statement of the EPUB (dc:rights), e.g. a
+// copyright notice or a license.
+func (e *Epub) SetRights(rights string) {
+	e.Lock()
+	defer e.Unlock()
+	rights = e.sanitize("", "rights", rights)
+	e.rights = rights
+	e.pkg.setRights(rights)
+}
+
+// SetDate sets the publication date of the EPUB (dc:date), not to be confused
+// with its last modification date. The date must follow the W3C Date and Time
+// Formats, e.g. "2024", "2024-05" or "2024-05-01"; other dates are reported by
+// the validator (RuleInvalidDate).
+func (e *Epub) SetDate(date string) {
+	e.Lock()
+	defer e.Unlock()
+	date = e.sanitize("", "date", date)
+	e.date = date
+	e.pkg.setDate(date)
+}
+
+// SetSource sets the resource the EPUB is derived from (dc:source), e.g. the
+// ISBN of the print edition.
+func (e *Epub) SetSource(source string) {
+	e.Lock()
+	defer e.Unlock()
+	source = e.sanitize("", "source", source)
+	e.source = source
+	e.pkg.setSource(source)
+}
+
+// SetType sets the type of the EPUB (dc:type), e.g. "dictionary" or
+// "anthology".
+func (e *Epub) SetType(dcType string) {
+	e.Lock()
+	defer e.Unlock()
+	dcType = e.sanitize("", "type", dcType)
+	e.dcType = dcType
+	e.pkg.setType(dcType)
+}
+
+// SetCoverage sets the spatial or temporal topic of the EPUB (dc:coverage).
+func (e *Epub) SetCoverage(coverage string) {
+	e.Lock()
+	defer e.Unlock()
+	coverage = e.sanitize("", "coverage", coverage)
+	e.coverage = coverage
+	e.pkg.setCoverage(coverage)
+}
+
+// SetRelation sets a resource related to the EPUB (dc:relation).
+func (e *Epub) SetRelation(relation string) {
+	e.Lock()
+	defer e.Unlock()
+	relation = e.sanitize("", "relation", relation)
+	e.relation = relation
+	e.pkg.setRelation(relation)
+}
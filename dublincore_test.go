@@ -0,0 +1,101 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDublinCore(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetPublisher("Quail & Co")
+	e.SetRights("CC BY 4.0")
+	e.SetDate("2024-05-01")
+	e.SetSource("urn:isbn:9780000000000")
+	e.SetType("anthology")
+	e.SetCoverage("Europe, 1900-1950")
+	e.SetRelation("https://example.com/series")
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, element := range []string{
+		"<dc:publisher>Quail &amp; Co</dc:publisher>",
+		"<dc:rights>CC BY 4.0</dc:rights>",
+		"<dc:date>2024-05-01</dc:date>",
+		"<dc:source>urn:isbn:9780000000000</dc:source>",
+		"<dc:type>anthology</dc:type>",
+		"<dc:coverage>Europe, 1900-1950</dc:coverage>",
+		"<dc:relation>https://example.com/series</dc:relation>",
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Publisher() != e.Publisher() || opened.Rights() != e.Rights() || opened.Date() != e.Date() ||
+		opened.Source() != e.Source() || opened.Type() != e.Type() || opened.Coverage() != e.Coverage() || opened.Relation() != e.Relation() {
+		t.Errorf("Dublin Core metadata not read back: %q %q %q %q %q %q %q", opened.Publisher(), opened.Rights(), opened.Date(),
+			opened.Source(), opened.Type(), opened.Coverage(), opened.Relation())
+	}
+}
+
+func TestDublinCoreOmitted(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, element := range []string{"dc:publisher", "dc:rights", "dc:date", "dc:source", "dc:type", "dc:coverage", "dc:relation"} {
+		if strings.Contains(opf, element) {
+			t.Errorf("Expected no %s element when it isn't set", element)
+		}
+	}
+}
+
+func TestSetDateValidation(t *testing.T) {
+	for date, valid := range map[string]bool{
+		"2024":                      true,
+		"2024-05":                   true,
+		"2024-05-01":                true,
+		"2024-05-01T10:20Z":         true,
+		"2024-05-01T10:20:30+02:00": true,
+		"May 1, 2024":               false,
+		"2024/05/01":                false,
+		"2024-05-01T10:20":          false,
+	} {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetDate(date)
+		invalid := false
+		for _, issue := range e.Validate().Issues {
+			if issue.Rule == RuleInvalidDate {
+				invalid = true
+			}
+		}
+		if invalid == valid {
+			t.Errorf("Date %q: expected valid %v", date, valid)
+		}
+	}
+}
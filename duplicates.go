@@ -0,0 +1,94 @@
+package epub
+
+import "path/filepath"
+
+// DuplicatePolicy defines how a file added with a filename already used by a
+// file of the same kind is handled, e.g. an image added twice with AddImage
+// or a section added twice with AddSection with the same internal filename.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesDefault returns FilenameAlreadyUsedError if the internal
+	// filename given is already used, and gives a generated filename to the
+	// media whose filename, taken from their source, is already used
+	// (RuleRenamedFile)
+	DuplicatesDefault DuplicatePolicy = iota
+	// DuplicatesError returns FilenameAlreadyUsedError whenever the filename
+	// is already used, including the filenames taken from the sources
+	DuplicatesError
+	// DuplicatesReplace replaces the file already added with the new one,
+	// keeping its filename and, for sections, their place in the EPUB, their
+	// subsections and their settings such as SetSectionMetadata
+	// (RuleDuplicateFile)
+	DuplicatesReplace
+	// DuplicatesSkip keeps the file already added and leaves the new one out,
+	// returning the path of the file already added (RuleDuplicateFile)
+	DuplicatesSkip
+)
+
+// SetDuplicatePolicy sets how the files added with a filename already used
+// are handled by AddCSS, AddFont, AddImage, AddVideo, AddAudio, AddFile, the
+// methods adding sections and their variants. See DuplicatePolicy.
+func (e *Epub) SetDuplicatePolicy(policy DuplicatePolicy) {
+	e.Lock()
+	defer e.Unlock()
+	e.duplicates = policy
+}
+
+// Apply the duplicate policy to a file added with a filename already used:
+// report whether the new file replaces the one already added, or whether it
+// is left out, or return FilenameAlreadyUsedError. warnings may be nil.
+func (p DuplicatePolicy) resolve(warnings *ValidationReport, filename string) (bool, error) {
+	switch p {
+	case DuplicatesReplace:
+		if warnings != nil {
+			warnings.add(SeverityWarning, RuleDuplicateFile, filename, "added again, the previous file was replaced")
+		}
+		return true, nil
+	case DuplicatesSkip:
+		if warnings != nil {
+			warnings.add(SeverityWarning, RuleDuplicateFile, filename, "added again, the new file was left out")
+		}
+		return false, nil
+	}
+	return false, &FilenameAlreadyUsedError{Filename: filename}
+}
+
+// Return the grabber used to add the files generated by the EPUB itself, such
+// as the stylesheet of the cover page: they get another filename rather than
+// being subject to the duplicate policy if their filename is already used
+func (e *Epub) generatedGrabber() grabber {
+	g := e.grabber()
+	g.duplicates = DuplicatesDefault
+	return g
+}
+
+// Return the internal filename of a section, with the .xhtml extension
+func normalizeSectionFilename(internalFilename string) string {
+	if filepath.Ext(internalFilename) != ".xhtml" {
+		return internalFilename + ".xhtml"
+	}
+	return internalFilename
+}
+
+// Add the XHTML document as a new section like insertSection, applying the
+// duplicate policy if the internal filename is already used
+func (e *Epub) insertNewSection(parentFilename string, x *xhtml, internalFilename string) (string, error) {
+	if internalFilename == "" {
+		return e.insertSection(parentFilename, x, internalFilename)
+	}
+	internalFilename = normalizeSectionFilename(internalFilename)
+	section, ok := e.findSection(internalFilename)
+	if !ok {
+		return e.insertSection(parentFilename, x, internalFilename)
+	}
+	replace, err := e.duplicates.resolve(&e.warnings, internalFilename)
+	if err != nil {
+		return "", err
+	}
+	if replace {
+		x.setMetadata(section.metadata)
+		section.xhtml = x
+	}
+	return internalFilename, nil
+}
@@ -0,0 +1,121 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestSetDuplicatePolicy(t *testing.T) {
+	first := dataurl.EncodeBytes([]byte("p { color: red; }"))
+	second := dataurl.EncodeBytes([]byte("p { color: blue; }"))
+	for _, test := range []struct {
+		policy  DuplicatePolicy
+		err     bool
+		css     string
+		section string
+		warning bool
+	}{
+		{policy: DuplicatesDefault, err: true, css: "red", section: "First"},
+		{policy: DuplicatesError, err: true, css: "red", section: "First"},
+		{policy: DuplicatesReplace, css: "blue", section: "Second", warning: true},
+		{policy: DuplicatesSkip, css: "red", section: "First", warning: true},
+	} {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetDuplicatePolicy(test.policy)
+		if _, err := e.AddCSS(first, "style.css"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSection("<p>First</p>", "Chapter", "chapter.xhtml", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSubSection("chapter.xhtml", "<p>Child</p>", "Child", "child", ""); err != nil {
+			t.Fatal(err)
+		}
+
+		var used *FilenameAlreadyUsedError
+		cssPath, err := e.AddCSS(second, "style.css")
+		if test.err != errors.As(err, &used) {
+			t.Errorf("Policy %d: unexpected error %v adding a CSS file twice", test.policy, err)
+		}
+		if err == nil && cssPath != "../css/style.css" {
+			t.Errorf("Policy %d: unexpected CSS path %s", test.policy, cssPath)
+		}
+		sectionPath, err := e.AddSection("<p>Second</p>", "Chapter", "chapter", "")
+		if test.err != errors.As(err, &used) {
+			t.Errorf("Policy %d: unexpected error %v adding a section twice", test.policy, err)
+		}
+		if err == nil && sectionPath != "chapter.xhtml" {
+			t.Errorf("Policy %d: unexpected section path %s", test.policy, sectionPath)
+		}
+		if test.warning != (len(e.warnings.Issues) == 2 && e.warnings.Issues[0].Rule == RuleDuplicateFile) {
+			t.Errorf("Policy %d: unexpected warnings %v", test.policy, e.warnings.Issues)
+		}
+
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		files := readZipFiles(t, b.Bytes())
+		if css := files["EPUB/css/style.css"]; !strings.Contains(css, test.css) {
+			t.Errorf("Policy %d: expected the %s CSS file, got %s", test.policy, test.css, css)
+		}
+		if section := files["EPUB/xhtml/chapter.xhtml"]; !strings.Contains(section, test.section) {
+			t.Errorf("Policy %d: expected the %s section, got:\n%s", test.policy, test.section, section)
+		}
+		if _, ok := files["EPUB/xhtml/child.xhtml"]; !ok {
+			t.Errorf("Policy %d: expected the subsection to be kept", test.policy)
+		}
+	}
+}
+
+func TestDuplicatePolicyFilenameFromSource(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Renamed by default
+	if imagePath, err := e.AddImage(testImageFromFileSource, ""); err != nil || imagePath != "../images/image0002.png" {
+		t.Errorf("Expected the image to be renamed, got %s, %v", imagePath, err)
+	}
+	e.SetDuplicatePolicy(DuplicatesError)
+	var used *FilenameAlreadyUsedError
+	if _, err := e.AddImage(testImageFromFileSource, ""); !errors.As(err, &used) {
+		t.Errorf("Expected a FilenameAlreadyUsedError, got %v", err)
+	}
+}
+
+func TestDuplicatePolicyGeneratedFiles(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetDuplicatePolicy(DuplicatesReplace)
+	userCSS := dataurl.EncodeBytes([]byte("body { margin: 0; }"))
+	if _, err := e.AddCSS(userCSS, defaultCoverCSSFilename); err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCover(imagePath, ""); err != nil {
+		t.Fatal(err)
+	}
+	// The cover stylesheet doesn't replace the one of the user
+	if e.css[defaultCoverCSSFilename] != userCSS {
+		t.Error("Expected the CSS file of the user to be kept")
+	}
+	if len(e.css) != 2 {
+		t.Errorf("Expected the cover stylesheet to get another filename, got %v", e.css)
+	}
+}
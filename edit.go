@@ -0,0 +1,61 @@
+package epub
+
+// ReplaceSection replaces the body, title and CSS of a section, identified by
+// its internal filename, e.g. to correct a chapter of an EPUB read with Open.
+// The arguments are the same as for AddSection. The section keeps its place
+// in the book, its subsections and its other settings. The body refers to the
+// other files with their internal paths, e.g. ../images/a.png, even if they
+// were in other folders of the EPUB read (see OpenReader).
+func (e *Epub) ReplaceSection(sectionFilename string, body string, sectionTitle string, internalCSSPath string) error {
+	e.Lock()
+	defer e.Unlock()
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	x, err := e.newSectionXhtml(body, sectionTitle, sectionFilename, internalCSSPath)
+	if err != nil {
+		return err
+	}
+	section.xhtml = x
+	return nil
+}
+
+// RemoveSection removes a section, identified by its internal filename, along
+// with its subsections and their media overlays. Removing the cover page
+// removes the cover (see SetCover), but not the cover image.
+func (e *Epub) RemoveSection(sectionFilename string) error {
+	e.Lock()
+	defer e.Unlock()
+	if sectionFilename == e.cover.xhtmlFilename && sectionFilename != "" {
+		e.removeCover()
+		return nil
+	}
+	var removed *epubSection
+	var remove func(sections []*epubSection) ([]*epubSection, bool)
+	remove = func(sections []*epubSection) ([]*epubSection, bool) {
+		for i, s := range sections {
+			if s.filename == sectionFilename {
+				removed = s
+				return append(sections[:i:i], sections[i+1:]...), true
+			}
+			if children, ok := remove(s.children); ok {
+				s.children = children
+				return sections, true
+			}
+		}
+		return sections, false
+	}
+	sections, ok := remove(e.sections)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	e.sections = sections
+	for filename := range getFilenames([]*epubSection{removed}) {
+		if overlay, ok := e.sectionOverlays[filename]; ok {
+			delete(e.overlays, overlay)
+			delete(e.sectionOverlays, filename)
+		}
+	}
+	return nil
+}
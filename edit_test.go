@@ -0,0 +1,136 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripEditing(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCover(imagePath, ""); err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := e.AddSection("<p>Chapter one with a typo</p>", "Chapter 1", "chapter1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(chapter, "<p>Part A</p>", "Part A", "part-a.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>Chapter two</p>", "Chapter 2", "chapter2.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var original bytes.Buffer
+	if _, err := e.WriteTo(&original); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(original.Bytes()), int64(original.Len()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opened.cover.xhtmlFilename == "" {
+		t.Fatal("Expected the cover page to be found")
+	}
+	opened.SetTitle("Corrected title")
+	if err := opened.ReplaceSection("chapter1.xhtml", "<p>Chapter one, corrected</p>", "Chapter 1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := opened.RemoveSection("chapter2.xhtml"); err != nil {
+		t.Fatal(err)
+	}
+	var notFound *SectionDoesNotExistError
+	if err := opened.RemoveSection("chapter2.xhtml"); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError removing a missing section, got %v", err)
+	}
+	newCover, err := opened.AddImage(testImageFromFileSource, "new-cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := opened.SetCover(newCover, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var edited bytes.Buffer
+	if _, err := opened.WriteTo(&edited); err != nil {
+		t.Fatalf("Unexpected error writing the edited EPUB: %v", err)
+	}
+	reopened, err := OpenReader(bytes.NewReader(edited.Bytes()), int64(edited.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Title() != "Corrected title" {
+		t.Errorf("Expected the new title, got %q", reopened.Title())
+	}
+	toc := reopened.TOC()
+	if len(toc) != 1 || toc[0].Filename != "chapter1.xhtml" || len(toc[0].Children) != 1 {
+		t.Fatalf("Unexpected table of contents: %+v", toc)
+	}
+	content, err := reopened.SectionContent("chapter1.xhtml")
+	if err != nil || !strings.Contains(content, "Chapter one, corrected") {
+		t.Errorf("Expected the replaced section, got %q (%v)", content, err)
+	}
+	if reopened.cover.imageFilename != "new-cover.png" {
+		t.Errorf("Expected the new cover image, got %q", reopened.cover.imageFilename)
+	}
+	// The old cover page was replaced rather than kept as a section
+	if len(reopened.sections) != 2 {
+		t.Errorf("Expected the cover page and a chapter, got %d sections", len(reopened.sections))
+	}
+}
+
+func TestRoundTripEditingForeignLayout(t *testing.T) {
+	data := foreignLayoutTestEpub(t)
+	opened, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body := `<p id="two">Two, corrected <img src="../images/pic.png" alt=""/> <a href="ch1.xhtml">back</a></p>`
+	if err := opened.ReplaceSection("ch2.xhtml", body, "Two", "../css/s.css"); err != nil {
+		t.Fatal(err)
+	}
+	epubPath := filepath.Join(t.TempDir(), testEpubFilename)
+	if err := opened.Write(epubPath); err != nil {
+		t.Fatalf("Unexpected error writing the edited EPUB: %v", err)
+	}
+	checkNoBrokenLinks(t, opened)
+
+	edited, err := os.ReadFile(epubPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, edited)
+	for _, filename := range []string{"EPUB/images/pic.png", "EPUB/images/bg.png", "EPUB/css/s.css", "EPUB/fonts/f.ttf"} {
+		if _, ok := files[filename]; !ok {
+			t.Errorf("Expected %s to be written", filename)
+		}
+	}
+	if ch1 := files["EPUB/xhtml/ch1.xhtml"]; !strings.Contains(ch1, `src="../images/pic.png"`) || !strings.Contains(ch1, `href="ch2.xhtml#two"`) {
+		t.Errorf("Expected the untouched section to point to the written files, got:\n%s", ch1)
+	}
+	if ch2 := files["EPUB/xhtml/ch2.xhtml"]; !strings.Contains(ch2, "Two, corrected") || !strings.Contains(ch2, "../css/s.css") {
+		t.Errorf("Expected the replaced section, got:\n%s", ch2)
+	}
+
+	// The written EPUB reads back with the same layout
+	reopened, err := OpenReader(bytes.NewReader(edited), int64(len(edited)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := reopened.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	checkNoBrokenLinks(t, reopened)
+}
@@ -0,0 +1,54 @@
+package epub
+
+import (
+	"fmt"
+	"io"
+)
+
+// Edition is a language edition of a book built by WriteEditions.
+type Edition struct {
+	// Language of the edition, set with SetLang before Build is called
+	Lang string
+	// Build adds the sections and metadata specific to the edition (title,
+	// description, etc.) to an EPUB that already contains the shared content.
+	Build func(e *Epub) error
+	// Destination of the edition
+	Dst io.Writer
+}
+
+// WriteEditions builds and writes several language editions of a book in one
+// pass. For each edition, a new EPUB is created with the given title and
+// shared is called to add the content common to all the editions (images,
+// fonts, CSS, authors...), then the Build function of the edition adds its own
+// sections and metadata before the EPUB is written to the destination of the
+// edition.
+//
+// The editions share a MediaCache, so shared sources are only retrieved once.
+// Since shared is called for every edition, the internal paths it gets from
+// AddImage, AddFont, etc. are the same in every edition.
+func WriteEditions(title string, shared func(e *Epub) error, editions []Edition) error {
+	cache := NewMediaCache()
+	for _, edition := range editions {
+		e, err := NewEpub(title)
+		if err != nil {
+			return err
+		}
+		e.SetMediaCache(cache)
+		e.SetLang(edition.Lang)
+
+		if shared != nil {
+			if err := shared(e); err != nil {
+				return fmt.Errorf("unable to add shared content to the %s edition: %w", edition.Lang, err)
+			}
+		}
+		if edition.Build != nil {
+			if err := edition.Build(e); err != nil {
+				return fmt.Errorf("unable to build the %s edition: %w", edition.Lang, err)
+			}
+		}
+		if _, err := e.WriteTo(edition.Dst); err != nil {
+			return fmt.Errorf("unable to write the %s edition: %w", edition.Lang, err)
+		}
+	}
+	return nil
+}
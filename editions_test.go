@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteEditions(t *testing.T) {
+	server, gets := newCountingServer(t)
+
+	var imagePath string
+	shared := func(e *Epub) error {
+		var err error
+		imagePath, err = e.AddImage(server.URL+"/gophercolor16x16.png", "gopher.png")
+		return err
+	}
+	titles := map[string]string{"en": "Chapter 1", "fr": "Chapitre 1"}
+	var en, fr bytes.Buffer
+	editions := []Edition{
+		{Lang: "en", Dst: &en},
+		{Lang: "fr", Dst: &fr},
+	}
+	for i := range editions {
+		lang := editions[i].Lang
+		editions[i].Build = func(e *Epub) error {
+			_, err := e.AddSection(`<h1>`+titles[lang]+`</h1><img src="`+imagePath+`" alt=""/>`, titles[lang], "", "")
+			return err
+		}
+	}
+
+	if err := WriteEditions(testEpubTitle, shared, editions); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(gets); n != 1 {
+		t.Errorf("Expected the shared image to be downloaded once, got %d downloads", n)
+	}
+
+	for lang, b := range map[string]*bytes.Buffer{"en": &en, "fr": &fr} {
+		files := readZipFiles(t, b.Bytes())
+		opf := files["EPUB/package.opf"]
+		if !strings.Contains(opf, "<dc:language>"+lang+"</dc:language>") {
+			t.Errorf("The %s edition doesn't have the right language:\n%s", lang, opf)
+		}
+		if _, ok := files["EPUB/images/gopher.png"]; !ok {
+			t.Errorf("The %s edition doesn't contain the shared image", lang)
+		}
+		if !strings.Contains(files["EPUB/xhtml/section0001.xhtml"], titles[lang]) {
+			t.Errorf("The %s edition doesn't contain its section", lang)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package epub
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	encryptionFilename = "encryption.xml"
+	xmlnsContainer     = "urn:oasis:names:tc:opendocument:xmlns:container"
+	xmlnsEnc           = "http://www.w3.org/2001/04/xmlenc#"
+)
+
+// ResourceEncrypter encrypts resources when the EPUB is written. Each
+// encrypted resource is listed in META-INF/encryption.xml with the algorithm
+// of the encrypter, so reading systems that implement the protection scheme
+// can decrypt it.
+type ResourceEncrypter interface {
+	// Algorithm returns the URI identifying the encryption algorithm, e.g.
+	// http://www.w3.org/2001/04/xmlenc#aes256-cbc
+	Algorithm() string
+	// Encrypt returns the encrypted content of the resource. The name is the
+	// path of the resource in the container, e.g. EPUB/images/image0001.png
+	Encrypt(name string, data []byte) ([]byte, error)
+}
+
+// SetResourceEncrypter sets the encrypter used to encrypt resources when the
+// EPUB is written. The filter is called with the path of every resource in the
+// container (e.g. EPUB/fonts/font.otf) and reports whether it should be
+// encrypted; if it is nil, every resource that may be encrypted is.
+//
+// The mimetype file, the files of the META-INF folder and the package file are
+// never encrypted, as required by the EPUB specification.
+//
+// Setting a nil encrypter disables encryption.
+func (e *Epub) SetResourceEncrypter(encrypter ResourceEncrypter, filter func(name string) bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.encrypter = encrypter
+	e.encryptFilter = filter
+}
+
+// Report whether the resource stored at name in the container should be
+// encrypted
+func (e *Epub) shouldEncrypt(name string) bool {
+	if e.encrypter == nil ||
+		name == mimetypeFilename ||
+		strings.HasPrefix(name, metaInfFolderName+"/") ||
+		name == path.Join(contentFolderName, pkgFilename) {
+		return false
+	}
+	return e.encryptFilter == nil || e.encryptFilter(name)
+}
+
+type encryptionRoot struct {
+	XMLName       xml.Name                  `xml:"encryption"`
+	Xmlns         string                    `xml:"xmlns,attr"`
+	XmlnsEnc      string                    `xml:"xmlns:enc,attr"`
+	EncryptedData []encryptionEncryptedData `xml:"enc:EncryptedData"`
+}
+
+type encryptionEncryptedData struct {
+	Method encryptionMethod `xml:"enc:EncryptionMethod"`
+	URI    encryptionURI    `xml:"enc:CipherData>enc:CipherReference"`
+}
+
+type encryptionMethod struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+type encryptionURI struct {
+	URI string `xml:"URI,attr"`
+}
+
+// Add META-INF/encryption.xml listing the encrypted resources to the zip
+// archive. The file is hashed for the provenance statement if hashes isn't nil.
+func writeEncryptionFile(z *zip.Writer, hashes provenanceHasher, algorithm string, names []string) error {
+	content, err := encryptionFileContent(algorithm, names)
+	if err != nil {
+		return err
+	}
+	name := path.Join(metaInfFolderName, encryptionFilename)
+	w, err := z.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating zip writer: %w", err)
+	}
+	w = hashes.wrap(name, w)
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("Error writing encryption file: %w", err)
+	}
+	return nil
+}
+
+// Render META-INF/encryption.xml for the given encrypted resources
+func encryptionFileContent(algorithm string, names []string) ([]byte, error) {
+	r := encryptionRoot{
+		Xmlns:    xmlnsContainer,
+		XmlnsEnc: xmlnsEnc,
+	}
+	for _, name := range names {
+		r.EncryptedData = append(r.EncryptedData, encryptionEncryptedData{
+			Method: encryptionMethod{Algorithm: algorithm},
+			URI:    encryptionURI{URI: (&url.URL{Path: name}).String()},
+		})
+	}
+	output, err := xml.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling XML for encryption file: %w", err)
+	}
+	output = append([]byte(xml.Header), output...)
+	return append(output, "\n"...), nil
+}
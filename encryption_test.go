@@ -0,0 +1,109 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// xorEncrypter is a toy encrypter used to test the encryption hook
+type xorEncrypter struct {
+	names []string
+}
+
+func (x *xorEncrypter) Algorithm() string {
+	return "urn:example:xor"
+}
+
+func (x *xorEncrypter) Encrypt(name string, data []byte) ([]byte, error) {
+	x.names = append(x.names, name)
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0xff
+	}
+	return out, nil
+}
+
+func TestSetResourceEncrypter(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFont(testFontFromFileSource, "font.ttf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	encrypter := &xorEncrypter{}
+	e.SetResourceEncrypter(encrypter, func(name string) bool {
+		return strings.HasPrefix(name, "EPUB/fonts/")
+	})
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if len(encrypter.names) != 1 || encrypter.names[0] != "EPUB/fonts/font.ttf" {
+		t.Errorf("Unexpected encrypted resources %v", encrypter.names)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := z.Open("EPUB/fonts/font.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedFont, _ := io.ReadAll(font)
+	originalFont, err := os.ReadFile(testFontFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encryptedFont) != len(originalFont) || encryptedFont[0] != originalFont[0]^0xff {
+		t.Error("Font wasn't encrypted")
+	}
+
+	f, err := z.Open("META-INF/encryption.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, _ := io.ReadAll(f)
+	for _, expected := range []string{
+		`<enc:EncryptionMethod Algorithm="urn:example:xor"></enc:EncryptionMethod>`,
+		`<enc:CipherReference URI="EPUB/fonts/font.ttf"></enc:CipherReference>`,
+	} {
+		if !strings.Contains(string(contents), expected) {
+			t.Errorf("Encryption file doesn't contain %s\nGot: %s", expected, contents)
+		}
+	}
+}
+
+func TestShouldEncrypt(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.shouldEncrypt("EPUB/fonts/font.ttf") {
+		t.Error("Nothing should be encrypted without an encrypter")
+	}
+	e.SetResourceEncrypter(&xorEncrypter{}, nil)
+	for name, expected := range map[string]bool{
+		"mimetype":                 false,
+		"META-INF/container.xml":   false,
+		"EPUB/package.opf":         false,
+		"EPUB/fonts/font.ttf":      true,
+		"EPUB/xhtml/section.xhtml": true,
+	} {
+		if e.shouldEncrypt(name) != expected {
+			t.Errorf("Expected shouldEncrypt(%s) to be %t", name, expected)
+		}
+	}
+}
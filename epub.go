@@ -31,9 +31,14 @@ Basic usage:
 package epub
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
 	"fmt"
-	"io/fs"
-	"log"
+	"html/template"
+	"image"
+	"io"
+	"log/slog"
 	"mime"
 	"net/http"
 	"net/url"
@@ -43,13 +48,15 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/vincent-petithory/dataurl"
 )
 
 // FilenameAlreadyUsedError is thrown by AddCSS, AddFont, AddImage, or AddSection
-// if the same filename is used more than once.
+// if the same filename is used more than once, unless the duplicate policy
+// handles it otherwise (see SetDuplicatePolicy).
 type FilenameAlreadyUsedError struct {
 	Filename string // Filename that caused the error
 }
@@ -69,6 +76,20 @@ func (e *FileRetrievalError) Error() string {
 	return fmt.Sprintf("Error retrieving %q from source: %+v", e.Source, e.Err)
 }
 
+func (e *FileRetrievalError) Unwrap() error {
+	return e.Err
+}
+
+// RemoteSourceError is thrown by AddCSS, AddFont, AddImage, or Write if a
+// remote URL source is used while the EPUB is in offline-only mode.
+type RemoteSourceError struct {
+	Source string // The remote source that was rejected
+}
+
+func (e *RemoteSourceError) Error() string {
+	return fmt.Sprintf("Remote source %q is not allowed in offline-only mode", e.Source)
+}
+
 // ParentDoesNotExistError is thrown by AddSubSection if the parent with the
 // previously defined internal filename does not exist.
 type ParentDoesNotExistError struct {
@@ -79,13 +100,43 @@ func (e *ParentDoesNotExistError) Error() string {
 	return fmt.Sprintf("Parent with the internal filename %s does not exist", e.Filename)
 }
 
+// SectionDoesNotExistError is thrown by the methods that change or use an
+// existing section, such as AddTable, AddFigure or SetSectionMetadata, if the
+// section with the given internal filename does not exist.
+type SectionDoesNotExistError struct {
+	Filename string // Filename that caused the error
+}
+
+func (e *SectionDoesNotExistError) Error() string {
+	return fmt.Sprintf("Section with the internal filename %s does not exist", e.Filename)
+}
+
+// RawSectionError is thrown by AddTable, AddFigure, AddEpigraph or
+// SetSectionMetadata if the section is a complete document written verbatim,
+// such as the sections added with AddRawSection or read with Open, which they
+// can't change.
+type RawSectionError struct {
+	Filename string // Filename that caused the error
+}
+
+func (e *RawSectionError) Error() string {
+	return fmt.Sprintf("Section %s is a complete document and can't be changed", e.Filename)
+}
+
 // Folder names used for resources inside the EPUB
 const (
-	CSSFolderName   = "css"
-	FontFolderName  = "fonts"
-	ImageFolderName = "images"
-	VideoFolderName = "videos"
-	AudioFolderName = "audios"
+	CSSFolderName    = "css"
+	FontFolderName   = "fonts"
+	ImageFolderName  = "images"
+	VideoFolderName  = "videos"
+	AudioFolderName  = "audios"
+	ScriptFolderName = "scripts"
+	// Media overlays (SMIL files)
+	MediaOverlayFolderName = "overlays"
+	// Font licenses, see AddFontLicense
+	FontLicenseFolderName = "licenses"
+	// WebVTT captions, see AddCaptions
+	CaptionsFolderName = "captions"
 )
 
 const (
@@ -104,17 +155,28 @@ img {
   max-width: 100%;
 }
 `
-	defaultCoverCSSFilename   = "cover.css"
-	defaultCoverCSSSource     = "cover.css"
-	defaultCoverImgFormat     = "cover%s"
-	defaultCoverXhtmlFilename = "cover.xhtml"
-	defaultEpubLang           = "en"
-	fontFileFormat            = "font%04d%s"
-	imageFileFormat           = "image%04d%s"
-	videoFileFormat           = "video%04d%s"
-	sectionFileFormat         = "section%04d.xhtml"
-	urnUUIDPrefix             = "urn:uuid:"
-	audioFileFormat           = "audio%04d%s"
+	defaultCoverCSSFilename        = "cover.css"
+	defaultCoverCSSSource          = "cover.css"
+	defaultCoverImgFormat          = "cover%s"
+	defaultCoverThumbnailImgFormat = "cover-thumbnail%s"
+	defaultCoverXhtmlFilename      = "cover.xhtml"
+	defaultEpubLang                = "en"
+	fontFileFormat                 = "font%04d%s"
+	imageFileFormat                = "image%04d%s"
+	videoFileFormat                = "video%04d%s"
+	scriptFileFormat               = "script%04d%s"
+	sectionFileFormat              = "section%04d.xhtml"
+	urnUUIDPrefix                  = "urn:uuid:"
+	audioFileFormat                = "audio%04d%s"
+)
+
+// Maximum size of the cover image and its thumbnail generated by
+// SetCoverFromReader
+const (
+	CoverMaxWidth           = 1600
+	CoverMaxHeight          = 2560
+	CoverThumbnailMaxWidth  = 200
+	CoverThumbnailMaxHeight = 320
 )
 
 // Epub implements an EPUB file.
@@ -128,24 +190,196 @@ type Epub struct {
 	// The key is the font filename, the value is the font source
 	fonts      map[string]string
 	identifier string
+	// Scheme of the unique identifier and the other identifiers, see
+	// AddIdentifier
+	identifierScheme string
+	otherIdentifiers []Identifier
 	// The key is the image filename, the value is the image source
 	images map[string]string
 	// The key is the video filename, the value is the video source
 	videos map[string]string
 	// The key is the audio filename, the value is the audio source
 	audios map[string]string
+	// The key is the script filename, the value is the script source
+	scripts map[string]string
+	// The key is the media overlay filename, the value is the overlay source
+	overlays map[string]string
+	// The key is the font license filename, the value is the license source
+	fontLicenses map[string]string
+	// The key is the font license filename, the value is the font filename
+	licensedFonts map[string]string
+	// The key is the captions filename, the value is the captions source
+	captions map[string]string
+	// The key is the captions filename, the value is the path to the captioned
+	// audio or video file
+	captionedMedia map[string]string
+	// The key is the path of the file relative to the content folder, see
+	// AddFile
+	files map[string]epubFile
+	// The key is the section filename, the value is its media overlay filename
+	sectionOverlays map[string]string
 	// Language
 	lang string
 	// Description
 	desc string
+	// Other Dublin Core metadata (dc:publisher, dc:rights, etc)
+	publisher string
+	rights    string
+	date      string
+	source    string
+	dcType    string
+	coverage  string
+	relation  string
+	// Subjects, see AddSubject
+	subjects []Subject
+	// Contributors besides the author, see AddContributor
+	contributors []Contributor
+	// Vocabulary prefixes declared with AddPrefix, the value is their URI
+	prefixes map[string]string
+	// Series the EPUB belongs to and its position, see SetSeries
+	seriesName     string
+	seriesPosition float64
 	// Page progression direction
 	ppd string
 	// The package file (package.opf)
 	pkg      *pkg
 	sections []*epubSection
 	title    string
+	// Titles besides the main title and the position of the main title, see
+	// AddTitle
+	titles         []epubTitle
+	mainDisplaySeq int
+	// Languages besides the main language, see AddLang
+	otherLangs []string
+	// Languages of the main title and of the author, see SetTitleLang and
+	// SetAuthorLang
+	titleLang  string
+	authorLang string
 	// Table of contents
 	toc *toc
+	// Internal paths of the stylesheet and script shared by interactive widgets
+	widgets *epubWidgets
+	// Number of tables added with AddTable, used to number them
+	tableCount int
+	// Figures added with AddFigure, in order
+	figures []epubFigure
+	// Filename of the section generated by AddListOfFigures
+	listOfFigures string
+	// Encrypter and filter of the resources to encrypt
+	encrypter     ResourceEncrypter
+	encryptFilter func(name string) bool
+	// Key used to sign the provenance statement
+	provenanceKey ed25519.PrivateKey
+	// Reject remote sources, see SetOfflineOnly
+	offlineOnly bool
+	// Generator of the manifest ids and the identifier, see SetIDGenerator
+	idGenerator IDGenerator
+	// Whether the identifier was set with SetIdentifier
+	customIdentifier bool
+	// Derive the manifest ids from the filenames, see SetReadableIDs
+	readableIDs bool
+	// Manifest ids assigned to the filenames when readable ids are enabled
+	slugIDs map[string]string
+	// Cache of the retrieved media sources, may be nil
+	mediaCache *MediaCache
+	// Write a placeholder section if there are none, see SetMetadataOnly
+	metadataOnly bool
+	// Add prefetch links to the sections, see SetPrefetchHints
+	prefetchHints bool
+	// Minify the sections when they are written, see SetMinify
+	minify bool
+	// How issues are handled when the EPUB is written, see SetBuildMode
+	buildMode BuildMode
+	// Placeholder section written in place of the sections during a write,
+	// see SetMetadataOnly
+	placeholder *epubSection
+	// Leave out the media only referenced by the sections excluded from an
+	// export during the write, see ExportTo
+	pruneMedia bool
+	// Issues found by the last write
+	report *ValidationReport
+	// Media left out of the last write because they couldn't be retrieved
+	skippedMedia map[string]bool
+	// Issues found outside of writes, e.g. by EmbedImages
+	warnings ValidationReport
+	// Limiter of the requests to remote sources, see SetFetchLimiter
+	fetchLimiter *FetchLimiter
+	// How media that aren't core media types are handled, see
+	// SetMediaTypePolicy
+	mediaTypePolicy MediaTypePolicy
+	// How the files added with a filename already used are handled, see
+	// SetDuplicatePolicy
+	duplicates DuplicatePolicy
+	// Stylesheets linked to all the sections, relative to them, see
+	// SetDefaultStylesheets
+	defaultCSS []string
+	// Transcoder of the audio files, see SetAudioTranscoder
+	audioTranscoder AudioTranscoder
+	// Transcoder of the video files, see SetVideoTranscoder
+	videoTranscoder VideoTranscoder
+	// Rasterizer of the SVG covers, see SetSVGRasterizer
+	svgRasterizer SVGRasterizer
+	// Maximum total size of the media in bytes, see SetMediaSizeBudget
+	mediaSizeBudget int64
+	// Render chapter thumbnails, see SetChapterThumbnails
+	chapterThumbnails bool
+	// Chapter thumbnails rendered by the last write
+	thumbnails []ChapterThumbnail
+	// Workarounds for the targeted reading systems, see SetCompatProfile
+	compat CompatProfile
+	// Logger of the issues and media retrievals, see SetLogger
+	logger *slog.Logger
+	// Data the section bodies are executed against, see SetTemplateData
+	templateData map[string]interface{}
+	// Tags of the content written, see SetBuildTags
+	buildTags []string
+	// Add the images referenced by the sections, see SetAutoAddImages
+	autoAddImages bool
+	// Internal paths of the images added automatically, by source
+	autoImages map[string]string
+	// How the requests to remote sources are retried, see SetRetryPolicy
+	retryPolicy RetryPolicy
+	// Policy applied to the external links of the sections, see
+	// SetExternalLinkPolicy
+	linkPolicy ExternalLinkPolicy
+	// Renormalize the heading levels of the sections, see
+	// SetNormalizeHeadings
+	normalizeHeadings bool
+	// Called as a write progresses, see SetProgressFunc
+	progress func(stage string, done, total int)
+	// Write byte-identical EPUBs for the same content, see SetReproducible
+	reproducible bool
+	// Modification date of the EPUB, see SetModified
+	modified time.Time
+	// Work directory of the checkpoints of the writes, see SetCheckpointDir
+	checkpointDir string
+	// Display options and metadata of Apple Books, see SetAppleBooksOptions
+	appleBooks *AppleBooksOptions
+	// Accessibility metadata, see SetAccessibility
+	a11y AccessibilityMetadata
+	// Audience metadata, see SetTypicalAgeRange, SetAudience and
+	// SetEducationalMetadata
+	typicalAgeRange string
+	audiences       []string
+	education       EducationalMetadata
+	// Refresher of the expired signed URLs, see SetSignedURLRefresher
+	signedURLRefresher SignedURLRefresher
+	// Convert CMYK images and strip ICC profiles, see
+	// SetNormalizeColorProfiles
+	normalizeColorProfiles bool
+	// Apply the EXIF orientation and strip the metadata of the images, see
+	// SetStripImageMetadata
+	stripImageMetadata bool
+	// Add break hints to the sections, see SetBreakHints
+	breakHints bool
+	// Internal path of the break hints stylesheet, once added
+	breakHintsCSSPath string
+	// Checkers of the text of the sections, see AddProofChecker
+	proofCheckers []ProofChecker
+	// How the files are compressed, see SetCompression
+	compression CompressionOptions
+	// Template of the chapter openers, see SetChapterOpeners
+	chapterOpeners *template.Template
 }
 
 type epubCover struct {
@@ -153,12 +387,30 @@ type epubCover struct {
 	cssTempFile   string
 	imageFilename string
 	xhtmlFilename string
+	// Raster image referenced by the EPUB 2 cover meta element instead of
+	// an SVG cover image, see SetCoverSVG
+	fallbackFilename string
 }
 
 type epubSection struct {
 	filename string
 	xhtml    *xhtml
 	children []*epubSection
+	// Space-separated manifest properties, e.g. "scripted"
+	properties string
+	// Whether the properties have been set with SetSectionProperties rather
+	// than detected
+	manualProperties bool
+	// Tags used to export part of the book, see SetSectionTags
+	tags []string
+	// Access of the section, see SetSectionAccess
+	access SectionAccess
+	// Epigraph and illustration of the chapter opener, see SetChapterOpener
+	opener ChapterOpener
+	// Label of the page the section starts on, see SetPageLabel
+	pageLabel string
+	// Author, date and source of the section, see SetSectionMetadata
+	metadata SectionMetadata
 }
 
 // NewEpub returns a new Epub.
@@ -177,6 +429,15 @@ func NewEpub(title string) (*Epub, error) {
 	e.images = make(map[string]string)
 	e.videos = make(map[string]string)
 	e.audios = make(map[string]string)
+	e.scripts = make(map[string]string)
+	e.overlays = make(map[string]string)
+	e.fontLicenses = make(map[string]string)
+	e.licensedFonts = make(map[string]string)
+	e.captions = make(map[string]string)
+	e.captionedMedia = make(map[string]string)
+	e.files = make(map[string]epubFile)
+	e.sectionOverlays = make(map[string]string)
+	e.compat = ProfileGeneric
 	e.pkg, err = newPackage()
 	if err != nil {
 		return nil, fmt.Errorf("can't create NewEpub: %w", err)
@@ -186,7 +447,7 @@ func NewEpub(title string) (*Epub, error) {
 		return nil, fmt.Errorf("can't create NewEpub: %w", err)
 	}
 	// Set minimal required attributes
-	e.SetIdentifier(urnUUIDPrefix + uuid.Must(uuid.NewV4()).String())
+	e.setIdentifier(urnUUIDPrefix + uuid.Must(uuid.NewV4()).String())
 	e.SetLang(defaultEpubLang)
 	e.SetTitle(title)
 
@@ -198,7 +459,9 @@ func NewEpub(title string) (*Epub, error) {
 // ../CSSFolderName/internalFilename
 //
 // The CSS source should either be a URL, a path to a local file, or an embedded data URL; in any
-// case, the CSS file will be retrieved and stored in the EPUB.
+// case, the CSS file will be retrieved and stored in the EPUB. Data URLs
+// may be base64 or URL-encoded; the filename generated for them has the
+// extension of their media type.
 //
 // The internal filename will be used when storing the CSS file in the EPUB
 // and must be unique among all CSS files. If the same filename is used more
@@ -211,7 +474,15 @@ func (e *Epub) AddCSS(source string, internalFilename string) (string, error) {
 }
 
 func (e *Epub) addCSS(source string, internalFilename string) (string, error) {
-	return addMedia(e.Client, source, internalFilename, cssFileFormat, CSSFolderName, e.css)
+	return addMedia(e.grabber(), source, internalFilename, cssFileFormat, CSSFolderName, e.css)
+}
+
+// AddCSSContext is like AddCSS, but retrieving the source is cancelled when
+// ctx is done.
+func (e *Epub) AddCSSContext(ctx context.Context, source string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber().withContext(ctx), source, internalFilename, cssFileFormat, CSSFolderName, e.css)
 }
 
 // AddFont adds a font file to the EPUB and returns a relative path to the font
@@ -219,7 +490,9 @@ func (e *Epub) addCSS(source string, internalFilename string) (string, error) {
 // ../FontFolderName/internalFilename
 //
 // The font source should either be a URL, a path to a local file, or an embedded data URL; in any
-// case, the font file will be retrieved and stored in the EPUB.
+// case, the font file will be retrieved and stored in the EPUB. Data URLs
+// may be base64 or URL-encoded; the filename generated for them has the
+// extension of their media type.
 //
 // The internal filename will be used when storing the font file in the EPUB
 // and must be unique among all font files. If the same filename is used more
@@ -228,7 +501,15 @@ func (e *Epub) addCSS(source string, internalFilename string) (string, error) {
 func (e *Epub) AddFont(source string, internalFilename string) (string, error) {
 	e.Lock()
 	defer e.Unlock()
-	return addMedia(e.Client, source, internalFilename, fontFileFormat, FontFolderName, e.fonts)
+	return addMedia(e.grabber(), source, internalFilename, fontFileFormat, FontFolderName, e.fonts)
+}
+
+// AddFontContext is like AddFont, but retrieving the source is cancelled when
+// ctx is done.
+func (e *Epub) AddFontContext(ctx context.Context, source string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber().withContext(ctx), source, internalFilename, fontFileFormat, FontFolderName, e.fonts)
 }
 
 // AddImage adds an image to the EPUB and returns a relative path to the image
@@ -236,7 +517,9 @@ func (e *Epub) AddFont(source string, internalFilename string) (string, error) {
 // ../ImageFolderName/internalFilename
 //
 // The image source should either be a URL, a path to a local file, or an embedded data URL; in any
-// case, the image file will be retrieved and stored in the EPUB.
+// case, the image file will be retrieved and stored in the EPUB. Data URLs
+// may be base64 or URL-encoded; the filename generated for them has the
+// extension of their media type.
 //
 // The internal filename will be used when storing the image file in the EPUB
 // and must be unique among all image files. If the same filename is used more
@@ -245,7 +528,15 @@ func (e *Epub) AddFont(source string, internalFilename string) (string, error) {
 func (e *Epub) AddImage(source string, imageFilename string) (string, error) {
 	e.Lock()
 	defer e.Unlock()
-	return addMedia(e.Client, source, imageFilename, imageFileFormat, ImageFolderName, e.images)
+	return addMedia(e.grabber(), source, imageFilename, imageFileFormat, ImageFolderName, e.images)
+}
+
+// AddImageContext is like AddImage, but retrieving the source is cancelled when
+// ctx is done.
+func (e *Epub) AddImageContext(ctx context.Context, source string, imageFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber().withContext(ctx), source, imageFilename, imageFileFormat, ImageFolderName, e.images)
 }
 
 // AddVideo adds an video to the EPUB and returns a relative path to the video
@@ -262,7 +553,15 @@ func (e *Epub) AddImage(source string, imageFilename string) (string, error) {
 func (e *Epub) AddVideo(source string, videoFilename string) (string, error) {
 	e.Lock()
 	defer e.Unlock()
-	return addMedia(e.Client, source, videoFilename, videoFileFormat, VideoFolderName, e.videos)
+	return addMedia(e.grabber(), source, videoFilename, videoFileFormat, VideoFolderName, e.videos)
+}
+
+// AddVideoContext is like AddVideo, but retrieving the source is cancelled when
+// ctx is done.
+func (e *Epub) AddVideoContext(ctx context.Context, source string, videoFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber().withContext(ctx), source, videoFilename, videoFileFormat, VideoFolderName, e.videos)
 }
 
 // AddAudio adds an audio to the EPUB and returns a relative path to the audio
@@ -279,7 +578,15 @@ func (e *Epub) AddVideo(source string, videoFilename string) (string, error) {
 func (e *Epub) AddAudio(source string, audioFilename string) (string, error) {
 	e.Lock()
 	defer e.Unlock()
-	return addMedia(e.Client, source, audioFilename, audioFileFormat, AudioFolderName, e.audios)
+	return addMedia(e.grabber(), source, audioFilename, audioFileFormat, AudioFolderName, e.audios)
+}
+
+// AddAudioContext is like AddAudio, but retrieving the source is cancelled when
+// ctx is done.
+func (e *Epub) AddAudioContext(ctx context.Context, source string, audioFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber().withContext(ctx), source, audioFilename, audioFileFormat, AudioFolderName, e.audios)
 }
 
 // AddSection adds a new section (chapter, etc) to the EPUB and returns a
@@ -300,7 +607,8 @@ func (e *Epub) AddAudio(source string, audioFilename string) (string, error) {
 // optional; if no filename is provided, one will be generated.
 //
 // The internal path to an already-added CSS file (as returned by AddCSS) to be
-// used for the section is optional.
+// used for the section is optional. Several stylesheets can be set with
+// SetSectionStylesheets.
 func (e *Epub) AddSection(body string, sectionTitle string, internalFilename string, internalCSSPath string) (string, error) {
 	e.Lock()
 	defer e.Unlock()
@@ -334,8 +642,66 @@ func (e *Epub) AddSubSection(parentFilename string, body string, sectionTitle st
 	return e.addSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
 }
 
+// AddRawSection adds a new section to the EPUB from a complete, caller-authored
+// XHTML document, e.g. a chapter of a hand-built EPUB with its own head, meta
+// elements and styles, and returns a relative path to the section that can be
+// used from another section (for links).
+//
+// The document is written verbatim instead of being wrapped in the XHTML
+// template of the sections: it must be well-formed and should link the CSS
+// files it needs itself. Its title element is used for the table of contents;
+// if it has none, the section will not be added to the table of contents.
+// Since the document is written as is, AddTable, AddFigure, AddEpigraph and
+// SetSectionMetadata return RawSectionError for the section.
+//
+// The internal filename works as for AddSection.
+func (e *Epub) AddRawSection(xhtmlDocument string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	if elementDepth(xhtmlDocument, MaxElementDepth) > MaxElementDepth {
+		return "", &NestingTooDeepError{Filename: internalFilename, Limit: MaxElementDepth}
+	}
+	x, err := newRawXhtml(xhtmlDocument)
+	if err != nil {
+		return "", fmt.Errorf("can't add section: %w", err)
+	}
+	return e.insertNewSection("", x, internalFilename)
+}
+
 func (e *Epub) addSection(parentFilename string, body string, sectionTitle string, internalFilename string, internalCSSPath string) (string, error) {
+	x, err := e.newSectionXhtml(body, sectionTitle, internalFilename, internalCSSPath)
+	if err != nil {
+		return internalFilename, err
+	}
+	return e.insertNewSection(parentFilename, x, internalFilename)
+}
 
+// Create the XHTML document of a section
+func (e *Epub) newSectionXhtml(body string, sectionTitle string, internalFilename string, internalCSSPath string) (*xhtml, error) {
+	if elementDepth(body, MaxElementDepth) > MaxElementDepth {
+		return nil, &NestingTooDeepError{Filename: internalFilename, Limit: MaxElementDepth}
+	}
+	body = e.sanitize(internalFilename, "section body", body)
+	sectionTitle = e.sanitize(internalFilename, "section title", sectionTitle)
+	if e.autoAddImages {
+		body = e.addInlineImages(internalFilename, body)
+	}
+	x, err := newXhtml(body)
+	if err != nil {
+		return nil, fmt.Errorf("can't add section we cant create xhtml: %w", err)
+	}
+	x.setTitle(sectionTitle)
+	x.setXmlnsEpub(xmlnsEpub)
+
+	if internalCSSPath != "" {
+		x.setCSS(internalCSSPath)
+	}
+	return x, nil
+}
+
+// Add the XHTML document as a new section, appended to the root or to the
+// parent section
+func (e *Epub) insertSection(parentFilename string, x *xhtml, internalFilename string) (string, error) {
 	// get list of all xhtml filename inside of epub
 	filenamelist := getFilenames(e.sections)
 	parentIndex := filenamelist[parentFilename] - 1
@@ -343,6 +709,9 @@ func (e *Epub) addSection(parentFilename string, body string, sectionTitle strin
 	if parentFilename != "" && parentIndex == -1 {
 		return "", &ParentDoesNotExistError{Filename: parentFilename}
 	}
+	if parentFilename != "" && sectionDepth(e.sections, parentFilename) >= MaxSectionDepth {
+		return "", &NestingTooDeepError{Filename: parentFilename, Limit: MaxSectionDepth}
+	}
 
 	// Generate a filename if one isn't provided
 	if internalFilename == "" {
@@ -357,25 +726,15 @@ func (e *Epub) addSection(parentFilename string, body string, sectionTitle strin
 		// if internalFilename is not empty, check that it has .xhtml at the end.
 		// if it doesn't have add .xhtml at the end
 		// than if it is duplicate return error
-		if filepath.Ext(internalFilename) != ".xhtml" {
-			internalFilename += ".xhtml"
+		internalFilename = normalizeSectionFilename(internalFilename)
+		if err := ValidateFilename(internalFilename); err != nil {
+			return "", err
 		}
 		if keyExists(filenamelist, internalFilename) {
 			return "", &FilenameAlreadyUsedError{Filename: internalFilename}
 		}
 	}
 
-	x, err := newXhtml(body)
-	if err != nil {
-		return internalFilename, fmt.Errorf("can't add section we cant create xhtml: %w", err)
-	}
-	x.setTitle(sectionTitle)
-	x.setXmlnsEpub(xmlnsEpub)
-
-	if internalCSSPath != "" {
-		x.setCSS(internalCSSPath)
-	}
-
 	s := &epubSection{
 		filename: internalFilename,
 		xhtml:    x,
@@ -426,8 +785,9 @@ func (e *Epub) Ppd() string {
 func (e *Epub) SetAuthor(author string) {
 	e.Lock()
 	defer e.Unlock()
+	author = e.sanitize("", "author", author)
 	e.author = author
-	e.pkg.setAuthor(author)
+	e.pkg.setAuthor(author, e.authorLang)
 }
 
 // SetCover sets the cover page for the EPUB using the provided image source and
@@ -442,36 +802,26 @@ func (e *Epub) SetAuthor(author string) {
 func (e *Epub) SetCover(internalImagePath string, internalCSSPath string) error {
 	e.Lock()
 	defer e.Unlock()
-	// If a cover already exists
-	if e.cover.xhtmlFilename != "" {
-		// Remove the xhtml file
-		for i, section := range e.sections {
-			if section.filename == e.cover.xhtmlFilename {
-				e.sections = append(e.sections[:i], e.sections[i+1:]...)
-				break
-			}
-		}
-
-		// Remove the image
-		delete(e.images, e.cover.imageFilename)
+	return e.setCover(internalImagePath, internalCSSPath)
+}
 
-		// Remove the CSS
-		delete(e.css, e.cover.cssFilename)
+func (e *Epub) setCover(internalImagePath string, internalCSSPath string) error {
+	return e.setCoverPage(internalImagePath, internalCSSPath, fmt.Sprintf(defaultCoverBody, internalImagePath))
+}
 
-		if e.cover.cssTempFile != "" {
-			os.Remove(e.cover.cssTempFile)
-		}
-	}
+// Set the cover image and generate a cover page with the given body, see
+// SetCover
+func (e *Epub) setCoverPage(internalImagePath string, internalCSSPath string, coverBody string) error {
+	e.removeCover()
 
 	e.cover.imageFilename = filepath.Base(internalImagePath)
-	e.pkg.setCover(e.cover.imageFilename)
 
 	// Use default cover stylesheet if one isn't provided
 	if internalCSSPath == "" {
 		// Encode the default CSS
 		e.cover.cssTempFile = dataurl.EncodeBytes([]byte(defaultCoverCSSContent))
 		var err error
-		internalCSSPath, err = e.addCSS(e.cover.cssTempFile, defaultCoverCSSFilename)
+		internalCSSPath, err = addMedia(e.generatedGrabber(), e.cover.cssTempFile, defaultCoverCSSFilename, cssFileFormat, CSSFolderName, e.css)
 		// If that doesn't work, generate a filename
 		if _, ok := err.(*FilenameAlreadyUsedError); ok {
 			coverCSSFilename := fmt.Sprintf(
@@ -480,7 +830,7 @@ func (e *Epub) SetCover(internalImagePath string, internalCSSPath string) error
 				".css",
 			)
 
-			internalCSSPath, err = e.addCSS(e.cover.cssTempFile, coverCSSFilename)
+			internalCSSPath, err = addMedia(e.generatedGrabber(), e.cover.cssTempFile, coverCSSFilename, cssFileFormat, CSSFolderName, e.css)
 			if _, ok := err.(*FilenameAlreadyUsedError); ok {
 				// This shouldn't cause an error
 				return fmt.Errorf("Error adding default cover CSS file: %w", err)
@@ -494,13 +844,16 @@ func (e *Epub) SetCover(internalImagePath string, internalCSSPath string) error
 	}
 	e.cover.cssFilename = filepath.Base(internalCSSPath)
 
-	coverBody := fmt.Sprintf(defaultCoverBody, internalImagePath)
 	// Title won't be used since the cover won't be added to the TOC
+	x, err := e.newSectionXhtml(coverBody, "", defaultCoverXhtmlFilename, internalCSSPath)
+	if err != nil {
+		return err
+	}
 	// First try to use the default cover filename
-	coverPath, err := e.addSection("", coverBody, "", defaultCoverXhtmlFilename, internalCSSPath)
+	coverPath, err := e.insertSection("", x, defaultCoverXhtmlFilename)
 	// If that doesn't work, generate a filename
 	if _, ok := err.(*FilenameAlreadyUsedError); ok {
-		coverPath, err = e.addSection("", coverBody, "", "", internalCSSPath)
+		coverPath, err = e.insertSection("", x, "")
 		if _, ok := err.(*FilenameAlreadyUsedError); ok {
 			// This shouldn't cause an error since we're not specifying a filename
 			return fmt.Errorf("Error adding default cover XHTML file: %w", err)
@@ -510,14 +863,166 @@ func (e *Epub) SetCover(internalImagePath string, internalCSSPath string) error
 	return nil
 }
 
+// SetCoverFromReader sets the cover page for the EPUB using an image read from
+// r (PNG, JPEG or GIF) and optional CSS, like SetCover. It returns the
+// relative paths to the cover image and to a thumbnail of it.
+//
+// Images that fit within CoverMaxWidth x CoverMaxHeight are kept as they
+// are, with their metadata; larger ones are scaled down to a size suitable for
+// reading systems, after their EXIF orientation is applied. The thumbnail fits
+// within CoverThumbnailMaxWidth x CoverThumbnailMaxHeight, with the EXIF
+// orientation of the image applied as well, and is added as a regular image,
+// so only the cover image is marked as the cover in the package file.
+//
+// The scaled-down JPEG images are stored as JPEG; any other format is stored
+// as PNG.
+func (e *Epub) SetCoverFromReader(r io.Reader, internalCSSPath string) (string, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read cover image: %w", err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", "", fmt.Errorf("unable to decode cover image: %w", err)
+	}
+	if format == "jpeg" {
+		img = orientedImage(img, jpegOrientation(data))
+	}
+	coverData, ext := data, "."+format
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	if b := img.Bounds(); b.Dx() > CoverMaxWidth || b.Dy() > CoverMaxHeight {
+		coverData, ext, err = encodeImage(downscaleImage(img, CoverMaxWidth, CoverMaxHeight), format)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	thumbnailData, _, err := encodeImage(downscaleImage(img, CoverThumbnailMaxWidth, CoverThumbnailMaxHeight), format)
+	if err != nil {
+		return "", "", err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	coverPath, err := e.addGeneratedImage(coverData, fmt.Sprintf(defaultCoverImgFormat, ext))
+	if err != nil {
+		return "", "", err
+	}
+	thumbnailPath, err := e.addGeneratedImage(thumbnailData, fmt.Sprintf(defaultCoverThumbnailImgFormat, ext))
+	if err != nil {
+		return "", "", err
+	}
+	if err := e.setCover(coverPath, internalCSSPath); err != nil {
+		return "", "", err
+	}
+	return coverPath, thumbnailPath, nil
+}
+
+// Add an image generated in memory, falling back to a generated filename if
+// the preferred one is already used
+func (e *Epub) addGeneratedImage(data []byte, filename string) (string, error) {
+	source := dataurl.EncodeBytes(data)
+	imagePath, err := addMedia(e.generatedGrabber(), source, filename, imageFileFormat, ImageFolderName, e.images)
+	if _, ok := err.(*FilenameAlreadyUsedError); ok {
+		imagePath, err = addMedia(e.generatedGrabber(), source, fmt.Sprintf(imageFileFormat, len(e.images)+1, filepath.Ext(filename)), imageFileFormat, ImageFolderName, e.images)
+	}
+	return imagePath, err
+}
+
+// SetCoverImage sets the cover image of the EPUB without generating a cover
+// page. The image is only referenced from the package file (with the
+// cover-image manifest property and the EPUB 2 cover meta element), which is
+// what some fixed-layout designs require.
+//
+// The internal path to an already-added image file (as returned by AddImage) is
+// required.
+func (e *Epub) SetCoverImage(internalImagePath string) error {
+	e.Lock()
+	defer e.Unlock()
+	e.removeCover()
+
+	e.cover.imageFilename = filepath.Base(internalImagePath)
+	return nil
+}
+
+// SetCoverXHTML sets the cover of the EPUB using the provided image and a
+// complete, caller-authored XHTML document for the cover page. The document is
+// written verbatim; it must be well-formed and should reference the image and
+// any CSS it needs itself.
+//
+// The internal path to an already-added image file (as returned by AddImage) is
+// required.
+func (e *Epub) SetCoverXHTML(internalImagePath string, xhtmlDocument string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	x, err := newRawXhtml(xhtmlDocument)
+	if err != nil {
+		return fmt.Errorf("Error adding cover XHTML file: %w", err)
+	}
+	e.removeCover()
+
+	e.cover.imageFilename = filepath.Base(internalImagePath)
+
+	coverPath, err := e.insertSection("", x, defaultCoverXhtmlFilename)
+	if _, ok := err.(*FilenameAlreadyUsedError); ok {
+		coverPath, err = e.insertSection("", x, "")
+	}
+	if err != nil {
+		return fmt.Errorf("Error adding cover XHTML file: %w", err)
+	}
+	e.cover.xhtmlFilename = filepath.Base(coverPath)
+	return nil
+}
+
+// Remove the cover page and the files that were added for it, if a cover was
+// set
+func (e *Epub) removeCover() {
+	if e.cover.xhtmlFilename != "" {
+		// Remove the xhtml file
+		for i, section := range e.sections {
+			if section.filename == e.cover.xhtmlFilename {
+				e.sections = append(e.sections[:i], e.sections[i+1:]...)
+				break
+			}
+		}
+
+		// The image was added by the caller and may be reused by the new cover,
+		// so only remove the CSS
+		delete(e.css, e.cover.cssFilename)
+
+		if e.cover.cssTempFile != "" {
+			os.Remove(e.cover.cssTempFile)
+		}
+	}
+	e.cover.xhtmlFilename = ""
+	e.cover.cssFilename = ""
+	e.cover.cssTempFile = ""
+	e.cover.fallbackFilename = ""
+}
+
 // SetIdentifier sets the unique identifier of the EPUB, such as a UUID, DOI,
 // ISBN or ISSN. If no identifier is set, a UUID will be automatically
 // generated.
 func (e *Epub) SetIdentifier(identifier string) {
 	e.Lock()
 	defer e.Unlock()
+	e.customIdentifier = true
+	e.setIdentifier(e.sanitize("", "identifier", identifier))
+}
+
+func (e *Epub) setIdentifier(identifier string) {
 	e.identifier = identifier
-	e.pkg.setIdentifier(identifier)
+	e.identifierScheme = ""
+	others := e.otherIdentifiers[:0]
+	for _, other := range e.otherIdentifiers {
+		if other.Value != identifier {
+			others = append(others, other)
+		}
+	}
+	e.otherIdentifiers = others
+	e.pkg.setIdentifiers(e.identifiers())
 	e.toc.setIdentifier(identifier)
 }
 
@@ -526,13 +1031,25 @@ func (e *Epub) SetLang(lang string) {
 	e.Lock()
 	defer e.Unlock()
 	e.lang = lang
-	e.pkg.setLang(lang)
+	e.pkg.setLangs(e.langs())
+}
+
+// SetOfflineOnly enables or disables the offline-only mode. In offline-only
+// mode, remote URL sources are rejected with a RemoteSourceError, both when
+// they are added and when the EPUB is written, so that builds never depend on
+// the network. Local files and data URLs are still allowed.
+func (e *Epub) SetOfflineOnly(offline bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.offlineOnly = offline
 }
 
-// SetDescription sets the description of the EPUB.
+// SetDescription sets the description of the EPUB, as plain text. See
+// SetHTMLDescription for a description with formatting.
 func (e *Epub) SetDescription(desc string) {
 	e.Lock()
 	defer e.Unlock()
+	desc = e.sanitize("", "description", desc)
 	e.desc = desc
 	e.pkg.setDescription(desc)
 }
@@ -549,6 +1066,7 @@ func (e *Epub) SetPpd(direction string) {
 func (e *Epub) SetTitle(title string) {
 	e.Lock()
 	defer e.Unlock()
+	title = e.sanitize("", "title", title)
 	e.title = title
 	e.pkg.setTitle(title)
 	e.toc.setTitle(title)
@@ -570,7 +1088,8 @@ func (e *Epub) Title() string {
 // and must be unique among all image files. If the same filename is used more
 // than once, FilenameAlreadyUsedError will be returned. The internal filename is
 // optional; if no filename is provided, one will be generated.
-// if go-epub can't download image it keep it untoch and not return any error just log that
+// if go-epub can't download image it keep it untoch and not return any error, the issue is reported by
+// Warnings after the EPUB is written
 
 // Just call EmbedImages() after section added
 func (e *Epub) EmbedImages() {
@@ -605,19 +1124,24 @@ func (e *Epub) EmbedImages() {
 				match[0] = match[0][:firstSrcIndex+len(" src=")] + strings.ReplaceAll(match[0][firstSrcIndex+len(" src="):], " src=", " data-src=")
 				parsedImageURL, err := url.Parse(imageURL)
 				if err != nil {
-					log.Printf("can't parse image URL: %s", err)
+					e.warnings.add(SeverityWarning, RuleEmbedImage, section.filename, "can't parse image URL: %s", err)
+					continue
+				}
+				g := e.grabber()
+				if g.offline && detectMediaType(imageURL) == "URL" {
+					e.warnings.add(SeverityWarning, RuleEmbedImage, section.filename, "can't add image to the epub: %s", &RemoteSourceError{Source: imageURL})
 					continue
 				}
 				extension := filepath.Ext(parsedImageURL.Path)
-				if extension == "" {
-					res, err := http.Head(imageURL)
+				if extension == "" && detectMediaType(imageURL) == "URL" {
+					contentType, err := g.remoteContentType(imageURL)
 					if err != nil {
-						log.Printf("can't get image headers: %s", err)
+						e.warnings.add(SeverityWarning, RuleEmbedImage, section.filename, "can't get image headers: %s", err)
 					} else {
 						// Get extension from the file content type
-						extensions, err := mime.ExtensionsByType(res.Header.Get("Content-Type"))
+						extensions, err := mime.ExtensionsByType(contentType)
 						if err != nil {
-							log.Printf("can't get file type from content type: %s", err)
+							e.warnings.add(SeverityWarning, RuleEmbedImage, section.filename, "can't get file type from content type: %s", err)
 						} else if len(extensions) > 0 {
 							extension = extensions[0]
 						}
@@ -626,7 +1150,7 @@ func (e *Epub) EmbedImages() {
 				filename := fmt.Sprintf("image%04d%s", len(e.images)+1, extension)
 				filePath, err := e.AddImage(string(imageURL), filename)
 				if err != nil {
-					log.Printf("can't add image to the epub: %s", err)
+					e.warnings.add(SeverityWarning, RuleEmbedImage, section.filename, "can't add image to the epub: %s", err)
 					continue
 				}
 				newImgTag := strings.ReplaceAll(match[0], imageURL, filePath)
@@ -638,30 +1162,52 @@ func (e *Epub) EmbedImages() {
 
 // Add a media file to the EPUB and return the path relative to the EPUB section
 // files
-func addMedia(client *http.Client, source string, internalFilename string, mediaFileFormat string, mediaFolderName string, mediaMap map[string]string) (string, error) {
-	err := grabber{client}.checkMedia(source)
+func addMedia(g grabber, source string, internalFilename string, mediaFileFormat string, mediaFolderName string, mediaMap map[string]string) (string, error) {
+	if g.offline && detectMediaType(source) == "URL" {
+		return "", &RemoteSourceError{Source: source}
+	}
+	err := g.checkMedia(source)
 	if err != nil {
 		return "", &FileRetrievalError{
 			Source: source,
 			Err:    err,
 		}
 	}
+	if internalFilename == "" && detectMediaType(source) == "DataURL" {
+		// Data URLs have no filename, generate one with the extension of
+		// their media type
+		internalFilename = fmt.Sprintf(mediaFileFormat, len(mediaMap)+1, dataURLExtension(source, mediaFolderName))
+	}
 	if internalFilename == "" {
 		// If a filename isn't provided, use the filename from the source
 		internalFilename = filepath.Base(source)
 		_, ok := mediaMap[internalFilename]
 		// if filename is too long, invalid or already used, try to generate a unique filename
-		if len(internalFilename) > 255 || !fs.ValidPath(internalFilename) || ok {
+		if ValidateFilename(internalFilename) != nil || ok && g.duplicates == DuplicatesDefault {
+			sourceFilename := internalFilename
 			internalFilename = fmt.Sprintf(
 				mediaFileFormat,
 				len(mediaMap)+1,
 				strings.ToLower(filepath.Ext(source)),
 			)
+			if g.warnings != nil {
+				g.warnings.add(SeverityWarning, RuleRenamedFile, internalFilename, "%s was renamed to %s", sourceFilename, internalFilename)
+			}
 		}
 	}
 
+	if err := ValidateFilename(internalFilename); err != nil {
+		return "", err
+	}
 	if _, ok := mediaMap[internalFilename]; ok {
-		return "", &FilenameAlreadyUsedError{Filename: internalFilename}
+		replace, err := g.duplicates.resolve(g.warnings, internalFilename)
+		if err != nil {
+			return "", err
+		}
+		if replace {
+			mediaMap[internalFilename] = source
+		}
+		return path.Join("..", mediaFolderName, internalFilename), nil
 	}
 
 	mediaMap[internalFilename] = source
@@ -673,6 +1219,11 @@ func addMedia(client *http.Client, source string, internalFilename string, media
 	), nil
 }
 
+// grabber returns the grabber used to retrieve media sources
+func (e *Epub) grabber() grabber {
+	return grabber{Client: e.Client, offline: e.offlineOnly, cache: e.mediaCache, warnings: &e.warnings, limiter: e.fetchLimiter, logger: e.logger, retry: e.retryPolicy, checkpointDir: e.checkpointDir, refreshSigned: e.signedURLRefresher, duplicates: e.duplicates}
+}
+
 // getFilenames returns a map of section filenames and index numbers within an ebook
 func getFilenames(sections []*epubSection) map[string]int {
 	filenames := make(map[string]int)
@@ -700,6 +1251,24 @@ func keyExists(m map[string]int, key string) bool {
 	return ok
 }
 
+// Find the section with the given filename, including subsections
+func (e *Epub) findSection(filename string) (*epubSection, bool) {
+	var find func(sections []*epubSection) *epubSection
+	find = func(sections []*epubSection) *epubSection {
+		for _, section := range sections {
+			if section.filename == filename {
+				return section
+			}
+			if s := find(section.children); s != nil {
+				return s
+			}
+		}
+		return nil
+	}
+	s := find(e.sections)
+	return s, s != nil
+}
+
 // Find parent section and append epubSection to it
 func sectionAppender(sections []*epubSection, parentFilename string, targetSection *epubSection) error {
 	for _, section := range sections {
@@ -3,8 +3,11 @@ package epub
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
 	"log"
 	"net/http"
@@ -19,6 +22,7 @@ import (
 
 	"github.com/quailyquaily/go-epub/internal/storage"
 	"github.com/gofrs/uuid/v5"
+	"github.com/vincent-petithory/dataurl"
 )
 
 const (
@@ -1005,6 +1009,72 @@ func TestUnableToCreateEpubError(t *testing.T) {
 	}
 }
 
+func TestOfflineOnly(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("./testdata/")))
+	defer server.Close()
+	remoteSource := server.URL + "/gophercolor16x16.png"
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Error(err)
+	}
+	// Added before the offline-only mode is enabled
+	if _, err := e.AddImage(remoteSource, "remote.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	e.SetOfflineOnly(true)
+	_, err = e.AddImage(remoteSource, "")
+	if _, ok := err.(*RemoteSourceError); !ok {
+		t.Errorf("Expected error RemoteSourceError not returned. Returned instead: %+v", err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Errorf("Unexpected error adding a local image in offline-only mode: %s", err)
+	}
+
+	_, err = e.WriteTo(io.Discard)
+	if _, ok := err.(*RemoteSourceError); !ok {
+		t.Errorf("Expected error RemoteSourceError not returned by WriteTo. Returned instead: %+v", err)
+	}
+
+	e.SetOfflineOnly(false)
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Errorf("Unexpected error writing EPUB: %s", err)
+	}
+}
+
+func TestOfflineOnlyEmbedImages(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, testImageFromFileSource)
+	}))
+	defer server.Close()
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionBody := `<p><img src="` + server.URL + `/image" alt=""/></p>`
+	if _, err := e.AddSection(sectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetOfflineOnly(true)
+	e.EmbedImages()
+	if requests != 0 {
+		t.Errorf("Expected no request in offline-only mode, got %d", requests)
+	}
+	if len(e.images) != 0 {
+		t.Errorf("Expected no image to be embedded, got %v", e.images)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if w := e.Warnings(); len(w) == 0 || w[0].Rule != RuleEmbedImage {
+		t.Errorf("Expected an embed-image warning, got %v", w)
+	}
+}
+
 func TestEmbedImage(t *testing.T) {
 	fs := http.FileServer(http.Dir("./testdata/"))
 
@@ -1480,3 +1550,267 @@ func TestAddSubSectionWithCustomFilename(t *testing.T) {
 
 	cleanup(testEpubFilename, tempDir)
 }
+
+func TestSetCoverImage(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Error(err)
+	}
+
+	testImagePath, err := e.AddImage(testImageFromFileSource, testImageFromFileFilename)
+	if err != nil {
+		t.Error(err)
+	}
+	err = e.SetCover(testImagePath, "")
+	if err != nil {
+		t.Error(err)
+	}
+	// Replacing the cover should remove the cover page but keep the image
+	err = e.SetCoverImage(testImagePath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+
+	if _, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, xhtmlFolderName, defaultCoverXhtmlFilename)); err == nil {
+		t.Error("Cover XHTML file shouldn't be generated")
+	}
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, pkgFilename))
+	if err != nil {
+		t.Errorf("Unexpected error reading package file: %s", err)
+	}
+	if !strings.Contains(string(contents), `href="images/testfromfile.png" media-type="image/png" properties="cover-image"`) {
+		t.Errorf("Cover image manifest item not found\nGot: %s", contents)
+	}
+	if strings.Contains(string(contents), "<itemref") {
+		t.Errorf("Spine shouldn't contain a cover page\nGot: %s", contents)
+	}
+
+	cleanup(testEpubFilename, tempDir)
+}
+
+func TestSetCoverXHTML(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Error(err)
+	}
+
+	testImagePath, err := e.AddImage(testImageFromFileSource, testImageFromFileFilename)
+	if err != nil {
+		t.Error(err)
+	}
+	testCoverContents := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml">
+  <head>
+    <title>Cover</title>
+    <meta name="viewport" content="width=600, height=800" />
+  </head>
+  <body>
+    <div><img src="%s" alt="Cover" /></div>
+  </body>
+</html>
+`, testImagePath)
+	err = e.SetCoverXHTML(testImagePath, testCoverContents)
+	if err != nil {
+		t.Error(err)
+	}
+	err = e.SetCoverXHTML(testImagePath, "<html><body>")
+	if err == nil {
+		t.Error("Expected an error for a malformed cover document")
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, xhtmlFolderName, defaultCoverXhtmlFilename))
+	if err != nil {
+		t.Errorf("Unexpected error reading cover XHTML file: %s", err)
+	}
+	if string(contents) != testCoverContents {
+		t.Errorf(
+			"Cover file contents don't match\n"+
+				"Got: %s\n"+
+				"Expected: %s",
+			contents,
+			testCoverContents)
+	}
+
+	cleanup(testEpubFilename, tempDir)
+}
+
+func TestAddRawSection(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+  <head>
+    <title>Hand-built chapter</title>
+    <meta name="author" content="Jane Doe" />
+    <style>p { text-indent: 1em; }</style>
+  </head>
+  <body>
+    <p>Chapter text</p>
+  </body>
+</html>
+`
+	sectionPath, err := e.AddRawSection(doc, "chapter1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sectionPath != "chapter1.xhtml" {
+		t.Errorf("Unexpected section path %s", sectionPath)
+	}
+	if _, err := e.AddRawSection(doc, "chapter1.xhtml"); err == nil {
+		t.Error("Expected an error for a filename already used")
+	}
+	if _, err := e.AddRawSection("<html><body>", ""); err == nil {
+		t.Error("Expected an error for a malformed document")
+	}
+	untitled := `<html xmlns="http://www.w3.org/1999/xhtml"><head></head><body><p>Untitled</p></body></html>`
+	if _, err := e.AddRawSection(untitled, "untitled.xhtml"); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw *RawSectionError
+	if _, err := e.AddTable(sectionPath, [][]string{{"a"}}, ""); !errors.As(err, &raw) {
+		t.Errorf("Expected a RawSectionError from AddTable, got %v", err)
+	}
+	if _, err := e.AddFigure(sectionPath, testImageFromFileSource, "", ""); !errors.As(err, &raw) {
+		t.Errorf("Expected a RawSectionError from AddFigure, got %v", err)
+	}
+	if err := e.AddEpigraph(sectionPath, "Quote", ""); !errors.As(err, &raw) {
+		t.Errorf("Expected a RawSectionError from AddEpigraph, got %v", err)
+	}
+	if err := e.SetSectionMetadata(sectionPath, SectionMetadata{Author: "Jane Doe"}); !errors.As(err, &raw) {
+		t.Errorf("Expected a RawSectionError from SetSectionMetadata, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/xhtml/chapter1.xhtml"] != doc {
+		t.Errorf("Expected the document written verbatim, got:\n%s", files["EPUB/xhtml/chapter1.xhtml"])
+	}
+	if nav := files["EPUB/nav.xhtml"]; !strings.Contains(nav, `<a href="xhtml/chapter1.xhtml">Hand-built chapter</a>`) {
+		t.Errorf("Expected the title of the document in the nav document, got:\n%s", nav)
+	}
+	for _, name := range []string{"EPUB/nav.xhtml", "EPUB/toc.ncx"} {
+		if strings.Contains(files[name], "untitled.xhtml") {
+			t.Errorf("Expected the untitled document to be left out of %s, got:\n%s", name, files[name])
+		}
+	}
+	if len(e.images) != 0 {
+		t.Errorf("Expected no image to be added for the figure, got %v", e.images)
+	}
+}
+
+func TestSetCoverFromReader(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var b bytes.Buffer
+	err = png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, CoverMaxWidth*2, CoverMaxWidth)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coverPath, thumbnailPath, err := e.SetCoverFromReader(&b, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverPath != "../images/cover.png" || thumbnailPath != "../images/cover-thumbnail.png" {
+		t.Errorf("Unexpected cover paths %s and %s", coverPath, thumbnailPath)
+	}
+
+	for p, expected := range map[string]image.Point{
+		coverPath:     {CoverMaxWidth, CoverMaxWidth / 2},
+		thumbnailPath: {CoverThumbnailMaxWidth, CoverThumbnailMaxWidth / 2},
+	} {
+		data, err := dataurl.DecodeString(e.images[filepath.Base(p)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width != expected.X || cfg.Height != expected.Y {
+			t.Errorf("Expected %s to be %v, got %dx%d", p, expected, cfg.Width, cfg.Height)
+		}
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, pkgFilename))
+	if err != nil {
+		t.Errorf("Unexpected error reading package file: %s", err)
+	}
+	if strings.Count(string(contents), coverImageProperties) != 1 {
+		t.Errorf("Only the cover image should have the cover-image property\nGot: %s", contents)
+	}
+	coverID, _ := fixXMLId("cover.png")
+	if !strings.Contains(string(contents), fmt.Sprintf(`<meta name="cover" content="%s">`, coverID)) {
+		t.Errorf("EPUB 2 cover meta element not found\nGot: %s", contents)
+	}
+
+	cleanup(testEpubFilename, tempDir)
+}
+
+func TestAddMediaDataURL(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := os.ReadFile(testImageFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Base64 data URLs whose data may contain slashes, and URL-encoded ones
+	imagePath, err := e.AddImage("data:image/png;base64,"+base64.StdEncoding.EncodeToString(png), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imagePath != "../images/image0001.png" {
+		t.Errorf("Expected a filename with the extension of the media type, got %s", imagePath)
+	}
+	cssPath, err := e.AddCSS("data:text/css,p%20%7B%20margin%3A%200%3B%20%7D", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cssPath != "../css/css0001.css" {
+		t.Errorf("Unexpected CSS path %s", cssPath)
+	}
+	// Without a media type, the extension is detected from the data
+	fontPath, err := e.AddFont("data:;base64,"+base64.StdEncoding.EncodeToString(png), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fontPath != "../fonts/font0001.png" {
+		t.Errorf("Unexpected font path %s", fontPath)
+	}
+	if _, err := e.AddImage("data:image/png;base64,not base64!", ""); err == nil {
+		t.Error("Expected an error for an invalid data URL")
+	}
+	for _, w := range e.Validate().Warnings() {
+		if w.Rule == RuleRenamedFile {
+			t.Errorf("Unexpected warning for a data URL: %s", w)
+		}
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/images/image0001.png"] != string(png) || files["EPUB/css/css0001.css"] != "p { margin: 0; }" {
+		t.Error("Expected the decoded data URLs in the EPUB")
+	}
+}
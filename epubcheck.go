@@ -0,0 +1,158 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// EPUBCheck runs EPUBCheck, the reference EPUB validator, which must be
+// installed, and reports its messages in a ValidationReport like the built-in
+// validator (see Validate).
+//
+// The Rule of the issues is the ID of the EPUBCheck message (e.g. RSC-005) and
+// their Filename is the path of the file in the container, e.g.
+// EPUB/xhtml/section0001.xhtml.
+type EPUBCheck struct {
+	// Path of the epubcheck jar; if empty, the epubcheck executable installed
+	// by some package managers is run instead
+	Jar string
+	// Path of the java executable, or of the epubcheck executable if Jar is
+	// empty; if empty, it is looked up in PATH
+	Path string
+	// Also report the usage and info messages, as warnings
+	Usage bool
+}
+
+// Severities of the EPUBCheck messages
+const (
+	epubcheckFatal   = "FATAL"
+	epubcheckError   = "ERROR"
+	epubcheckWarning = "WARNING"
+)
+
+// The JSON report of EPUBCheck, written with its --json option
+type epubcheckReport struct {
+	Messages []struct {
+		ID        string `json:"ID"`
+		Severity  string `json:"severity"`
+		Message   string `json:"message"`
+		Locations []struct {
+			Path   string `json:"path"`
+			Line   int    `json:"line"`
+			Column int    `json:"column"`
+		} `json:"locations"`
+	} `json:"messages"`
+}
+
+// Check runs EPUBCheck on the EPUB file at epubPath and returns the issues it
+// found. An error is returned only if EPUBCheck couldn't be run, not if the
+// EPUB has issues.
+func (c EPUBCheck) Check(epubPath string) (*ValidationReport, error) {
+	return c.CheckContext(context.Background(), epubPath)
+}
+
+// CheckContext is like Check, but EPUBCheck is killed when ctx is done.
+func (c EPUBCheck) CheckContext(ctx context.Context, epubPath string) (*ValidationReport, error) {
+	dir, err := os.MkdirTemp("", tempDirPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	jsonPath := filepath.Join(dir, "epubcheck.json")
+
+	path, args := c.Path, []string{epubPath, "--json", jsonPath}
+	if c.Jar != "" {
+		if path == "" {
+			path = "java"
+		}
+		args = append([]string{"-jar", c.Jar}, args...)
+	} else if path == "" {
+		path = "epubcheck"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("unable to run epubcheck: %w", runErr)
+	}
+
+	// EPUBCheck exits with an error status when the EPUB has errors, the
+	// report tells whether it ran
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("epubcheck failed: %w: %s", runErr, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, fmt.Errorf("unable to read the epubcheck report: %w", err)
+	}
+	return c.parseReport(data)
+}
+
+// CheckEpub writes the EPUB to a temporary file and runs EPUBCheck on it, see
+// Check.
+func (c EPUBCheck) CheckEpub(e *Epub) (*ValidationReport, error) {
+	return c.CheckEpubContext(context.Background(), e)
+}
+
+// CheckEpubContext is like CheckEpub, but the write and EPUBCheck stop when
+// ctx is done.
+func (c EPUBCheck) CheckEpubContext(ctx context.Context, e *Epub) (*ValidationReport, error) {
+	f, err := os.CreateTemp("", tempDirPrefix+"-*.epub")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = e.WriteToContext(ctx, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write the EPUB: %w", err)
+	}
+	return c.CheckContext(ctx, f.Name())
+}
+
+// Convert the JSON report of EPUBCheck to a ValidationReport
+func (c EPUBCheck) parseReport(data []byte) (*ValidationReport, error) {
+	var r epubcheckReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unable to parse the epubcheck report: %w", err)
+	}
+	report := &ValidationReport{}
+	for _, m := range r.Messages {
+		var severity Severity
+		switch m.Severity {
+		case epubcheckFatal, epubcheckError:
+			severity = SeverityError
+		case epubcheckWarning:
+			severity = SeverityWarning
+		default:
+			if !c.Usage {
+				continue
+			}
+			severity = SeverityWarning
+		}
+		var filename string
+		message := m.Message
+		if len(m.Locations) > 0 {
+			l := m.Locations[0]
+			filename = l.Path
+			if l.Line > 0 {
+				message = fmt.Sprintf("%s (line %d, column %d)", message, l.Line, l.Column)
+			}
+		}
+		report.add(severity, m.ID, filename, "%s", message)
+	}
+	return report, nil
+}
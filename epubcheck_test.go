@@ -0,0 +1,100 @@
+package epub
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+const testEPUBCheckReport = `{
+  "checker": {"path": "book.epub", "nFatal": 0, "nError": 1, "nWarning": 1, "nUsage": 1},
+  "messages": [
+    {"ID": "RSC-005", "severity": "ERROR", "message": "Error while parsing file: element \"foo\" not allowed here",
+     "locations": [{"path": "EPUB/xhtml/section0001.xhtml", "line": 12, "column": 7}]},
+    {"ID": "PKG-010", "severity": "WARNING", "message": "Filename contains spaces",
+     "locations": [{"path": "EPUB/images/a b.png", "line": -1, "column": -1}]},
+    {"ID": "ACC-004", "severity": "USAGE", "message": "Link text is empty", "locations": []}
+  ]
+}`
+
+func TestEPUBCheckParseReport(t *testing.T) {
+	report, err := EPUBCheck{}.parseReport([]byte(testEPUBCheckReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 2 || !report.HasErrors() || len(report.Warnings()) != 1 {
+		t.Fatalf("Unexpected issues: %v", report.Issues)
+	}
+	issue := report.Issues[0]
+	if issue.Rule != "RSC-005" || issue.Filename != "EPUB/xhtml/section0001.xhtml" ||
+		issue.Message != `Error while parsing file: element "foo" not allowed here (line 12, column 7)` {
+		t.Errorf("Unexpected issue: %+v", issue)
+	}
+	if report.Issues[1].Message != "Filename contains spaces" {
+		t.Errorf("Expected no position for the warning, got %q", report.Issues[1].Message)
+	}
+
+	report, err = EPUBCheck{Usage: true}.parseReport([]byte(testEPUBCheckReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 3 || report.Issues[2].Severity != SeverityWarning {
+		t.Errorf("Expected the usage message to be reported as a warning, got %v", report.Issues)
+	}
+
+	if _, err := (EPUBCheck{}).parseReport([]byte("not json")); err == nil {
+		t.Error("Expected an error for an invalid report")
+	}
+}
+
+func TestEPUBCheckCheckEpub(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake epubcheck is a shell script")
+	}
+	// A fake epubcheck writing the report to the path given after --json and
+	// exiting with an error status, as EPUBCheck does when the EPUB has errors
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(reportPath, []byte(testEPUBCheckReport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "epubcheck")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntest -f \"$1\" || exit 2\ncp "+reportPath+" \"$3\"\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := EPUBCheck{Path: script}.CheckEpub(e)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Issues) != 2 {
+		t.Errorf("Unexpected issues: %v", report.Issues)
+	}
+
+	if _, err := (EPUBCheck{Path: filepath.Join(dir, "missing")}).Check(reportPath); err == nil {
+		t.Error("Expected an error when epubcheck can't be run")
+	}
+
+	// EPUBCheck is killed when the context is done
+	slow := filepath.Join(dir, "slow-epubcheck")
+	if err := os.WriteFile(slow, []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := (EPUBCheck{Path: slow}).CheckEpubContext(ctx, e); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected epubcheck to be killed when the deadline is exceeded, it took %s", elapsed)
+	}
+}
@@ -0,0 +1,242 @@
+// Package epubtest provides helpers to regression-test the EPUBs generated with
+// go-epub against golden directories.
+//
+// A golden directory holds the files of an EPUB, as if it was unzipped. Since
+// some of the content of an EPUB changes from one build to the next (the
+// generated identifier, the modification date...), the generated files are
+// canonicalized before they are compared:
+//
+//	func TestBook(t *testing.T) {
+//		e := buildBook(t)
+//		epubtest.AssertGolden(t, epubtest.Render(t, e), "testdata/book")
+//	}
+//
+// Run the tests with EPUBTEST_UPDATE=1 to create or update the golden
+// directories.
+package epubtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/go-epub"
+)
+
+// Update makes AssertGolden write the files to the golden directory instead
+// of comparing them. It is true if the EPUBTEST_UPDATE environment variable is
+// set.
+var Update = os.Getenv("EPUBTEST_UPDATE") != ""
+
+// Placeholders used by Canonicalize
+const (
+	CanonicalUUID     = "00000000-0000-0000-0000-000000000000"
+	CanonicalModified = "2000-01-01T00:00:00Z"
+)
+
+// The provenance statement, which Canonicalize leaves out since it is signed
+// over content that changes from one build to the next
+const provenanceFilename = "META-INF/provenance.json"
+
+var (
+	urnUUIDRegex  = regexp.MustCompile(`urn:uuid:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	modifiedRegex = regexp.MustCompile(`(<meta property="dcterms:modified">)[^<]*(</meta>)`)
+)
+
+// Files maps the paths of the files of an EPUB (e.g. EPUB/package.opf) to
+// their content.
+type Files map[string][]byte
+
+// Unzip returns the files of an EPUB.
+func Unzip(data []byte) (Files, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read EPUB: %w", err)
+	}
+	files := make(Files, len(z.File))
+	for _, f := range z.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	return files, nil
+}
+
+// Canonicalize returns a copy of the files where the content that changes from
+// one build to the next is replaced: the uuid identifiers are replaced by
+// CanonicalUUID, the modification date by CanonicalModified and line endings
+// are normalized. The provenance statement is left out since its signature
+// covers that content.
+func Canonicalize(files Files) Files {
+	canonical := make(Files, len(files))
+	for name, content := range files {
+		if name == provenanceFilename {
+			continue
+		}
+		if isText(name) {
+			content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+			content = urnUUIDRegex.ReplaceAll(content, []byte("urn:uuid:"+CanonicalUUID))
+			content = modifiedRegex.ReplaceAll(content, []byte("${1}"+CanonicalModified+"${2}"))
+		}
+		canonical[name] = content
+	}
+	return canonical
+}
+
+// Report whether the file is a text file, based on its extension
+func isText(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".css", ".js", ".ncx", ".opf", ".smil", ".svg", ".txt", ".xhtml", ".xml", ".json":
+		return true
+	}
+	return path.Base(name) == "mimetype"
+}
+
+// ReadDir returns the files of a golden directory.
+func ReadDir(dir string) (Files, error) {
+	files := make(Files)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(name)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read golden directory %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// WriteDir writes the files to a golden directory, replacing its content.
+func WriteDir(dir string, files Files) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("unable to remove golden directory %s: %w", dir, err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return fmt.Errorf("unable to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(p, content, 0644); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Diff describes the differences between two sets of files, one per line, in
+// path order. It returns an empty string if the files are the same.
+func Diff(got Files, want Files) string {
+	names := make(map[string]bool)
+	for name := range got {
+		names[name] = true
+	}
+	for name := range want {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, name := range sorted {
+		g, inGot := got[name]
+		w, inWant := want[name]
+		switch {
+		case !inWant:
+			fmt.Fprintf(&b, "%s: unexpected file\n", name)
+		case !inGot:
+			fmt.Fprintf(&b, "%s: missing file\n", name)
+		case !bytes.Equal(g, w):
+			fmt.Fprintf(&b, "%s: %s\n", name, describeDifference(name, g, w))
+		}
+	}
+	return b.String()
+}
+
+// Describe the first difference between two versions of a file
+func describeDifference(name string, got []byte, want []byte) string {
+	if !isText(name) {
+		return fmt.Sprintf("content differs (%d bytes, want %d bytes)", len(got), len(want))
+	}
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("line %d is %q, want %q", i+1, g, w)
+		}
+	}
+	return "content differs"
+}
+
+// Render writes the EPUB and returns its canonicalized files. The test fails if
+// the EPUB can't be written.
+func Render(t testing.TB, e *epub.Epub) Files {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatalf("unable to write EPUB: %v", err)
+	}
+	files, err := Unzip(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Canonicalize(files)
+}
+
+// AssertGolden compares the files to the content of a golden directory and
+// reports the differences as a test error. If Update is true, the files are
+// written to the golden directory instead.
+func AssertGolden(t testing.TB, files Files, dir string) {
+	t.Helper()
+	if Update {
+		if err := WriteDir(dir, files); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%v (run with EPUBTEST_UPDATE=1 to create it)", err)
+	}
+	if diff := Diff(files, want); diff != "" {
+		t.Errorf("EPUB differs from golden directory %s:\n%s", dir, diff)
+	}
+}
@@ -0,0 +1,69 @@
+package epubtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/go-epub"
+)
+
+func TestAssertGolden(t *testing.T) {
+	e, err := epub.NewEpub("My title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAuthor("Hingle McCringleberry")
+	imagePath, err := e.AddImage("../testdata/gophercolor16x16.png", "gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `<h1>Section 1</h1><p><img src="` + imagePath + `" alt="Gopher"/></p>`
+	if _, err := e.AddSection(body, "Section 1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	AssertGolden(t, Render(t, e), "testdata/basic")
+}
+
+func TestCanonicalize(t *testing.T) {
+	files := Canonicalize(Files{
+		"EPUB/package.opf":         []byte("<dc:identifier>urn:uuid:fe93046f-af57-475a-a0cb-a0d4bc99ba6d</dc:identifier>\r\n<meta property=\"dcterms:modified\">2011-01-01T12:00:00Z</meta>"),
+		"EPUB/images/a.png":        []byte("urn:uuid:fe93046f-af57-475a-a0cb-a0d4bc99ba6d\r\n"),
+		"META-INF/provenance.json": []byte("{}"),
+	})
+	want := "<dc:identifier>urn:uuid:" + CanonicalUUID + "</dc:identifier>\n<meta property=\"dcterms:modified\">" + CanonicalModified + "</meta>"
+	if got := string(files["EPUB/package.opf"]); got != want {
+		t.Errorf("Unexpected package file:\n%s\nwant:\n%s", got, want)
+	}
+	if got := string(files["EPUB/images/a.png"]); got != "urn:uuid:fe93046f-af57-475a-a0cb-a0d4bc99ba6d\r\n" {
+		t.Errorf("Expected binary files to be left as is, got %q", got)
+	}
+	if _, ok := files["META-INF/provenance.json"]; ok {
+		t.Error("Expected the provenance statement to be left out")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := Files{
+		"a.xhtml": []byte("one\ntwo\n"),
+		"b.png":   []byte{1, 2},
+		"c.css":   []byte(""),
+	}
+	if diff := Diff(want, want); diff != "" {
+		t.Errorf("Expected no differences, got:\n%s", diff)
+	}
+	got := Files{
+		"a.xhtml": []byte("one\nthree\n"),
+		"b.png":   []byte{1, 2, 3},
+		"d.js":    []byte(""),
+	}
+	lines := strings.Split(strings.TrimSuffix(Diff(got, want), "\n"), "\n")
+	expected := []string{
+		`a.xhtml: line 2 is "three", want "two"`,
+		"b.png: content differs (3 bytes, want 2 bytes)",
+		"c.css: missing file",
+		"d.js: unexpected file",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected differences:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
@@ -0,0 +1,186 @@
+package epub
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// SetSectionTags sets the tags of a section (e.g. "volume1" or
+// "teacher-only"), used to export part of the book with ExportTo. Subsections
+// inherit the tags of their parents.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection). Setting no tags removes the tags of the section.
+func (e *Epub) SetSectionTags(sectionFilename string, tags ...string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	section.tags = append([]string(nil), tags...)
+	return nil
+}
+
+// ExportTo writes an EPUB containing only the sections whose tags (including
+// the tags inherited from their parents) match the filter to the dest
+// io.Writer. The cover is always included. Subsections of an excluded section
+// that match the filter take its place in the table of contents.
+//
+// The table of contents and the manifest only list the exported sections;
+// images, videos and audio files only referenced by excluded sections, as well
+// as the media overlays of excluded sections, are left out. The references of
+// the sections are those of the documents written, e.g. with their chapter
+// openers (see SetChapterOpeners) and their templates executed (see
+// SetTemplateData). The CSS files and
+// the fonts are all kept, along with the images the CSS files reference. The
+// EPUB itself isn't modified.
+//
+// The return values are the same as WriteTo.
+func (e *Epub) ExportTo(dst io.Writer, filter func(tags []string) bool) (int64, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	restore := e.prune(filter)
+	defer restore()
+	return e.writeTo(context.Background(), dst)
+}
+
+// ExportToContext is like ExportTo, but the export is cancelled when ctx is
+// done, see WriteToContext.
+func (e *Epub) ExportToContext(ctx context.Context, dst io.Writer, filter func(tags []string) bool) (int64, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	restore := e.prune(filter)
+	defer restore()
+	return e.writeTo(ctx, dst)
+}
+
+// HasAnyTag returns a filter for ExportTo matching the sections that have at
+// least one of the given tags.
+func HasAnyTag(tags ...string) func([]string) bool {
+	return func(sectionTags []string) bool {
+		for _, t := range sectionTags {
+			for _, tag := range tags {
+				if t == tag {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Remove the sections that don't match the filter, along with their media
+// overlays and figures; the media only they use are removed during the write,
+// see pruneUnusedMedia. The returned function restores the EPUB as it was.
+func (e *Epub) prune(filter func(tags []string) bool) (restore func()) {
+	sections, images, videos, audios := e.sections, e.images, e.videos, e.audios
+	overlays, sectionOverlays, figures := e.overlays, e.sectionOverlays, e.figures
+	restore = func() {
+		e.sections, e.images, e.videos, e.audios = sections, images, videos, audios
+		e.overlays, e.sectionOverlays, e.figures = overlays, sectionOverlays, figures
+		e.pruneMedia = false
+	}
+
+	var keep func(sections []*epubSection, inherited []string) []*epubSection
+	keep = func(sections []*epubSection, inherited []string) []*epubSection {
+		var kept []*epubSection
+		for _, s := range sections {
+			tags := append(append([]string(nil), inherited...), s.tags...)
+			children := keep(s.children, tags)
+			if s.filename != e.cover.xhtmlFilename && !filter(tags) {
+				// Promote the subsections that are kept
+				kept = append(kept, children...)
+				continue
+			}
+			// Copy the section so the original keeps its subsections
+			pruned := *s
+			pruned.children = children
+			kept = append(kept, &pruned)
+		}
+		return kept
+	}
+	e.sections = keep(sections, nil)
+
+	e.sectionOverlays = make(map[string]string)
+	e.overlays = make(map[string]string)
+	for sectionFilename, overlay := range sectionOverlays {
+		if _, ok := e.findSection(sectionFilename); ok {
+			e.sectionOverlays[sectionFilename] = overlay
+			e.overlays[overlay] = overlays[overlay]
+		}
+	}
+
+	e.figures = nil
+	for _, f := range figures {
+		if _, ok := e.findSection(f.sectionFilename); ok {
+			e.figures = append(e.figures, f)
+		}
+	}
+	e.pruneMedia = true
+
+	return restore
+}
+
+// Leave out the images, videos and audio files that neither the sections of
+// an export, as they are written (e.g. with their chapter openers or their
+// templates executed), nor the CSS files reference. The CSS files are all
+// kept, and so are the images they use (e.g. background images), in case a
+// kept section uses their rules.
+//
+// Must be called after the CSS files have been written
+func (e *Epub) pruneUnusedMedia(a *archive) {
+	var contents []string
+	var walk func(sections []*epubSection)
+	walk = func(sections []*epubSection) {
+		for _, s := range sections {
+			// A document that can't be built makes the write fail or
+			// leaves the section out
+			if doc, err := e.sectionDocument(s); err == nil {
+				if content, err := doc.content(); err == nil {
+					contents = append(contents, string(content))
+				}
+			}
+			walk(s.children)
+		}
+	}
+	walk(e.sections)
+	for _, filename := range sortedKeys(a.css) {
+		contents = append(contents, string(a.css[filename]))
+	}
+
+	referenced := func(folder string, filename string) bool {
+		if filename == e.cover.imageFilename || path.Join(folder, filename) == e.toc.navLogo {
+			return true
+		}
+		for _, p := range []string{path.Join(folder, filename), path.Join(folder, url.PathEscape(filename))} {
+			for _, c := range contents {
+				if strings.Contains(c, p) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	pruneMedia := func(media map[string]string, folder string, keepAll bool) map[string]string {
+		kept := make(map[string]string, len(media))
+		for filename, source := range media {
+			if keepAll || referenced(folder, filename) {
+				kept[filename] = source
+			}
+		}
+		a.mediaTotal -= len(media) - len(kept)
+		return kept
+	}
+	e.images = pruneMedia(e.images, ImageFolderName, false)
+	e.videos = pruneMedia(e.videos, VideoFolderName, false)
+	// The audio files used by the media overlays are referenced by the
+	// overlays rather than the sections, so keep them all if any is left
+	e.audios = pruneMedia(e.audios, AudioFolderName, len(e.overlays) > 0)
+}
@@ -0,0 +1,186 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestExportTo(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image1, err := e.AddImage(testImageFromFileSource, "volume1.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image2, err := e.AddImage(testImageFromFileSource, "volume2.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume1, err := e.AddSection(`<h1>Volume 1</h1><img src="`+image1+`" alt=""/>`, "Volume 1", "volume1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(volume1, `<h1>Chapter 1</h1>`, "Chapter 1", "chapter1.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(volume1, `<h1>Answers</h1>`, "Answers", "answers.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	volume2, err := e.AddSection(`<h1>Volume 2</h1><img src="`+image2+`" alt=""/>`, "Volume 2", "volume2.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionTags(volume1, "volume1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionTags(volume2, "volume2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionTags("answers.xhtml", "teacher-only"); err != nil {
+		t.Fatal(err)
+	}
+	// An image only referenced by a CSS file
+	background, err := e.AddImage(testImageFromFileSource, "background.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddCSS(dataurl.EncodeBytes([]byte("body { background-image: url("+background+"); }")), "theme.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Export the first volume without the teacher-only sections
+	var b bytes.Buffer
+	_, err = e.ExportTo(&b, func(tags []string) bool {
+		return HasAnyTag("volume1")(tags) && !HasAnyTag("teacher-only")(tags)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	for _, name := range []string{"EPUB/xhtml/volume1.xhtml", "EPUB/xhtml/chapter1.xhtml", "EPUB/images/volume1.png", "EPUB/images/background.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing from the export", name)
+		}
+	}
+	for _, name := range []string{"EPUB/xhtml/volume2.xhtml", "EPUB/xhtml/answers.xhtml", "EPUB/images/volume2.png"} {
+		if _, ok := files[name]; ok {
+			t.Errorf("%s shouldn't be part of the export", name)
+		}
+	}
+	for _, name := range []string{"EPUB/package.opf", "EPUB/nav.xhtml", "EPUB/toc.ncx"} {
+		if strings.Contains(files[name], "volume2") || strings.Contains(files[name], "answers") {
+			t.Errorf("%s references excluded content:\n%s", name, files[name])
+		}
+	}
+	if !strings.Contains(files["EPUB/nav.xhtml"], "chapter1.xhtml") {
+		t.Errorf("The TOC doesn't contain the exported subsection:\n%s", files["EPUB/nav.xhtml"])
+	}
+
+	// The EPUB itself is unchanged
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files = readZipFiles(t, b.Bytes())
+	for _, name := range []string{"EPUB/xhtml/volume2.xhtml", "EPUB/xhtml/answers.xhtml", "EPUB/images/volume2.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing from the EPUB after the export", name)
+		}
+	}
+}
+
+func TestExportToWrittenReferences(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opener, err := e.AddImage(testImageFromFileSource, "opener.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logo, err := e.AddImage(testImageFromFileSource, "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, "unused.png"); err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := e.AddSection(`<p>{{.Publisher}}</p><img src="{{.Logo}}" alt=""/>`, "Chapter 1", "chapter1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionTags(chapter, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterOpeners(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterOpener(chapter, ChapterOpener{Image: opener}); err != nil {
+		t.Fatal(err)
+	}
+	e.SetTemplateData(map[string]interface{}{"Publisher": "Gopher Press", "Logo": logo})
+
+	var b bytes.Buffer
+	if _, err := e.ExportTo(&b, HasAnyTag("a")); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	for _, name := range []string{"EPUB/images/opener.png", "EPUB/images/logo.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing from the export", name)
+		}
+	}
+	if _, ok := files["EPUB/images/unused.png"]; ok {
+		t.Error("EPUB/images/unused.png shouldn't be part of the export")
+	}
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			t.Errorf("Unexpected broken link: %v", w)
+		}
+	}
+}
+
+func TestExportToPromotesSubsections(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := e.AddSection(`<h1>Part</h1>`, "Part", "part.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(parent, `<h1>Extra</h1>`, "Extra", "extra.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionTags("extra.xhtml", "extra"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.ExportTo(&b, HasAnyTag("extra")); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if _, ok := files["EPUB/xhtml/part.xhtml"]; ok {
+		t.Error("The untagged parent shouldn't be part of the export")
+	}
+	if !strings.Contains(files["EPUB/nav.xhtml"], "extra.xhtml") {
+		t.Errorf("The tagged subsection is missing from the TOC:\n%s", files["EPUB/nav.xhtml"])
+	}
+}
+
+func TestSetSectionTagsError(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.SetSectionTags("missing.xhtml", "tag")
+	if _, ok := err.(*SectionDoesNotExistError); !ok {
+		t.Errorf("Expected error SectionDoesNotExistError not returned. Returned instead: %+v", err)
+	}
+}
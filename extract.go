@@ -0,0 +1,118 @@
+package epub
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UnsafeEntryError is thrown by SafeExtract if the name of an entry of the
+// EPUB could write outside of the destination directory (absolute paths, ".."
+// elements...) or if the entry is a symbolic link.
+type UnsafeEntryError struct {
+	Name   string // Name of the entry in the EPUB
+	Reason string // Why the entry was rejected
+}
+
+func (e *UnsafeEntryError) Error() string {
+	return fmt.Sprintf("Unsafe EPUB entry %q: %s", e.Name, e.Reason)
+}
+
+// ValidateEntryName checks that the name of an entry of an EPUB (a ZIP
+// archive) is a relative path that stays inside the archive once extracted. It
+// returns an UnsafeEntryError otherwise.
+func ValidateEntryName(name string) error {
+	// Directories are stored with a trailing slash
+	p := strings.TrimSuffix(name, "/")
+	reason := ""
+	switch {
+	case p == "":
+		reason = "empty name"
+	case strings.HasPrefix(p, "/"):
+		reason = "absolute path"
+	case strings.Contains(p, `\`):
+		// Backslashes aren't separators in ZIP archives, but they are on
+		// Windows
+		reason = "contains a backslash"
+	case len(p) >= 2 && p[1] == ':':
+		reason = "volume name"
+	case strings.IndexFunc(p, func(r rune) bool { return r < 0x20 || r == 0x7F }) >= 0:
+		reason = "contains a control character"
+	case !fs.ValidPath(p):
+		// Rejects "..", "." and empty path elements
+		reason = "not a clean relative path"
+	default:
+		return nil
+	}
+	return &UnsafeEntryError{Name: name, Reason: reason}
+}
+
+// SafeExtract extracts the EPUB read from r to the dir directory on the local
+// filesystem, which is created if needed. All the entries are validated with
+// ValidateEntryName before anything is written, so an EPUB with an unsafe entry
+// is rejected as a whole; symbolic links are rejected as well.
+func SafeExtract(r io.ReaderAt, size int64, dir string) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("unable to read EPUB: %w", err)
+	}
+	for _, f := range z.File {
+		if err := ValidateEntryName(f.Name); err != nil {
+			return err
+		}
+		if f.Mode()&fs.ModeSymlink != 0 {
+			return &UnsafeEntryError{Name: f.Name, Reason: "symbolic link"}
+		}
+	}
+
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return fmt.Errorf("unable to create directory %s: %w", dir, err)
+	}
+	for _, f := range z.File {
+		if err := extractEntry(f, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract a validated entry to dir
+func extractEntry(f *zip.File, dir string) error {
+	target := filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(f.Name, "/")))
+	// Validated entries can't escape dir, check anyway in case the platform
+	// interprets the name differently
+	if rel, err := filepath.Rel(dir, target); err != nil || !filepath.IsLocal(rel) {
+		return &UnsafeEntryError{Name: f.Name, Reason: "outside of the destination directory"}
+	}
+	if strings.HasSuffix(f.Name, "/") {
+		if err := os.MkdirAll(target, dirPermissions); err != nil {
+			return fmt.Errorf("unable to create directory %s: %w", target, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+		return fmt.Errorf("unable to create directory for %s: %w", f.Name, err)
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", f.Name, err)
+	}
+	defer r.Close()
+	w, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermissions)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("unable to extract %s: %w", f.Name, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("unable to extract %s: %w", f.Name, err)
+	}
+	return nil
+}
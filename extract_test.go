@@ -0,0 +1,100 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateEntryName(t *testing.T) {
+	for _, name := range []string{"mimetype", "META-INF/container.xml", "EPUB/", "EPUB/xhtml/section0001.xhtml", "EPUB/..png"} {
+		if err := ValidateEntryName(name); err != nil {
+			t.Errorf("Unexpected error for %q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "/etc/passwd", "../evil", "EPUB/../../evil", `EPUB\..\evil`, "C:/evil", "EPUB//evil", "./evil", "EPUB/a\x00b"} {
+		var unsafe *UnsafeEntryError
+		if err := ValidateEntryName(name); !errors.As(err, &unsafe) {
+			t.Errorf("Expected an UnsafeEntryError for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestSafeExtract(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := SafeExtract(bytes.NewReader(b.Bytes()), int64(b.Len()), dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{mimetypeFilename, "META-INF/container.xml", "EPUB/package.opf", "EPUB/xhtml/section0001.xhtml"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s to be extracted: %v", name, err)
+		}
+	}
+}
+
+func TestSafeExtractUnsafeEntry(t *testing.T) {
+	tests := map[string]func(z *zip.Writer) error{
+		"Traversal": func(z *zip.Writer) error {
+			_, err := z.Create("../evil.txt")
+			return err
+		},
+		"Symlink": func(z *zip.Writer) error {
+			h := &zip.FileHeader{Name: "EPUB/link"}
+			h.SetMode(fs.ModeSymlink | 0777)
+			w, err := z.CreateHeader(h)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte("/etc/passwd"))
+			return err
+		},
+	}
+	for name, add := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			z := zip.NewWriter(&b)
+			w, err := z.Create("EPUB/safe.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("safe"))
+			if err := add(z); err != nil {
+				t.Fatal(err)
+			}
+			if err := z.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "book")
+			err = SafeExtract(bytes.NewReader(b.Bytes()), int64(b.Len()), dir)
+			var unsafe *UnsafeEntryError
+			if !errors.As(err, &unsafe) {
+				t.Fatalf("Expected an UnsafeEntryError, got %v", err)
+			}
+			// Nothing is extracted from an unsafe EPUB
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("Expected nothing to be extracted, got %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !os.IsNotExist(err) {
+				t.Errorf("Expected the unsafe entry not to be extracted, got %v", err)
+			}
+		})
+	}
+}
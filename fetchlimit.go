@@ -0,0 +1,122 @@
+package epub
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// FetchLimiter limits the requests made to each host when retrieving remote
+// media sources, so that image hosts aren't hammered during a build. It is safe
+// for concurrent use and meant to be shared by the EPUBs built at the same
+// time.
+type FetchLimiter struct {
+	maxPerHost int
+	delay      time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	// Requests in progress, nil if they aren't limited
+	slots chan struct{}
+	// Earliest time of the next request
+	next time.Time
+}
+
+// NewFetchLimiter returns a FetchLimiter that allows at most maxPerHost
+// requests in progress to the same host, and starts the requests to the same
+// host at least delay apart. A maxPerHost of 0 doesn't limit the number of
+// requests.
+func NewFetchLimiter(maxPerHost int, delay time.Duration) *FetchLimiter {
+	return &FetchLimiter{
+		maxPerHost: maxPerHost,
+		delay:      delay,
+		hosts:      make(map[string]*hostLimit),
+	}
+}
+
+// SetFetchLimiter sets the limiter of the requests made to retrieve remote
+// media sources, both when they are added and when the EPUB is written.
+// Setting a nil limiter removes the limits.
+func (e *Epub) SetFetchLimiter(limiter *FetchLimiter) {
+	e.Lock()
+	defer e.Unlock()
+	e.fetchLimiter = limiter
+}
+
+// Wait until a request can be made to the host of the source URL, or until ctx
+// is done. The returned function must be called once the request is done. A
+// nil limiter doesn't wait.
+func (l *FetchLimiter) acquire(ctx context.Context, source string) (release func(), err error) {
+	u, err := url.Parse(source)
+	if l == nil || err != nil || u.Host == "" {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	h, ok := l.hosts[u.Host]
+	if !ok {
+		h = &hostLimit{}
+		if l.maxPerHost > 0 {
+			h.slots = make(chan struct{}, l.maxPerHost)
+		}
+		l.hosts[u.Host] = h
+	}
+	l.mu.Unlock()
+
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if h.slots != nil {
+				<-h.slots
+			}
+		})
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(l.delay)
+	l.mu.Unlock()
+	if wait := start.Sub(now); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// The body of a response to a request for a remote source, which releases its
+// FetchLimiter slot when it is closed
+type responseBody struct {
+	io.ReadCloser
+	// Header of the response, to cache its validators
+	header  http.Header
+	release func()
+}
+
+func (b *responseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchLimiterMaxPerHost(t *testing.T) {
+	var inFlight, maxInFlight int32
+	fs := http.FileServer(http.Dir("./testdata/"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fs.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	limiter := NewFetchLimiter(2, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := NewEpub(testEpubTitle)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			e.SetFetchLimiter(limiter)
+			if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in progress, got %d", maxInFlight)
+	}
+}
+
+func TestFetchLimiterDelay(t *testing.T) {
+	server, _ := newCountingServer(t)
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delay := 50 * time.Millisecond
+	e.SetFetchLimiter(NewFetchLimiter(0, delay))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("Expected the requests to be at least %v apart, took %v for 3 requests", delay, elapsed)
+	}
+}
+
+func TestFetchLimiterContext(t *testing.T) {
+	limiter := NewFetchLimiter(1, 0)
+	release, err := limiter.acquire(context.Background(), "https://example.com/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "https://example.com/b.png"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait for a slot to end with the context, got %v", err)
+	}
+}
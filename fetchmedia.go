@@ -1,10 +1,13 @@
 package epub
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -18,6 +21,41 @@ import (
 // if onlyChecl is true, the methods will not perform actual grab to spare memory and bandwidth
 type grabber struct {
 	*http.Client
+	// If offline is true, remote sources are rejected
+	offline bool
+	// Cache of the retrieved sources, may be nil
+	cache *MediaCache
+	// Report of the non-fatal issues found when adding media, may be nil
+	warnings *ValidationReport
+	// Limiter of the requests to remote sources, may be nil
+	limiter *FetchLimiter
+	// Context of the requests to remote sources, may be nil
+	ctx context.Context
+	// Logger of the retrievals, may be nil
+	logger *slog.Logger
+	// How the requests to remote sources are retried
+	retry RetryPolicy
+	// Work directory of the checkpoints of the retrieved sources, see
+	// SetCheckpointDir
+	checkpointDir string
+	// Refresher of the expired signed URLs, may be nil
+	refreshSigned SignedURLRefresher
+	// How the media added with a filename already used are handled
+	duplicates DuplicatePolicy
+}
+
+// Return a copy of the grabber whose requests use ctx
+func (g grabber) withContext(ctx context.Context) grabber {
+	g.ctx = ctx
+	return g
+}
+
+// Return the context of the requests to remote sources
+func (g grabber) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
 }
 
 func detectMediaType(mediaSource string) string {
@@ -33,6 +71,9 @@ func detectMediaType(mediaSource string) string {
 }
 
 func (g grabber) checkMedia(mediaSource string) error {
+	if _, ok := g.cache.get(mediaSource); ok {
+		return nil
+	}
 	var fetchErrors []error // Declare fetchErrors variable
 	var f func(string, bool) (io.ReadCloser, error)
 	switch detectMediaType(mediaSource) {
@@ -56,20 +97,108 @@ func (g grabber) checkMedia(mediaSource string) error {
 	return &FileRetrievalError{Source: mediaSource, Err: fetchError(fetchErrors)}
 }
 
-// fetchMedia from mediaSource into mediaFolderPath as mediaFilename returning its type.
-// the mediaSource can be a URL, a local path or an inline dataurl (as specified in RFC 2397)
-func (g grabber) fetchMedia(mediaSource, mediaFolderPath, mediaFilename string) (mediaType string, err error) {
+// fetchMediaData returns the content of mediaSource and its type, mediaFilename
+// being the name the media is stored under. The mediaSource can be a URL, a
+// local path or an inline dataurl (as specified in RFC 2397)
+func (g grabber) fetchMediaData(mediaSource, mediaFilename string) (data []byte, mediaType string, err error) {
+	stream, mediaType, err := g.openMediaStream(mediaSource, mediaFilename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer stream.Close()
+
+	data, err = io.ReadAll(stream)
+	if err != nil {
+		return nil, "", &FileRetrievalError{Source: mediaSource, Err: err}
+	}
+	return data, mediaType, nil
+}
+
+// Number of bytes of a media source read ahead to detect its type, and to
+// read the header of WAV files
+const mediaStreamPeekSize = 64 * 1024
 
-	mediaFilePath := filepath.Join(
-		mediaFolderPath,
-		mediaFilename,
-	)
-	// failfast, create the output file handler at the begining, if we cannot write the file, bail out
-	w, err := filesystem.Create(mediaFilePath)
+// A media source being read, whose first bytes can be inspected with Peek
+// before it is read
+type mediaStream struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// openMediaStream returns a reader for the content of mediaSource and its
+// type, mediaFilename being the name the media is stored under, so that large
+// media can be written without being held in memory
+func (g grabber) openMediaStream(mediaSource, mediaFilename string) (*mediaStream, string, error) {
+	if g.offline && detectMediaType(mediaSource) == "URL" {
+		return nil, "", &RemoteSourceError{Source: mediaSource}
+	}
+
+	logDebug(g.logger, "retrieving media", "source", mediaSource, "filename", mediaFilename)
+	source, err := g.openMedia(mediaSource)
 	if err != nil {
-		return "", fmt.Errorf("unable to create file %s: %s", mediaFilePath, err)
+		return nil, "", err
+	}
+	stream := &mediaStream{Reader: bufio.NewReaderSize(source, mediaStreamPeekSize), Closer: source}
+	// Only the first bytes are used to detect the media type
+	head, err := stream.Peek(mediaStreamPeekSize)
+	if err != nil && err != io.EOF {
+		source.Close()
+		return nil, "", &FileRetrievalError{Source: mediaSource, Err: err}
+	}
+	return stream, sniffMediaType(mediaSource, mediaFilename, head), nil
+}
+
+// Detect the type of a media from its first bytes and its name
+func sniffMediaType(mediaSource, mediaFilename string, data []byte) string {
+	mime := mimetype.Detect(data)
+
+	// Is it CSS, JavaScript or SMIL?
+	mtype := mime.String()
+	if filepath.Ext(mediaSource) == ".smil" || filepath.Ext(mediaFilename) == ".smil" {
+		mtype = mediaTypeSmil
+	}
+	if mime.Is("text/plain") {
+		if filepath.Ext(mediaSource) == ".css" || filepath.Ext(mediaFilename) == ".css" {
+			mtype = "text/css"
+		}
+		if filepath.Ext(mediaSource) == ".js" || filepath.Ext(mediaFilename) == ".js" {
+			mtype = mediaTypeJavascript
+		}
+	}
+	return mtype
+}
+
+// openMedia returns a reader for the content of mediaSource, from the cache if
+// it has already been retrieved
+func (g grabber) openMedia(mediaSource string) (io.ReadCloser, error) {
+	if entry, ok := g.cache.lookup(mediaSource); ok {
+		if !g.cache.needsRevalidation(entry) {
+			return io.NopCloser(bytes.NewReader(entry.data)), nil
+		}
+		data, err := g.revalidate(mediaSource, entry)
+		if err != nil {
+			return nil, &FileRetrievalError{Source: mediaSource, Err: err}
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
 	}
-	defer w.Close()
+	if g.cache.accepts(mediaSource) {
+		done, err := g.cache.claim(g.context(), mediaSource)
+		if err != nil {
+			return nil, &FileRetrievalError{Source: mediaSource, Err: err}
+		}
+		defer done()
+		// The source may have been retrieved while waiting
+		if data, ok := g.cache.get(mediaSource); ok {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	if data, ok := g.readCheckpoint(mediaSource); ok {
+		if g.cache.accepts(mediaSource) {
+			g.cache.put(mediaSource, data, nil)
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
 	var source io.ReadCloser
 	fetchErrors := make([]error, 0)
 	for _, f := range []func(string, bool) (io.ReadCloser, error){
@@ -86,54 +215,119 @@ func (g grabber) fetchMedia(mediaSource, mediaFolderPath, mediaFilename string)
 		break
 	}
 	if source == nil {
-		return "", &FileRetrievalError{Source: mediaSource, Err: fetchError(fetchErrors)}
-
+		return nil, &FileRetrievalError{Source: mediaSource, Err: fetchError(fetchErrors)}
+	}
+	if !g.cache.accepts(mediaSource) && g.checkpointPath(mediaSource) == "" {
+		return source, nil
 	}
+
 	defer source.Close()
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return nil, &FileRetrievalError{Source: mediaSource, Err: err}
+	}
+	var header http.Header
+	if body, ok := source.(*responseBody); ok {
+		header = body.header
+	}
+	if g.cache.accepts(mediaSource) {
+		g.cache.put(mediaSource, data, header)
+	}
+	g.writeCheckpoint(mediaSource, data)
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
 
-	_, err = io.Copy(w, source)
+// Request a cached URL source again with the validators of the cache entry,
+// and return its content: the cached content if it hasn't changed, the new
+// content otherwise
+func (g grabber) revalidate(mediaSource string, entry mediaCacheEntry) ([]byte, error) {
+	target, err := g.signedURL(mediaSource, false)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(g.context(), http.MethodGet, target, nil)
 	if err != nil {
-		// There shouldn't be any problem with the writer, but the reader
-		// might have an issue
-		return "", &FileRetrievalError{Source: mediaSource, Err: err}
+		return nil, err
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
 	}
 
-	// Detect the mediaType
-	r, err := filesystem.Open(mediaFilePath)
+	resp, release, err := g.send(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer r.Close()
-	mime, err := mimetype.DetectReader(r)
+	defer release()
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return entry.data, nil
+	case http.StatusOK:
+	default:
+		// Don't replace the cached content with an error page
+		return nil, fmt.Errorf("cannot revalidate file, bad return code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("unable to detect media type: %w", err)
+		return nil, err
 	}
+	g.cache.put(mediaSource, data, resp.Header)
+	return data, nil
+}
 
-	// Is it CSS?
-	mtype := mime.String()
-	if mime.Is("text/plain") {
-		if filepath.Ext(mediaSource) == ".css" || filepath.Ext(mediaFilename) == ".css" {
-			mtype = "text/css"
-		}
+// Return the media type a remote source is served with, from the headers of a
+// HEAD request
+func (g grabber) remoteContentType(mediaSource string) (string, error) {
+	if g.offline {
+		return "", &RemoteSourceError{Source: mediaSource}
+	}
+	source, err := g.httpHandler(mediaSource, true)
+	if err != nil {
+		return "", err
 	}
-	return mtype, nil
+	defer source.Close()
+	return source.(*responseBody).header.Get("Content-Type"), nil
 }
 
 func (g grabber) httpHandler(mediaSource string, onlyCheck bool) (io.ReadCloser, error) {
-	var resp *http.Response
-	var err error
+	method := http.MethodGet
 	if onlyCheck {
-		resp, err = g.Head(mediaSource)
-	} else {
-		resp, err = g.Get(mediaSource)
+		method = http.MethodHead
+	}
+	target, err := g.signedURL(mediaSource, false)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(g.context(), method, target, nil)
+	if err != nil {
+		return nil, err
 	}
+	resp, release, err := g.send(req)
 	if err != nil {
 		return nil, err
 	}
+	if target == mediaSource && g.signedURLDenied(mediaSource, resp) {
+		resp.Body.Close()
+		release()
+		if target, err = g.signedURL(mediaSource, true); err != nil {
+			return nil, err
+		}
+		if req, err = http.NewRequestWithContext(g.context(), method, target, nil); err != nil {
+			return nil, err
+		}
+		if resp, release, err = g.send(req); err != nil {
+			return nil, err
+		}
+	}
 	if resp.StatusCode > 400 {
+		resp.Body.Close()
+		release()
 		return nil, errors.New("cannot get file, bad return code")
 	}
-	return resp.Body, nil
+	return &responseBody{ReadCloser: resp.Body, header: resp.Header, release: release}, nil
 }
 
 func (g grabber) localHandler(mediaSource string, onlyCheck bool) (io.ReadCloser, error) {
@@ -167,3 +361,7 @@ func (f fetchError) Error() string {
 	}
 	return message
 }
+
+func (f fetchError) Unwrap() []error {
+	return f
+}
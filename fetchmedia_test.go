@@ -3,7 +3,6 @@ package epub
 import (
 	"fmt"
 	"io"
-	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -34,24 +33,7 @@ Tv/Ip07//uF2//7hdv/+4Xb//uF2/8zBlv/Kv4//pZJU/3tzTv9UTjj/19nd/////wD///8A4eLl
 //8AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA
 AAAAAAAAAAAAAA==`, "\n", "", -1)
 
-func Test_fetchMedia(t *testing.T) {
-	t.Run("LocalFS", func(t *testing.T) {
-		err := Use(OsFS)
-		if err != nil {
-			t.Error(err)
-		}
-		testFetchMedia(t)
-	})
-	t.Run("MemoryFS", func(t *testing.T) {
-		err := Use(MemoryFS)
-		if err != nil {
-			t.Error(err)
-		}
-		testFetchMedia(t)
-	})
-}
-
-func testFetchMedia(t *testing.T) {
+func Test_fetchMediaData(t *testing.T) {
 	filename := "gophercolor16x16.png"
 	mux := http.NewServeMux()
 	mux.HandleFunc("/image.png", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -72,9 +54,8 @@ func testFetchMedia(t *testing.T) {
 	defer ts.Close()
 
 	type args struct {
-		mediaSource     string
-		mediaFolderPath string
-		mediaFilename   string
+		mediaSource   string
+		mediaFilename string
 	}
 	tests := []struct {
 		name          string
@@ -85,9 +66,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"URL request with test filename",
 			args{
-				mediaSource:     ts.URL + "/image.png",
-				mediaFolderPath: "/",
-				mediaFilename:   "test",
+				mediaSource:   ts.URL + "/image.png",
+				mediaFilename: "test",
 			},
 			"image/png",
 			false,
@@ -95,9 +75,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"local file with test filename",
 			args{
-				mediaSource:     filepath.Join("testdata", filename),
-				mediaFolderPath: "/",
-				mediaFilename:   "test",
+				mediaSource:   filepath.Join("testdata", filename),
+				mediaFilename: "test",
 			},
 			"image/png",
 			false,
@@ -105,9 +84,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"dataurl media with test filename",
 			args{
-				mediaSource:     `data:image/vnd.microsoft.icon;name=golang%20favicon;base64,` + golangFavicon,
-				mediaFolderPath: "/",
-				mediaFilename:   "test",
+				mediaSource:   `data:image/vnd.microsoft.icon;name=golang%20favicon;base64,` + golangFavicon,
+				mediaFilename: "test",
 			},
 			"image/x-icon",
 			false,
@@ -115,9 +93,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"bad request",
 			args{
-				mediaSource:     "badRequest",
-				mediaFolderPath: "/",
-				mediaFilename:   "test",
+				mediaSource:   "badRequest",
+				mediaFilename: "test",
 			},
 			"",
 			true,
@@ -125,19 +102,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"empty filename",
 			args{
-				mediaSource:     "badRequest",
-				mediaFolderPath: "/",
-				mediaFilename:   "",
-			},
-			"",
-			true,
-		},
-		{
-			"bad path",
-			args{
-				mediaSource:     "badRequest",
-				mediaFolderPath: "-",
-				mediaFilename:   "",
+				mediaSource:   "badRequest",
+				mediaFilename: "",
 			},
 			"",
 			true,
@@ -145,9 +111,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"CSS",
 			args{
-				mediaSource:     ts.URL + "/test.css",
-				mediaFolderPath: "/",
-				mediaFilename:   "test.css",
+				mediaSource:   ts.URL + "/test.css",
+				mediaFilename: "test.css",
 			},
 			"text/css",
 			false,
@@ -155,9 +120,8 @@ func testFetchMedia(t *testing.T) {
 		{
 			"bad request",
 			args{
-				mediaSource:     ts.URL + "/nonexistent",
-				mediaFolderPath: "/",
-				mediaFilename:   "test.css",
+				mediaSource:   ts.URL + "/nonexistent",
+				mediaFilename: "test.css",
 			},
 			"",
 			true,
@@ -165,21 +129,17 @@ func testFetchMedia(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			g := &grabber{http.DefaultClient}
-			gotMediaType, err := g.fetchMedia(tt.args.mediaSource, tt.args.mediaFolderPath, tt.args.mediaFilename)
+			g := &grabber{Client: http.DefaultClient}
+			data, gotMediaType, err := g.fetchMediaData(tt.args.mediaSource, tt.args.mediaFilename)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("fetchMedia() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("fetchMediaData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if gotMediaType != tt.wantMediaType {
-				t.Errorf("fetchMedia() = %v, want %v", gotMediaType, tt.wantMediaType)
-			}
-			var file fs.File
-			if file, err = filesystem.Open(filepath.Join(tt.args.mediaFolderPath, tt.args.mediaFilename)); os.IsNotExist(err) {
-				t.Errorf("fetchMedia(): file %v does not exist (source %v): %v", filepath.Join(tt.args.mediaFolderPath, tt.args.mediaFilename), tt.args.mediaSource, err)
+				t.Errorf("fetchMediaData() = %v, want %v", gotMediaType, tt.wantMediaType)
 			}
-			if err == nil {
-				file.Close()
+			if err == nil && len(data) == 0 {
+				t.Errorf("fetchMediaData(): no content retrieved from %v", tt.args.mediaSource)
 			}
 		})
 	}
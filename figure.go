@@ -0,0 +1,125 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	figureIDFormat      = "figure%d"
+	figureLabelFormat   = "Figure %d"
+	figureImageStyle    = "max-width: 100%;"
+	listOfFiguresClass  = "epub-list-of-figures"
+	listOfFiguresFormat = "<li><a href=\"%s#%s\">%s</a></li>\n"
+)
+
+type epubFigure struct {
+	sectionFilename string
+	id              string
+	number          int
+	caption         string
+}
+
+// AddFigure adds an image to the EPUB (see AddImage) and appends it to the end
+// of the body of an existing section as a numbered figure with a caption. It
+// returns a relative path to the figure (the section filename followed by the
+// figure id as a fragment) that can be used for links.
+//
+// The alternative text describes the image for readers who can't see it and
+// should be provided unless the image is purely decorative. The caption and
+// alternative text are plain text and will be escaped.
+//
+// Figures are numbered in the order they are added and are listed by
+// AddListOfFigures.
+func (e *Epub) AddFigure(sectionFilename string, imageSource string, caption string, alt string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return "", &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	if section.xhtml.raw != "" {
+		return "", &RawSectionError{Filename: sectionFilename}
+	}
+	imagePath, err := addMedia(e.grabber(), imageSource, "", imageFileFormat, ImageFolderName, e.images)
+	if err != nil {
+		return "", err
+	}
+
+	f := epubFigure{
+		sectionFilename: sectionFilename,
+		id:              fmt.Sprintf(figureIDFormat, len(e.figures)+1),
+		number:          len(e.figures) + 1,
+		caption:         e.sanitize(sectionFilename, "figure caption", caption),
+	}
+	alt = e.sanitize(sectionFilename, "figure alternative text", alt)
+	e.figures = append(e.figures, f)
+
+	section.xhtml.xml.Body.XML += fmt.Sprintf(
+		"<figure id=\"%s\">\n<img src=\"%s\" alt=\"%s\" style=\"%s\" />\n<figcaption>%s</figcaption>\n</figure>\n",
+		f.id,
+		html.EscapeString(imagePath),
+		html.EscapeString(alt),
+		figureImageStyle,
+		f.label(),
+	)
+
+	return sectionFilename + "#" + f.id, nil
+}
+
+// AddListOfFigures adds a section listing every figure added with AddFigure,
+// with links to each of them, and returns a relative path to the section like
+// AddSection.
+//
+// The list is generated when the EPUB is written, so figures added after
+// calling AddListOfFigures are included as well.
+//
+// The title and internal filename follow the same rules as AddSection.
+func (e *Epub) AddListOfFigures(sectionTitle string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	sectionPath, err := e.addSection("", "", sectionTitle, internalFilename, "")
+	if err != nil {
+		return "", err
+	}
+	e.listOfFigures = sectionPath
+	return sectionPath, nil
+}
+
+// Label of the figure used in its caption and in the list of figures
+func (f epubFigure) label() string {
+	label := fmt.Sprintf(figureLabelFormat, f.number)
+	if f.caption != "" {
+		label += ". " + html.EscapeString(f.caption)
+	}
+	return label
+}
+
+// Generate the body of the list of figures section, if there is one
+func (e *Epub) writeListOfFigures() {
+	if e.listOfFigures == "" {
+		return
+	}
+	section, ok := e.findSection(e.listOfFigures)
+	if !ok {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<nav class=\"%s\">\n", listOfFiguresClass)
+	if title := section.xhtml.Title(); title != "" {
+		fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(title))
+	}
+	if len(e.figures) > 0 {
+		body.WriteString("<ol>\n")
+		for _, f := range e.figures {
+			fmt.Fprintf(&body, listOfFiguresFormat, html.EscapeString(f.sectionFilename), f.id, f.label())
+		}
+		body.WriteString("</ol>\n")
+	}
+	body.WriteString("</nav>")
+	section.xhtml.setBody(body.String())
+}
@@ -0,0 +1,70 @@
+package epub
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/go-epub/internal/storage"
+)
+
+func TestAddFigure(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lofPath, err := e.AddListOfFigures("List of figures", "figures.xhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	figurePath, err := e.AddFigure(sectionPath, testImageFromFileSource, "A gopher & friends", "The Go gopher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if figurePath != sectionPath+"#figure1" {
+		t.Errorf("Unexpected figure path: %s", figurePath)
+	}
+	_, err = e.AddFigure(sectionPath, testImageFromFileSource, "", "Another gopher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.images) != 2 {
+		t.Errorf("Expected 2 images to be added, got %d", len(e.images))
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+	defer cleanup(testEpubFilename, tempDir)
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, xhtmlFolderName, sectionPath))
+	if err != nil {
+		t.Fatalf("Unexpected error reading section file: %s", err)
+	}
+	for _, expected := range []string{
+		`<figure id="figure1">`,
+		`alt="The Go gopher"`,
+		`<figcaption>Figure 1. A gopher &amp; friends</figcaption>`,
+		`<figcaption>Figure 2</figcaption>`,
+	} {
+		if !strings.Contains(string(contents), expected) {
+			t.Errorf("Section doesn't contain %s\nGot: %s", expected, contents)
+		}
+	}
+
+	contents, err = storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, xhtmlFolderName, lofPath))
+	if err != nil {
+		t.Fatalf("Unexpected error reading section file: %s", err)
+	}
+	for _, expected := range []string{
+		`<a href="` + sectionPath + `#figure1">Figure 1. A gopher &amp; friends</a>`,
+		`<a href="` + sectionPath + `#figure2">Figure 2</a>`,
+	} {
+		if !strings.Contains(string(contents), expected) {
+			t.Errorf("List of figures doesn't contain %s\nGot: %s", expected, contents)
+		}
+	}
+}
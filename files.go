@@ -0,0 +1,140 @@
+package epub
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Folders and files of the content folder written by the EPUB itself, which
+// the files added with AddFile can't replace
+var reservedContentPaths = map[string]bool{
+	CSSFolderName: true, FontFolderName: true, ImageFolderName: true,
+	VideoFolderName: true, AudioFolderName: true, ScriptFolderName: true,
+	MediaOverlayFolderName: true, FontLicenseFolderName: true, CaptionsFolderName: true,
+	xhtmlFolderName: true, pkgFilename: true, tocNavFilename: true, tocNcxFilename: true,
+}
+
+// A file added with AddFile
+type epubFile struct {
+	source     string
+	mediaType  string
+	properties string
+}
+
+// AddFile adds a file to the EPUB as is, at the given path relative to the
+// content folder (e.g. data/lexicon.pls), and returns a relative path to it
+// that can be used from the sections. It is meant for the resources the other
+// methods don't handle, such as JSON data, pronunciation lexicons, media
+// overlays or scripts referenced from elsewhere than the sections, or XML
+// data.
+//
+// The source is a file path, URL or data URL, like the source of AddImage.
+// mediaType is the media type of the manifest item; if it is empty, it is
+// detected from the content of the file. properties is the value of the
+// properties attribute of the manifest item (e.g. "scripted"), or "".
+//
+// Each element of internalPath must be a valid filename, see ValidateFilename,
+// and it can't be one of the folders or files the EPUB writes itself (css,
+// images, xhtml, package.opf...). FilenameAlreadyUsedError is returned if a
+// file has already been added at this path, see SetDuplicatePolicy.
+func (e *Epub) AddFile(source string, internalPath string, mediaType string, properties string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.addFile(source, internalPath, mediaType, properties)
+}
+
+// Add a file at the given path relative to the content folder, see AddFile
+func (e *Epub) addFile(source string, internalPath string, mediaType string, properties string) (string, error) {
+	for _, element := range strings.Split(internalPath, "/") {
+		if err := ValidateFilename(element); err != nil {
+			return "", &InvalidFilenameError{Filename: internalPath, Reason: err.(*InvalidFilenameError).Reason}
+		}
+	}
+	if reservedContentPaths[strings.SplitN(internalPath, "/", 2)[0]] {
+		return "", &InvalidFilenameError{Filename: internalPath, Reason: "reserved path"}
+	}
+	g := e.grabber()
+	if g.offline && detectMediaType(source) == "URL" {
+		return "", &RemoteSourceError{Source: source}
+	}
+	if err := g.checkMedia(source); err != nil {
+		return "", &FileRetrievalError{
+			Source: source,
+			Err:    err,
+		}
+	}
+	if _, ok := e.files[internalPath]; ok {
+		replace, err := e.duplicates.resolve(&e.warnings, internalPath)
+		if err != nil || !replace {
+			return path.Join("..", internalPath), err
+		}
+	}
+	e.files[internalPath] = epubFile{source: source, mediaType: mediaType, properties: properties}
+	return path.Join("..", internalPath), nil
+}
+
+// Return the sources of the files added with AddFile, by path
+func (e *Epub) fileSources() map[string]string {
+	sources := make(map[string]string, len(e.files))
+	for filePath, f := range e.files {
+		sources[filePath] = f.source
+	}
+	return sources
+}
+
+// Write a file added with AddFile to the archive, streamed unless it is
+// encrypted
+func (e *Epub) writeFile(a *archive, filePath string, mediaType string, stream *mediaStream) error {
+	defer stream.Close()
+	name := path.Join(contentFolderName, filePath)
+	if !e.shouldEncrypt(name) {
+		return a.addMediaStream(name, mediaType, stream)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	return a.addMedia(name, mediaType, data)
+}
+
+// Get the files added with AddFile from their source, write them to the
+// archive and add them to the manifest
+func (e *Epub) writeFiles(a *archive) error {
+	// Add the files in path order so the manifest is the same from one build
+	// to the next
+	filePaths := make([]string, 0, len(e.files))
+	for filePath := range e.files {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	for _, filePath := range filePaths {
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+		f := e.files[filePath]
+		stream, mediaType, err := e.grabber().withContext(a.ctx).openMediaStream(f.source, path.Base(filePath))
+		if err != nil {
+			if err := e.mediaFailure(a, filePath, f.source, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.mediaType != "" {
+			mediaType = f.mediaType
+		}
+		if err := e.writeFile(a, filePath, mediaType, stream); err != nil {
+			return err
+		}
+		xmlID, err := e.mediaID(filePath)
+		if err != nil {
+			return fmt.Errorf("error creating xml id: %w", err)
+		}
+		e.pkg.addToManifest(xmlID, filePath, mediaType, f.properties)
+		a.mediaWritten()
+	}
+	return nil
+}
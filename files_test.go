@@ -0,0 +1,60 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestAddFile(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	lexicon := `<?xml version="1.0" encoding="UTF-8"?><lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="ipa" xml:lang="en"/>`
+	lexiconPath, err := e.AddFile(dataurl.EncodeBytes([]byte(lexicon)), "data/lexicon.pls", "application/pls+xml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lexiconPath != "../data/lexicon.pls" {
+		t.Errorf("Unexpected file path %s", lexiconPath)
+	}
+	if _, err := e.AddFile(dataurl.EncodeBytes([]byte(`{"a":1}`)), "data.json", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var used *FilenameAlreadyUsedError
+	if _, err := e.AddFile(dataurl.EncodeBytes([]byte(lexicon)), "data/lexicon.pls", "", ""); !errors.As(err, &used) {
+		t.Errorf("Expected a FilenameAlreadyUsedError, got %v", err)
+	}
+	for _, internalPath := range []string{"", "../lexicon.pls", "/lexicon.pls", "data//lexicon.pls", "images/lexicon.pls", "package.opf", "nav.xhtml"} {
+		var invalid *InvalidFilenameError
+		if _, err := e.AddFile(dataurl.EncodeBytes([]byte(lexicon)), internalPath, "", ""); !errors.As(err, &invalid) {
+			t.Errorf("Expected an InvalidFilenameError for %q, got %v", internalPath, err)
+		}
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/data/lexicon.pls"] != lexicon {
+		t.Errorf("Expected the lexicon in the EPUB, got %q", files["EPUB/data/lexicon.pls"])
+	}
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`href="data/lexicon.pls" media-type="application/pls+xml"></item>`,
+		`href="data.json" media-type="application/json"></item>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+}
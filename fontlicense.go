@@ -0,0 +1,78 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+const fontLicenseFileFormat = "license%04d%s"
+
+// AddFontLicense adds the license of a font of the EPUB, which several
+// foundries require to be distributed along with their fonts, and returns a
+// relative path to the license file in the format:
+// ../FontLicenseFolderName/internalFilename
+//
+// fontFilename is the internal filename of the font or the path returned by
+// AddFont; ParentDoesNotExistError is returned if the EPUB has no such font.
+// The license source and the internal filename follow the same rules as
+// AddFont; the license is usually a text or HTML file. Several licenses may be
+// added for the same font.
+//
+// When the EPUB is written, the license is stored in the EPUB and linked from
+// the package file with a dcterms:license link refining the font's manifest
+// item.
+func (e *Epub) AddFontLicense(fontFilename string, source string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	fontFilename = filepath.Base(fontFilename)
+	if _, ok := e.fonts[fontFilename]; !ok {
+		return "", &ParentDoesNotExistError{Filename: fontFilename}
+	}
+	licensePath, err := addMedia(e.grabber(), source, internalFilename, fontLicenseFileFormat, FontLicenseFolderName, e.fontLicenses)
+	if err != nil {
+		return "", err
+	}
+	e.licensedFonts[filepath.Base(licensePath)] = fontFilename
+	return licensePath, nil
+}
+
+// Get font licenses from their source, write them to the archive and link
+// them to their font from the package file
+//
+// Must be called after writeFonts
+func (e *Epub) writeFontLicenses(a *archive) error {
+	e.pkg.setLinks(pkgLicenseRel, nil)
+	if len(e.fontLicenses) == 0 {
+		return nil
+	}
+	err := e.writeMedia(a, e.fontLicenses, FontLicenseFolderName)
+	if err != nil {
+		return err
+	}
+
+	licenses := make([]string, 0, len(e.fontLicenses))
+	for filename := range e.fontLicenses {
+		licenses = append(licenses, filename)
+	}
+	sort.Strings(licenses)
+	var links []pkgLink
+	for _, filename := range licenses {
+		font := e.licensedFonts[filename]
+		if e.skippedMedia[filename] || e.skippedMedia[font] {
+			continue
+		}
+		id, err := e.mediaID(font)
+		if err != nil {
+			return fmt.Errorf("error creating xml id: %w", err)
+		}
+		links = append(links, pkgLink{
+			Rel:     pkgLicenseRel,
+			Href:    path.Join(FontLicenseFolderName, filename),
+			Refines: "#" + id,
+		})
+	}
+	e.pkg.setLinks(pkgLicenseRel, links)
+	return nil
+}
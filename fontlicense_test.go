@@ -0,0 +1,67 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestAddFontLicense(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fontPath, err := e.AddFont(testFontFromFileSource, "font.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	license := "Copyright (c) The Font Authors\nSIL Open Font License, Version 1.1\n"
+	licensePath, err := e.AddFontLicense(fontPath, dataurl.EncodeBytes([]byte(license)), "OFL.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if licensePath != "../licenses/OFL.txt" {
+		t.Errorf("Unexpected license path %s", licensePath)
+	}
+	var notFound *ParentDoesNotExistError
+	if _, err := e.AddFontLicense("missing.ttf", dataurl.EncodeBytes([]byte(license)), ""); !errors.As(err, &notFound) {
+		t.Errorf("Expected a ParentDoesNotExistError for a missing font, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/licenses/OFL.txt"] != license {
+		t.Errorf("Expected the license in the EPUB, got %q", files["EPUB/licenses/OFL.txt"])
+	}
+	fontID, err := e.mediaID("font.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`href="licenses/OFL.txt" media-type="text/plain; charset=utf-8"></item>`,
+		`<link rel="dcterms:license" href="licenses/OFL.txt" refines="#` + fontID + `"></link>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+
+	// Writing again doesn't duplicate the links
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(readZipFiles(t, b.Bytes())["EPUB/package.opf"], "<link "); n != 1 {
+		t.Errorf("Expected 1 link in the package file, got %d", n)
+	}
+}
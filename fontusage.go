@@ -0,0 +1,159 @@
+package epub
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	cssFontFaceRegexp   = regexp.MustCompile(`(?is)@font-face\s*\{([^}]*)\}`)
+	cssFontFamilyRegexp = regexp.MustCompile(`(?i)(?:^|[;{\s])font-family\s*:\s*([^;{}]+)`)
+	cssURLRegexp        = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)\s]*))\s*\)`)
+	styleElementRegexp  = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	styleAttrRegexp     = regexp.MustCompile(`(?is)\sstyle\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Generic font families and CSS-wide keywords, which don't need to be
+// embedded
+var genericFontFamilies = map[string]bool{
+	"serif": true, "sans-serif": true, "monospace": true, "cursive": true,
+	"fantasy": true, "system-ui": true, "ui-serif": true, "ui-sans-serif": true,
+	"ui-monospace": true, "ui-rounded": true, "emoji": true, "math": true,
+	"fangsong": true, "inherit": true, "initial": true, "unset": true,
+	"revert": true, "revert-layer": true,
+}
+
+// The styles of a file of the EPUB: a CSS file, or the style elements and
+// attributes of a section
+type fontUsageStyles struct {
+	filename string
+	// Folder of the file, which the URLs of the styles are relative to
+	folder string
+	css    string
+}
+
+// Check the fonts of the EPUB against the CSS of the CSS files and of the
+// sections, reporting the two classic font packaging mistakes: fonts that no
+// @font-face rule uses, and font families that no @font-face rule declares
+// with an embedded font. Generic font families (serif, monospace, etc) aren't
+// reported.
+//
+// Must be called after the CSS files, the fonts and the sections have been
+// written
+func (e *Epub) checkFontUsage(a *archive) {
+	var styles []fontUsageStyles
+	for _, filename := range sortedKeys(a.css) {
+		styles = append(styles, fontUsageStyles{filename: filename, folder: CSSFolderName, css: string(a.css[filename])})
+	}
+	for _, filename := range sortedKeys(a.sections) {
+		styles = append(styles, fontUsageStyles{filename: filename, folder: xhtmlFolderName, css: sectionStyles(a.sections[filename])})
+	}
+
+	used := make(map[string]bool)
+	declared := make(map[string]bool)
+	for _, s := range styles {
+		css := cssCommentRegexp.ReplaceAllString(s.css, "")
+		for _, m := range cssFontFaceRegexp.FindAllStringSubmatch(css, -1) {
+			families := fontFamilies(m[1])
+			embedded := false
+			for _, u := range cssURLRegexp.FindAllStringSubmatch(m[1], -1) {
+				ref := u[1] + u[2] + u[3]
+				parsed, err := url.Parse(ref)
+				if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+					// Remote fonts aren't embedded but are available all the
+					// same
+					embedded = true
+					continue
+				}
+				target := path.Join(s.folder, parsed.Path)
+				filename := path.Base(target)
+				if _, ok := e.fonts[filename]; ok && path.Dir(target) == FontFolderName && !e.skippedMedia[filename] {
+					used[filename] = true
+					embedded = true
+					continue
+				}
+				e.report.add(SeverityWarning, RuleFontUsage, s.filename, "@font-face source %s isn't a font of the EPUB", ref)
+			}
+			if embedded {
+				for _, family := range families {
+					declared[strings.ToLower(family)] = true
+				}
+			}
+		}
+	}
+
+	for _, s := range styles {
+		css := cssFontFaceRegexp.ReplaceAllString(cssCommentRegexp.ReplaceAllString(s.css, ""), "")
+		reported := make(map[string]bool)
+		for _, m := range cssFontFamilyRegexp.FindAllStringSubmatch(css, -1) {
+			for _, family := range splitFontFamilies(m[1]) {
+				key := strings.ToLower(family)
+				if genericFontFamilies[key] || declared[key] || reported[key] {
+					continue
+				}
+				reported[key] = true
+				e.report.add(SeverityWarning, RuleFontUsage, s.filename, "font family %q isn't embedded by any @font-face rule", family)
+			}
+		}
+	}
+
+	for _, filename := range sortedKeys(e.fonts) {
+		if !used[filename] && !e.skippedMedia[filename] {
+			e.report.add(SeverityWarning, RuleFontUsage, filename, "the font isn't used by any @font-face rule")
+		}
+	}
+}
+
+// Return the font families of the font-family declarations of CSS
+// declarations
+func fontFamilies(declarations string) []string {
+	var families []string
+	for _, m := range cssFontFamilyRegexp.FindAllStringSubmatch(declarations, -1) {
+		families = append(families, splitFontFamilies(m[1])...)
+	}
+	return families
+}
+
+// Split the value of a font-family declaration into its font families
+func splitFontFamilies(value string) []string {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+	var families []string
+	for _, family := range strings.Split(value, ",") {
+		family = strings.Trim(strings.TrimSpace(family), `"'`)
+		family = strings.Join(strings.Fields(family), " ")
+		if family != "" {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// Return the CSS of the style elements and style attributes of an XHTML
+// document
+func sectionStyles(doc string) string {
+	var b strings.Builder
+	for _, m := range styleElementRegexp.FindAllStringSubmatch(doc, -1) {
+		b.WriteString(m[1])
+		b.WriteString("\n")
+	}
+	for _, m := range styleAttrRegexp.FindAllStringSubmatch(doc, -1) {
+		b.WriteString("{")
+		b.WriteString(html.UnescapeString(m[1] + m[2]))
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// Return the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestCheckFontUsage(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFont(testFontFromFileSource, "used.ttf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFont(testFontFromFileSource, "unused.ttf"); err != nil {
+		t.Fatal(err)
+	}
+	css := `/* font-family: "Commented Out" */
+@font-face {
+  font-family: "Body Font";
+  src: url("../fonts/used.ttf") format("truetype");
+}
+@font-face {
+  font-family: Missing;
+  src: url(../fonts/missing.ttf);
+}
+body { font-family: "Body Font", serif; }
+h1 { font-family: 'Heading Font', Missing, sans-serif !important; }`
+	cssPath, err := e.AddCSS(dataurl.EncodeBytes([]byte(css)), "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `<p style="font-family: &quot;Inline Font&quot;, monospace">Text</p>`
+	if _, err := e.AddSection(body, "Section", "section.xhtml", cssPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	var issues []string
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleFontUsage {
+			issues = append(issues, w.Filename+": "+w.Message)
+		}
+	}
+	expected := []string{
+		"style.css: @font-face source ../fonts/missing.ttf isn't a font of the EPUB",
+		`style.css: font family "Heading Font" isn't embedded by any @font-face rule`,
+		`style.css: font family "Missing" isn't embedded by any @font-face rule`,
+		`section.xhtml: font family "Inline Font" isn't embedded by any @font-face rule`,
+		"unused.ttf: the font isn't used by any @font-face rule",
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s\nexpected:\n%s", strings.Join(issues, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCheckFontUsageEmbedded(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFont(testFontFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	cssPath, err := e.AddCSS(testFontCSSSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", cssPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleFontUsage {
+			t.Errorf("Unexpected issue: %s", w)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package epub
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/vincent-petithory/dataurl"
+)
+
+// AddCSSFromReader adds a CSS file read from r to the EPUB, e.g. a stylesheet
+// generated at runtime, and returns its relative path like AddCSS. The
+// internal filename is optional, as for AddCSS.
+func (e *Epub) AddCSSFromReader(r io.Reader, internalFilename string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read CSS: %w", err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaData(data, internalFilename, cssFileFormat, CSSFolderName, e.css)
+}
+
+// AddFontFromReader adds a font file read from r to the EPUB and returns its
+// relative path like AddFont. The internal filename is optional, as for
+// AddFont.
+func (e *Epub) AddFontFromReader(r io.Reader, internalFilename string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read font: %w", err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaData(data, internalFilename, fontFileFormat, FontFolderName, e.fonts)
+}
+
+// AddImageFromReader adds an image read from r to the EPUB, e.g. a chart
+// rendered at runtime, and returns its relative path like AddImage. The image
+// filename is optional, as for AddImage.
+func (e *Epub) AddImageFromReader(r io.Reader, imageFilename string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read image: %w", err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaData(data, imageFilename, imageFileFormat, ImageFolderName, e.images)
+}
+
+// AddVideoFromReader adds a video read from r to the EPUB and returns its
+// relative path like AddVideo. The video filename is optional, as for
+// AddVideo.
+func (e *Epub) AddVideoFromReader(r io.Reader, videoFilename string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read video: %w", err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaData(data, videoFilename, videoFileFormat, VideoFolderName, e.videos)
+}
+
+// AddAudioFromReader adds an audio file read from r to the EPUB and returns
+// its relative path like AddAudio. The audio filename is optional, as for
+// AddAudio.
+func (e *Epub) AddAudioFromReader(r io.Reader, audioFilename string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read audio: %w", err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaData(data, audioFilename, audioFileFormat, AudioFolderName, e.audios)
+}
+
+// Add a media file whose content is in memory. If no filename is provided,
+// one is generated with the extension of the detected media type.
+func (e *Epub) addMediaData(data []byte, internalFilename string, mediaFileFormat string, mediaFolderName string, mediaMap map[string]string) (string, error) {
+	if internalFilename == "" {
+		internalFilename = fmt.Sprintf(mediaFileFormat, len(mediaMap)+1, mediaExtension("", data, mediaFolderName))
+	}
+	return addMedia(e.grabber(), dataurl.EncodeBytes(data), internalFilename, mediaFileFormat, mediaFolderName, mediaMap)
+}
+
+// Return the extension of a media file of the given folder, from its declared
+// media type if it is known, or else from its content
+func mediaExtension(mediaType string, data []byte, mediaFolderName string) string {
+	if mediaFolderName == CSSFolderName {
+		// CSS is detected as plain text
+		return ".css"
+	}
+	if mediaFolderName == ScriptFolderName {
+		return ".js"
+	}
+	if m := mimetype.Lookup(mediaType); m != nil && m.Extension() != "" {
+		return m.Extension()
+	}
+	return mimetype.Detect(data).Extension()
+}
+
+// Return the extension of the media file in a data URL, see mediaExtension
+func dataURLExtension(source string, mediaFolderName string) string {
+	d, err := dataurl.DecodeString(source)
+	if err != nil {
+		return ""
+	}
+	mediaType := d.MediaType.ContentType()
+	if strings.HasPrefix(source, "data:;") || strings.HasPrefix(source, "data:,") {
+		// The media type defaults to text/plain, the content tells better
+		mediaType = ""
+	}
+	return mediaExtension(mediaType, d.Data, mediaFolderName)
+}
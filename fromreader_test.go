@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAddFromReader(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := os.ReadFile(testImageFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImageFromReader(bytes.NewReader(png), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imagePath != "../images/image0001.png" {
+		t.Errorf("Expected a generated filename with the detected extension, got %s", imagePath)
+	}
+	cssPath, err := e.AddCSSFromReader(strings.NewReader("p { margin: 0; }"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cssPath != "../css/css0001.css" {
+		t.Errorf("Unexpected CSS path %s", cssPath)
+	}
+	if _, err := e.AddImageFromReader(bytes.NewReader(png), "chart.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImageFromReader(bytes.NewReader(png), "chart.png"); !errors.As(err, new(*FilenameAlreadyUsedError)) {
+		t.Errorf("Expected FilenameAlreadyUsedError, got %v", err)
+	}
+	if _, err := e.AddFontFromReader(iotest.ErrReader(errors.New("broken")), ""); err == nil {
+		t.Error("Expected the read error to be returned")
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/images/chart.png"] != string(png) || files["EPUB/css/css0001.css"] != "p { margin: 0; }" {
+		t.Error("Expected the media read from the readers in the EPUB")
+	}
+}
@@ -0,0 +1,90 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	dedicationStyle          = "margin-top: 30%; text-align: center; font-style: italic;"
+	epigraphStyle            = "margin: 1em 0 2em 30%; font-style: italic;"
+	epigraphAttributionStyle = "text-align: right; font-style: normal;"
+)
+
+// Matches the first closing heading tag of a section body
+var headingEndRegex = regexp.MustCompile(`(?i)</h[1-6]\s*>`)
+
+// AddDedication adds a dedication page and returns a relative path to the
+// section, like AddSection.
+//
+// The text is plain text and will be escaped; blank lines separate
+// paragraphs. The section is marked with the "dedication" structural semantic
+// so reading systems can identify it as front matter.
+//
+// The title and internal filename follow the same rules as AddSection. A
+// dedication usually has no title, which keeps it out of the table of
+// contents.
+func (e *Epub) AddDedication(text string, sectionTitle string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	body := fmt.Sprintf(
+		"<section epub:type=\"dedication\" class=\"epub-dedication\" style=\"%s\">\n%s</section>",
+		dedicationStyle,
+		textToParagraphs(text),
+	)
+	return e.addSection("", body, sectionTitle, internalFilename, "")
+}
+
+// AddEpigraph adds an epigraph to an existing section. It is inserted after
+// the first heading of the section, or at the start of the section if it has
+// no heading.
+//
+// The quote and attribution are plain text and will be escaped; blank lines in
+// the quote separate paragraphs. The attribution is optional.
+func (e *Epub) AddEpigraph(sectionFilename string, quote string, attribution string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	if section.xhtml.raw != "" {
+		return &RawSectionError{Filename: sectionFilename}
+	}
+
+	var epigraph strings.Builder
+	fmt.Fprintf(&epigraph, "\n<blockquote epub:type=\"epigraph\" class=\"epub-epigraph\" style=\"%s\">\n", epigraphStyle)
+	epigraph.WriteString(textToParagraphs(quote))
+	if attribution != "" {
+		fmt.Fprintf(&epigraph, "<footer style=\"%s\">— %s</footer>\n", epigraphAttributionStyle, html.EscapeString(attribution))
+	}
+	epigraph.WriteString("</blockquote>\n")
+	text := e.sanitize(sectionFilename, "epigraph", epigraph.String())
+
+	body := section.xhtml.xml.Body.XML
+	insertAt := 0
+	if loc := headingEndRegex.FindStringIndex(body); loc != nil {
+		insertAt = loc[1]
+	}
+	section.xhtml.xml.Body.XML = body[:insertAt] + text + body[insertAt:]
+	return nil
+}
+
+// Convert plain text to escaped paragraphs, using blank lines as paragraph
+// separators
+func textToParagraphs(text string) string {
+	var b strings.Builder
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(html.EscapeString(p), "\n", "<br />"))
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddDedication(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.AddDedication("For Ada,\nwho asked.\n\nAnd for Bob.", "", "dedication.xhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := e.sections[0].xhtml.xml.Body.XML
+	for _, expected := range []string{
+		`<section epub:type="dedication"`,
+		`<p>For Ada,<br />who asked.</p>`,
+		`<p>And for Bob.</p>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Dedication doesn't contain %s\nGot: %s", expected, body)
+		}
+	}
+}
+
+func TestAddEpigraph(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withHeading, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutHeading, err := e.AddSection("<p>Text</p>", testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.AddEpigraph(withHeading, "Simplicity is complicated.", "Rob Pike"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddEpigraph(withoutHeading, "Anonymous & proud", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddEpigraph("doesnotexist.xhtml", "Quote", ""); err == nil {
+		t.Error("Expected an error when adding an epigraph to a section that doesn't exist")
+	}
+
+	body := e.sections[0].xhtml.xml.Body.XML
+	heading := strings.Index(body, "</h1>")
+	epigraph := strings.Index(body, `<blockquote epub:type="epigraph"`)
+	paragraph := strings.Index(body, "<p>This is a paragraph.</p>")
+	if !(heading < epigraph && epigraph < paragraph) {
+		t.Errorf("Epigraph should be between the heading and the text\nGot: %s", body)
+	}
+	if !strings.Contains(body, "— Rob Pike</footer>") {
+		t.Errorf("Epigraph attribution missing\nGot: %s", body)
+	}
+
+	body = e.sections[1].xhtml.xml.Body.XML
+	if !strings.HasPrefix(strings.TrimSpace(body), `<blockquote epub:type="epigraph"`) {
+		t.Errorf("Epigraph should be at the start of the section\nGot: %s", body)
+	}
+	if strings.Contains(body, "<footer") {
+		t.Errorf("Epigraph shouldn't have an attribution\nGot: %s", body)
+	}
+}
@@ -11,8 +11,8 @@ import (
 
 type FSType int
 
-// filesystem is the current filesytem used as the underlying layer to manage the files.
-// See the storage.Use method to change it.
+// filesystem is the storage selected with Use. The EPUBs are streamed to their
+// archive, so nothing is staged on it when they are written.
 var filesystem storage.Storage = osfs.NewOSFS(os.TempDir())
 
 const (
@@ -22,8 +22,13 @@ const (
 	MemoryFS
 )
 
-// Use s as default storage/ This is typically used in an init function.
+// Use s as default storage. This is typically used in an init function.
 // Default to local filesystem
+//
+// Deprecated: the EPUBs are written directly to their zip archive, without
+// staging their files on a storage, so the storage has no effect on the
+// writes. To write an EPUB elsewhere than to a local file, use WriteTo or
+// WriteToDestination.
 func Use(s FSType) error {
 	switch s {
 	case OsFS:
@@ -0,0 +1,96 @@
+package epub
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// AddCSSFS adds a CSS file read from fsys, e.g. an embed.FS, to the EPUB and
+// returns its relative path like AddCSS. The internal filename is optional; if
+// it isn't provided, the name of the file is used, or one is generated if it
+// is already used.
+func (e *Epub) AddCSSFS(fsys fs.FS, name string, internalFilename string) (string, error) {
+	data, err := readFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaFS(data, name, internalFilename, cssFileFormat, CSSFolderName, e.css)
+}
+
+// AddFontFS adds a font file read from fsys to the EPUB and returns its
+// relative path like AddFont. The internal filename is optional, as for
+// AddCSSFS.
+func (e *Epub) AddFontFS(fsys fs.FS, name string, internalFilename string) (string, error) {
+	data, err := readFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaFS(data, name, internalFilename, fontFileFormat, FontFolderName, e.fonts)
+}
+
+// AddImageFS adds an image read from fsys, e.g. an image bundled with
+// go:embed, to the EPUB and returns its relative path like AddImage. The image
+// filename is optional, as for AddCSSFS.
+func (e *Epub) AddImageFS(fsys fs.FS, name string, imageFilename string) (string, error) {
+	data, err := readFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaFS(data, name, imageFilename, imageFileFormat, ImageFolderName, e.images)
+}
+
+// AddVideoFS adds a video read from fsys to the EPUB and returns its relative
+// path like AddVideo. The video filename is optional, as for AddCSSFS.
+func (e *Epub) AddVideoFS(fsys fs.FS, name string, videoFilename string) (string, error) {
+	data, err := readFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaFS(data, name, videoFilename, videoFileFormat, VideoFolderName, e.videos)
+}
+
+// AddAudioFS adds an audio file read from fsys to the EPUB and returns its
+// relative path like AddAudio. The audio filename is optional, as for
+// AddCSSFS.
+func (e *Epub) AddAudioFS(fsys fs.FS, name string, audioFilename string) (string, error) {
+	data, err := readFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaFS(data, name, audioFilename, audioFileFormat, AudioFolderName, e.audios)
+}
+
+// Read a file of fsys
+func readFS(fsys fs.FS, name string) ([]byte, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, &FileRetrievalError{Source: name, Err: err}
+	}
+	return data, nil
+}
+
+// Add a media file read from a file system, named after the file if no
+// filename is provided
+func (e *Epub) addMediaFS(data []byte, name string, internalFilename string, mediaFileFormat string, mediaFolderName string, mediaMap map[string]string) (string, error) {
+	if internalFilename == "" {
+		internalFilename = path.Base(name)
+		if _, ok := mediaMap[internalFilename]; ok && e.duplicates == DuplicatesDefault || ValidateFilename(internalFilename) != nil {
+			sourceFilename := internalFilename
+			internalFilename = fmt.Sprintf(mediaFileFormat, len(mediaMap)+1, strings.ToLower(path.Ext(name)))
+			e.warnings.add(SeverityWarning, RuleRenamedFile, internalFilename, "%s was renamed to %s", sourceFilename, internalFilename)
+		}
+	}
+	return e.addMediaData(data, internalFilename, mediaFileFormat, mediaFolderName, mediaMap)
+}
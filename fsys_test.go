@@ -0,0 +1,60 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAddFS(t *testing.T) {
+	png, err := os.ReadFile(testImageFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"assets/logo.png":  {Data: png},
+		"assets/style.css": {Data: []byte("p { margin: 0; }")},
+	}
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImageFS(fsys, "assets/logo.png", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imagePath != "../images/logo.png" {
+		t.Errorf("Expected the image to keep its name, got %s", imagePath)
+	}
+	renamedPath, err := e.AddImageFS(fsys, "assets/logo.png", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamedPath != "../images/image0002.png" {
+		t.Errorf("Expected a generated name for the second image, got %s", renamedPath)
+	}
+	if _, err := e.AddCSSFS(fsys, "assets/style.css", "main.css"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFontFS(fsys, "assets/missing.ttf", ""); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+
+	// The local filesystem works as well
+	if _, err := e.AddImageFS(os.DirFS("testdata"), "gophercolor16x16.png", "gopher.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/images/logo.png"] != string(png) || files["EPUB/css/main.css"] != "p { margin: 0; }" || files["EPUB/images/gopher.png"] != string(png) {
+		t.Error("Expected the files of the file systems in the EPUB")
+	}
+}
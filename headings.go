@@ -0,0 +1,155 @@
+package epub
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// SetNormalizeHeadings sets whether the heading levels of the sections are
+// renormalized when the EPUB is written, so that no level is skipped: a
+// heading is at most one level below the heading it follows, e.g. the h4 of
+// an h1, h4, h5 sequence becomes an h2 and the h5 an h3. The first heading of
+// a section keeps its level. Raw documents (e.g. a custom cover page) are
+// written as is.
+//
+// Skipped levels, which break the navigation of screen readers, are reported
+// by Validate (RuleSkippedHeading) when the headings aren't normalized.
+func (e *Epub) SetNormalizeHeadings(normalize bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.normalizeHeadings = normalize
+}
+
+// A heading tag of a section body
+type headingTag struct {
+	// Offsets of the tag in the body
+	start, end int
+	level      int
+	closing    bool
+}
+
+// Return the heading start and end tags of a section body, in order
+func headingTags(body string) []headingTag {
+	const prefix = "<body>"
+	d := xml.NewDecoder(strings.NewReader(prefix + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var tags []headingTag
+	for {
+		start := int(d.InputOffset()) - len(prefix)
+		tok, err := d.Token()
+		if err != nil {
+			return tags
+		}
+		end := int(d.InputOffset()) - len(prefix)
+		var name string
+		closing := false
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+		case xml.EndElement:
+			name, closing = t.Name.Local, true
+		default:
+			continue
+		}
+		level := headingLevel(name)
+		// The end of a self-closing tag has no text of its own
+		if level == 0 || start == end || end > len(body) {
+			continue
+		}
+		tags = append(tags, headingTag{start: start, end: end, level: level, closing: closing})
+	}
+}
+
+// Return the level of a heading element, or 0 if the element isn't a heading
+func headingLevel(name string) int {
+	if len(name) != 2 || (name[0] != 'h' && name[0] != 'H') || name[1] < '1' || name[1] > '6' {
+		return 0
+	}
+	return int(name[1] - '0')
+}
+
+// Return the normalized level of each heading (start tag) of tags, see
+// SetNormalizeHeadings
+func normalizedHeadingLevels(tags []headingTag) []int {
+	type level struct{ original, normalized int }
+	var levels []int
+	var stack []level
+	for _, t := range tags {
+		if t.closing {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].original >= t.level {
+			stack = stack[:len(stack)-1]
+		}
+		normalized := t.level
+		if len(stack) > 0 && normalized > stack[len(stack)-1].normalized+1 {
+			normalized = stack[len(stack)-1].normalized + 1
+		}
+		stack = append(stack, level{t.level, normalized})
+		levels = append(levels, normalized)
+	}
+	return levels
+}
+
+// Renormalize the heading levels of a section body. The rest of the body is
+// kept verbatim.
+func normalizeHeadings(body string) string {
+	tags := headingTags(body)
+	levels := normalizedHeadingLevels(tags)
+
+	var b strings.Builder
+	var open []int
+	last, i := 0, 0
+	for _, t := range tags {
+		var level int
+		if t.closing {
+			if len(open) == 0 {
+				continue
+			}
+			level, open = open[len(open)-1], open[:len(open)-1]
+		} else {
+			level = levels[i]
+			i++
+			open = append(open, level)
+		}
+		if level == t.level {
+			continue
+		}
+		// The level digit follows "<h" or "</h"
+		digit := t.start + 2
+		if t.closing {
+			digit++
+		}
+		b.WriteString(body[last:digit])
+		b.WriteByte(byte('0' + level))
+		last = digit + 1
+	}
+	if last == 0 {
+		return body
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// Return the skipped heading levels of a section body, as the pairs of levels
+// of a heading and of the heading it follows
+func skippedHeadingLevels(body string) [][2]int {
+	var skipped [][2]int
+	var stack []int
+	for _, t := range headingTags(body) {
+		if t.closing {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1] >= t.level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && t.level > stack[len(stack)-1]+1 {
+			skipped = append(skipped, [2]int{stack[len(stack)-1], t.level})
+		}
+		stack = append(stack, t.level)
+	}
+	return skipped
+}
@@ -0,0 +1,54 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHeadings(t *testing.T) {
+	for body, expected := range map[string]string{
+		`<h1>A</h1><h4 class="x">B</h4><h5>C</h5><H3>D</H3><h1>E</h1>`: `<h1>A</h1><h2 class="x">B</h2><h3>C</h3><H2>D</H2><h1>E</h1>`,
+		// The first heading keeps its level
+		`<h2>A</h2><p>text</p><h3>B</h3><h6>C<br/></h6>`: `<h2>A</h2><p>text</p><h3>B</h3><h4>C<br/></h4>`,
+		`<h1>A</h1><h2>B</h2><h2>C</h2>`:                 `<h1>A</h1><h2>B</h2><h2>C</h2>`,
+		`<p>No headings</p>`:                             `<p>No headings</p>`,
+	} {
+		if got := normalizeHeadings(body); got != expected {
+			t.Errorf("Unexpected normalized body for %s:\n%s\nexpected:\n%s", body, got, expected)
+		}
+	}
+}
+
+func TestSetNormalizeHeadings(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<h1>Chapter</h1><h4>Part</h4>`, "Chapter", "chapter.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var skipped []string
+	for _, w := range e.Validate().Issues {
+		if w.Rule == RuleSkippedHeading {
+			skipped = append(skipped, w.Filename+": "+w.Message)
+		}
+	}
+	if len(skipped) != 1 || skipped[0] != "chapter.xhtml: h4 follows h1, skipping heading levels" {
+		t.Errorf("Unexpected skipped heading issues: %v", skipped)
+	}
+
+	e.SetNormalizeHeadings(true)
+	for _, w := range e.Validate().Issues {
+		if w.Rule == RuleSkippedHeading {
+			t.Errorf("Unexpected issue with normalized headings: %s", w)
+		}
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if section := readZipFiles(t, b.Bytes())["EPUB/xhtml/chapter.xhtml"]; !strings.Contains(section, "<h1>Chapter</h1><h2>Part</h2>") {
+		t.Errorf("Expected the headings to be normalized, got:\n%s", section)
+	}
+}
@@ -0,0 +1,92 @@
+package epub
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// FetchOptions configures the HTTP client used to retrieve remote media
+// sources, e.g. in build environments behind an intercepting proxy.
+type FetchOptions struct {
+	// URL of the proxy to use (e.g. http://proxy.example.com:3128). If empty,
+	// the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables.
+	ProxyURL string
+	// Certificate authorities used to verify the servers, in addition to the
+	// system ones, e.g. the CA of an intercepting proxy
+	RootCAs []*x509.Certificate
+	// TLS configuration the client starts from, may be nil. It is copied, and
+	// RootCAs are added to its RootCAs.
+	TLSConfig *tls.Config
+	// Time limit of each request, no limit if zero
+	Timeout time.Duration
+}
+
+// InvalidProxyURLError is thrown by SetFetchOptions if the proxy URL can't be
+// parsed.
+type InvalidProxyURLError struct {
+	URL string // The URL that was given
+	Err error  // The underlying error that was thrown
+}
+
+func (e *InvalidProxyURLError) Error() string {
+	return fmt.Sprintf("Invalid proxy URL %q: %+v", e.URL, e.Err)
+}
+
+// SetFetchOptions replaces the HTTP client of the EPUB (Client) with a client
+// configured with the options.
+func (e *Epub) SetFetchOptions(opts FetchOptions) error {
+	client, err := newFetchClient(opts)
+	if err != nil {
+		return err
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.Client = client
+	return nil
+}
+
+func newFetchClient(opts FetchOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err == nil && (proxy.Scheme == "" || proxy.Host == "") {
+			err = fmt.Errorf("missing scheme or host")
+		}
+		if err != nil {
+			return nil, &InvalidProxyURLError{URL: opts.ProxyURL, Err: err}
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{}
+	if opts.TLSConfig != nil {
+		tlsConfig = opts.TLSConfig.Clone()
+	}
+	if len(opts.RootCAs) > 0 {
+		if tlsConfig.RootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			tlsConfig.RootCAs = pool
+		} else {
+			// Don't modify the pool of the caller
+			tlsConfig.RootCAs = tlsConfig.RootCAs.Clone()
+		}
+		for _, ca := range opts.RootCAs {
+			tlsConfig.RootCAs.AddCert(ca)
+		}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}, nil
+}
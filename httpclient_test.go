@@ -0,0 +1,78 @@
+package epub
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetFetchOptionsRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.FileServer(http.Dir("./testdata/")))
+	defer server.Close()
+	source := server.URL + "/gophercolor16x16.png"
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetFetchOptions(FetchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(source, ""); err == nil {
+		t.Error("Expected an error for a server with an unknown certificate authority")
+	}
+
+	if err := e.SetFetchOptions(FetchOptions{RootCAs: []*x509.Certificate{server.Certificate()}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(source, ""); err != nil {
+		t.Errorf("Unexpected error with the certificate authority of the server: %v", err)
+	}
+}
+
+func TestSetFetchOptionsProxy(t *testing.T) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests to a proxy have an absolute URL
+		if r.URL.IsAbs() {
+			atomic.AddInt32(&proxied, 1)
+		}
+		http.FileServer(http.Dir("./testdata/")).ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetFetchOptions(FetchOptions{ProxyURL: proxy.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage("http://images.example.com/gophercolor16x16.png", ""); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&proxied) == 0 {
+		t.Error("Expected the request to go through the proxy")
+	}
+}
+
+func TestSetFetchOptionsInvalidProxy(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := e.Client
+	for _, proxy := range []string{"://bad", "proxy.example.com"} {
+		err := e.SetFetchOptions(FetchOptions{ProxyURL: proxy})
+		var invalid *InvalidProxyURLError
+		if !errors.As(err, &invalid) {
+			t.Errorf("Expected an InvalidProxyURLError for %q, got %v", proxy, err)
+		}
+	}
+	if e.Client != client {
+		t.Error("Expected the client to be left as is")
+	}
+}
@@ -0,0 +1,176 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Schemes of the identifiers of the EPUB, see AddIdentifier
+const (
+	IdentifierISBN = "isbn"
+	IdentifierDOI  = "doi"
+	IdentifierUUID = "uuid"
+)
+
+const (
+	pkgIdentifierIDFormat     = pkgUniqueIdentifier + "-%d"
+	pkgIdentifierTypeProperty = "identifier-type"
+	pkgIdentifierTypeScheme   = "onix:codelist5"
+	// ONIX codes of the identifier types (code list 5)
+	onixIdentifierTypeISBN10 = "02"
+	onixIdentifierTypeDOI    = "06"
+	onixIdentifierTypeISBN13 = "15"
+	isbn10Length             = 10
+	urnISBNPrefix            = "urn:isbn:"
+)
+
+// Identifier is an identifier of the EPUB (dc:identifier).
+type Identifier struct {
+	Value string `json:"value"`
+	// Scheme of the identifier, e.g. IdentifierISBN, or "" if unspecified
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// UnknownIdentifierError is returned by SetUniqueIdentifier if the identifier
+// isn't an identifier of the EPUB.
+type UnknownIdentifierError struct {
+	Identifier string // The identifier that was given
+}
+
+func (e *UnknownIdentifierError) Error() string {
+	return fmt.Sprintf("Identifier %s is not an identifier of the EPUB", e.Identifier)
+}
+
+// Identifiers returns the identifiers of the EPUB, starting with its unique
+// identifier.
+func (e *Epub) Identifiers() []Identifier {
+	e.Lock()
+	defer e.Unlock()
+	return e.identifiers()
+}
+
+func (e *Epub) identifiers() []Identifier {
+	return append([]Identifier{{Value: e.identifier, Scheme: e.identifierScheme}}, e.otherIdentifiers...)
+}
+
+// AddIdentifier adds an identifier to the EPUB besides its unique identifier,
+// e.g. the ISBN of an EPUB identified by a UUID, or sets the scheme of an
+// identifier already added. The scheme is written to the package file as an
+// identifier-type refinement: ISBNs and DOIs with their ONIX code, other
+// schemes as they are. It may be empty.
+//
+// SetUniqueIdentifier makes an added identifier the unique identifier of the
+// EPUB.
+func (e *Epub) AddIdentifier(identifier string, scheme string) {
+	e.Lock()
+	defer e.Unlock()
+	e.addIdentifier(e.sanitize("", "identifier", identifier), e.sanitize("", "identifier scheme", scheme))
+}
+
+func (e *Epub) addIdentifier(identifier string, scheme string) {
+	switch {
+	case identifier == e.identifier:
+		e.identifierScheme = scheme
+	default:
+		found := false
+		for i := range e.otherIdentifiers {
+			if e.otherIdentifiers[i].Value == identifier {
+				e.otherIdentifiers[i].Scheme = scheme
+				found = true
+			}
+		}
+		if !found {
+			e.otherIdentifiers = append(e.otherIdentifiers, Identifier{Value: identifier, Scheme: scheme})
+		}
+	}
+	e.pkg.setIdentifiers(e.identifiers())
+}
+
+// SetUniqueIdentifier makes an identifier added with AddIdentifier the unique
+// identifier of the EPUB (the package unique-identifier), like SetIdentifier;
+// the previous unique identifier is kept as another identifier of the EPUB.
+// UnknownIdentifierError is returned if identifier isn't an identifier of the
+// EPUB.
+func (e *Epub) SetUniqueIdentifier(identifier string) error {
+	e.Lock()
+	defer e.Unlock()
+	if identifier == e.identifier {
+		e.customIdentifier = true
+		return nil
+	}
+	for i, other := range e.otherIdentifiers {
+		if other.Value == identifier {
+			e.otherIdentifiers[i] = Identifier{Value: e.identifier, Scheme: e.identifierScheme}
+			e.customIdentifier = true
+			e.setIdentifier(other.Value)
+			e.identifierScheme = other.Scheme
+			e.pkg.setIdentifiers(e.identifiers())
+			return nil
+		}
+	}
+	return &UnknownIdentifierError{Identifier: identifier}
+}
+
+// Set the dc:identifier elements, the first one being the unique identifier,
+// and the identifier-type refinements of their schemes
+func (p *pkg) setIdentifiers(identifiers []Identifier) {
+	p.xml.Metadata.Identifiers = p.xml.Metadata.Identifiers[:0]
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.Property != pkgIdentifierTypeProperty {
+			metas = append(metas, m)
+		}
+	}
+	for i, identifier := range identifiers {
+		id := pkgUniqueIdentifier
+		if i > 0 {
+			id = fmt.Sprintf(pkgIdentifierIDFormat, i+1)
+		}
+		p.xml.Metadata.Identifiers = append(p.xml.Metadata.Identifiers, pkgIdentifier{ID: id, Data: identifier.Value})
+		if identifier.Scheme == "" {
+			continue
+		}
+		meta := pkgMeta{Refines: "#" + id, Property: pkgIdentifierTypeProperty, Data: identifier.Scheme}
+		if code := onixIdentifierType(identifier); code != "" {
+			meta.Scheme = pkgIdentifierTypeScheme
+			meta.Data = code
+		}
+		metas = append(metas, meta)
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Return the ONIX code of the type of an identifier, or "" if there is none
+func onixIdentifierType(identifier Identifier) string {
+	switch strings.ToLower(identifier.Scheme) {
+	case IdentifierISBN:
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' || r == 'X' || r == 'x' {
+				return r
+			}
+			return -1
+		}, strings.TrimPrefix(strings.ToLower(identifier.Value), urnISBNPrefix))
+		if len(digits) == isbn10Length {
+			return onixIdentifierTypeISBN10
+		}
+		return onixIdentifierTypeISBN13
+	case IdentifierDOI:
+		return onixIdentifierTypeDOI
+	}
+	return ""
+}
+
+// Return the scheme of an identifier-type refinement of an EPUB read with
+// Open
+func readIdentifierScheme(m readPackageMeta) string {
+	value := strings.TrimSpace(m.Value)
+	if m.Scheme == pkgIdentifierTypeScheme {
+		switch value {
+		case onixIdentifierTypeISBN10, onixIdentifierTypeISBN13:
+			return IdentifierISBN
+		case onixIdentifierTypeDOI:
+			return IdentifierDOI
+		}
+	}
+	return value
+}
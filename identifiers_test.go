@@ -0,0 +1,83 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddIdentifier(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	uuid := e.Identifier()
+	e.AddIdentifier("urn:isbn:9780000000002", IdentifierISBN)
+	e.AddIdentifier("10.1000/182", IdentifierDOI)
+	e.AddIdentifier("internal-42", "")
+	if err := e.SetUniqueIdentifier("urn:isbn:9780000000002"); err != nil {
+		t.Fatal(err)
+	}
+	var unknown *UnknownIdentifierError
+	if err := e.SetUniqueIdentifier("missing"); !errors.As(err, &unknown) {
+		t.Errorf("Expected an UnknownIdentifierError, got %v", err)
+	}
+	expected := []Identifier{
+		{Value: "urn:isbn:9780000000002", Scheme: IdentifierISBN},
+		{Value: uuid},
+		{Value: "10.1000/182", Scheme: IdentifierDOI},
+		{Value: "internal-42"},
+	}
+	if !reflect.DeepEqual(e.Identifiers(), expected) {
+		t.Errorf("Unexpected identifiers %v, expected %v", e.Identifiers(), expected)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	opf := files["EPUB/package.opf"]
+	for _, element := range []string{
+		`unique-identifier="pub-id"`,
+		`<dc:identifier id="pub-id">urn:isbn:9780000000002</dc:identifier>`,
+		`<dc:identifier id="pub-id-2">` + uuid + `</dc:identifier>`,
+		`<dc:identifier id="pub-id-3">10.1000/182</dc:identifier>`,
+		`<dc:identifier id="pub-id-4">internal-42</dc:identifier>`,
+		`<meta refines="#pub-id" property="identifier-type" scheme="onix:codelist5">15</meta>`,
+		`<meta refines="#pub-id-3" property="identifier-type" scheme="onix:codelist5">06</meta>`,
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+	if !strings.Contains(files["EPUB/toc.ncx"], `content="urn:isbn:9780000000002"`) {
+		t.Error("Expected the unique identifier in the NCX")
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened.Identifiers(), expected) {
+		t.Errorf("Identifiers not read back: %v", opened.Identifiers())
+	}
+}
+
+func TestOnixIdentifierType(t *testing.T) {
+	for identifier, code := range map[Identifier]string{
+		{Value: "978-0-00-000000-2", Scheme: IdentifierISBN}: "15",
+		{Value: "urn:isbn:0-00-000000-X", Scheme: "ISBN"}:    "02",
+		{Value: "10.1000/182", Scheme: IdentifierDOI}:        "06",
+		{Value: "urn:uuid:x", Scheme: IdentifierUUID}:        "",
+	} {
+		if got := onixIdentifierType(identifier); got != code {
+			t.Errorf("Identifier %v: expected ONIX code %q, got %q", identifier, code, got)
+		}
+	}
+}
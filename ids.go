@@ -0,0 +1,162 @@
+package epub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// IDGenerator generates the ids used in the EPUB: the ids of the manifest
+// items and the unique identifier of the publication. It can be set with
+// SetIDGenerator to produce deterministic or human-readable ids (e.g. img-001,
+// chap-02) instead of the default UUIDs.
+type IDGenerator interface {
+	// ManifestID returns the id of the manifest item of the file with the
+	// given filename (e.g. image0001.png or section0001.xhtml). It must always
+	// return the same id for a given filename, the ids must be unique within
+	// the EPUB and they must be valid XML ids.
+	ManifestID(filename string) string
+	// Identifier returns the unique identifier of the publication. It is only
+	// used if no identifier has been set with SetIdentifier.
+	Identifier() string
+}
+
+// InvalidIDError is thrown by Write if an IDGenerator returns an id that isn't
+// a valid XML id.
+type InvalidIDError struct {
+	ID       string // The invalid id
+	Filename string // Filename the id was generated for
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf("Invalid id %q generated for %s", e.ID, e.Filename)
+}
+
+// Simplified NCName, see https://www.w3.org/TR/REC-xml-names/#NT-NCName
+var xmlIDRegex = regexp.MustCompile(`^[\pL_][\pL\pN._-]*$`)
+
+// SetReadableIDs enables or disables readable manifest ids. When enabled, the
+// manifest ids are derived from the filenames (e.g. image0001.png becomes
+// image0001-png) instead of being UUIDs, which makes the package file much
+// easier to review. Colliding ids get a numeric suffix (-2, -3...), assigned in
+// filename order so the ids are stable from one build to the next.
+//
+// An IDGenerator set with SetIDGenerator takes precedence over this mode.
+func (e *Epub) SetReadableIDs(readable bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.readableIDs = readable
+}
+
+// SetIDGenerator sets the generator used for the manifest ids and, unless an
+// identifier has been set with SetIdentifier, the unique identifier of the
+// publication. Setting a nil generator restores the default UUID-based ids.
+func (e *Epub) SetIDGenerator(generator IDGenerator) {
+	e.Lock()
+	defer e.Unlock()
+	e.idGenerator = generator
+	if generator != nil && !e.customIdentifier {
+		e.setIdentifier(generator.Identifier())
+	}
+}
+
+// Return the id of the manifest item of a media file
+func (e *Epub) mediaID(filename string) (string, error) {
+	if e.idGenerator != nil {
+		return e.generatedID(filename)
+	}
+	if id, ok := e.slugIDs[filename]; ok {
+		return id, nil
+	}
+	return fixXMLId(filename)
+}
+
+// Return the id of the manifest item of a section. By default sections use
+// their filename.
+func (e *Epub) sectionID(filename string) (string, error) {
+	if e.idGenerator != nil {
+		return e.generatedID(filename)
+	}
+	if id, ok := e.slugIDs[filename]; ok {
+		return id, nil
+	}
+	return filename, nil
+}
+
+func (e *Epub) generatedID(filename string) (string, error) {
+	id := e.idGenerator.ManifestID(filename)
+	if !xmlIDRegex.MatchString(id) {
+		return "", &InvalidIDError{ID: id, Filename: filename}
+	}
+	return id, nil
+}
+
+// Assign the readable ids of all the files of the EPUB, if readable ids are
+// enabled. Must be called before the manifest is generated.
+func (e *Epub) assignSlugIDs() {
+	e.slugIDs = nil
+	if !e.readableIDs || e.idGenerator != nil {
+		return
+	}
+
+	var filenames []string
+	for _, m := range []map[string]string{e.css, e.fonts, e.images, e.videos, e.audios, e.scripts, e.overlays, e.fontLicenses, e.captions, e.fileSources()} {
+		for filename := range m {
+			filenames = append(filenames, filename)
+		}
+	}
+	var addSections func([]*epubSection)
+	addSections = func(sections []*epubSection) {
+		for _, section := range sections {
+			filenames = append(filenames, section.filename)
+			addSections(section.children)
+		}
+	}
+	addSections(e.sections)
+	sort.Strings(filenames)
+
+	// The ids of the TOC files are fixed
+	used := map[string]bool{tocNavItemID: true, tocNcxItemID: true}
+	e.slugIDs = make(map[string]string, len(filenames))
+	for _, filename := range filenames {
+		if _, ok := e.slugIDs[filename]; ok {
+			continue
+		}
+		slug := slugify(filename)
+		id := slug
+		for i := 2; used[id]; i++ {
+			id = fmt.Sprintf("%s-%d", slug, i)
+		}
+		used[id] = true
+		e.slugIDs[filename] = id
+	}
+}
+
+// Turn a filename into a valid XML id made of lowercase letters, digits and
+// dashes, e.g. "Chapter 1.xhtml" becomes "chapter-1-xhtml"
+func slugify(filename string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(filename) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	slug := b.String()
+	if slug == "" {
+		return "id"
+	}
+	// XML ids can't start with a digit
+	if r := []rune(slug)[0]; !unicode.IsLetter(r) {
+		slug = "id-" + slug
+	}
+	return slug
+}
@@ -0,0 +1,153 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"testing"
+)
+
+// prefixIDGenerator names manifest items after their kind and order of first
+// use, e.g. img-001 or chap-01
+type prefixIDGenerator struct {
+	ids    map[string]string
+	counts map[string]int
+}
+
+func (g *prefixIDGenerator) ManifestID(filename string) string {
+	if id, ok := g.ids[filename]; ok {
+		return id
+	}
+	prefix, format := "img", "%s-%03d"
+	if path.Ext(filename) == ".xhtml" {
+		prefix, format = "chap", "%s-%02d"
+	}
+	g.counts[prefix]++
+	g.ids[filename] = fmt.Sprintf(format, prefix, g.counts[prefix])
+	return g.ids[filename]
+}
+
+func (g *prefixIDGenerator) Identifier() string {
+	return "urn:example:book"
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetIDGenerator(&prefixIDGenerator{ids: map[string]string{}, counts: map[string]int{}})
+	if e.Identifier() != "urn:example:book" {
+		t.Errorf("Identifier wasn't generated: got %s", e.Identifier())
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCoverImage(imagePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	opf := writeAndReadPackage(t, e)
+	for _, expected := range []string{
+		`<dc:identifier id="pub-id">urn:example:book</dc:identifier>`,
+		`id="img-001"`,
+		`id="chap-01"`,
+		`<itemref idref="chap-01"></itemref>`,
+		`<meta name="cover" content="img-001"></meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Package file doesn't contain %s\n%s", expected, opf)
+		}
+	}
+}
+
+func TestSetIDGeneratorKeepsIdentifier(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetIdentifier(testEpubIdentifier)
+	e.SetIDGenerator(&prefixIDGenerator{ids: map[string]string{}, counts: map[string]int{}})
+	if e.Identifier() != testEpubIdentifier {
+		t.Errorf("Identifier set with SetIdentifier was replaced: got %s", e.Identifier())
+	}
+}
+
+type constantIDGenerator string
+
+func (g constantIDGenerator) ManifestID(string) string { return string(g) }
+func (g constantIDGenerator) Identifier() string       { return string(g) }
+
+func TestSetIDGeneratorInvalidID(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetIDGenerator(constantIDGenerator("1 invalid"))
+	_, err = e.WriteTo(io.Discard)
+	if _, ok := err.(*InvalidIDError); !ok {
+		t.Errorf("Expected error InvalidIDError not returned. Returned instead: %+v", err)
+	}
+}
+
+// Write the EPUB and return the content of its package file
+func writeAndReadPackage(t *testing.T, e *Epub) string {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	return readZipFiles(t, b.Bytes())[path.Join(contentFolderName, pkgFilename)]
+}
+
+func TestSetReadableIDs(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetReadableIDs(true)
+	if _, err := e.AddImage(testImageFromFileSource, "Cover Image.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, "cover_image.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "1.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	opf := writeAndReadPackage(t, e)
+	for _, expected := range []string{
+		`id="cover-image-png" href="images/Cover Image.png"`,
+		`id="cover-image-png-2" href="images/cover_image.png"`,
+		`id="id-1-xhtml" href="xhtml/1.xhtml"`,
+		`<itemref idref="id-1-xhtml"></itemref>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Package file doesn't contain %s\n%s", expected, opf)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for input, expected := range map[string]string{
+		"image0001.png":       "image0001-png",
+		"Chapter 1.xhtml":     "chapter-1-xhtml",
+		"--weird__name--.css": "weird-name-css",
+		"2024.xhtml":          "id-2024-xhtml",
+		"...":                 "id",
+	} {
+		if got := slugify(input); got != expected {
+			t.Errorf("slugify(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+const (
+	// EXIF tag of the orientation of the image
+	exifOrientationTag = 0x0112
+	exifHeader         = "Exif\x00\x00"
+	// JPEG markers of the metadata segments
+	jpegMarkerAPP0    = 0xe0
+	jpegMarkerAPP1    = 0xe1
+	jpegMarkerAPP2    = 0xe2
+	jpegMarkerAPP14   = 0xee
+	jpegMarkerAPP15   = 0xef
+	jpegMarkerComment = 0xfe
+)
+
+// PNG chunks holding metadata, such as the camera or the location of a photo
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true,
+}
+
+// SetStripImageMetadata enables or disables the removal of the metadata of the
+// images when the EPUB is written: the EXIF orientation of JPEG images is
+// applied to their pixels, since some reading systems ignore it and display
+// photos sideways, then their EXIF and XMP metadata (e.g. the location where
+// a photo was taken or the camera serial number), comments and vendor
+// segments are removed, as well as the text, time and EXIF chunks of PNG
+// images. Color profiles are kept, see SetNormalizeColorProfiles. The rotated
+// images are reported with RuleConvertedMedia.
+func (e *Epub) SetStripImageMetadata(strip bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.stripImageMetadata = strip
+}
+
+// Apply the EXIF orientation of an image and remove its metadata, see
+// SetStripImageMetadata. Images that can't be parsed are returned as is.
+func (e *Epub) stripMetadata(mediaFilename string, mediaType string, data []byte) []byte {
+	switch mediaType {
+	case "image/jpeg":
+		var orientation int
+		var iccProfiles [][]byte
+		stripped, err := filterJPEGSegments(data, func(marker byte, payload []byte) bool {
+			if marker == jpegMarkerAPP1 && bytes.HasPrefix(payload, []byte(exifHeader)) && orientation == 0 {
+				orientation = exifOrientation(payload[len(exifHeader):])
+			}
+			if isICCProfileSegment(marker, payload) {
+				iccProfiles = append(iccProfiles, jpegSegment(marker, payload))
+			}
+			return isJPEGMetadataSegment(marker, payload)
+		})
+		if err != nil {
+			return data
+		}
+		if orientation > 1 && orientation <= 8 {
+			rotated, err := orientJPEG(stripped, orientation, iccProfiles)
+			if err == nil {
+				e.report.add(SeverityWarning, RuleConvertedMedia, mediaFilename, "rotated according to its EXIF orientation (%d)", orientation)
+				return rotated
+			}
+		}
+		return stripped
+	case "image/png":
+		if stripped, err := filterPNGChunks(data, func(chunkType string) bool { return !pngMetadataChunks[chunkType] }); err == nil {
+			return stripped
+		}
+	}
+	return data
+}
+
+// Report whether a JPEG segment holds metadata: the application segments
+// besides JFIF (APP0), ICC profiles (APP2) and the Adobe color transform
+// (APP14), which are needed to render the image, and the comments
+func isJPEGMetadataSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == jpegMarkerComment:
+		return true
+	case marker == jpegMarkerAPP2:
+		return !isICCProfileSegment(marker, payload)
+	case marker > jpegMarkerAPP0 && marker <= jpegMarkerAPP15:
+		return marker != jpegMarkerAPP14
+	}
+	return false
+}
+
+// Return a JPEG marker segment
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// Return the orientation of an image (1 to 8) from its EXIF data (a TIFF
+// structure), or 0 if it has none
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	// The first image file directory (IFD0) describes the main image
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			// A SHORT value, stored at the start of the value field
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// Return the EXIF orientation of a JPEG image (1 to 8), or 0 if it has none
+// or can't be parsed
+func jpegOrientation(data []byte) int {
+	orientation := 0
+	filterJPEGSegments(data, func(marker byte, payload []byte) bool {
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(payload, []byte(exifHeader)) && orientation == 0 {
+			orientation = exifOrientation(payload[len(exifHeader):])
+		}
+		return false
+	})
+	return orientation
+}
+
+// Apply an EXIF orientation to the pixels of a JPEG image and re-encode it
+// with the given ICC profile segments
+func orientJPEG(data []byte, orientation int, iccProfiles [][]byte) ([]byte, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	encoded, _, err := encodeImage(orientedImage(img, orientation), "jpeg")
+	if err != nil {
+		return nil, err
+	}
+	// The ICC profiles follow the start of image marker
+	out := append([]byte(nil), encoded[:2]...)
+	for _, segment := range iccProfiles {
+		out = append(out, segment...)
+	}
+	return append(out, encoded[2:]...), nil
+}
+
+// Return img laid out according to an EXIF orientation, or img itself if the
+// orientation is upright or unknown, see orientImage
+func orientedImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Rect, img, img.Bounds().Min, draw.Src)
+	return orientImage(src, orientation)
+}
+
+// Return an image with the pixels of src laid out according to an EXIF
+// orientation, so that it displays upright without the orientation
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	// Orientations 5 to 8 swap the width and the height
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
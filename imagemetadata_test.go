@@ -0,0 +1,198 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"path"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Return the payload of an APP1 EXIF segment with the given orientation, in
+// big-endian order
+func testEXIF(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	// Two entries: the orientation and a GPS IFD pointer
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.BigEndian.AppendUint16(tiff, 4)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	return append([]byte(exifHeader), tiff...)
+}
+
+// Return a 16x8 JPEG image, red on the left half and blue on the right half,
+// with the given marker segments
+func testPhoto(t *testing.T, segments ...[]byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 8 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(nil), b.Bytes()[:2]...)
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	return append(data, b.Bytes()[2:]...)
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	icc := jpegSegment(jpegMarkerAPP2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	upright := testPhoto(t,
+		jpegSegment(jpegMarkerAPP1, testEXIF(1)),
+		jpegSegment(jpegMarkerAPP1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+		jpegSegment(jpegMarkerComment, []byte("Taken at home")),
+		icc,
+	)
+	sideways := testPhoto(t, jpegSegment(jpegMarkerAPP1, testEXIF(6)), icc)
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetStripImageMetadata(true)
+	for filename, data := range map[string][]byte{"upright.jpg": upright, "sideways.jpg": sideways} {
+		if _, err := e.AddImage(dataurl.EncodeBytes(data), filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+
+	if written, expected := []byte(files["EPUB/images/upright.jpg"]), testPhoto(t, icc); !bytes.Equal(written, expected) {
+		t.Errorf("Expected the metadata to be stripped and the ICC profile to be kept, got %d bytes instead of %d", len(written), len(expected))
+	}
+
+	written := []byte(files["EPUB/images/sideways.jpg"])
+	if bytes.Contains(written, []byte(exifHeader)) || !bytes.Contains(written, icc) {
+		t.Error("Expected the EXIF data to be stripped and the ICC profile to be kept")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotated 90 degrees clockwise: the left half is now on top
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 16 {
+		t.Fatalf("Expected a 8x16 image, got %v", img.Bounds())
+	}
+	if r, _, b, _ := img.At(4, 2).RGBA(); r < b {
+		t.Error("Expected the top of the rotated image to be red")
+	}
+	if r, _, b, _ := img.At(4, 13).RGBA(); b < r {
+		t.Error("Expected the bottom of the rotated image to be blue")
+	}
+	rotated := false
+	for _, w := range e.Warnings() {
+		rotated = rotated || w.Rule == RuleConvertedMedia && w.Filename == "sideways.jpg"
+	}
+	if !rotated {
+		t.Error("Expected the rotation to be reported")
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2x3 image whose pixels are numbered row by row
+	src := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	for i := 0; i < 6; i++ {
+		src.Pix[i*4] = byte(i + 1)
+	}
+	for orientation, expected := range map[int][]byte{
+		1: {1, 2, 3, 4, 5, 6},
+		2: {2, 1, 4, 3, 6, 5},
+		3: {6, 5, 4, 3, 2, 1},
+		4: {5, 6, 3, 4, 1, 2},
+		5: {1, 3, 5, 2, 4, 6},
+		6: {5, 3, 1, 6, 4, 2},
+		7: {6, 4, 2, 5, 3, 1},
+		8: {2, 4, 6, 1, 3, 5},
+	} {
+		dst := orientImage(src, orientation)
+		got := make([]byte, 6)
+		for i := range got {
+			got[i] = dst.Pix[i*4]
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("Orientation %d: expected %v, got %v", orientation, expected, got)
+		}
+	}
+}
+
+func TestSetCoverFromReaderKeepsOriginal(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func(imagePath string) (image.Image, []byte) {
+		t.Helper()
+		data, err := dataurl.DecodeString(e.images[path.Base(imagePath)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, _, err := image.Decode(bytes.NewReader(data.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img, data.Data
+	}
+
+	// A small photo taken in portrait is kept with its EXIF orientation,
+	// while the thumbnail has it applied
+	photo := testPhoto(t, jpegSegment(jpegMarkerAPP1, testEXIF(6)))
+	coverPath, thumbnailPath, err := e.SetCoverFromReader(bytes.NewReader(photo), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverPath != "../images/cover.jpg" {
+		t.Errorf("Unexpected cover path %s", coverPath)
+	}
+	if _, data := decode(coverPath); !bytes.Equal(data, photo) {
+		t.Error("Expected the cover image to be kept as is")
+	}
+	thumbnail, _ := decode(thumbnailPath)
+	if b := thumbnail.Bounds(); b.Dx() != 8 || b.Dy() != 16 {
+		t.Errorf("Expected an upright 8x16 thumbnail, got %dx%d", b.Dx(), b.Dy())
+	}
+	if r, _, b, _ := thumbnail.At(4, 2).RGBA(); r < b {
+		t.Error("Expected the left half of the photo to be at the top of the thumbnail")
+	}
+
+	// A landscape image in pixels that fits once rotated isn't scaled down
+	img := image.NewRGBA(image.Rect(0, 0, CoverMaxHeight, CoverMaxWidth))
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	large := append(append([]byte{0xff, 0xd8}, jpegSegment(jpegMarkerAPP1, testEXIF(6))...), b.Bytes()[2:]...)
+	coverPath, thumbnailPath, err = e.SetCoverFromReader(bytes.NewReader(large), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, data := decode(coverPath); !bytes.Equal(data, large) {
+		t.Error("Expected the cover image that fits once rotated to be kept as is")
+	}
+	thumbnail, _ = decode(thumbnailPath)
+	if b := thumbnail.Bounds(); b.Dx() != CoverThumbnailMaxWidth || b.Dy() != CoverThumbnailMaxHeight {
+		t.Errorf("Expected a %dx%d thumbnail, got %dx%d", CoverThumbnailMaxWidth, CoverThumbnailMaxHeight, b.Dx(), b.Dy())
+	}
+}
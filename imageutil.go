@@ -0,0 +1,93 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+const jpegQuality = 90
+
+// Scale the image down to fit within maxWidth x maxHeight while keeping its
+// aspect ratio. Images that already fit are returned unchanged.
+//
+// Each destination pixel is the average of the source pixels it covers (a box
+// filter), which gives good results when shrinking.
+func downscaleImage(src image.Image, maxWidth int, maxHeight int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxWidth && h <= maxHeight || w == 0 || h == 0 {
+		return src
+	}
+	scale := float64(maxWidth) / float64(w)
+	if s := float64(maxHeight) / float64(h); s < scale {
+		scale = s
+	}
+	dw, dh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// Work on premultiplied RGBA pixels so transparency is averaged correctly
+	rgba, ok := src.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(rgba, rgba.Rect, src, b.Min, draw.Src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		sy0, sy1 := dy*h/dh, (dy+1)*h/dh
+		if sy1 == sy0 {
+			sy1 = sy0 + 1
+		}
+		for dx := 0; dx < dw; dx++ {
+			sx0, sx1 := dx*w/dw, (dx+1)*w/dw
+			if sx1 == sx0 {
+				sx1 = sx0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				i := rgba.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint64(rgba.Pix[i])
+					g += uint64(rgba.Pix[i+1])
+					bl += uint64(rgba.Pix[i+2])
+					a += uint64(rgba.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(dx, dy)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Encode the image in the given format ("png" or "jpeg", as returned by
+// image.Decode) and return the encoded data along with the file extension to
+// use. Any other format is encoded as PNG, which supports transparency.
+func encodeImage(img image.Image, format string) ([]byte, string, error) {
+	var b bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", fmt.Errorf("unable to encode JPEG image: %w", err)
+		}
+		return b.Bytes(), ".jpg", nil
+	}
+	if err := png.Encode(&b, img); err != nil {
+		return nil, "", fmt.Errorf("unable to encode PNG image: %w", err)
+	}
+	return b.Bytes(), ".png", nil
+}
@@ -0,0 +1,29 @@
+package epub
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDownscaleImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	dst := downscaleImage(src, 200, 200)
+	if dst.Bounds().Dx() != 200 || dst.Bounds().Dy() != 50 {
+		t.Errorf("Expected a 200x50 image, got %v", dst.Bounds())
+	}
+	if r, g, b, a := dst.At(10, 10).RGBA(); r != 0xffff || g != 0 || b != 0 || a != 0xffff {
+		t.Errorf("Unexpected pixel color %v", dst.At(10, 10))
+	}
+
+	// Images that already fit are left alone
+	if small := downscaleImage(src, 1000, 1000); small != image.Image(src) {
+		t.Error("Image that fits shouldn't be scaled")
+	}
+}
@@ -0,0 +1,75 @@
+package epub
+
+// Langs returns the languages of the EPUB: the main language (see SetLang),
+// then the languages added with AddLang.
+func (e *Epub) Langs() []string {
+	return e.langs()
+}
+
+func (e *Epub) langs() []string {
+	return append([]string{e.lang}, e.otherLangs...)
+}
+
+// AddLang adds a language to the EPUB besides its main language (see
+// SetLang), e.g. the second language of a bilingual edition. Languages that
+// are already set are ignored.
+func (e *Epub) AddLang(lang string) {
+	e.Lock()
+	defer e.Unlock()
+	for _, l := range e.langs() {
+		if l == lang {
+			return
+		}
+	}
+	e.otherLangs = append(e.otherLangs, lang)
+	e.pkg.setLangs(e.langs())
+}
+
+// TitleLang returns the language of the main title of the EPUB, see
+// SetTitleLang.
+func (e *Epub) TitleLang() string {
+	return e.titleLang
+}
+
+// SetTitleLang sets the language of the main title of the EPUB (its xml:lang
+// attribute), when it isn't in the main language of the EPUB. An empty
+// language removes it.
+func (e *Epub) SetTitleLang(lang string) {
+	e.Lock()
+	defer e.Unlock()
+	e.titleLang = lang
+	e.pkg.setTitles(e.mainTitle(), e.titles)
+}
+
+// AddTranslatedTitle adds a title of the EPUB in another language, e.g. the
+// title of a bilingual edition in its second language. It is written after
+// the main title with an xml:lang attribute, like the titles added with
+// AddTitle.
+func (e *Epub) AddTranslatedTitle(title string, lang string) {
+	e.Lock()
+	defer e.Unlock()
+	e.addTitle(title, "", 0, lang)
+	e.pkg.setTitles(e.mainTitle(), e.titles)
+}
+
+// AuthorLang returns the language of the author name, see SetAuthorLang.
+func (e *Epub) AuthorLang() string {
+	return e.authorLang
+}
+
+// SetAuthorLang sets the language of the author name (the xml:lang attribute
+// of the dc:creator element), e.g. for a name written in another script than
+// the main language of the EPUB. An empty language removes it.
+func (e *Epub) SetAuthorLang(lang string) {
+	e.Lock()
+	defer e.Unlock()
+	e.authorLang = lang
+	e.pkg.setAuthorLang(lang)
+}
+
+// Set the xml:lang attribute of the dc:creator element, if any
+func (p *pkg) setAuthorLang(lang string) {
+	if p.xml.Metadata.Creator != nil {
+		p.xml.Metadata.Creator.Lang = lang
+	}
+}
@@ -0,0 +1,80 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAddLang(t *testing.T) {
+	e, err := NewEpub("Grammaire française")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetLang("fr")
+	e.AddLang("en")
+	e.AddLang("fr")
+	e.SetTitleLang("fr")
+	e.AddTranslatedTitle("French Grammar", "en")
+	e.SetAuthor("Сергей Иванов")
+	e.SetAuthorLang("ru")
+	if langs := e.Langs(); strings.Join(langs, ",") != "fr,en" {
+		t.Errorf("Unexpected languages %v", langs)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`<dc:title id="title" xml:lang="fr">Grammaire française</dc:title>`,
+		`<dc:title id="title-2" xml:lang="en">French Grammar</dc:title>`,
+		`<dc:language>fr</dc:language>`,
+		`<dc:language>en</dc:language>`,
+		`<dc:creator id="creator" xml:lang="ru">Сергей Иванов</dc:creator>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+	if strings.Count(opf, "<dc:language>") != 2 {
+		t.Errorf("Expected 2 languages, got:\n%s", opf)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(opened.Langs(), ",") != "fr,en" || opened.TitleLang() != "fr" || opened.AuthorLang() != "ru" {
+		t.Errorf("Languages not read back: %v %q %q", opened.Langs(), opened.TitleLang(), opened.AuthorLang())
+	}
+	b.Reset()
+	if _, err := opened.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if reopf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]; !strings.Contains(reopf, `<dc:title id="title-2" xml:lang="en">French Grammar</dc:title>`) {
+		t.Errorf("Expected the translated title to be written back, got:\n%s", reopf)
+	}
+}
+
+func TestValidateMetadataLangs(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddLang("not a language")
+	e.SetAuthorLang("fr")
+	var issues []string
+	for _, i := range e.Validate().Issues {
+		if i.Rule == RuleInvalidLang {
+			issues = append(issues, i.Message)
+		}
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0], `"not a language"`) {
+		t.Errorf("Unexpected language issues %v", issues)
+	}
+}
@@ -0,0 +1,253 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ExternalLinkPolicy rewrites the external links (http and https links) of the
+// sections when the EPUB is written, see SetExternalLinkPolicy.
+type ExternalLinkPolicy interface {
+	// RewriteLink returns what to do with the link to href of the section
+	// with the given internal filename (e.g. section0001.xhtml)
+	RewriteLink(sectionFilename string, href string) ExternalLinkRewrite
+}
+
+// ExternalLinkRewrite is the rewriting of an external link by an
+// ExternalLinkPolicy.
+type ExternalLinkRewrite struct {
+	// New URL of the link; if empty, the link keeps its URL
+	URL string
+	// Remove the link, keeping its content
+	Unlink bool
+	// Text of a footnote referenced after the link, e.g. the printed URL; if
+	// empty, no footnote is added
+	Footnote string
+}
+
+// ExternalLinkPolicyFunc is a function used as an ExternalLinkPolicy.
+type ExternalLinkPolicyFunc func(sectionFilename string, href string) ExternalLinkRewrite
+
+// RewriteLink calls f(sectionFilename, href).
+func (f ExternalLinkPolicyFunc) RewriteLink(sectionFilename string, href string) ExternalLinkRewrite {
+	return f(sectionFilename, href)
+}
+
+// SetExternalLinkPolicy sets the policy applied uniformly to the external links
+// of all the sections when the EPUB is written, e.g. UTMParameters,
+// StripTrackers or LinkFootnotes; several policies can be combined with
+// ChainLinkPolicies. Raw documents (e.g. a custom cover page) are written as
+// is. Setting a nil policy, the default, leaves the links as they are.
+func (e *Epub) SetExternalLinkPolicy(policy ExternalLinkPolicy) {
+	e.Lock()
+	defer e.Unlock()
+	e.linkPolicy = policy
+}
+
+// UTMParameters is an ExternalLinkPolicy adding UTM parameters to the external
+// links, so that the visits coming from the EPUB can be tracked. Empty
+// parameters and parameters already set by a link are left out.
+type UTMParameters struct {
+	Source   string
+	Medium   string
+	Campaign string
+}
+
+// RewriteLink adds the UTM parameters to href.
+func (p UTMParameters) RewriteLink(sectionFilename string, href string) ExternalLinkRewrite {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ExternalLinkRewrite{}
+	}
+	q := u.Query()
+	for _, param := range [][2]string{{"utm_source", p.Source}, {"utm_medium", p.Medium}, {"utm_campaign", p.Campaign}} {
+		if param[1] != "" && !q.Has(param[0]) {
+			q.Set(param[0], param[1])
+		}
+	}
+	u.RawQuery = q.Encode()
+	return ExternalLinkRewrite{URL: u.String()}
+}
+
+// Query parameters removed by StripTrackers, in addition to the UTM ones
+var trackingParameters = map[string]bool{
+	"dclid":   true,
+	"fbclid":  true,
+	"gclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"_ga":     true,
+	"_gl":     true,
+}
+
+// StripTrackers is an ExternalLinkPolicy removing the tracking parameters
+// (UTM parameters, click identifiers...) from the query of the external links.
+type StripTrackers struct{}
+
+// RewriteLink removes the tracking parameters of href.
+func (StripTrackers) RewriteLink(sectionFilename string, href string) ExternalLinkRewrite {
+	u, err := url.Parse(href)
+	if err != nil || u.RawQuery == "" {
+		return ExternalLinkRewrite{}
+	}
+	q := u.Query()
+	stripped := false
+	for param := range q {
+		if strings.HasPrefix(param, "utm_") || trackingParameters[param] {
+			q.Del(param)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return ExternalLinkRewrite{}
+	}
+	u.RawQuery = q.Encode()
+	return ExternalLinkRewrite{URL: u.String()}
+}
+
+// LinkFootnotes is an ExternalLinkPolicy adding a footnote with the printed
+// URL after each external link, for reading systems and printouts where the
+// links can't be followed.
+type LinkFootnotes struct {
+	// Also remove the links, keeping their content
+	Unlink bool
+}
+
+// RewriteLink adds a footnote with href.
+func (p LinkFootnotes) RewriteLink(sectionFilename string, href string) ExternalLinkRewrite {
+	return ExternalLinkRewrite{Unlink: p.Unlink, Footnote: href}
+}
+
+// ChainLinkPolicies returns an ExternalLinkPolicy applying the given policies
+// in order, each one to the URL rewritten by the previous ones. A policy
+// removing the link stops the chain, and the last footnote wins.
+func ChainLinkPolicies(policies ...ExternalLinkPolicy) ExternalLinkPolicy {
+	return ExternalLinkPolicyFunc(func(sectionFilename string, href string) ExternalLinkRewrite {
+		var r ExternalLinkRewrite
+		for _, p := range policies {
+			current := href
+			if r.URL != "" {
+				current = r.URL
+			}
+			pr := p.RewriteLink(sectionFilename, current)
+			if pr.URL != "" {
+				r.URL = pr.URL
+			}
+			if pr.Footnote != "" {
+				r.Footnote = pr.Footnote
+			}
+			if pr.Unlink {
+				r.Unlink = true
+				break
+			}
+		}
+		return r
+	})
+}
+
+const (
+	// Format of the ids of the footnotes added for the external links
+	linkFootnoteIDFormat = "link-note-%d"
+	linkFootnoteRef      = `<sup><a epub:type="noteref" role="doc-noteref" href="#%s">%d</a></sup>`
+	linkFootnote         = `<aside epub:type="footnote" role="doc-footnote" id="%s"><p>%s</p></aside>`
+)
+
+// The href attribute of a start tag
+var hrefAttrRegexp = regexp.MustCompile(`(?i)(\shref\s*=\s*)("[^"]*"|'[^']*')`)
+
+// Rewrite the external links of a section body with the policy. The rest of
+// the body is kept verbatim.
+func rewriteExternalLinks(sectionFilename string, body string, policy ExternalLinkPolicy) string {
+	const prefix = "<body>"
+	d := xml.NewDecoder(strings.NewReader(prefix + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	var footnotes []string
+	last := 0
+	for {
+		start := int(d.InputOffset()) - len(prefix)
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || strings.ToLower(se.Name.Local) != "a" {
+			continue
+		}
+		var href string
+		for _, a := range se.Attr {
+			if a.Name.Space == "" && a.Name.Local == "href" {
+				href = strings.TrimSpace(a.Value)
+			}
+		}
+		if !isExternalLink(href) {
+			continue
+		}
+		r := policy.RewriteLink(sectionFilename, href)
+		tagEnd := int(d.InputOffset()) - len(prefix)
+		if err := d.Skip(); err != nil {
+			break
+		}
+		end := int(d.InputOffset()) - len(prefix)
+		if end > len(body) {
+			end = len(body)
+		}
+
+		b.WriteString(body[last:start])
+		tag, content, closing := body[start:tagEnd], body[tagEnd:end], ""
+		if i := strings.LastIndex(content, "</"); i >= 0 {
+			content, closing = content[:i], content[i:]
+		}
+		switch {
+		case r.Unlink:
+			b.WriteString(content)
+		case r.URL != "" && r.URL != href:
+			b.WriteString(setHrefAttr(tag, r.URL) + content + closing)
+		default:
+			b.WriteString(body[start:end])
+		}
+		if r.Footnote != "" {
+			id := fmt.Sprintf(linkFootnoteIDFormat, len(footnotes)+1)
+			fmt.Fprintf(&b, linkFootnoteRef, id, len(footnotes)+1)
+			footnotes = append(footnotes, fmt.Sprintf(linkFootnote, id, html.EscapeString(r.Footnote)))
+		}
+		last = end
+	}
+	if last == 0 {
+		return body
+	}
+	b.WriteString(body[last:])
+	for _, f := range footnotes {
+		b.WriteString(f + "\n")
+	}
+	return b.String()
+}
+
+// Report whether href is an http or https URL
+func isExternalLink(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// Replace the value of the href attribute of a start tag
+func setHrefAttr(tag string, href string) string {
+	m := hrefAttrRegexp.FindStringSubmatchIndex(tag)
+	if m == nil {
+		return tag
+	}
+	return tag[:m[4]] + `"` + html.EscapeString(href) + `"` + tag[m[5]:]
+}
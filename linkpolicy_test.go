@@ -0,0 +1,79 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRewriteExternalLinks(t *testing.T) {
+	body := `<p><a href="https://example.com/a?utm_source=x&amp;id=1" class="ext">first</a> <a href="#local">local</a> <a href='http://example.org/'><em>second</em></a></p>`
+	tests := []struct {
+		name     string
+		policy   ExternalLinkPolicy
+		expected string
+	}{
+		{
+			"utm",
+			UTMParameters{Source: "book", Medium: "epub"},
+			`<p><a href="https://example.com/a?id=1&amp;utm_medium=epub&amp;utm_source=x" class="ext">first</a> <a href="#local">local</a> <a href="http://example.org/?utm_medium=epub&amp;utm_source=book"><em>second</em></a></p>`,
+		},
+		{
+			"strip trackers",
+			StripTrackers{},
+			`<p><a href="https://example.com/a?id=1" class="ext">first</a> <a href="#local">local</a> <a href='http://example.org/'><em>second</em></a></p>`,
+		},
+		{
+			"footnotes",
+			ChainLinkPolicies(StripTrackers{}, LinkFootnotes{Unlink: true}),
+			`<p>first<sup><a epub:type="noteref" role="doc-noteref" href="#link-note-1">1</a></sup> <a href="#local">local</a> <em>second</em><sup><a epub:type="noteref" role="doc-noteref" href="#link-note-2">2</a></sup></p>` +
+				`<aside epub:type="footnote" role="doc-footnote" id="link-note-1"><p>https://example.com/a?id=1</p></aside>` + "\n" +
+				`<aside epub:type="footnote" role="doc-footnote" id="link-note-2"><p>http://example.org/</p></aside>` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rewriteExternalLinks("section.xhtml", body, test.policy); got != test.expected {
+				t.Errorf("Unexpected body:\n%s\nexpected:\n%s", got, test.expected)
+			}
+		})
+	}
+
+	noLinks := `<p>No <a href="other.xhtml">external</a> links</p>`
+	if got := rewriteExternalLinks("section.xhtml", noLinks, LinkFootnotes{}); got != noLinks {
+		t.Errorf("Expected the body to be left as is, got %s", got)
+	}
+}
+
+func TestSetExternalLinkPolicy(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sections []string
+	e.SetExternalLinkPolicy(ExternalLinkPolicyFunc(func(sectionFilename string, href string) ExternalLinkRewrite {
+		sections = append(sections, sectionFilename)
+		return ExternalLinkRewrite{Footnote: href}
+	}))
+	if _, err := e.AddSection(`<p><a href="https://example.com/">link</a></p>`, "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	section := readZipFiles(t, b.Bytes())["EPUB/xhtml/one.xhtml"]
+	if !strings.Contains(section, `<a href="https://example.com/">link</a><sup><a epub:type="noteref"`) ||
+		!strings.Contains(section, `id="link-note-1"><p>https://example.com/</p></aside>`) {
+		t.Errorf("Expected a footnote for the link, got:\n%s", section)
+	}
+	if len(sections) != 1 || sections[0] != "one.xhtml" {
+		t.Errorf("Unexpected sections passed to the policy: %v", sections)
+	}
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			t.Errorf("Unexpected broken link: %s", w)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package epub
+
+import (
+	"encoding/xml"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Check that the internal links and references of the written sections (href
+// and src attributes, including the links to the CSS files) resolve to a file
+// of the EPUB, and to an element of the section for links with a fragment, as
+// well as the text references of the media overlays, and that the ids of each
+// section are unique, reporting the ones that don't
+//
+// Must be called after the media and the sections have been written
+func (e *Epub) checkLinks(a *archive) {
+	// Targets by path relative to the content folder
+	targets := map[string]bool{tocNavFilename: true}
+	for folder, media := range map[string]map[string]string{
+		CSSFolderName:          e.css,
+		FontFolderName:         e.fonts,
+		ImageFolderName:        e.images,
+		VideoFolderName:        e.videos,
+		AudioFolderName:        e.audios,
+		ScriptFolderName:       e.scripts,
+		MediaOverlayFolderName: e.overlays,
+		FontLicenseFolderName:  e.fontLicenses,
+		CaptionsFolderName:     e.captions,
+	} {
+		for filename := range media {
+			if !e.skippedMedia[filename] {
+				targets[path.Join(folder, filename)] = true
+			}
+		}
+	}
+	for filePath := range e.files {
+		if !e.skippedMedia[filePath] {
+			targets[filePath] = true
+		}
+	}
+	ids := make(map[string]map[string][]int, len(a.sections))
+	for filename, doc := range a.sections {
+		targets[path.Join(xhtmlFolderName, filename)] = true
+		ids[filename] = elementIDs(doc)
+	}
+
+	filenames := make([]string, 0, len(a.sections))
+	for filename := range a.sections {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		e.checkDuplicateIDs(filename, ids[filename])
+		for _, l := range sectionLinks(a.sections[filename]) {
+			e.checkLink(filename, xhtmlFolderName, l, targets, ids)
+		}
+	}
+
+	// The text elements of the media overlays point to elements of the
+	// sections as well
+	overlays := make([]string, 0, len(a.overlays))
+	for filename := range a.overlays {
+		overlays = append(overlays, filename)
+	}
+	sort.Strings(overlays)
+	for _, filename := range overlays {
+		for _, l := range sectionLinks(string(a.overlays[filename])) {
+			e.checkLink(filename, MediaOverlayFolderName, l, targets, ids)
+		}
+	}
+}
+
+// Check a link of the file filename of the given folder, see checkLinks
+func (e *Epub) checkLink(filename string, folder string, l sectionLink, targets map[string]bool, ids map[string]map[string][]int) {
+	if l.ref == "" || strings.HasPrefix(l.ref, "data:") {
+		return
+	}
+	u, err := url.Parse(l.ref)
+	if err != nil {
+		e.report.add(SeverityWarning, RuleBrokenLink, filename, "line %d: invalid reference %q", l.line, l.ref)
+		return
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return
+	}
+	target := path.Join(folder, filename)
+	if u.Path != "" {
+		target = path.Join(folder, u.Path)
+		if !targets[target] {
+			e.report.add(SeverityWarning, RuleBrokenLink, filename, "line %d: %s doesn't resolve to a file of the EPUB", l.line, l.ref)
+			return
+		}
+	}
+	section := strings.TrimPrefix(target, xhtmlFolderName+"/")
+	if sectionIDs, ok := ids[section]; ok && u.Fragment != "" && len(sectionIDs[u.Fragment]) == 0 {
+		e.report.add(SeverityWarning, RuleBrokenLink, filename, "line %d: %s doesn't resolve to an element of %s", l.line, l.ref, section)
+	}
+}
+
+// A reference of a section to another file or element
+type sectionLink struct {
+	ref string
+	// Line of the reference in the XHTML document
+	line int
+}
+
+// Return the href and src attributes of an XHTML document
+func sectionLinks(doc string) []sectionLink {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	var links []sectionLink
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return links
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		line, _ := d.InputPos()
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "href" || attr.Name.Local == "src" {
+				links = append(links, sectionLink{ref: strings.TrimSpace(attr.Value), line: line})
+			}
+		}
+	}
+}
+
+// Report the ids used by several elements of a section: links and media
+// overlays with these ids as fragment may point to the wrong element
+func (e *Epub) checkDuplicateIDs(filename string, ids map[string][]int) {
+	var duplicates []string
+	for id, lines := range ids {
+		if len(lines) > 1 {
+			duplicates = append(duplicates, id)
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return ids[duplicates[i]][0] < ids[duplicates[j]][0]
+	})
+	for _, id := range duplicates {
+		lines := make([]string, len(ids[id]))
+		for i, line := range ids[id] {
+			lines[i] = strconv.Itoa(line)
+		}
+		e.report.add(SeverityWarning, RuleDuplicateID, filename, "id %q is used by several elements (lines %s)", id, strings.Join(lines, ", "))
+	}
+}
+
+// Return the ids of the elements of an XHTML document, with the lines of the
+// elements that have them
+func elementIDs(doc string) map[string][]int {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	ids := make(map[string][]int)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ids
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "id" && attr.Name.Space == "" {
+					line, _ := d.InputPos()
+					ids[attr.Value] = append(ids[attr.Value], line)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package epub
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestCheckLinks(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<h1 id="top">One</h1><p><a href="#missing">self</a></p>`, "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	body := `<p><img src="` + imagePath + `" alt=""/> <a href="one.xhtml#top">ok</a> <a href="https://example.com/x">external</a> <a href="#local">local</a></p>
+<p id="local"><a href="one.xhtml#nowhere">bad anchor</a></p>
+<p><img src="../images/missing.png" alt=""/><a href="three.xhtml">bad file</a> <a href="../nav.xhtml">nav</a></p>`
+	if _, err := e.AddSection(body, "Two", "two.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	var broken []string
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			broken = append(broken, w.Filename+": "+w.Message)
+		}
+	}
+	// Lines of the written XHTML files, whose body starts on line 8
+	expected := []string{
+		"one.xhtml: line 8: #missing doesn't resolve to an element of one.xhtml",
+		"two.xhtml: line 9: one.xhtml#nowhere doesn't resolve to an element of one.xhtml",
+		"two.xhtml: line 10: ../images/missing.png doesn't resolve to a file of the EPUB",
+		"two.xhtml: line 10: three.xhtml doesn't resolve to a file of the EPUB",
+	}
+	if strings.Join(broken, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected broken links:\n%s\nexpected:\n%s", strings.Join(broken, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCheckLinksIDs(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `<p id="p1">One</p>
+<p id="p2">Two</p>
+<p id="p1">Three</p>
+<p id="p1"><span id="p2">Four</span></p>`
+	sectionPath, err := e.AddSection(body, "Section", "section.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioPath, err := e.AddAudio(testAudioFromFileSource, testAudioFromFileFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// p3 isn't an element of the section
+	overlay := dataurl.EncodeBytes([]byte(fmt.Sprintf(testMediaOverlayTemplate, sectionPath, audioPath)))
+	if _, err := e.AddMediaOverlay(overlay, sectionPath, "section.smil"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	var issues []string
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleDuplicateID || w.Rule == RuleBrokenLink {
+			issues = append(issues, w.Filename+": "+w.Message)
+		}
+	}
+	expected := []string{
+		`section.xhtml: id "p1" is used by several elements (lines 8, 10, 11)`,
+		`section.xhtml: id "p2" is used by several elements (lines 9, 11)`,
+		"section.smil: line 7: ../xhtml/section.xhtml#p3 doesn't resolve to an element of section.xhtml",
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s\nexpected:\n%s", strings.Join(issues, "\n"), strings.Join(expected, "\n"))
+	}
+}
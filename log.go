@@ -0,0 +1,40 @@
+package epub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SetLogger sets the logger of the EPUB, which receives the issues found while
+// building and writing it (see ValidationReport) as warnings or errors, with
+// their rule and filename as attributes, and the retrievals of media sources
+// at the debug level. Setting a nil logger, the default, disables logging.
+func (e *Epub) SetLogger(logger *slog.Logger) {
+	e.Lock()
+	defer e.Unlock()
+	e.logger = logger
+	e.warnings.logger = logger
+}
+
+// Log an issue with the level of its severity, if the report has a logger
+func (r *ValidationReport) log(i ValidationIssue) {
+	if r.logger == nil {
+		return
+	}
+	level := slog.LevelWarn
+	if i.Severity == SeverityError {
+		level = slog.LevelError
+	}
+	args := []any{"rule", i.Rule}
+	if i.Filename != "" {
+		args = append(args, "filename", i.Filename)
+	}
+	r.logger.Log(context.Background(), level, i.Message, args...)
+}
+
+// Log a debug message, if logger isn't nil
+func logDebug(logger *slog.Logger, msg string, args ...any) {
+	if logger != nil {
+		logger.Debug(msg, args...)
+	}
+}
@@ -0,0 +1,59 @@
+package epub
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetLogger(logger)
+	e.SetLang("not a language")
+	if _, err := e.AddImage(testImageFromFileSource, testImageFromFileFilename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>\x07</p>", testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	// Logged as soon as it is found
+	if !strings.Contains(b.String(), "rule="+RuleSanitized) {
+		t.Errorf("Expected the sanitized section to be logged, got:\n%s", b.String())
+	}
+
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	log := b.String()
+	for _, s := range []string{
+		"level=WARN",
+		"rule=" + RuleInvalidLang,
+		"level=DEBUG msg=\"retrieving media\"",
+		"filename=" + testImageFromFileFilename,
+	} {
+		if !strings.Contains(log, s) {
+			t.Errorf("Expected the log to contain %s, got:\n%s", s, log)
+		}
+	}
+	if n := strings.Count(log, "rule="+RuleSanitized); n != 1 {
+		t.Errorf("Expected the sanitized section to be logged once, got %d times", n)
+	}
+
+	// No logger, no logs
+	b.Reset()
+	e.SetLogger(nil)
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("Expected nothing to be logged, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,125 @@
+package epub
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	sectionPropertyMathML          = "mathml"
+	sectionPropertyRemoteResources = "remote-resources"
+)
+
+// Attributes of the elements that refer to a resource rendered with the
+// section rather than to another document, by element
+var resourceElements = map[string]string{
+	"audio": "src", "embed": "src", "iframe": "src", "img": "src",
+	"input": "src", "link": "href", "object": "data", "script": "src",
+	"source": "src", "track": "src", "video": "src", "image": "href",
+	"use": "href",
+}
+
+// SetSectionProperties sets the properties of the manifest item of a section,
+// a space-separated list such as "scripted svg", e.g. for a section whose
+// scripts create MathML. By default the properties of the sections are
+// detected when the EPUB is written: mathml if a section contains MathML, svg
+// if it embeds SVG, scripted if it has scripts or forms and remote-resources
+// if it uses resources outside of the EPUB such as remote images, as EPUB 3
+// requires. Once set, the properties of the section are written as given and
+// aren't detected anymore.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection).
+func (e *Epub) SetSectionProperties(sectionFilename string, properties string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	section.properties = strings.Join(strings.Fields(properties), " ")
+	section.manualProperties = true
+	return nil
+}
+
+// Return the properties of the manifest item of a section, with the ones
+// detected in its document unless they have been set with
+// SetSectionProperties
+func sectionProperties(s *epubSection, doc string) string {
+	properties := s.properties
+	if s.manualProperties || doc == "" {
+		return properties
+	}
+	for _, p := range detectSectionProperties(doc) {
+		properties = addProperty(properties, p)
+	}
+	return properties
+}
+
+// Detect the manifest properties of an XHTML document: mathml, svg, scripted
+// and remote-resources, in this order
+//
+// Spec: https://www.w3.org/TR/epub-33/#app-item-properties-vocab
+func detectSectionProperties(doc string) []string {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	found := make(map[string]bool)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(start.Name.Local)
+		switch name {
+		case "math":
+			found[sectionPropertyMathML] = true
+		case "svg":
+			found[sectionPropertySVG] = true
+		case "script", "form":
+			found[sectionPropertyScripted] = true
+		}
+		attr, ok := resourceElements[name]
+		if !ok || name == "link" && !isStylesheetLink(start) {
+			continue
+		}
+		for _, a := range start.Attr {
+			if a.Name.Local == attr && isRemoteResource(a.Value) {
+				found[sectionPropertyRemoteResources] = true
+			}
+		}
+	}
+
+	var properties []string
+	for _, p := range []string{sectionPropertyMathML, sectionPropertySVG, sectionPropertyScripted, sectionPropertyRemoteResources} {
+		if found[p] {
+			properties = append(properties, p)
+		}
+	}
+	return properties
+}
+
+// Report whether a link element links to a stylesheet or preloads a resource,
+// such as a font, which are resources of the document unlike the links to
+// other documents
+func isStylesheetLink(start xml.StartElement) bool {
+	for _, a := range start.Attr {
+		if a.Name.Local == "rel" {
+			return hasProperty(strings.ToLower(a.Value), "stylesheet") || hasProperty(strings.ToLower(a.Value), "preload")
+		}
+	}
+	return false
+}
+
+// Report whether a reference points to a resource outside of the EPUB
+func isRemoteResource(ref string) bool {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "//")
+}
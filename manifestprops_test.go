@@ -0,0 +1,78 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectSectionProperties(t *testing.T) {
+	tests := []struct {
+		doc      string
+		expected []string
+	}{
+		{`<p>Text with a <a href="https://example.com">link</a></p>`, nil},
+		{`<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi></math>`, []string{"mathml"}},
+		{`<p><svg xmlns="http://www.w3.org/2000/svg"><circle r="1"/></svg></p>`, []string{"svg"}},
+		{`<script src="../scripts/app.js"></script><form></form>`, []string{"scripted"}},
+		{`<img src="https://example.com/image.png" alt=""/>`, []string{"remote-resources"}},
+		{`<img src="../images/image.png" alt=""/>`, nil},
+		{`<link rel="stylesheet" href="//example.com/style.css"/>`, []string{"remote-resources"}},
+		{`<link rel="alternate" href="https://example.com/"/>`, nil},
+		{`<svg><image xlink:href="http://example.com/a.png"/></svg><math></math>`, []string{"mathml", "svg", "remote-resources"}},
+	}
+	for _, test := range tests {
+		if got := detectSectionProperties(test.doc); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Expected %v for %s, got %v", test.expected, test.doc, got)
+		}
+	}
+}
+
+func TestSectionProperties(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mathSection, err := e.AddSection(`<p><math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi></math></p>`, "Math", "math.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteSection, err := e.AddSection(`<p><img src="https://example.com/image.png" alt=""/></p>`, "Remote", "remote.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p><math><mi>y</mi></math></p>`, "Manual", "manual.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionProperties("manual.xhtml", " scripted  mathml "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p><svg xmlns="http://www.w3.org/2000/svg"/></p>`, "None", "none.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionProperties("none.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var missing *SectionDoesNotExistError
+	if err := e.SetSectionProperties("missing.xhtml", "mathml"); !errors.As(err, &missing) {
+		t.Errorf("Expected a SectionDoesNotExistError, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`href="xhtml/` + mathSection + `" media-type="application/xhtml+xml" properties="mathml">`,
+		`href="xhtml/` + remoteSection + `" media-type="application/xhtml+xml" properties="remote-resources">`,
+		`href="xhtml/manual.xhtml" media-type="application/xhtml+xml" properties="scripted mathml">`,
+		`href="xhtml/none.xhtml" media-type="application/xhtml+xml">`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+}
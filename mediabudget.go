@@ -0,0 +1,93 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MediaSize is the size of a media file of the EPUB.
+type MediaSize struct {
+	Filename string // Internal path of the file, e.g. videos/video0001.mp4
+	Size     int64  // Size of the file in bytes, once transcoded
+}
+
+// MediaBudgetExceededError is thrown by Write and WriteTo if the images, audio
+// and video files of the EPUB are larger than the budget set with
+// SetMediaSizeBudget.
+type MediaBudgetExceededError struct {
+	Budget int64 // Size budget in bytes
+	Size   int64 // Total size of the media in bytes
+	// Largest media files, the ones that take the size over the budget, from
+	// the largest to the smallest
+	Assets []MediaSize
+}
+
+func (e *MediaBudgetExceededError) Error() string {
+	assets := make([]string, len(e.Assets))
+	for i, a := range e.Assets {
+		assets[i] = fmt.Sprintf("%s (%d bytes)", a.Filename, a.Size)
+	}
+	return fmt.Sprintf("Media size of %d bytes exceeds the budget of %d bytes, largest files: %s", e.Size, e.Budget, strings.Join(assets, ", "))
+}
+
+// SetMediaSizeBudget sets the maximum total size in bytes of the images, audio
+// and video files of the EPUB, once transcoded (see SetAudioTranscoder and
+// SetVideoTranscoder). If it is exceeded, Write and WriteTo fail with
+// MediaBudgetExceededError, except in lenient build mode where the largest
+// files are reported as warnings. A budget of 0 means no limit.
+func (e *Epub) SetMediaSizeBudget(budget int64) {
+	e.Lock()
+	defer e.Unlock()
+	e.mediaSizeBudget = budget
+}
+
+// Check that the media written to the archive don't exceed the size budget
+func (e *Epub) checkMediaBudget(a *archive) error {
+	if e.mediaSizeBudget <= 0 {
+		return nil
+	}
+	var sizes []MediaSize
+	total := int64(0)
+	for folder, media := range map[string]map[string]string{
+		ImageFolderName: e.images,
+		AudioFolderName: e.audios,
+		VideoFolderName: e.videos,
+	} {
+		for filename := range media {
+			if e.skippedMedia[filename] {
+				continue
+			}
+			mediaPath := path.Join(folder, filename)
+			size := a.sizes[path.Join(contentFolderName, mediaPath)]
+			sizes = append(sizes, MediaSize{Filename: mediaPath, Size: size})
+			total += size
+		}
+	}
+	if total <= e.mediaSizeBudget {
+		return nil
+	}
+
+	// Report the largest files, until leaving them out would fit the budget
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Size != sizes[j].Size {
+			return sizes[i].Size > sizes[j].Size
+		}
+		return sizes[i].Filename < sizes[j].Filename
+	})
+	excess := total - e.mediaSizeBudget
+	var assets []MediaSize
+	for _, s := range sizes {
+		if excess <= 0 {
+			break
+		}
+		assets = append(assets, s)
+		excess -= s.Size
+		e.report.add(SeverityWarning, RuleMediaBudget, s.Filename, "%d bytes out of a media size of %d bytes, over the budget of %d bytes", s.Size, total, e.mediaSizeBudget)
+	}
+	if e.buildMode == BuildModeLenient {
+		return nil
+	}
+	return &MediaBudgetExceededError{Budget: e.mediaSizeBudget, Size: total, Assets: assets}
+}
@@ -0,0 +1,56 @@
+package epub
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMediaSizeBudget(t *testing.T) {
+	newEpub := func(budget int64) *Epub {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetMediaSizeBudget(budget)
+		if _, err := e.AddImage(testImageFromFileSource, "image.png"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddVideo(testVideoFromFileSource, "video.mp4"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddAudio("testdata/sample_audio.wav", "audio.wav"); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	if _, err := newEpub(1 << 20).WriteTo(io.Discard); err != nil {
+		t.Errorf("Unexpected error within the budget: %v", err)
+	}
+
+	// Leaving the video out is enough to fit the budget
+	e := newEpub(10000)
+	_, err := e.WriteTo(io.Discard)
+	var exceeded *MediaBudgetExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Expected a MediaBudgetExceededError, got %v", err)
+	}
+	if exceeded.Budget != 10000 || len(exceeded.Assets) != 1 || exceeded.Assets[0].Filename != "videos/video.mp4" {
+		t.Errorf("Expected the video to be reported, got %+v", exceeded)
+	}
+
+	e.SetBuildMode(BuildModeLenient)
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatalf("Unexpected error in lenient mode: %v", err)
+	}
+	found := false
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleMediaBudget && w.Filename == "videos/video.mp4" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a media budget warning for the video, got %v", e.Warnings())
+	}
+}
@@ -0,0 +1,126 @@
+package epub
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// MediaCache keeps the content of retrieved media sources in memory, so that a
+// source used by several EPUBs (or written several times) is only retrieved
+// once. It is safe for concurrent use.
+//
+// Data URLs are never cached since their content is already in memory.
+type MediaCache struct {
+	mu         sync.Mutex
+	entries    map[string]mediaCacheEntry
+	revalidate bool
+	// The key is a source being retrieved, the channel is closed once it is
+	// done
+	pending map[string]chan struct{}
+}
+
+type mediaCacheEntry struct {
+	data []byte
+	// Validators of the response the data comes from, if it was retrieved
+	// from a URL
+	etag         string
+	lastModified string
+}
+
+// NewMediaCache returns a new, empty MediaCache.
+func NewMediaCache() *MediaCache {
+	return &MediaCache{
+		entries: make(map[string]mediaCacheEntry),
+		pending: make(map[string]chan struct{}),
+	}
+}
+
+// SetRevalidate enables or disables the revalidation of the cached remote
+// sources. When enabled, a source retrieved from a URL whose response had an
+// ETag or Last-Modified header is requested again with If-None-Match or
+// If-Modified-Since when the EPUB is written, so that a rebuild only downloads
+// the sources that changed.
+func (c *MediaCache) SetRevalidate(revalidate bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revalidate = revalidate
+}
+
+// SetMediaCache sets the cache used to retrieve the media sources of the EPUB
+// (CSS, fonts, images, etc.). Setting a nil cache disables caching.
+func (e *Epub) SetMediaCache(cache *MediaCache) {
+	e.Lock()
+	defer e.Unlock()
+	e.mediaCache = cache
+}
+
+// Return the cached content of a source. A nil cache never has anything.
+func (c *MediaCache) get(source string) ([]byte, bool) {
+	entry, ok := c.lookup(source)
+	return entry.data, ok
+}
+
+// Return the cache entry of a source
+func (c *MediaCache) lookup(source string) (mediaCacheEntry, bool) {
+	if c == nil {
+		return mediaCacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[source]
+	return entry, ok
+}
+
+// Report whether a cache entry must be revalidated before it is used
+func (c *MediaCache) needsRevalidation(entry mediaCacheEntry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.revalidate && (entry.etag != "" || entry.lastModified != "")
+}
+
+// Report whether the source can be cached
+func (c *MediaCache) accepts(source string) bool {
+	return c != nil && detectMediaType(source) != "DataURL"
+}
+
+// Wait until the source isn't being retrieved by another EPUB or goroutine,
+// then claim its retrieval, so that EPUBs built at the same time don't retrieve
+// the same source concurrently (see Batch). The returned function must be
+// called once the retrieval is done, whether it succeeded or not.
+func (c *MediaCache) claim(ctx context.Context, source string) (done func(), err error) {
+	for {
+		c.mu.Lock()
+		wait, ok := c.pending[source]
+		if !ok {
+			ch := make(chan struct{})
+			c.pending[source] = ch
+			c.mu.Unlock()
+			return func() {
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				delete(c.pending, source)
+				close(ch)
+			}, nil
+		}
+		c.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Cache the content of a source, with the validators of the response it comes
+// from if header isn't nil
+func (c *MediaCache) put(source string, data []byte, header http.Header) {
+	entry := mediaCacheEntry{data: data}
+	if header != nil {
+		entry.etag = header.Get("ETag")
+		entry.lastModified = header.Get("Last-Modified")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[source] = entry
+}
@@ -0,0 +1,173 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Start a test server for the testdata folder counting the GET requests
+func newCountingServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var gets int32
+	fs := http.FileServer(http.Dir("./testdata/"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &gets
+}
+
+func TestMediaCache(t *testing.T) {
+	server, gets := newCountingServer(t)
+	cache := NewMediaCache()
+
+	for i := 0; i < 2; i++ {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetMediaCache(cache)
+		if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.WriteTo(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(gets); n != 1 {
+		t.Errorf("Expected the image to be downloaded once, got %d downloads", n)
+	}
+}
+
+func TestMediaCacheDisabled(t *testing.T) {
+	server, gets := newCountingServer(t)
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := e.WriteTo(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(gets); n != 2 {
+		t.Errorf("Expected the image to be downloaded at each write, got %d downloads", n)
+	}
+}
+
+func TestMediaCacheRevalidate(t *testing.T) {
+	var mu sync.Mutex
+	content, etag := "body { color: red; }", `"v1"`
+	var downloads, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&downloads, 1)
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	cache := NewMediaCache()
+	cache.SetRevalidate(true)
+	build := func() string {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetMediaCache(cache)
+		if _, err := e.AddCSS(server.URL+"/style.css", "style.css"); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		return readZipFiles(t, b.Bytes())["EPUB/css/style.css"]
+	}
+
+	build()
+	if got := build(); got != content {
+		t.Errorf("Expected the cached content, got %q", got)
+	}
+	if downloads != 1 || notModified != 1 {
+		t.Errorf("Expected 1 download and 1 revalidation, got %d and %d", downloads, notModified)
+	}
+
+	mu.Lock()
+	content, etag = "body { color: blue; }", `"v2"`
+	mu.Unlock()
+	if got := build(); got != "body { color: blue; }" {
+		t.Errorf("Expected the new content, got %q", got)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected the changed source to be downloaded again, got %d downloads", downloads)
+	}
+}
+
+func TestMediaCacheRevalidateError(t *testing.T) {
+	var status int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "" {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("body { color: red; }"))
+			return
+		}
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte("error page"))
+	}))
+	defer server.Close()
+
+	cache := NewMediaCache()
+	cache.SetRevalidate(true)
+	build := func() (string, error) {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetMediaCache(cache)
+		if _, err := e.AddCSS(server.URL+"/style.css", "style.css"); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			return "", err
+		}
+		return readZipFiles(t, b.Bytes())["EPUB/css/style.css"], nil
+	}
+
+	if _, err := build(); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []int32{http.StatusBadRequest, http.StatusMultipleChoices, http.StatusNoContent} {
+		atomic.StoreInt32(&status, code)
+		var retrievalErr *FileRetrievalError
+		if _, err := build(); !errors.As(err, &retrievalErr) {
+			t.Errorf("Expected a FileRetrievalError for a %d response, got %v", code, err)
+		}
+	}
+	atomic.StoreInt32(&status, http.StatusNotModified)
+	if got, err := build(); err != nil || got != "body { color: red; }" {
+		t.Errorf("Expected the cached content to be kept, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,260 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mediaOverlayFileFormat    = "overlay%04d%s"
+	mediaTypeSmil             = "application/smil+xml"
+	pkgMediaDurationProperty  = "media:duration"
+	wavHeaderChunkID          = "RIFF"
+	wavFormatChunkID          = "fmt "
+	wavDataChunkID            = "data"
+	wavFormatChunkMinimumSize = 16
+)
+
+// AddMediaOverlay adds a SMIL media overlay to the EPUB and links it to an
+// existing section, so that reading systems can play the narration in sync
+// with the text. It returns a relative path to the overlay file.
+//
+// The overlay source should either be a URL, a path to a local file, or an
+// embedded data URL. The audio files it references must be added with
+// AddAudio and referenced relative to the overlay, e.g. ../audios/audio.mp3.
+//
+// The internal filename follows the same rules as AddAudio.
+//
+// When the EPUB is written, the duration of each overlay is computed from its
+// audio clips and written to the package file, along with the total duration,
+// as required by the Media Overlays specification. The clips without clipEnd
+// play until the end of their audio file, whose duration is only known for WAV
+// files: for the other files, the duration of the overlay and the total
+// duration are left out and reported (RuleMediaDuration).
+func (e *Epub) AddMediaOverlay(source string, sectionFilename string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.addMediaOverlay(source, sectionFilename, internalFilename)
+}
+
+func (e *Epub) addMediaOverlay(source string, sectionFilename string, internalFilename string) (string, error) {
+	if _, ok := e.findSection(sectionFilename); !ok {
+		return "", &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	overlayPath, err := addMedia(e.grabber(), source, internalFilename, mediaOverlayFileFormat, MediaOverlayFolderName, e.overlays)
+	if err != nil {
+		return "", err
+	}
+	e.sectionOverlays[sectionFilename] = filepath.Base(overlayPath)
+	return overlayPath, nil
+}
+
+// Get media overlays from their source, write them to the archive and add
+// their durations to the package file
+func (e *Epub) writeMediaOverlays(a *archive) error {
+	if len(e.overlays) == 0 {
+		return nil
+	}
+	a.overlays = make(map[string][]byte, len(e.overlays))
+	err := e.writeMedia(a, e.overlays, MediaOverlayFolderName)
+	if err != nil {
+		return err
+	}
+
+	var total time.Duration
+	totalKnown := true
+	durations := make(map[string]string, len(e.overlays))
+	for _, filename := range sortedKeys(e.overlays) {
+		if e.skippedMedia[filename] {
+			continue
+		}
+		d, err := smilDuration(a.overlays[filename], path.Join(MediaOverlayFolderName, filename), a.wavDurations)
+		var unknown *unknownDurationError
+		if errors.As(err, &unknown) {
+			e.report.add(SeverityWarning, RuleMediaDuration, filename, "%v, the duration of the media overlay is left out", err)
+			totalKnown = false
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to compute duration of media overlay %s: %w", filename, err)
+		}
+		total += d
+		id, err := e.mediaID(filename)
+		if err != nil {
+			return fmt.Errorf("error creating xml id: %w", err)
+		}
+		durations[id] = formatClockValue(d)
+	}
+	if !totalKnown {
+		e.pkg.setMediaDurations("", durations)
+		return nil
+	}
+	e.pkg.setMediaDurations(formatClockValue(total), durations)
+	return nil
+}
+
+// Error of smilDuration when an audio clip plays until the end of a file whose
+// duration is unknown
+type unknownDurationError struct {
+	filename string
+}
+
+func (e *unknownDurationError) Error() string {
+	return fmt.Sprintf("unable to determine the duration of %s: clipEnd is required for audio files other than WAV", e.filename)
+}
+
+type smilAudio struct {
+	Src       string `xml:"src,attr"`
+	ClipBegin string `xml:"clipBegin,attr"`
+	ClipEnd   string `xml:"clipEnd,attr"`
+}
+
+// Compute the duration of a SMIL file by adding up the durations of its audio
+// clips. The overlay path is relative to the content folder, as are the paths
+// of the WAV files whose durations are given. unknownDurationError is returned
+// if a clip without clipEnd plays another file.
+func smilDuration(overlay []byte, overlayPath string, wavDurations map[string]time.Duration) (time.Duration, error) {
+	var total time.Duration
+	d := xml.NewDecoder(bytes.NewReader(overlay))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "audio" {
+			continue
+		}
+		var a smilAudio
+		if err := d.DecodeElement(&a, &start); err != nil {
+			return 0, err
+		}
+
+		var begin, end time.Duration
+		if a.ClipBegin != "" {
+			if begin, err = parseClockValue(a.ClipBegin); err != nil {
+				return 0, err
+			}
+		}
+		if a.ClipEnd != "" {
+			end, err = parseClockValue(a.ClipEnd)
+		} else {
+			// The clip plays until the end of the audio file
+			audioPath := path.Join(path.Dir(overlayPath), a.Src)
+			var ok bool
+			if end, ok = wavDurations[audioPath]; !ok {
+				err = &unknownDurationError{filename: path.Base(audioPath)}
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if end > begin {
+			total += end - begin
+		}
+	}
+	return total, nil
+}
+
+// Parse a SMIL clock value: full (01:02:03.5) or partial (02:03.5) clock
+// values, or timecount values (3.5s, 200ms, 2min, 1.5h, or a plain number of
+// seconds).
+func parseClockValue(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if strings.Contains(v, ":") {
+		parts := strings.Split(v, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid clock value %q", v)
+		}
+		var seconds float64
+		for _, p := range parts {
+			n, err := strconv.ParseFloat(p, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid clock value %q", v)
+			}
+			seconds = seconds*60 + n
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	unit := time.Second
+	for _, u := range []struct {
+		suffix   string
+		duration time.Duration
+	}{
+		{"ms", time.Millisecond},
+		{"min", time.Minute},
+		{"h", time.Hour},
+		{"s", time.Second},
+	} {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSuffix(v, u.suffix)
+			unit = u.duration
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid clock value %q", v)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// Format a duration as a full SMIL clock value, e.g. 0:01:02.500
+func formatClockValue(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// Compute the duration of a WAV file from its header
+func wavDuration(f io.Reader, filename string) (time.Duration, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, fmt.Errorf("unable to read audio file header: %w", err)
+	}
+	if string(header[:4]) != wavHeaderChunkID {
+		return 0, fmt.Errorf("unable to determine the duration of %s: clipEnd is required for audio files other than WAV", filename)
+	}
+
+	var byteRate uint32
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, chunk); err != nil {
+			return 0, fmt.Errorf("unable to read audio file: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		switch string(chunk[:4]) {
+		case wavFormatChunkID:
+			if size < wavFormatChunkMinimumSize {
+				return 0, fmt.Errorf("invalid WAV format chunk")
+			}
+			format := make([]byte, size+size%2)
+			if _, err := io.ReadFull(f, format); err != nil {
+				return 0, fmt.Errorf("unable to read audio file: %w", err)
+			}
+			byteRate = binary.LittleEndian.Uint32(format[8:12])
+		case wavDataChunkID:
+			if byteRate == 0 {
+				return 0, fmt.Errorf("invalid WAV file: missing format chunk")
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), nil
+		default:
+			// Chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, f, int64(size+size%2)); err != nil {
+				return 0, fmt.Errorf("unable to read audio file: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/go-epub/internal/storage"
+	"github.com/vincent-petithory/dataurl"
+)
+
+const testMediaOverlayTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<smil xmlns="http://www.w3.org/ns/SMIL" xmlns:epub="http://www.idpf.org/2007/ops" version="3.0">
+  <body>
+    <seq epub:textref="../xhtml/%[1]s">
+      <par><text src="../xhtml/%[1]s#p1"/><audio src="%[2]s" clipBegin="0:00:01.500" clipEnd="0:00:03.000"/></par>
+      <par><text src="../xhtml/%[1]s#p2"/><audio src="%[2]s" clipBegin="3s" clipEnd="3500ms"/></par>
+      <par><text src="../xhtml/%[1]s#p3"/><audio src="%[2]s"/></par>
+    </seq>
+  </body>
+</smil>`
+
+func TestAddMediaOverlay(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioPath, err := e.AddAudio(testAudioFromFileSource, testAudioFromFileFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := dataurl.EncodeBytes([]byte(fmt.Sprintf(testMediaOverlayTemplate, sectionPath, audioPath)))
+	if _, err := e.AddMediaOverlay(overlay, sectionPath, "section.smil"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddMediaOverlay(overlay, "doesnotexist.xhtml", ""); err == nil {
+		t.Error("Expected an error when adding an overlay to a section that doesn't exist")
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+	defer cleanup(testEpubFilename, tempDir)
+
+	audio, err := filesystem.Open(filepath.Join(tempDir, contentFolderName, AudioFolderName, testAudioFromFileFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audio.Close()
+	wav, err := wavDuration(audio, testAudioFromFileFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first two clips last 2 seconds, the last one plays the whole file
+	expected := formatClockValue(2*time.Second + wav)
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, pkgFilename))
+	if err != nil {
+		t.Fatalf("Unexpected error reading package file: %s", err)
+	}
+	overlayID, _ := fixXMLId("section.smil")
+	for _, s := range []string{
+		fmt.Sprintf(`<meta property="media:duration">%s</meta>`, expected),
+		fmt.Sprintf(`<meta refines="#%s" property="media:duration">%s</meta>`, overlayID, expected),
+		`href="overlays/section.smil" media-type="application/smil+xml"`,
+		fmt.Sprintf(`href="xhtml/%s" media-type="application/xhtml+xml" media-overlay="%s"`, sectionPath, overlayID),
+	} {
+		if !strings.Contains(string(contents), s) {
+			t.Errorf("Package file doesn't contain %s\nGot: %s", s, contents)
+		}
+	}
+}
+
+func TestMediaOverlayUnknownDuration(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The duration of an MP3 file isn't known, so the last clip can't be
+	// measured
+	audioPath, err := e.AddAudio(dataurl.New([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), "audio/mpeg").String(), "narration.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := dataurl.EncodeBytes([]byte(fmt.Sprintf(testMediaOverlayTemplate, sectionPath, audioPath)))
+	if _, err := e.AddMediaOverlay(overlay, sectionPath, "section.smil"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]; strings.Contains(opf, "media:duration") {
+		t.Errorf("Expected no duration in the package file, got:\n%s", opf)
+	}
+	if w := e.Warnings(); len(w) == 0 || w[0].Rule != RuleMediaDuration || w[0].Filename != "section.smil" {
+		t.Errorf("Expected a media-duration warning for section.smil, got %v", w)
+	}
+}
+
+func TestParseClockValue(t *testing.T) {
+	for v, expected := range map[string]time.Duration{
+		"02:30:03.5": 2*time.Hour + 30*time.Minute + 3500*time.Millisecond,
+		"00:03":      3 * time.Second,
+		"1.5h":       90 * time.Minute,
+		"2min":       2 * time.Minute,
+		"12.25s":     12250 * time.Millisecond,
+		"200ms":      200 * time.Millisecond,
+		"7":          7 * time.Second,
+	} {
+		d, err := parseClockValue(v)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %s", v, err)
+		}
+		if d != expected {
+			t.Errorf("Expected %s to be %s, got %s", v, expected, d)
+		}
+	}
+	if _, err := parseClockValue("soon"); err == nil {
+		t.Error("Expected an error for an invalid clock value")
+	}
+}
@@ -0,0 +1,92 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+)
+
+// MediaTypePolicy defines how images and audio files whose format isn't one of
+// the EPUB core media types are handled when the EPUB is written. Such
+// resources need a fallback that go-epub doesn't provide, so they make the
+// EPUB invalid.
+//
+// Spec: https://www.w3.org/TR/epub-33/#sec-core-media-types
+type MediaTypePolicy int
+
+const (
+	// MediaTypesAny writes images and audio files of any format
+	MediaTypesAny MediaTypePolicy = iota
+	// MediaTypesCoreOnly makes the write fail with ForeignMediaTypeError if
+	// an image or audio file isn't a core media type
+	MediaTypesCoreOnly
+	// MediaTypesConvert converts the images that aren't a core media type to
+	// PNG, provided their format can be decoded with the image package (the
+	// decoders of other formats, such as golang.org/x/image/bmp, must be
+	// registered by importing them). Images that can't be decoded and audio
+	// files that aren't a core media type are rejected like with
+	// MediaTypesCoreOnly; audio files can be converted with an
+	// AudioTranscoder instead.
+	MediaTypesConvert
+)
+
+// Core media types of images and audio files, as detected by the mimetype
+// package
+var coreMediaTypes = map[string]bool{
+	"image/gif":     true,
+	"image/jpeg":    true,
+	"image/png":     true,
+	"image/svg+xml": true,
+	"image/webp":    true,
+	"audio/mpeg":    true,
+	"audio/mp4":     true,
+	"audio/x-m4a":   true,
+	"audio/ogg":     true,
+}
+
+// ForeignMediaTypeError is thrown by Write and WriteTo if an image or audio
+// file isn't a core media type and the media type policy doesn't allow it.
+// See SetMediaTypePolicy.
+type ForeignMediaTypeError struct {
+	Filename  string // Internal filename of the file
+	MediaType string // Detected media type of the file
+}
+
+func (e *ForeignMediaTypeError) Error() string {
+	return fmt.Sprintf("%s is %s, which isn't an EPUB core media type", e.Filename, e.MediaType)
+}
+
+// SetMediaTypePolicy sets how images and audio files whose format isn't one
+// of the EPUB core media types are handled when the EPUB is written. See
+// MediaTypePolicy.
+func (e *Epub) SetMediaTypePolicy(policy MediaTypePolicy) {
+	e.Lock()
+	defer e.Unlock()
+	e.mediaTypePolicy = policy
+}
+
+// Apply the media type policy to the content of a media file and return it
+// with its media type, which both change if the file is converted
+func (e *Epub) enforceMediaTypePolicy(mediaFolderName string, mediaFilename string, mediaType string, data []byte) ([]byte, string, error) {
+	if e.mediaTypePolicy == MediaTypesAny || coreMediaTypes[mediaType] ||
+		mediaFolderName != ImageFolderName && mediaFolderName != AudioFolderName {
+		return data, mediaType, nil
+	}
+	if e.mediaTypePolicy == MediaTypesConvert && mediaFolderName == ImageFolderName {
+		if converted, err := convertToPNG(data); err == nil {
+			e.report.add(SeverityWarning, RuleConvertedMedia, mediaFilename, "converted from %s to PNG", mediaType)
+			return converted, "image/png", nil
+		}
+	}
+	return nil, "", &ForeignMediaTypeError{Filename: mediaFilename, MediaType: mediaType}
+}
+
+// Convert an image to PNG
+func convertToPNG(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	converted, _, err := encodeImage(img, "png")
+	return converted, err
+}
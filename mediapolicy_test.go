@@ -0,0 +1,94 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Magic of a fake image format registered for the tests
+const testFakeImageMagic = "FAKEIMG"
+
+func init() {
+	image.RegisterFormat("fakeimg", testFakeImageMagic, func(r io.Reader) (image.Image, error) {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		img.Set(0, 0, color.RGBA{R: 255, A: 255})
+		return img, nil
+	}, func(r io.Reader) (image.Config, error) {
+		return image.Config{ColorModel: color.RGBAModel, Width: 2, Height: 2}, nil
+	})
+}
+
+func TestMediaTypePolicy(t *testing.T) {
+	newEpub := func(policy MediaTypePolicy) *Epub {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetMediaTypePolicy(policy)
+		if _, err := e.AddImage(testImageFromFileSource, "core.png"); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	t.Run("Any", func(t *testing.T) {
+		e := newEpub(MediaTypesAny)
+		if _, err := e.AddAudio("testdata/sample_audio.wav", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.WriteTo(io.Discard); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("CoreOnly", func(t *testing.T) {
+		e := newEpub(MediaTypesCoreOnly)
+		if _, err := e.WriteTo(io.Discard); err != nil {
+			t.Fatalf("Unexpected error for core media types: %v", err)
+		}
+		if _, err := e.AddAudio("testdata/sample_audio.wav", "audio.wav"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := e.WriteTo(io.Discard)
+		var foreign *ForeignMediaTypeError
+		if !errors.As(err, &foreign) || foreign.Filename != "audio.wav" {
+			t.Errorf("Expected a ForeignMediaTypeError for audio.wav, got %v", err)
+		}
+	})
+	t.Run("Convert", func(t *testing.T) {
+		e := newEpub(MediaTypesConvert)
+		source := dataurl.New([]byte(testFakeImageMagic+"data"), "application/octet-stream").String()
+		if _, err := e.AddImage(source, "fake.img"); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		files := readZipFiles(t, b.Bytes())
+		if _, format, err := image.DecodeConfig(strings.NewReader(files["EPUB/images/fake.img"])); err != nil || format != "png" {
+			t.Errorf("Expected the image to be converted to PNG, got %q (%v)", format, err)
+		}
+		if !strings.Contains(files["EPUB/package.opf"], `href="images/fake.img" media-type="image/png"`) {
+			t.Error("Expected the converted image to be listed as image/png")
+		}
+		if w := e.Warnings(); len(w) == 0 || w[len(w)-1].Rule != RuleConvertedMedia {
+			t.Errorf("Expected a converted-media warning, got %v", w)
+		}
+
+		// Audio files can't be converted
+		if _, err := e.AddAudio("testdata/sample_audio.wav", ""); err != nil {
+			t.Fatal(err)
+		}
+		var foreign *ForeignMediaTypeError
+		if _, err := e.WriteTo(io.Discard); !errors.As(err, &foreign) {
+			t.Errorf("Expected a ForeignMediaTypeError for the audio file, got %v", err)
+		}
+	})
+}
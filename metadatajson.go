@@ -0,0 +1,221 @@
+package epub
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	opdsBookType       = "http://schema.org/Book"
+	opdsAcquisitionRel = "http://opds-spec.org/acquisition"
+	opdsEPUBMediaType  = "application/epub+zip"
+)
+
+// Roles of the Readium Web Publication Manifest used by OPDS 2.0, by MARC
+// relator code; other roles are listed as contributors
+var opdsContributorRoles = map[string]string{
+	ContributorRoleAuthor:      "author",
+	ContributorRoleEditor:      "editor",
+	ContributorRoleIllustrator: "illustrator",
+	ContributorRoleNarrator:    "narrator",
+	ContributorRoleTranslator:  "translator",
+	"art":                      "artist",
+}
+
+// PublicationMetadata is the metadata of the EPUB, as returned by
+// MetadataJSON.
+type PublicationMetadata struct {
+	// Unique identifier of the EPUB
+	Identifier string `json:"identifier"`
+	// All the identifiers of the EPUB, starting with the unique identifier
+	Identifiers  []Identifier  `json:"identifiers"`
+	Title        string        `json:"title"`
+	Subtitle     string        `json:"subtitle,omitempty"`
+	Author       string        `json:"author,omitempty"`
+	Contributors []Contributor `json:"contributors,omitempty"`
+	// The main language first
+	Languages   []string `json:"languages"`
+	Description string   `json:"description,omitempty"`
+	Publisher   string   `json:"publisher,omitempty"`
+	Rights      string   `json:"rights,omitempty"`
+	// Publication date, see SetDate
+	Date string `json:"date,omitempty"`
+	// Modification date in RFC 3339 format, if set with SetModified
+	Modified       string                 `json:"modified,omitempty"`
+	Subjects       []Subject              `json:"subjects,omitempty"`
+	Series         string                 `json:"series,omitempty"`
+	SeriesPosition float64                `json:"seriesPosition,omitempty"`
+	Accessibility  *AccessibilityMetadata `json:"accessibility,omitempty"`
+	// Audience metadata, see SetTypicalAgeRange, SetAudience and
+	// SetEducationalMetadata
+	TypicalAgeRange string               `json:"typicalAgeRange,omitempty"`
+	Audience        []string             `json:"audience,omitempty"`
+	Education       *EducationalMetadata `json:"education,omitempty"`
+}
+
+// MetadataJSON returns the metadata of the EPUB (see PublicationMetadata) as
+// JSON, so that catalog systems can index the books without reading their
+// package file.
+func (e *Epub) MetadataJSON() ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+	b, err := json.MarshalIndent(e.publicationMetadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to write metadata: %w", err)
+	}
+	return b, nil
+}
+
+func (e *Epub) publicationMetadata() PublicationMetadata {
+	m := PublicationMetadata{
+		Identifier:      e.identifier,
+		Identifiers:     e.identifiers(),
+		Title:           e.title,
+		Subtitle:        e.Subtitle(),
+		Author:          e.author,
+		Contributors:    e.contributors,
+		Languages:       e.langs(),
+		Description:     e.desc,
+		Publisher:       e.publisher,
+		Rights:          e.rights,
+		Date:            e.date,
+		Subjects:        e.subjects,
+		Series:          e.seriesName,
+		SeriesPosition:  e.seriesPosition,
+		TypicalAgeRange: e.typicalAgeRange,
+		Audience:        e.audiences,
+	}
+	if !e.modified.IsZero() {
+		m.Modified = e.modified.UTC().Format(time.RFC3339)
+	}
+	if a11y := e.a11y; len(a11y.AccessModes) > 0 || len(a11y.AccessModesSufficient) > 0 || len(a11y.Features) > 0 ||
+		len(a11y.Hazards) > 0 || a11y.Summary != "" || a11y.ConformsTo != "" || a11y.CertifiedBy != "" {
+		m.Accessibility = &a11y
+	}
+	if edu := e.education; len(edu.EducationalUses) > 0 || len(edu.LearningResourceTypes) > 0 ||
+		edu.InteractivityType != "" || edu.TimeRequired != "" || len(edu.Alignments) > 0 {
+		m.Education = &edu
+	}
+	return m
+}
+
+// An OPDS 2.0 publication
+type opdsPublication struct {
+	Metadata opdsMetadata `json:"metadata"`
+	Links    []opdsLink   `json:"links"`
+	Images   []opdsLink   `json:"images,omitempty"`
+}
+
+type opdsMetadata struct {
+	Type        string         `json:"@type"`
+	Identifier  string         `json:"identifier"`
+	Title       string         `json:"title"`
+	Subtitle    string         `json:"subtitle,omitempty"`
+	Author      []opdsContrib  `json:"author,omitempty"`
+	Translator  []opdsContrib  `json:"translator,omitempty"`
+	Editor      []opdsContrib  `json:"editor,omitempty"`
+	Artist      []opdsContrib  `json:"artist,omitempty"`
+	Illustrator []opdsContrib  `json:"illustrator,omitempty"`
+	Narrator    []opdsContrib  `json:"narrator,omitempty"`
+	Contributor []opdsContrib  `json:"contributor,omitempty"`
+	Publisher   string         `json:"publisher,omitempty"`
+	Language    []string       `json:"language"`
+	Description string         `json:"description,omitempty"`
+	Published   string         `json:"published,omitempty"`
+	Modified    string         `json:"modified,omitempty"`
+	Subject     []opdsSubject  `json:"subject,omitempty"`
+	BelongsTo   *opdsBelongsTo `json:"belongsTo,omitempty"`
+}
+
+type opdsBelongsTo struct {
+	Series []opdsSeries `json:"series"`
+}
+
+type opdsContrib struct {
+	Name string `json:"name"`
+	// MARC relator code of the contributors without a role of their own
+	Role string `json:"role,omitempty"`
+}
+
+type opdsSubject struct {
+	Name   string `json:"name"`
+	Scheme string `json:"scheme,omitempty"`
+	Code   string `json:"code,omitempty"`
+}
+
+type opdsSeries struct {
+	Name     string  `json:"name"`
+	Position float64 `json:"position,omitempty"`
+}
+
+type opdsLink struct {
+	Rel  string `json:"rel,omitempty"`
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+// OPDSPublication returns the metadata of the EPUB as an OPDS 2.0 publication,
+// to be listed in the publications of an OPDS feed. acquisitionHref is the
+// URL the EPUB is downloaded from, and coverHref the URL of its cover image
+// if it is published, or "".
+func (e *Epub) OPDSPublication(acquisitionHref string, coverHref string) ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	m := e.publicationMetadata()
+	pub := opdsPublication{
+		Metadata: opdsMetadata{
+			Type:        opdsBookType,
+			Identifier:  m.Identifier,
+			Title:       m.Title,
+			Subtitle:    m.Subtitle,
+			Publisher:   m.Publisher,
+			Language:    m.Languages,
+			Description: m.Description,
+			Published:   m.Date,
+			Modified:    m.Modified,
+		},
+		Links: []opdsLink{{Rel: opdsAcquisitionRel, Href: acquisitionHref, Type: opdsEPUBMediaType}},
+	}
+	// The unique identifier is a URI, like the UUIDs generated by NewEpub
+	if strings.EqualFold(e.identifierScheme, IdentifierISBN) && !strings.HasPrefix(strings.ToLower(m.Identifier), urnISBNPrefix) {
+		pub.Metadata.Identifier = urnISBNPrefix + m.Identifier
+	}
+	if m.Author != "" {
+		pub.Metadata.Author = []opdsContrib{{Name: m.Author}}
+	}
+	contributors := map[string]*[]opdsContrib{
+		"author":      &pub.Metadata.Author,
+		"translator":  &pub.Metadata.Translator,
+		"editor":      &pub.Metadata.Editor,
+		"artist":      &pub.Metadata.Artist,
+		"illustrator": &pub.Metadata.Illustrator,
+		"narrator":    &pub.Metadata.Narrator,
+	}
+	for _, c := range m.Contributors {
+		if role, ok := opdsContributorRoles[c.Role]; ok {
+			*contributors[role] = append(*contributors[role], opdsContrib{Name: c.Name})
+		} else {
+			pub.Metadata.Contributor = append(pub.Metadata.Contributor, opdsContrib{Name: c.Name, Role: c.Role})
+		}
+	}
+	for _, s := range m.Subjects {
+		pub.Metadata.Subject = append(pub.Metadata.Subject, opdsSubject{Name: s.Term, Scheme: s.Authority, Code: s.Code})
+	}
+	if m.Series != "" {
+		pub.Metadata.BelongsTo = &opdsBelongsTo{Series: []opdsSeries{{Name: m.Series, Position: m.SeriesPosition}}}
+	}
+	if coverHref != "" {
+		pub.Images = []opdsLink{{Href: coverHref, Type: mime.TypeByExtension(path.Ext(e.cover.imageFilename))}}
+	}
+
+	b, err := json.MarshalIndent(pub, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to write OPDS publication: %w", err)
+	}
+	return b, nil
+}
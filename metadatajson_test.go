@@ -0,0 +1,110 @@
+package epub
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testCatalogEpub(t *testing.T) *Epub {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetIdentifier("9780000000002")
+	e.AddIdentifier("9780000000002", IdentifierISBN)
+	e.SetSubtitle("A Tale")
+	e.SetAuthor("Ann Author")
+	e.AddContributor("Tom Translator", ContributorRoleTranslator)
+	e.AddContributor("Carl Cover", "cov")
+	e.AddLang("fr")
+	e.SetDescription("A dragon.")
+	e.SetPublisher("Publisher")
+	e.SetDate("2024-05-01")
+	e.SetModified(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	e.AddCodedSubject("FICTION / Fantasy / Epic", SubjectAuthorityBISAC, "FIC009020")
+	e.SetSeries("Dragons", 2)
+	e.SetAccessibility(AccessibilityMetadata{Summary: "Accessible"})
+	imagePath, err := e.AddImage(testImageFromFileSource, "cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCover(imagePath, ""); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestMetadataJSON(t *testing.T) {
+	e := testCatalogEpub(t)
+	b, err := e.MetadataJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m PublicationMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := PublicationMetadata{
+		Identifier:  "9780000000002",
+		Identifiers: []Identifier{{Value: "9780000000002", Scheme: IdentifierISBN}},
+		Title:       testEpubTitle,
+		Subtitle:    "A Tale",
+		Author:      "Ann Author",
+		Contributors: []Contributor{
+			{Name: "Tom Translator", Role: ContributorRoleTranslator},
+			{Name: "Carl Cover", Role: "cov"},
+		},
+		Languages:      []string{"en", "fr"},
+		Description:    "A dragon.",
+		Publisher:      "Publisher",
+		Date:           "2024-05-01",
+		Modified:       "2024-06-01T12:00:00Z",
+		Subjects:       []Subject{{Term: "FICTION / Fantasy / Epic", Authority: SubjectAuthorityBISAC, Code: "FIC009020"}},
+		Series:         "Dragons",
+		SeriesPosition: 2,
+		Accessibility:  &AccessibilityMetadata{Summary: "Accessible"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected metadata:\n%s", b)
+	}
+}
+
+func TestOPDSPublication(t *testing.T) {
+	e := testCatalogEpub(t)
+	b, err := e.OPDSPublication("https://example.com/book.epub", "https://example.com/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pub map[string]interface{}
+	if err := json.Unmarshal(b, &pub); err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+  "metadata": {
+    "@type": "http://schema.org/Book",
+    "identifier": "urn:isbn:9780000000002",
+    "title": "My title",
+    "subtitle": "A Tale",
+    "author": [{"name": "Ann Author"}],
+    "translator": [{"name": "Tom Translator"}],
+    "contributor": [{"name": "Carl Cover", "role": "cov"}],
+    "publisher": "Publisher",
+    "language": ["en", "fr"],
+    "description": "A dragon.",
+    "published": "2024-05-01",
+    "modified": "2024-06-01T12:00:00Z",
+    "subject": [{"name": "FICTION / Fantasy / Epic", "scheme": "BISAC", "code": "FIC009020"}],
+    "belongsTo": {"series": [{"name": "Dragons", "position": 2}]}
+  },
+  "links": [{"rel": "http://opds-spec.org/acquisition", "href": "https://example.com/book.epub", "type": "application/epub+zip"}],
+  "images": [{"href": "https://example.com/cover.png", "type": "image/png"}]
+}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pub, expected) {
+		t.Errorf("Unexpected OPDS publication:\n%s", b)
+	}
+}
@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	placeholderSectionFilename = "placeholder.xhtml"
+	placeholderSectionClass    = "placeholder"
+)
+
+// SetMetadataOnly enables or disables the metadata-only mode, used to produce
+// minimal EPUBs before the content is available (e.g. for pre-orders). In
+// metadata-only mode, if the EPUB has no sections when it is written, a single
+// placeholder section showing the title, author and description of the EPUB is
+// generated so that the EPUB is still valid. The placeholder isn't added to
+// the sections of the EPUB.
+//
+// A cover isn't required in this mode, or in any other.
+func (e *Epub) SetMetadataOnly(metadataOnly bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.metadataOnly = metadataOnly
+}
+
+// Return whether a placeholder section is written in place of the sections
+func (e *Epub) needsPlaceholder() bool {
+	return e.metadataOnly && len(e.sections) == 0
+}
+
+// Generate the placeholder section of a metadata-only EPUB
+func (e *Epub) placeholderSection() (*epubSection, error) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "<section class=\"%s\">\n", placeholderSectionClass)
+	fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(e.title))
+	if e.author != "" {
+		fmt.Fprintf(&body, "<p>%s</p>\n", html.EscapeString(e.author))
+	}
+	body.WriteString(textToParagraphs(e.desc))
+	body.WriteString("</section>")
+
+	x, err := newXhtml(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("Error creating placeholder section: %w", err)
+	}
+	x.setTitle(e.title)
+	return &epubSection{
+		filename: placeholderSectionFilename,
+		xhtml:    x,
+	}, nil
+}
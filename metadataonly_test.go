@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetMetadataOnly(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAuthor(testEpubAuthor)
+	e.SetDescription("Coming soon & more")
+	e.SetMetadataOnly(true)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	placeholder, ok := files["EPUB/xhtml/"+placeholderSectionFilename]
+	if !ok {
+		t.Fatal("The placeholder section is missing")
+	}
+	for _, expected := range []string{testEpubTitle, testEpubAuthor, "Coming soon &amp; more"} {
+		if !strings.Contains(placeholder, expected) {
+			t.Errorf("The placeholder section doesn't contain %s\n%s", expected, placeholder)
+		}
+	}
+	if !strings.Contains(files["EPUB/package.opf"], `<itemref idref="placeholder.xhtml">`) {
+		t.Errorf("The placeholder section isn't in the spine\n%s", files["EPUB/package.opf"])
+	}
+	if !strings.Contains(files["EPUB/nav.xhtml"], "xhtml/placeholder.xhtml") {
+		t.Errorf("The placeholder section isn't in the TOC\n%s", files["EPUB/nav.xhtml"])
+	}
+	if len(e.sections) != 0 {
+		t.Error("The placeholder section shouldn't be added to the EPUB")
+	}
+
+	// Once there is content, the placeholder isn't needed anymore
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readZipFiles(t, b.Bytes())["EPUB/xhtml/"+placeholderSectionFilename]; ok {
+		t.Error("The placeholder section shouldn't be written when there are sections")
+	}
+}
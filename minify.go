@@ -0,0 +1,152 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Elements whose content is kept as is by the minifier
+var minifyPreservedElements = map[string]bool{
+	"pre":      true,
+	"textarea": true,
+	"script":   true,
+	"style":    true,
+}
+
+// SetMinify enables or disables the minification of the sections when the
+// EPUB is written: comments are removed and runs of whitespace are collapsed
+// into a single character, except in pre, textarea, script and style elements.
+// This shrinks text-heavy books and speeds up parsing on low-end devices.
+func (e *Epub) SetMinify(minify bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.minify = minify
+}
+
+// Return the transformation applied to the content of the sections when they
+// are written, if any
+func (e *Epub) sectionTransform() func([]byte) []byte {
+	if e.minify {
+		return minifyXHTML
+	}
+	return nil
+}
+
+// Minify an XHTML document. Collapsing whitespace is safe since a run of
+// whitespace is rendered as a single space in HTML, except in preformatted
+// content. A run that contains a line break is collapsed into a line break to
+// keep the document readable.
+func minifyXHTML(doc []byte) []byte {
+	var b bytes.Buffer
+	b.Grow(len(doc))
+	for i := 0; i < len(doc); {
+		switch {
+		case bytes.HasPrefix(doc[i:], []byte("<!--")):
+			end := bytes.Index(doc[i+4:], []byte("-->"))
+			if end < 0 {
+				b.Write(doc[i:])
+				return b.Bytes()
+			}
+			i += 4 + end + 3
+		case bytes.HasPrefix(doc[i:], []byte("<![CDATA[")):
+			end := bytes.Index(doc[i:], []byte("]]>"))
+			if end < 0 {
+				b.Write(doc[i:])
+				return b.Bytes()
+			}
+			b.Write(doc[i : i+end+3])
+			i += end + 3
+		case doc[i] == '<':
+			end := tagEnd(doc, i)
+			tag := doc[i:end]
+			b.Write(tag)
+			i = end
+			name, closing, selfClosing := tagName(tag)
+			if closing || selfClosing || !minifyPreservedElements[name] {
+				continue
+			}
+			// Copy the content of the element verbatim up to its end tag
+			closeTag := []byte("</" + name)
+			contentEnd := bytes.Index(bytes.ToLower(doc[i:]), closeTag)
+			if contentEnd < 0 {
+				contentEnd = len(doc) - i
+			}
+			b.Write(doc[i : i+contentEnd])
+			i += contentEnd
+		default:
+			end := bytes.IndexByte(doc[i:], '<')
+			if end < 0 {
+				end = len(doc) - i
+			}
+			collapseWhitespace(&b, doc[i:i+end])
+			i += end
+		}
+	}
+	return b.Bytes()
+}
+
+// Return the index following the end of the tag starting at start, ignoring
+// > characters in quoted attribute values
+func tagEnd(doc []byte, start int) int {
+	var quote byte
+	for i := start + 1; i < len(doc); i++ {
+		switch c := doc[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(doc)
+}
+
+// Return the lowercase name of an element from its tag, and whether the tag is
+// an end tag or a self-closing tag
+func tagName(tag []byte) (name string, closing bool, selfClosing bool) {
+	t := strings.TrimPrefix(string(tag), "<")
+	if strings.HasPrefix(t, "/") {
+		closing = true
+		t = t[1:]
+	}
+	selfClosing = strings.HasSuffix(t, "/>")
+	end := strings.IndexAny(t, " \t\r\n/>")
+	if end < 0 {
+		end = len(t)
+	}
+	return strings.ToLower(t[:end]), closing, selfClosing
+}
+
+// Write text to b with each run of whitespace collapsed into a single
+// character. A run continues the whitespace at the end of b, if any, since
+// removing a comment can join two runs.
+func collapseWhitespace(b *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); {
+		if !isXMLSpace(text[i]) {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		newline := false
+		if n := b.Len(); n > 0 && isXMLSpace(b.Bytes()[n-1]) {
+			newline = b.Bytes()[n-1] == '\n'
+			b.Truncate(n - 1)
+		}
+		for ; i < len(text) && isXMLSpace(text[i]); i++ {
+			newline = newline || text[i] == '\n'
+		}
+		if newline {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// Whitespace as defined by XML, which doesn't include non-breaking spaces
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
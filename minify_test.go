@@ -0,0 +1,80 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestMinifyXHTML(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+  <head>
+    <title>Title</title>
+    <!-- A comment -->
+    <style>
+      p  >  b { color: red; }
+    </style>
+  </head>
+  <body>
+    <p title="a  >  b">Some    <b>bold</b>
+        text</p>
+    <pre>  keep
+    this  </pre>
+    <PRE>  and   this </PRE>
+    <p>a&#160;&#160;b</p>
+  </body>
+</html>
+`
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<title>Title</title>
+<style>
+      p  >  b { color: red; }
+    </style>
+</head>
+<body>
+<p title="a  >  b">Some <b>bold</b>
+text</p>
+<pre>  keep
+    this  </pre>
+<PRE>  and   this </PRE>
+<p>a&#160;&#160;b</p>
+</body>
+</html>
+`
+	got := string(minifyXHTML([]byte(doc)))
+	if got != expected {
+		t.Errorf("Unexpected minified document:\n%s\nExpected:\n%s", got, expected)
+	}
+	if err := xml.Unmarshal([]byte(got), new(struct{})); err != nil {
+		t.Errorf("Minified document isn't well-formed: %s", err)
+	}
+}
+
+func TestSetMinify(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>\n\n    Text   with    spaces\n</p><!-- TODO -->", testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetMinify(true)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	section := readZipFiles(t, b.Bytes())["EPUB/xhtml/section.xhtml"]
+	if strings.Contains(section, "  ") || strings.Contains(section, "TODO") {
+		t.Errorf("Section wasn't minified:\n%s", section)
+	}
+	if !strings.Contains(section, "Text with spaces") {
+		t.Errorf("Section text was altered:\n%s", section)
+	}
+}
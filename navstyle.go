@@ -0,0 +1,63 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+)
+
+// SetNavCSS sets the stylesheet of the generated table of contents
+// (nav.xhtml), which some reading systems render directly.
+//
+// The internal path to an already-added CSS file (as returned by AddCSS) is
+// required. Setting an empty path removes the stylesheet.
+func (e *Epub) SetNavCSS(internalCSSPath string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if internalCSSPath == "" {
+		e.toc.navCSS = ""
+		return nil
+	}
+	filename := path.Base(internalCSSPath)
+	if _, ok := e.css[filename]; !ok {
+		return fmt.Errorf("CSS file %s has not been added", internalCSSPath)
+	}
+	// The nav document is at the root of the content folder
+	e.toc.navCSS = path.Join(CSSFolderName, filename)
+	return nil
+}
+
+// SetNavArticles enables or disables the articles mode of the generated table
+// of contents (nav.xhtml), for collections of articles such as newsletter
+// digests: the author and date of each section, as set with
+// SetSectionMetadata, are shown under its title, e.g. "Jane Doe, 2024-05-02".
+// The bylines have the toc-byline class, to be styled with SetNavCSS. The
+// EPUB 2 table of contents (toc.ncx) only has the titles.
+func (e *Epub) SetNavArticles(articles bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.toc.articles = articles
+}
+
+// SetNavLogo adds a logo image at the top of the generated table of contents
+// (nav.xhtml), with the given alternative text.
+//
+// The internal path to an already-added image file (as returned by AddImage) is
+// required. Setting an empty path removes the logo.
+func (e *Epub) SetNavLogo(internalImagePath string, alt string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if internalImagePath == "" {
+		e.toc.navLogo = ""
+		e.toc.navLogoAlt = ""
+		return nil
+	}
+	filename := path.Base(internalImagePath)
+	if _, ok := e.images[filename]; !ok {
+		return fmt.Errorf("image file %s has not been added", internalImagePath)
+	}
+	e.toc.navLogo = path.Join(ImageFolderName, filename)
+	e.toc.navLogoAlt = e.sanitize(tocNavFilename, "logo alternative text", alt)
+	return nil
+}
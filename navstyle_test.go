@@ -0,0 +1,106 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetNavStyle(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cssPath, err := e.AddCSS(testCoverCSSSource, "nav.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetNavCSS(cssPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetNavLogo(imagePath, "Publisher & Co"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	nav := readZipFiles(t, b.Bytes())["EPUB/nav.xhtml"]
+	for _, expected := range []string{
+		`<link rel="stylesheet" type="text/css" href="css/nav.css"></link>`,
+		`<img src="images/logo.png" alt="Publisher &amp; Co"/>`,
+	} {
+		if !strings.Contains(nav, expected) {
+			t.Errorf("Nav document doesn't contain %s\n%s", expected, nav)
+		}
+	}
+	if strings.Index(nav, "logo.png") > strings.Index(nav, "<nav") {
+		t.Errorf("The logo should be outside of the nav element\n%s", nav)
+	}
+}
+
+func TestSetNavStyleErrors(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetNavCSS("../css/missing.css"); err == nil {
+		t.Error("Expected an error for a CSS file that hasn't been added")
+	}
+	if err := e.SetNavLogo("../images/missing.png", ""); err == nil {
+		t.Error("Expected an error for an image that hasn't been added")
+	}
+}
+
+func TestSetNavArticles(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	articlePath, err := e.AddSection(testSectionBody, "Article", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "Editorial", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionMetadata(articlePath, SectionMetadata{Author: "Jane Doe", Date: "2024-05-02"}); err != nil {
+		t.Fatal(err)
+	}
+	e.SetNavArticles(true)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	nav := files["EPUB/nav.xhtml"]
+	for _, expected := range []string{
+		`<a href="xhtml/section0001.xhtml">Article`,
+		`<span class="toc-byline" style="display: block">Jane Doe, 2024-05-02</span>`,
+		`<a href="xhtml/section0002.xhtml">Editorial</a>`,
+	} {
+		if !strings.Contains(nav, expected) {
+			t.Errorf("Expected %s in the nav document, got:\n%s", expected, nav)
+		}
+	}
+	if ncx := files["EPUB/toc.ncx"]; strings.Contains(ncx, "Jane Doe") {
+		t.Errorf("Expected no byline in the NCX document, got:\n%s", ncx)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toc := opened.TOC(); len(toc) != 2 || toc[0].Title != "Article" {
+		t.Errorf("Expected the titles of the entries without their byline, got %+v", toc)
+	}
+}
@@ -0,0 +1,287 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ONIX codes of the title types (code list 15) and of the title element
+	// levels (code list 149)
+	onixTitleTypeDistinctive = "01"
+	onixTitleLevelProduct    = "01"
+	// ONIX code of the proprietary identifiers (code list 5), whose scheme is
+	// given by IDTypeName
+	onixIdentifierTypeProprietary = "01"
+	// ONIX codes of the subject schemes (code list 27)
+	onixSubjectSchemeBISAC    = "10"
+	onixSubjectSchemeKeywords = "20"
+	onixSubjectSchemeTHEMA    = "93"
+	// ONIX codes of the descriptions (code list 153), by order of preference
+	onixTextTypeDescription      = "03"
+	onixTextTypeShortDescription = "02"
+	// ONIX code of the publication date (code list 163)
+	onixPublishingDateRolePublication = "01"
+	// ONIX codes of the date formats (code list 55)
+	onixDateFormatYYYYMMDD = "00"
+	onixDateFormatYYYYMM   = "01"
+	onixDateFormatYYYY     = "05"
+	// ONIX contributor role of the authors (code list 17)
+	onixContributorRoleAuthor = "A01"
+)
+
+// MARC relator codes of the ONIX contributor roles (code list 17); other
+// roles are imported as ContributorRoleContributor
+var onixContributorRoles = map[string]string{
+	"A01": ContributorRoleAuthor,
+	"A02": ContributorRoleContributor,
+	"A06": "cmp",
+	"A07": "art",
+	"A08": ContributorRolePhotographer,
+	"A12": ContributorRoleIllustrator,
+	"A13": ContributorRolePhotographer,
+	"A19": "aft",
+	"A23": "aui",
+	"A24": "aui",
+	"A36": "cov",
+	"B01": ContributorRoleEditor,
+	"B06": ContributorRoleTranslator,
+	"B09": ContributorRoleEditor,
+	"E07": ContributorRoleNarrator,
+}
+
+// InvalidONIXError is returned by MetadataFromONIX if the ONIX record can't be
+// read.
+type InvalidONIXError struct {
+	Reason string // Why the record is invalid
+}
+
+func (e *InvalidONIXError) Error() string {
+	return fmt.Sprintf("Invalid ONIX record: %s", e.Reason)
+}
+
+// An ONIX 3.0 product record, with reference tag names. Elements are matched
+// by their local name, whatever their namespace.
+type onixProduct struct {
+	Identifiers []struct {
+		Type     string `xml:"ProductIDType"`
+		TypeName string `xml:"IDTypeName"`
+		Value    string `xml:"IDValue"`
+	} `xml:"ProductIdentifier"`
+	Titles []struct {
+		Type     string `xml:"TitleType"`
+		Elements []struct {
+			Level         string `xml:"TitleElementLevel"`
+			Text          string `xml:"TitleText"`
+			Prefix        string `xml:"TitlePrefix"`
+			WithoutPrefix string `xml:"TitleWithoutPrefix"`
+			Subtitle      string `xml:"Subtitle"`
+		} `xml:"TitleElement"`
+	} `xml:"DescriptiveDetail>TitleDetail"`
+	Contributors []struct {
+		Sequence       string   `xml:"SequenceNumber"`
+		Roles          []string `xml:"ContributorRole"`
+		PersonName     string   `xml:"PersonName"`
+		NamesBeforeKey string   `xml:"NamesBeforeKey"`
+		KeyNames       string   `xml:"KeyNames"`
+		CorporateName  string   `xml:"CorporateName"`
+	} `xml:"DescriptiveDetail>Contributor"`
+	Subjects []struct {
+		Scheme  string `xml:"SubjectSchemeIdentifier"`
+		Code    string `xml:"SubjectCode"`
+		Heading string `xml:"SubjectHeadingText"`
+	} `xml:"DescriptiveDetail>Subject"`
+	Texts []struct {
+		Type string `xml:"TextType"`
+		Text struct {
+			XML string `xml:",innerxml"`
+		} `xml:"Text"`
+	} `xml:"CollateralDetail>TextContent"`
+	PublishingDates []struct {
+		Role string `xml:"PublishingDateRole"`
+		Date struct {
+			Format string `xml:"dateformat,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"Date"`
+	} `xml:"PublishingDetail>PublishingDate"`
+}
+
+// MetadataFromONIX sets the metadata of the EPUB from the first product record
+// of an ONIX 3.0 message (reference tag names), as publishers maintain it for
+// the stores:
+//
+//   - the distinctive title and its subtitle (SetTitle, SetSubtitle)
+//   - the first author (SetAuthor), the other contributors with the MARC
+//     relator codes of their roles (AddContributor)
+//   - the ISBNs, DOIs and proprietary identifiers (AddIdentifier); the unique
+//     identifier of the EPUB is left as is, see SetUniqueIdentifier
+//   - the BISAC and THEMA subjects (AddCodedSubject) and the keywords
+//     (AddSubject)
+//   - the description, as plain text (SetDescription)
+//   - the publication date (SetDate)
+//
+// Metadata missing from the record is left as is. InvalidONIXError is returned
+// if the record can't be read or has no product.
+func (e *Epub) MetadataFromONIX(r io.Reader) error {
+	var message struct {
+		Products []onixProduct `xml:"Product"`
+	}
+	if err := xml.NewDecoder(r).Decode(&message); err != nil {
+		return &InvalidONIXError{Reason: err.Error()}
+	}
+	if len(message.Products) == 0 {
+		return &InvalidONIXError{Reason: "no product record"}
+	}
+	p := message.Products[0]
+
+	for _, t := range p.Titles {
+		if strings.TrimSpace(t.Type) != onixTitleTypeDistinctive {
+			continue
+		}
+		for _, el := range t.Elements {
+			if strings.TrimSpace(el.Level) != onixTitleLevelProduct {
+				continue
+			}
+			title := strings.TrimSpace(el.Text)
+			if title == "" {
+				title = strings.TrimSpace(strings.TrimSpace(el.Prefix) + " " + strings.TrimSpace(el.WithoutPrefix))
+			}
+			if title != "" {
+				e.SetTitle(title)
+			}
+			if subtitle := strings.TrimSpace(el.Subtitle); subtitle != "" {
+				e.SetSubtitle(subtitle)
+			}
+		}
+	}
+
+	contributors := p.Contributors
+	sort.SliceStable(contributors, func(i, j int) bool {
+		si, _ := strconv.Atoi(strings.TrimSpace(contributors[i].Sequence))
+		sj, _ := strconv.Atoi(strings.TrimSpace(contributors[j].Sequence))
+		return si < sj
+	})
+	hasAuthor := false
+	for _, c := range contributors {
+		name := strings.TrimSpace(c.PersonName)
+		if name == "" {
+			name = strings.TrimSpace(strings.TrimSpace(c.NamesBeforeKey) + " " + strings.TrimSpace(c.KeyNames))
+		}
+		if name == "" {
+			name = strings.TrimSpace(c.CorporateName)
+		}
+		if name == "" || len(c.Roles) == 0 {
+			continue
+		}
+		role := strings.TrimSpace(c.Roles[0])
+		if role == onixContributorRoleAuthor && !hasAuthor {
+			e.SetAuthor(name)
+			hasAuthor = true
+			continue
+		}
+		marcRole, ok := onixContributorRoles[role]
+		if !ok {
+			marcRole = ContributorRoleContributor
+		}
+		e.AddContributor(name, marcRole)
+	}
+
+	for _, id := range p.Identifiers {
+		value := strings.TrimSpace(id.Value)
+		if value == "" {
+			continue
+		}
+		switch strings.TrimSpace(id.Type) {
+		case onixIdentifierTypeISBN10, onixIdentifierTypeISBN13:
+			e.AddIdentifier(value, IdentifierISBN)
+		case onixIdentifierTypeDOI:
+			e.AddIdentifier(value, IdentifierDOI)
+		case onixIdentifierTypeProprietary:
+			e.AddIdentifier(value, strings.TrimSpace(id.TypeName))
+		}
+	}
+
+	for _, s := range p.Subjects {
+		heading, code := strings.TrimSpace(s.Heading), strings.TrimSpace(s.Code)
+		switch strings.TrimSpace(s.Scheme) {
+		case onixSubjectSchemeBISAC, onixSubjectSchemeTHEMA:
+			authority := SubjectAuthorityBISAC
+			if strings.TrimSpace(s.Scheme) == onixSubjectSchemeTHEMA {
+				authority = SubjectAuthorityTHEMA
+			}
+			if heading == "" {
+				heading = code
+			}
+			if heading != "" {
+				e.AddCodedSubject(heading, authority, code)
+			}
+		case onixSubjectSchemeKeywords:
+			// Keywords are separated by semicolons
+			for _, keyword := range strings.Split(heading, ";") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					e.AddSubject(keyword)
+				}
+			}
+		}
+	}
+
+	if desc := onixDescription(p); desc != "" {
+		e.SetDescription(desc)
+	}
+
+	for _, d := range p.PublishingDates {
+		if strings.TrimSpace(d.Role) != onixPublishingDateRolePublication {
+			continue
+		}
+		if date := onixDate(d.Date.Value, d.Date.Format); date != "" {
+			e.SetDate(date)
+		}
+	}
+	return nil
+}
+
+// Return the description of an ONIX product as plain text, or "" if it has
+// none
+func onixDescription(p onixProduct) string {
+	for _, textType := range []string{onixTextTypeDescription, onixTextTypeShortDescription} {
+		for _, t := range p.Texts {
+			if strings.TrimSpace(t.Type) != textType {
+				continue
+			}
+			// The markup of HTML and XHTML texts may be escaped or in a CDATA
+			// section, leaving tags once unescaped
+			text := proofText(t.Text.XML)
+			if strings.Contains(text, "<") {
+				text = proofText(text)
+			}
+			if text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// Return an ONIX date in the W3C Date and Time Formats, or "" if the format
+// isn't handled
+func onixDate(value string, format string) string {
+	value = strings.TrimSpace(value)
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	switch {
+	case (format == "" || format == onixDateFormatYYYYMMDD) && len(value) == 8:
+		return value[:4] + "-" + value[4:6] + "-" + value[6:]
+	case format == onixDateFormatYYYYMM && len(value) == 6:
+		return value[:4] + "-" + value[4:]
+	case format == onixDateFormatYYYY && len(value) == 4:
+		return value
+	}
+	return ""
+}
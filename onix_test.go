@@ -0,0 +1,145 @@
+package epub
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testONIXRecord = `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+  <Header><Sender><SenderName>Publisher</SenderName></Sender></Header>
+  <Product>
+    <RecordReference>com.example.9780000000002</RecordReference>
+    <ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9780000000002</IDValue></ProductIdentifier>
+    <ProductIdentifier><ProductIDType>06</ProductIDType><IDValue>10.1000/182</IDValue></ProductIdentifier>
+    <ProductIdentifier><ProductIDType>03</ProductIDType><IDValue>9780000000002</IDValue></ProductIdentifier>
+    <DescriptiveDetail>
+      <TitleDetail>
+        <TitleType>01</TitleType>
+        <TitleElement>
+          <TitleElementLevel>01</TitleElementLevel>
+          <TitlePrefix>The</TitlePrefix>
+          <TitleWithoutPrefix>Dragon Book</TitleWithoutPrefix>
+          <Subtitle>A Tale</Subtitle>
+        </TitleElement>
+      </TitleDetail>
+      <Contributor>
+        <SequenceNumber>2</SequenceNumber>
+        <ContributorRole>B06</ContributorRole>
+        <NamesBeforeKey>Tom</NamesBeforeKey><KeyNames>Translator</KeyNames>
+      </Contributor>
+      <Contributor>
+        <SequenceNumber>1</SequenceNumber>
+        <ContributorRole>A01</ContributorRole>
+        <PersonName>Ann Author</PersonName>
+      </Contributor>
+      <Contributor>
+        <SequenceNumber>3</SequenceNumber>
+        <ContributorRole>A01</ContributorRole>
+        <PersonName>Bob Coauthor</PersonName>
+      </Contributor>
+      <Subject>
+        <MainSubject/>
+        <SubjectSchemeIdentifier>10</SubjectSchemeIdentifier>
+        <SubjectCode>FIC009020</SubjectCode>
+        <SubjectHeadingText>FICTION / Fantasy / Epic</SubjectHeadingText>
+      </Subject>
+      <Subject><SubjectSchemeIdentifier>93</SubjectSchemeIdentifier><SubjectCode>FMB</SubjectCode></Subject>
+      <Subject><SubjectSchemeIdentifier>20</SubjectSchemeIdentifier><SubjectHeadingText>dragons; quests</SubjectHeadingText></Subject>
+    </DescriptiveDetail>
+    <CollateralDetail>
+      <TextContent><TextType>02</TextType><Text>Short</Text></TextContent>
+      <TextContent>
+        <TextType>03</TextType>
+        <Text textformat="02"><![CDATA[<p>A <b>dragon</b> &amp; a knight.</p><p>The end.</p>]]></Text>
+      </TextContent>
+    </CollateralDetail>
+    <PublishingDetail>
+      <PublishingDate><PublishingDateRole>02</PublishingDateRole><Date>20230101</Date></PublishingDate>
+      <PublishingDate><PublishingDateRole>01</PublishingDateRole><Date dateformat="00">20240501</Date></PublishingDate>
+    </PublishingDetail>
+  </Product>
+</ONIXMessage>`
+
+func TestMetadataFromONIX(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.MetadataFromONIX(strings.NewReader(testONIXRecord)); err != nil {
+		t.Fatal(err)
+	}
+	if e.Title() != "The Dragon Book" || e.Subtitle() != "A Tale" {
+		t.Errorf("Unexpected title %q and subtitle %q", e.Title(), e.Subtitle())
+	}
+	if e.Author() != "Ann Author" {
+		t.Errorf("Unexpected author %q", e.Author())
+	}
+	contributors := []Contributor{
+		{Name: "Tom Translator", Role: ContributorRoleTranslator},
+		{Name: "Bob Coauthor", Role: ContributorRoleAuthor},
+	}
+	if !reflect.DeepEqual(e.Contributors(), contributors) {
+		t.Errorf("Unexpected contributors %v", e.Contributors())
+	}
+	identifiers := e.Identifiers()[1:]
+	expectedIdentifiers := []Identifier{
+		{Value: "9780000000002", Scheme: IdentifierISBN},
+		{Value: "10.1000/182", Scheme: IdentifierDOI},
+	}
+	if !reflect.DeepEqual(identifiers, expectedIdentifiers) {
+		t.Errorf("Unexpected identifiers %v", identifiers)
+	}
+	subjects := []Subject{
+		{Term: "FICTION / Fantasy / Epic", Authority: SubjectAuthorityBISAC, Code: "FIC009020"},
+		{Term: "FMB", Authority: SubjectAuthorityTHEMA, Code: "FMB"},
+		{Term: "dragons"},
+		{Term: "quests"},
+	}
+	if !reflect.DeepEqual(e.Subjects(), subjects) {
+		t.Errorf("Unexpected subjects %v", e.Subjects())
+	}
+	if desc := "A dragon & a knight.\nThe end."; e.Description() != desc {
+		t.Errorf("Expected description %q, got %q", desc, e.Description())
+	}
+	if e.Date() != "2024-05-01" {
+		t.Errorf("Unexpected date %q", e.Date())
+	}
+}
+
+func TestMetadataFromONIXInvalid(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []string{
+		`<ONIXMessage><Header/></ONIXMessage>`,
+		`<ONIXMessage><Product>`,
+	} {
+		var onixErr *InvalidONIXError
+		if err := e.MetadataFromONIX(strings.NewReader(record)); !errors.As(err, &onixErr) {
+			t.Errorf("Expected InvalidONIXError for %s, got %v", record, err)
+		}
+	}
+	if e.Title() != testEpubTitle {
+		t.Errorf("Expected the metadata to be left as is, got title %q", e.Title())
+	}
+}
+
+func TestONIXDate(t *testing.T) {
+	for _, test := range []struct {
+		value, format, expected string
+	}{
+		{"20240501", "", "2024-05-01"},
+		{"202405", "01", "2024-05"},
+		{"2024", "05", "2024"},
+		{"2024", "00", ""},
+		{"2024-05-01", "", ""},
+	} {
+		if got := onixDate(test.value, test.format); got != test.expected {
+			t.Errorf("onixDate(%q, %q): expected %q, got %q", test.value, test.format, test.expected, got)
+		}
+	}
+}
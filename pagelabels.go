@@ -0,0 +1,122 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	tocPageListEpubType = "page-list"
+	// Types of the page targets of the NCX page list
+	tocNcxPageTypeNormal  = "normal"
+	tocNcxPageTypeFront   = "front"
+	tocNcxPageTypeSpecial = "special"
+)
+
+// Roman numerals, which label the pages of the front matter
+var romanNumeralRegex = regexp.MustCompile(`^(?i)m*(?:cm|cd|d?c{0,3})(?:xc|xl|l?x{0,3})(?:ix|iv|v?i{0,3})$`)
+
+// The page list of the EPUB v3 TOC file
+type tocPageListNav struct {
+	XMLName  xml.Name      `xml:"nav"`
+	EpubType string        `xml:"epub:type,attr"`
+	Hidden   string        `xml:"hidden,attr"`
+	Links    []*tocNavItem `xml:"ol>li"`
+}
+
+// The page list of the EPUB v2 TOC file
+type tocNcxPageList struct {
+	Targets []tocNcxPageTarget `xml:"pageTarget"`
+}
+
+type tocNcxPageTarget struct {
+	ID      string        `xml:"id,attr"`
+	Type    string        `xml:"type,attr"`
+	Value   string        `xml:"value,attr,omitempty"`
+	Text    string        `xml:"navLabel>text"`
+	Content tocNcxContent `xml:"content"`
+}
+
+// SetPageLabel sets the label of the page a section starts on, e.g. the page
+// number of the print edition ("iv", "12"), or the label of the page in a
+// book where each section is a page. The labels are listed in the page list
+// of the table of contents (and the page list of the NCX file), which reading
+// systems that honor them, such as Apple Books and Adobe Digital Editions,
+// display instead of their own page numbers, so that every reader shows the
+// same page furniture.
+//
+// An empty label removes the label of the section. SectionDoesNotExistError is
+// returned if the EPUB has no section with this filename.
+func (e *Epub) SetPageLabel(sectionFilename string, label string) error {
+	e.Lock()
+	defer e.Unlock()
+	s, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	s.pageLabel = strings.TrimSpace(e.sanitize(sectionFilename, "page label", label))
+	return nil
+}
+
+// Add a page to the page lists of the TOC
+func (t *toc) addPageTarget(label string, relativePath string) {
+	t.pageTargets = append(t.pageTargets, &tocNavItem{
+		A: tocNavLink{
+			Href: filepath.ToSlash(relativePath),
+			Data: label,
+		},
+	})
+}
+
+// Return the page list nav element of the EPUB v3 TOC file, or "" if there
+// are no pages
+func (t *toc) pageListNav() (string, error) {
+	if len(t.pageTargets) == 0 {
+		return "", nil
+	}
+	nav := tocPageListNav{
+		EpubType: tocPageListEpubType,
+		Hidden:   "hidden",
+		Links:    t.pageTargets,
+	}
+	content, err := xml.MarshalIndent(nav, "    ", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Error marshalling XML for the page list: %w", err)
+	}
+	return "\n    " + string(content), nil
+}
+
+// Return the page list of the EPUB v2 TOC file, or nil if there are no pages
+func (t *toc) ncxPageList() *tocNcxPageList {
+	if len(t.pageTargets) == 0 {
+		return nil
+	}
+	list := &tocNcxPageList{}
+	for i, target := range t.pageTargets {
+		pageType, value := tocNcxPageTypeSpecial, ""
+		switch label := target.A.Data; {
+		case isPageNumber(label):
+			pageType, value = tocNcxPageTypeNormal, label
+		case romanNumeralRegex.MatchString(label):
+			pageType = tocNcxPageTypeFront
+		}
+		list.Targets = append(list.Targets, tocNcxPageTarget{
+			ID:      "pageTarget-" + strconv.Itoa(i+1),
+			Type:    pageType,
+			Value:   value,
+			Text:    target.A.Data,
+			Content: tocNcxContent{Src: target.A.Href},
+		})
+	}
+	return list
+}
+
+// Report whether a page label is a positive page number
+func isPageNumber(label string) bool {
+	n, err := strconv.Atoi(label)
+	return err == nil && n > 0
+}
@@ -0,0 +1,91 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetPageLabel(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{"preface.xhtml", "one.xhtml", "plate.xhtml"} {
+		if _, err := e.AddSection(testSectionBody, filename, filename, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := e.AddSubSection("one.xhtml", testSectionBody, "Part A", "one-a.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	for filename, label := range map[string]string{
+		"preface.xhtml": "iv",
+		"one.xhtml":     "1",
+		"one-a.xhtml":   "12",
+		"plate.xhtml":   "Plate A",
+	} {
+		if err := e.SetPageLabel(filename, label); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var notFound *SectionDoesNotExistError
+	if err := e.SetPageLabel("missing.xhtml", "2"); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError, got %v", err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	nav := files["EPUB/nav.xhtml"]
+	expectedNav := `<nav epub:type="page-list" hidden="hidden">
+      <ol>
+        <li>
+          <a href="xhtml/preface.xhtml">iv</a>
+        </li>
+        <li>
+          <a href="xhtml/one.xhtml">1</a>
+        </li>
+        <li>
+          <a href="xhtml/one-a.xhtml">12</a>
+        </li>
+        <li>
+          <a href="xhtml/plate.xhtml">Plate A</a>
+        </li>
+      </ol>
+    </nav>`
+	if !strings.Contains(nav, expectedNav) {
+		t.Errorf("Expected the page list in the nav document:\n%s", nav)
+	}
+	ncx := files["EPUB/toc.ncx"]
+	for _, expected := range []string{
+		`<pageTarget id="pageTarget-1" type="front">`,
+		`<pageTarget id="pageTarget-2" type="normal" value="1">`,
+		`<pageTarget id="pageTarget-3" type="normal" value="12">`,
+		`<pageTarget id="pageTarget-4" type="special">`,
+		`<text>Plate A</text>`,
+		`<content src="xhtml/plate.xhtml"></content>`,
+	} {
+		if !strings.Contains(ncx, expected) {
+			t.Errorf("Expected %s in the NCX file:\n%s", expected, ncx)
+		}
+	}
+
+	// Removing the labels removes the page lists
+	for _, filename := range []string{"preface.xhtml", "one.xhtml", "one-a.xhtml", "plate.xhtml"} {
+		if err := e.SetPageLabel(filename, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files = readZipFiles(t, b.Bytes())
+	if strings.Contains(files["EPUB/nav.xhtml"], "page-list") || strings.Contains(files["EPUB/toc.ncx"], "pageList") {
+		t.Error("Expected no page lists without page labels")
+	}
+}
@@ -3,8 +3,9 @@ package epub
 import (
 	"encoding/xml"
 	"fmt"
+	"path"
 	"path/filepath"
-	"time"
+	"sort"
 )
 
 const (
@@ -28,6 +29,8 @@ const (
   </spine>
 </package>
 `
+	// Relation of the links to the font licenses
+	pkgLicenseRel       = "dcterms:license"
 	pkgModifiedProperty = "dcterms:modified"
 	pkgUniqueIdentifier = "pub-id"
 
@@ -47,18 +50,22 @@ type pkg struct {
 
 // This holds the actual XML for the package file
 type pkgRoot struct {
-	XMLName          xml.Name    `xml:"http://www.idpf.org/2007/opf package"`
-	UniqueIdentifier string      `xml:"unique-identifier,attr"`
-	Version          string      `xml:"version,attr"`
-	Metadata         pkgMetadata `xml:"metadata"`
-	ManifestItems    []pkgItem   `xml:"manifest>item"`
-	Spine            pkgSpine    `xml:"spine"`
+	XMLName          xml.Name `xml:"http://www.idpf.org/2007/opf package"`
+	UniqueIdentifier string   `xml:"unique-identifier,attr"`
+	Version          string   `xml:"version,attr"`
+	// Vocabulary prefixes, see AddPrefix
+	Prefix        string      `xml:"prefix,attr,omitempty"`
+	Metadata      pkgMetadata `xml:"metadata"`
+	ManifestItems []pkgItem   `xml:"manifest>item"`
+	Spine         pkgSpine    `xml:"spine"`
+	Guide         *pkgGuide   `xml:"guide,omitempty"`
 }
 
 // <dc:creator>, e.g. the author
 type pkgCreator struct {
 	XMLName xml.Name `xml:"dc:creator"`
 	ID      string   `xml:"id,attr"`
+	Lang    string   `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
 	Data    string   `xml:",chardata"`
 }
 
@@ -79,6 +86,8 @@ type pkgItem struct {
 	Href       string `xml:"href,attr"`
 	MediaType  string `xml:"media-type,attr"`
 	Properties string `xml:"properties,attr,omitempty"`
+	// ID of the media overlay of the item, if any
+	MediaOverlay string `xml:"media-overlay,attr,omitempty"`
 }
 
 // <itemref> elements, which define the reading order
@@ -104,21 +113,57 @@ type pkgMeta struct {
 
 // The <metadata> element
 type pkgMetadata struct {
-	XmlnsDc    string        `xml:"xmlns:dc,attr"`
-	Identifier pkgIdentifier `xml:"dc:identifier"`
+	XmlnsDc string `xml:"xmlns:dc,attr"`
+	// The first identifier is the unique identifier
+	Identifiers []pkgIdentifier `xml:"dc:identifier"`
 	// Ex: <dc:title>Your title here</dc:title>
-	Title string `xml:"dc:title"`
+	Titles []pkgTitle `xml:"dc:title"`
+	// The first language is the main language of the EPUB
 	// Ex: <dc:language>en</dc:language>
-	Language    string `xml:"dc:language"`
-	Description string `xml:"dc:description,omitempty"`
-	Creator     *pkgCreator
-	Meta        []pkgMeta `xml:"meta"`
+	Languages   []string `xml:"dc:language"`
+	Description string   `xml:"dc:description,omitempty"`
+	Publisher   string   `xml:"dc:publisher,omitempty"`
+	Rights      string   `xml:"dc:rights,omitempty"`
+	// Ex: <dc:date>2024-05-01</dc:date>
+	Date     string       `xml:"dc:date,omitempty"`
+	Source   string       `xml:"dc:source,omitempty"`
+	Type     string       `xml:"dc:type,omitempty"`
+	Coverage string       `xml:"dc:coverage,omitempty"`
+	Relation string       `xml:"dc:relation,omitempty"`
+	Subjects []pkgSubject `xml:"dc:subject"`
+	Creator  *pkgCreator
+	// Ex: <dc:contributor id="contributor-1">Jane Doe</dc:contributor>
+	Contributors []pkgContributor `xml:"dc:contributor"`
+	Meta         []pkgMeta        `xml:"meta"`
+	Links        []pkgLink        `xml:"link"`
+}
+
+// The <link> element, which links the publication or one of its resources to
+// a related resource
+// Ex: <link rel="dcterms:license" href="licenses/OFL.txt" refines="#font.otf"/>
+type pkgLink struct {
+	Rel     string `xml:"rel,attr"`
+	Href    string `xml:"href,attr"`
+	Refines string `xml:"refines,attr,omitempty"`
+}
+
+// The EPUB 2 <guide> element
+type pkgGuide struct {
+	References []pkgReference `xml:"reference"`
+}
+
+// <reference> elements of the EPUB 2 <guide> element
+// Ex: <reference type="cover" title="Cover" href="xhtml/cover.xhtml" />
+type pkgReference struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr"`
+	Href  string `xml:"href,attr"`
 }
 
 // The <spine> element
 type pkgSpine struct {
 	Items []pkgItemref `xml:"itemref"`
-	Toc   string       `xml:"toc,attr"`
+	Toc   string       `xml:"toc,attr,omitempty"`
 	Ppd   string       `xml:"page-progression-direction,attr,omitempty"`
 }
 
@@ -128,9 +173,6 @@ func newPackage() (*pkg, error) {
 		xml: &pkgRoot{
 			Metadata: pkgMetadata{
 				XmlnsDc: xmlnsDc,
-				Identifier: pkgIdentifier{
-					ID: pkgUniqueIdentifier,
-				},
 			},
 		},
 	}
@@ -143,6 +185,13 @@ func newPackage() (*pkg, error) {
 	return p, nil
 }
 
+// Remove the manifest and spine items, which are added each time the EPUB is
+// written
+func (p *pkg) resetItems() {
+	p.xml.ManifestItems = nil
+	p.xml.Spine.Items = nil
+}
+
 func (p *pkg) addToManifest(id string, href string, mediaType string, properties string) {
 	href = filepath.ToSlash(href)
 	i := &pkgItem{
@@ -154,6 +203,70 @@ func (p *pkg) addToManifest(id string, href string, mediaType string, properties
 	p.xml.ManifestItems = append(p.xml.ManifestItems, *i)
 }
 
+// Link the manifest item with the given id to its media overlay
+func (p *pkg) setMediaOverlay(id string, overlayID string) {
+	for i := range p.xml.ManifestItems {
+		if p.xml.ManifestItems[i].ID == id {
+			p.xml.ManifestItems[i].MediaOverlay = overlayID
+		}
+	}
+}
+
+// Set the total duration of the media overlays and the duration of each of
+// them (the key is the overlay manifest id), replacing any previous durations
+func (p *pkg) setMediaDurations(total string, overlays map[string]string) {
+	p.setRefinedMetas(pkgMediaDurationProperty, total, overlays)
+}
+
+// Set the metas of the publication with the given property, one per value,
+// replacing any previous ones
+func (p *pkg) setMetas(property string, values []string) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.Property != property || m.Refines != "" {
+			metas = append(metas, m)
+		}
+	}
+	for _, value := range values {
+		metas = append(metas, pkgMeta{
+			Property: property,
+			Data:     value,
+		})
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Set the metas with the given property, replacing any previous ones: one for
+// the publication (omitted if value is empty) and one refining each of the
+// given manifest ids
+func (p *pkg) setRefinedMetas(property string, value string, refined map[string]string) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.Property != property {
+			metas = append(metas, m)
+		}
+	}
+	if value != "" {
+		metas = append(metas, pkgMeta{
+			Property: property,
+			Data:     value,
+		})
+	}
+	ids := make([]string, 0, len(refined))
+	for id := range refined {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		metas = append(metas, pkgMeta{
+			Property: property,
+			Refines:  "#" + id,
+			Data:     refined[id],
+		})
+	}
+	p.xml.Metadata.Meta = metas
+}
+
 func (p *pkg) addToSpine(id string) {
 	i := &pkgItemref{
 		Idref: id,
@@ -162,10 +275,22 @@ func (p *pkg) addToSpine(id string) {
 	p.xml.Spine.Items = append(p.xml.Spine.Items, *i)
 }
 
-func (p *pkg) setAuthor(author string) {
+// Remove the item with the given id from the spine
+func (p *pkg) removeFromSpine(id string) {
+	var items []pkgItemref
+	for _, item := range p.xml.Spine.Items {
+		if item.Idref != id {
+			items = append(items, item)
+		}
+	}
+	p.xml.Spine.Items = items
+}
+
+func (p *pkg) setAuthor(author string, lang string) {
 	p.xml.Metadata.Creator = &pkgCreator{
 		Data: author,
 		ID:   pkgCreatorID,
+		Lang: lang,
 	}
 	p.authorMeta = &pkgMeta{
 		Data:     pkgAuthorData,
@@ -180,7 +305,6 @@ func (p *pkg) setAuthor(author string) {
 
 // Add an EPUB 2 cover meta element for backward compatibility (http://idpf.org/forum/topic-715)
 func (p *pkg) setCover(coverRef string) {
-	coverRef, _ = fixXMLId(coverRef)
 	p.coverMeta = &pkgMeta{
 		Name:    "cover",
 		Content: coverRef,
@@ -188,18 +312,72 @@ func (p *pkg) setCover(coverRef string) {
 	p.xml.Metadata.Meta = updateMeta(p.xml.Metadata.Meta, p.coverMeta)
 }
 
-func (p *pkg) setIdentifier(identifier string) {
-	p.xml.Metadata.Identifier.Data = identifier
+// Set the references of the EPUB 2 guide element, which is omitted if there
+// are none
+func (p *pkg) setGuide(refs []pkgReference) {
+	p.xml.Guide = nil
+	if len(refs) > 0 {
+		p.xml.Guide = &pkgGuide{References: refs}
+	}
 }
 
-func (p *pkg) setLang(lang string) {
-	p.xml.Metadata.Language = lang
+// Set whether the spine references the EPUB 2 table of contents
+func (p *pkg) setNcx(ncx bool) {
+	p.xml.Spine.Toc = ""
+	if ncx {
+		p.xml.Spine.Toc = tocNcxItemID
+	}
+}
+
+// Set the dc:language elements, the main language first
+func (p *pkg) setLangs(langs []string) {
+	p.xml.Metadata.Languages = langs
 }
 
 func (p *pkg) setDescription(desc string) {
 	p.xml.Metadata.Description = desc
 }
 
+func (p *pkg) setPublisher(publisher string) {
+	p.xml.Metadata.Publisher = publisher
+}
+
+func (p *pkg) setRights(rights string) {
+	p.xml.Metadata.Rights = rights
+}
+
+func (p *pkg) setDate(date string) {
+	p.xml.Metadata.Date = date
+}
+
+func (p *pkg) setSource(source string) {
+	p.xml.Metadata.Source = source
+}
+
+func (p *pkg) setType(dcType string) {
+	p.xml.Metadata.Type = dcType
+}
+
+func (p *pkg) setCoverage(coverage string) {
+	p.xml.Metadata.Coverage = coverage
+}
+
+func (p *pkg) setRelation(relation string) {
+	p.xml.Metadata.Relation = relation
+}
+
+// Set the links with the given rel, e.g. the links to the font licenses,
+// replacing any previous ones
+func (p *pkg) setLinks(rel string, links []pkgLink) {
+	kept := p.xml.Metadata.Links[:0]
+	for _, l := range p.xml.Metadata.Links {
+		if l.Rel != rel {
+			kept = append(kept, l)
+		}
+	}
+	p.xml.Metadata.Links = append(kept, links...)
+}
+
 func (p *pkg) setPpd(direction string) {
 	p.xml.Spine.Ppd = direction
 }
@@ -210,11 +388,21 @@ func (p *pkg) setModified(timestamp string) {
 		Property: pkgModifiedProperty,
 	}
 
-	p.xml.Metadata.Meta = updateMeta(p.xml.Metadata.Meta, p.modifiedMeta)
+	// Replace the date of a previous write, which may differ
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.Property != pkgModifiedProperty || m.Refines != "" {
+			metas = append(metas, m)
+		}
+	}
+	p.xml.Metadata.Meta = append(metas, *p.modifiedMeta)
 }
 
 func (p *pkg) setTitle(title string) {
-	p.xml.Metadata.Title = title
+	if len(p.xml.Metadata.Titles) == 0 {
+		p.xml.Metadata.Titles = []pkgTitle{{}}
+	}
+	p.xml.Metadata.Titles[0].Data = title
 }
 
 // Update the <meta> element
@@ -245,12 +433,9 @@ func updateMeta(a []pkgMeta, m *pkgMeta) []pkgMeta {
 	return a
 }
 
-// Write the package file to the temporary directory
-func (p *pkg) write(tempDir string) error {
-	now := time.Now().UTC().Format("2006-01-02T15:04:05Z")
-	p.setModified(now)
-
-	pkgFilePath := filepath.Join(tempDir, contentFolderName, pkgFilename)
+// Write the package file to the archive
+func (p *pkg) write(a *archive) error {
+	p.setModified(a.modified.UTC().Format("2006-01-02T15:04:05Z"))
 
 	output, err := xml.MarshalIndent(p.xml, "", "  ")
 	if err != nil {
@@ -261,7 +446,7 @@ func (p *pkg) write(tempDir string) error {
 	// It's generally nice to have files end with a newline
 	pkgFileContent = append(pkgFileContent, "\n"...)
 
-	if err := filesystem.WriteFile(pkgFilePath, []byte(pkgFileContent), filePermissions); err != nil {
+	if err := a.add(path.Join(contentFolderName, pkgFilename), pkgFileContent); err != nil {
 		return fmt.Errorf("Error writing package file: %w", err)
 	}
 	return nil
@@ -0,0 +1,61 @@
+package epub
+
+import (
+	"path"
+	"regexp"
+)
+
+// Relative references to the resources of the EPUB in a section body, e.g.
+// src="../images/image0001.png"
+var resourceRefRegex = regexp.MustCompile(`(?:src|href)="(\.\./[^"#?]+)"`)
+
+// SetPrefetchHints enables or disables prefetch hints. When enabled, each
+// section gets <link rel="prefetch"> elements in its head for the next section
+// in the reading order and the resources it uses (stylesheet, images, etc.),
+// so that browser-based reading systems can load them ahead of the page turn.
+//
+// Sections added as complete XHTML documents don't get hints.
+func (e *Epub) SetPrefetchHints(enabled bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.prefetchHints = enabled
+}
+
+// Set the prefetch links of the sections, or remove them if prefetch hints are
+// disabled
+func (e *Epub) writePrefetchHints() {
+	sections := e.spineSections()
+	for i, s := range sections {
+		var paths []string
+		if e.prefetchHints && i+1 < len(sections) {
+			paths = prefetchPaths(sections[i+1])
+		}
+		s.xhtml.setPrefetchLinks(paths)
+	}
+}
+
+// Return the paths of the section and the resources it uses, relative to the
+// sections folder
+func prefetchPaths(s *epubSection) []string {
+	paths := []string{s.filename}
+	seen := map[string]bool{s.filename: true}
+	add := func(p string) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, stylesheet := range s.xhtml.stylesheets() {
+		add(stylesheet)
+	}
+	for _, script := range s.xhtml.xml.Head.Scripts {
+		add(script.Src)
+	}
+	for _, m := range resourceRefRegex.FindAllStringSubmatch(s.xhtml.xml.Body.XML, -1) {
+		// Links to other sections aren't resources of the section
+		if path.Dir(m[1]) != path.Join("..", xhtmlFolderName) {
+			add(m[1])
+		}
+	}
+	return paths
+}
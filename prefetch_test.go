@@ -0,0 +1,61 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetPrefetchHints(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cssPath, err := e.AddCSS(testCoverCSSSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p><a href="../xhtml/ch2.xhtml">Next</a></p>`, "Chapter 1", "ch1.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p><img src="`+imagePath+`" alt=""/></p>`, "Chapter 2", "ch2.xhtml", cssPath); err != nil {
+		t.Fatal(err)
+	}
+	e.SetPrefetchHints(true)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	ch1 := files["EPUB/xhtml/ch1.xhtml"]
+	for _, expected := range []string{
+		`<link rel="prefetch" href="ch2.xhtml"></link>`,
+		`<link rel="prefetch" href="` + cssPath + `"></link>`,
+		`<link rel="prefetch" href="` + imagePath + `"></link>`,
+	} {
+		if !strings.Contains(ch1, expected) {
+			t.Errorf("Section doesn't contain %s\n%s", expected, ch1)
+		}
+	}
+	if strings.Contains(files["EPUB/xhtml/ch2.xhtml"], "prefetch") {
+		t.Errorf("The last section shouldn't have prefetch hints\n%s", files["EPUB/xhtml/ch2.xhtml"])
+	}
+	if !strings.Contains(files["EPUB/xhtml/ch2.xhtml"], `rel="stylesheet"`) {
+		t.Errorf("The stylesheet of the section is missing\n%s", files["EPUB/xhtml/ch2.xhtml"])
+	}
+
+	// Disabling the hints removes them
+	e.SetPrefetchHints(false)
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(readZipFiles(t, b.Bytes())["EPUB/xhtml/ch1.xhtml"], "prefetch") {
+		t.Error("Prefetch hints weren't removed")
+	}
+}
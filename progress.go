@@ -0,0 +1,40 @@
+package epub
+
+// Stages of a write reported to the progress function, see SetProgressFunc
+const (
+	// Media files retrieved and written, out of all the media files
+	ProgressMedia = "media"
+	// Sections written, out of all the sections
+	ProgressSections = "sections"
+	// Bytes of the EPUB written to the destination; the total isn't known
+	// until the end of the write, so it is 0
+	ProgressBytes = "bytes"
+)
+
+// SetProgressFunc sets a function called as Write and WriteTo progress, so
+// that applications can show the progress of long builds. The stage is one of
+// ProgressMedia, ProgressSections and ProgressBytes; done and total count the
+// items of the stage. The function is called while the EPUB is locked, so it
+// must not call the methods of the EPUB. Setting a nil function disables
+// progress reporting.
+func (e *Epub) SetProgressFunc(f func(stage string, done, total int)) {
+	e.Lock()
+	defer e.Unlock()
+	e.progress = f
+}
+
+// Report the progress of a stage of the write, if a progress function is set
+func (e *Epub) reportProgress(stage string, done, total int) {
+	if e.progress != nil {
+		e.progress(stage, done, total)
+	}
+}
+
+// Return the number of sections, including the subsections
+func sectionCount(sections []*epubSection) int {
+	n := len(sections)
+	for _, s := range sections {
+		n += sectionCount(s.children)
+	}
+	return n
+}
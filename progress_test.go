@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSetProgressFunc(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddCSS(testCoverCSSSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := e.AddSection(testSectionBody, "One", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(parent, testSectionBody, "Two", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var stages []string
+	var bytesWritten int
+	e.SetProgressFunc(func(stage string, done, total int) {
+		if stage == ProgressBytes {
+			if done < bytesWritten || total != 0 {
+				t.Errorf("Unexpected bytes progress %d/%d after %d", done, total, bytesWritten)
+			}
+			bytesWritten = done
+			return
+		}
+		stages = append(stages, fmt.Sprintf("%s %d/%d", stage, done, total))
+	})
+	n, err := e.WriteTo(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"media 1/2", "media 2/2", "sections 1/2", "sections 2/2"}
+	if strings.Join(stages, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Unexpected progress: %v", stages)
+	}
+	if int64(bytesWritten) != n {
+		t.Errorf("Expected the bytes progress to reach %d, got %d", n, bytesWritten)
+	}
+}
@@ -0,0 +1,137 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ProofChecker checks the text of the sections, e.g. for spelling, banned
+// words or style rules, see AddProofChecker.
+type ProofChecker interface {
+	// CheckText returns the findings in the text of the section with the
+	// given internal filename (e.g. section0001.xhtml). The text has its
+	// whitespace collapsed, with the blocks (paragraphs, headings, list
+	// items...) separated by a line break.
+	CheckText(sectionFilename string, text string) []ProofFinding
+}
+
+// ProofCheckerFunc is a function used as a ProofChecker.
+type ProofCheckerFunc func(sectionFilename string, text string) []ProofFinding
+
+// CheckText calls f(sectionFilename, text).
+func (f ProofCheckerFunc) CheckText(sectionFilename string, text string) []ProofFinding {
+	return f(sectionFilename, text)
+}
+
+// ProofFinding is an issue found by a ProofChecker.
+type ProofFinding struct {
+	// Identifier of the rule, e.g. "spelling"; RuleProofing if empty
+	Rule    string
+	Message string
+}
+
+// AddProofChecker registers a checker run over the text of every section by
+// Validate and when the EPUB is written. Its findings are reported as
+// warnings, the content of the sections isn't modified. Checkers run in the
+// order they were added.
+func (e *Epub) AddProofChecker(checker ProofChecker) {
+	e.Lock()
+	defer e.Unlock()
+	e.proofCheckers = append(e.proofCheckers, checker)
+}
+
+// BannedWords is a ProofChecker reporting the words or phrases of the list
+// found in the sections, ignoring case.
+type BannedWords []string
+
+// CheckText reports the banned words of text.
+func (w BannedWords) CheckText(sectionFilename string, text string) []ProofFinding {
+	var findings []ProofFinding
+	for _, word := range w {
+		re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+		if err != nil {
+			continue
+		}
+		if n := len(re.FindAllStringIndex(text, -1)); n > 0 {
+			findings = append(findings, ProofFinding{Rule: RuleBannedWord, Message: fmt.Sprintf("banned word %q used %d time(s)", word, n)})
+		}
+	}
+	return findings
+}
+
+// Elements ending a line of the text given to the proof checkers
+var proofBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"caption": true, "dd": true, "div": true, "dt": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "p": true,
+	"pre": true, "section": true, "td": true, "th": true, "tr": true,
+}
+
+// Return the text of a section body for the proof checkers, see
+// ProofChecker.CheckText
+func proofText(body string) string {
+	d := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var lines []string
+	var line strings.Builder
+	endLine := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "script" || name == "style" {
+				d.Skip()
+			} else if proofBlockElements[name] {
+				endLine()
+			}
+		case xml.EndElement:
+			if proofBlockElements[strings.ToLower(t.Name.Local)] {
+				endLine()
+			}
+		case xml.CharData:
+			line.Write(t)
+		}
+	}
+	endLine()
+	return strings.Join(lines, "\n")
+}
+
+// Run the proof checkers over the sections and add their findings to the
+// report
+func (e *Epub) proofread(r *ValidationReport) {
+	if len(e.proofCheckers) == 0 {
+		return
+	}
+	var walk func([]*epubSection)
+	walk = func(sections []*epubSection) {
+		for _, s := range sections {
+			text := proofText(s.xhtml.xml.Body.XML)
+			for _, c := range e.proofCheckers {
+				for _, f := range c.CheckText(s.filename, text) {
+					rule := f.Rule
+					if rule == "" {
+						rule = RuleProofing
+					}
+					r.add(SeverityWarning, rule, s.filename, "%s", f.Message)
+				}
+			}
+			walk(s.children)
+		}
+	}
+	walk(e.sections)
+}
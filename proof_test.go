@@ -0,0 +1,55 @@
+package epub
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProofText(t *testing.T) {
+	body := `<h1>Title</h1>
+<p>Some <em>emphasized</em>
+   text.<br/>Next line</p><script>var x = "hidden";</script><ul><li>One</li><li>Two</li></ul>`
+	expected := "Title\nSome emphasized text.\nNext line\nOne\nTwo"
+	if got := proofText(body); got != expected {
+		t.Errorf("Unexpected text:\n%q\nexpected:\n%q", got, expected)
+	}
+}
+
+func TestAddProofChecker(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `<p>It was basically a very, very simple plan.</p><p>Basically.</p>`
+	if _, err := e.AddSection(body, "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.AddProofChecker(BannedWords{"basically", "plan b"})
+	e.AddProofChecker(ProofCheckerFunc(func(sectionFilename string, text string) []ProofFinding {
+		if strings.Contains(text, "very, very") {
+			return []ProofFinding{{Message: "repeated word: very"}}
+		}
+		return nil
+	}))
+
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var findings []string
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBannedWord || w.Rule == RuleProofing {
+			findings = append(findings, w.String())
+		}
+	}
+	expected := []string{
+		`warning: one.xhtml: banned word "basically" used 2 time(s) (banned-word)`,
+		`warning: one.xhtml: repeated word: very (proofing)`,
+	}
+	if strings.Join(findings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected findings:\n%s", strings.Join(findings, "\n"))
+	}
+	if !strings.Contains(e.sections[0].xhtml.xml.Body.XML, "basically") {
+		t.Error("Expected the content not to be modified")
+	}
+}
@@ -0,0 +1,209 @@
+package epub
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+const (
+	provenanceFilename     = "provenance.json"
+	provenanceGenerator    = "github.com/quailyquaily/go-epub"
+	provenanceDevelVersion = "(devel)"
+)
+
+// ProvenanceStatement describes how an EPUB was built. It is embedded in
+// META-INF/provenance.json and signed when a provenance key is set with
+// SetProvenanceKey.
+type ProvenanceStatement struct {
+	// Module path of the generator, i.e. this package
+	Generator string `json:"generator"`
+	// Version of the generator module, or (devel) if it is unknown
+	GeneratorVersion string    `json:"generatorVersion"`
+	BuildTime        time.Time `json:"buildTime"`
+	// SHA-256 hashes of the files of the container (hex encoded), keyed by
+	// their path in the container. The mimetype file and the provenance file
+	// itself are not included.
+	Files map[string]string `json:"files"`
+}
+
+// The provenance file holds the statement exactly as it was signed
+type provenanceFile struct {
+	Statement json.RawMessage `json:"statement"`
+	Signature []byte          `json:"signature"`
+}
+
+// ProvenanceError is returned by VerifyProvenance if the provenance of the
+// EPUB can't be verified.
+type ProvenanceError struct {
+	Reason string
+}
+
+func (e *ProvenanceError) Error() string {
+	return fmt.Sprintf("Unable to verify EPUB provenance: %s", e.Reason)
+}
+
+// SetProvenanceKey enables embedding a signed provenance statement in the EPUB
+// when it is written. The statement records the generator version, the build
+// time and a hash of every file in the container, and can be checked with
+// VerifyProvenance and the matching public key.
+//
+// Setting a nil key disables the provenance statement.
+func (e *Epub) SetProvenanceKey(key ed25519.PrivateKey) {
+	e.Lock()
+	defer e.Unlock()
+	e.provenanceKey = key
+}
+
+// VerifyProvenance checks the provenance statement embedded in an EPUB: the
+// signature must match the public key and every file of the container must be
+// listed in the statement with a matching hash. It returns the statement if it
+// is valid, and a ProvenanceError otherwise.
+func VerifyProvenance(r io.ReaderAt, size int64, publicKey ed25519.PublicKey) (*ProvenanceStatement, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open EPUB: %w", err)
+	}
+
+	f, err := z.Open(path.Join(metaInfFolderName, provenanceFilename))
+	if err != nil {
+		return nil, &ProvenanceError{Reason: "no provenance statement"}
+	}
+	var pf provenanceFile
+	err = json.NewDecoder(f).Decode(&pf)
+	f.Close()
+	if err != nil {
+		return nil, &ProvenanceError{Reason: fmt.Sprintf("invalid provenance file: %s", err)}
+	}
+	if !ed25519.Verify(publicKey, pf.Statement, pf.Signature) {
+		return nil, &ProvenanceError{Reason: "invalid signature"}
+	}
+	var statement ProvenanceStatement
+	if err := json.Unmarshal(pf.Statement, &statement); err != nil {
+		return nil, &ProvenanceError{Reason: fmt.Sprintf("invalid provenance statement: %s", err)}
+	}
+
+	seen := make(map[string]bool, len(statement.Files))
+	for _, zf := range z.File {
+		if zf.FileInfo().IsDir() || !provenanceIncludes(zf.Name) {
+			continue
+		}
+		expected, ok := statement.Files[zf.Name]
+		if !ok {
+			return nil, &ProvenanceError{Reason: fmt.Sprintf("%s is not part of the statement", zf.Name)}
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s: %w", zf.Name, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", zf.Name, err)
+		}
+		if hex.EncodeToString(h.Sum(nil)) != expected {
+			return nil, &ProvenanceError{Reason: fmt.Sprintf("%s has been modified", zf.Name)}
+		}
+		seen[zf.Name] = true
+	}
+	for name := range statement.Files {
+		if !seen[name] {
+			return nil, &ProvenanceError{Reason: fmt.Sprintf("%s is missing", name)}
+		}
+	}
+	return &statement, nil
+}
+
+// Report whether the file stored at name in the container is covered by the
+// provenance statement
+func provenanceIncludes(name string) bool {
+	return name != mimetypeFilename && name != path.Join(metaInfFolderName, provenanceFilename)
+}
+
+// provenanceHasher keeps track of the hashes of the files written to the
+// container
+type provenanceHasher map[string]hashWriter
+
+type hashWriter interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+// Wrap w so that everything written to the file stored at name is hashed
+func (ph provenanceHasher) wrap(name string, w io.Writer) io.Writer {
+	if ph == nil || !provenanceIncludes(name) {
+		return w
+	}
+	h := sha256.New()
+	ph[name] = h
+	return io.MultiWriter(w, h)
+}
+
+// Add the signed provenance statement to the zip archive
+func (ph provenanceHasher) write(z *zip.Writer, key ed25519.PrivateKey, buildTime time.Time) error {
+	statement := ProvenanceStatement{
+		Generator:        provenanceGenerator,
+		GeneratorVersion: generatorVersion(),
+		BuildTime:        buildTime.UTC(),
+		Files:            make(map[string]string, len(ph)),
+	}
+	names := make([]string, 0, len(ph))
+	for name := range ph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		statement.Files[name] = hex.EncodeToString(ph[name].Sum(nil))
+	}
+
+	s, err := json.Marshal(statement)
+	if err != nil {
+		return fmt.Errorf("Error marshalling provenance statement: %w", err)
+	}
+	// The statement isn't indented, since it must be stored exactly as signed
+	content, err := json.Marshal(provenanceFile{
+		Statement: s,
+		Signature: ed25519.Sign(key, s),
+	})
+	if err != nil {
+		return fmt.Errorf("Error marshalling provenance file: %w", err)
+	}
+
+	w, err := z.Create(path.Join(metaInfFolderName, provenanceFilename))
+	if err != nil {
+		return fmt.Errorf("error creating zip writer: %w", err)
+	}
+	if _, err := w.Write(append(content, "\n"...)); err != nil {
+		return fmt.Errorf("Error writing provenance file: %w", err)
+	}
+	return nil
+}
+
+// Version of this module as recorded in the build information of the binary
+func generatorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return provenanceDevelVersion
+	}
+	if info.Main.Path == provenanceGenerator && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == provenanceGenerator {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			return dep.Version
+		}
+	}
+	return provenanceDevelVersion
+}
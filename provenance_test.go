@@ -0,0 +1,126 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestVerifyProvenance(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetProvenanceKey(priv)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	statement, err := VerifyProvenance(bytes.NewReader(b.Bytes()), int64(b.Len()), pub)
+	if err != nil {
+		t.Fatalf("Unexpected error verifying provenance: %s", err)
+	}
+	if statement.Generator != provenanceGenerator {
+		t.Errorf("Unexpected generator: %s", statement.Generator)
+	}
+	if statement.BuildTime.IsZero() {
+		t.Error("Build time is missing from the statement")
+	}
+	for _, name := range []string{"EPUB/package.opf", "EPUB/xhtml/section.xhtml", "META-INF/container.xml"} {
+		if _, ok := statement.Files[name]; !ok {
+			t.Errorf("%s is missing from the statement", name)
+		}
+	}
+	if _, ok := statement.Files[mimetypeFilename]; ok {
+		t.Error("The mimetype file shouldn't be part of the statement")
+	}
+
+	// A different key must not verify
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyProvenance(bytes.NewReader(b.Bytes()), int64(b.Len()), otherPub)
+	var provenanceErr *ProvenanceError
+	if !errors.As(err, &provenanceErr) {
+		t.Errorf("Expected a ProvenanceError with the wrong key, got %v", err)
+	}
+
+	// Neither must a modified file
+	tampered := rewriteZip(t, b.Bytes(), "EPUB/xhtml/section.xhtml", func(data []byte) []byte {
+		return bytes.Replace(data, []byte(testSectionBody), []byte("<p>Changed</p>"), 1)
+	})
+	_, err = VerifyProvenance(bytes.NewReader(tampered), int64(len(tampered)), pub)
+	if !errors.As(err, &provenanceErr) || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("Expected a ProvenanceError for the modified file, got %v", err)
+	}
+}
+
+func TestVerifyProvenanceWithoutStatement(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyProvenance(bytes.NewReader(b.Bytes()), int64(b.Len()), pub)
+	var provenanceErr *ProvenanceError
+	if !errors.As(err, &provenanceErr) {
+		t.Errorf("Expected a ProvenanceError, got %v", err)
+	}
+}
+
+// Copy the zip archive, passing the content of the named file through modify
+func rewriteZip(t *testing.T, data []byte, name string, modify func([]byte) []byte) []byte {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name == name {
+			content = modify(content)
+		}
+		fw, err := w.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
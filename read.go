@@ -0,0 +1,839 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// InvalidEpubError is thrown by Open and OpenReader if the file isn't a valid
+// EPUB, e.g. because its container file or its package file is missing.
+type InvalidEpubError struct {
+	Reason string // Why the EPUB is invalid
+}
+
+func (e *InvalidEpubError) Error() string {
+	return fmt.Sprintf("Invalid EPUB: %s", e.Reason)
+}
+
+// TOCEntry is an entry of the table of contents of the EPUB, as returned by
+// TOC.
+type TOCEntry struct {
+	Title string
+	// Internal filename of the section, e.g. section0001.xhtml
+	Filename string
+	// Author, date and source of the section, see SetSectionMetadata
+	Metadata SectionMetadata
+	Children []TOCEntry
+}
+
+// The container file, as read by OpenReader
+type readContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// The package file, as read by OpenReader. Elements are matched by their
+// local name, whatever their namespace.
+type readPackage struct {
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Identifiers []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"identifier"`
+		Titles []struct {
+			ID    string `xml:"id,attr"`
+			Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"title"`
+		Languages []string `xml:"language"`
+		Creators  []struct {
+			Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"creator"`
+		Contributors []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"contributor"`
+		Description string `xml:"description"`
+		Publisher   string `xml:"publisher"`
+		Rights      string `xml:"rights"`
+		Date        string `xml:"date"`
+		Source      string `xml:"source"`
+		Type        string `xml:"type"`
+		Coverage    string `xml:"coverage"`
+		Relation    string `xml:"relation"`
+		Subjects    []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subject"`
+		Metas []readPackageMeta `xml:"meta"`
+		Links []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"metadata"`
+	Items []readPackageItem `xml:"manifest>item"`
+	Guide []struct {
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"guide>reference"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Ppd      string `xml:"page-progression-direction,attr"`
+		Itemrefs []struct {
+			Idref  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type readPackageItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// An EPUB 2 (name and content) or EPUB 3 (property and value) meta element
+type readPackageMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Scheme   string `xml:"scheme,attr"`
+	ID       string `xml:"id,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// A list of the nav document
+type readNavList struct {
+	Items []struct {
+		A struct {
+			Href string `xml:"href,attr"`
+			XML  string `xml:",innerxml"`
+		} `xml:"a"`
+		List *readNavList `xml:"ol"`
+	} `xml:"li"`
+}
+
+// A navPoint of the NCX document
+type readNcxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []readNcxNavPoint `xml:"navPoint"`
+}
+
+// An entry of the table of contents read from the nav or NCX document, with
+// its href resolved relative to the root of the container
+type readTOCEntry struct {
+	title string
+	href  string
+	depth int
+}
+
+// Open parses the EPUB file at path. See OpenReader.
+func Open(path string) (*Epub, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return OpenReader(f, info.Size())
+}
+
+// OpenReader parses an EPUB into an Epub so that its metadata, table of
+// contents and sections can be inspected, whether it was built with this
+// package or not.
+//
+// The sections are the documents of the spine, in order, kept as is; their
+// titles come from the table of contents (the nav document, or the NCX
+// document for EPUB 2 files) and they are nested following it. The other
+// resources (images, CSS, fonts, etc.) are added to the Epub with their
+// content embedded as data URLs, under their base name. As the files are
+// moved to the folders this package writes them to (e.g. OEBPS/Images/a.png
+// to EPUB/images/a.png), the href and src attributes of the sections and the
+// media overlays and the url() references of the CSS files pointing to them
+// are rewritten accordingly. Resources whose media
+// type isn't handled are left out and reported by Validate, as are the entries
+// of the table of contents that don't follow the reading order
+// (RuleTOCOrder). The cover page, if one is found, is handled like one set
+// with SetCover.
+//
+// The Epub can then be modified like any other, e.g. with ReplaceSection,
+// RemoveSection, SetCover or SetTitle, and written back with Write or
+// WriteTo.
+func OpenReader(r io.ReaderAt, size int64) (*Epub, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read EPUB: %w", err)
+	}
+	files := make(map[string]*zip.File, len(z.File))
+	for _, f := range z.File {
+		if err := ValidateEntryName(f.Name); err != nil {
+			return nil, err
+		}
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, &InvalidEpubError{Reason: fmt.Sprintf("missing file %s", name)}
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", name, err)
+		}
+		return data, nil
+	}
+
+	data, err := read(path.Join(metaInfFolderName, containerFilename))
+	if err != nil {
+		return nil, err
+	}
+	var container readContainer
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return nil, fmt.Errorf("unable to parse container file: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, &InvalidEpubError{Reason: "no rootfile in container file"}
+	}
+	pkgPath := container.Rootfiles[0].FullPath
+	data, err = read(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	var p readPackage
+	if err := xml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unable to parse package file: %w", err)
+	}
+
+	_, title := readMainTitle(&p)
+	e, err := NewEpub(title)
+	if err != nil {
+		return nil, err
+	}
+	e.readMetadata(&p)
+
+	// Resolve the hrefs of the manifest relative to the package file
+	resolve := func(base string, href string) string {
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		return path.Join(path.Dir(base), href)
+	}
+	items := make(map[string]readPackageItem, len(p.Items))
+	for _, item := range p.Items {
+		item.Href = resolve(pkgPath, item.Href)
+		items[item.ID] = item
+	}
+
+	var toc []readTOCEntry
+	navPath, ncxPath := "", ""
+	for _, item := range items {
+		if hasProperty(item.Properties, tocNavItemProperties) {
+			navPath = item.Href
+		}
+	}
+	if item, ok := items[p.Spine.Toc]; ok {
+		ncxPath = item.Href
+	}
+	if navPath != "" {
+		if data, err := read(navPath); err == nil {
+			toc = readNavTOC(data, func(href string) string { return resolve(navPath, href) })
+		}
+	}
+	if len(toc) == 0 && ncxPath != "" {
+		if data, err := read(ncxPath); err == nil {
+			toc = readNcxTOC(data, func(href string) string { return resolve(ncxPath, href) })
+		}
+	}
+
+	// The files are moved to the folders of this package, e.g. a section
+	// OEBPS/Text/ch1.xhtml to EPUB/xhtml/ch1.xhtml: relocated gives their new
+	// path by their path in the EPUB being read, so that the references
+	// between them can be rewritten
+	relocated := make(map[string]string)
+
+	// The documents of the spine are the sections
+	inSpine := make(map[string]bool)
+	var spine []string
+	var spineItems []readPackageItem
+	nonLinear := make(map[string]bool)
+	sectionFilenames := make(map[string]string)
+	usedSectionFilenames := make(map[string]bool)
+	for _, itemref := range p.Spine.Itemrefs {
+		item, ok := items[itemref.Idref]
+		if !ok || item.Href == navPath || inSpine[item.ID] {
+			continue
+		}
+		inSpine[item.ID] = true
+		spine = append(spine, item.Href)
+		spineItems = append(spineItems, item)
+		if itemref.Linear == "no" {
+			nonLinear[item.Href] = true
+		}
+		filename := uniqueFilename(path.Base(item.Href), func(f string) bool { return usedSectionFilenames[f] })
+		usedSectionFilenames[filename] = true
+		sectionFilenames[item.Href] = filename
+		relocated[item.Href] = path.Join(contentFolderName, xhtmlFolderName, filename)
+	}
+
+	// The other resources are added under their base name
+	coverID := ""
+	for _, m := range p.Metadata.Metas {
+		if m.Name == "cover" {
+			coverID = m.Content
+		}
+	}
+	type readResource struct {
+		item     readPackageItem
+		media    map[string]string
+		filename string
+	}
+	var resources []readResource
+	usedMediaFilenames := make(map[string]bool)
+	for _, pi := range p.Items {
+		item := items[pi.ID]
+		if inSpine[item.ID] || item.Href == navPath || item.Href == ncxPath {
+			continue
+		}
+		media, folder := e.readMediaMap(item.MediaType)
+		if media == nil {
+			e.warnings.add(SeverityWarning, RuleSkippedResource, item.Href, "resource of type %s left out", item.MediaType)
+			continue
+		}
+		filename := uniqueFilename(path.Base(item.Href), func(f string) bool { return usedMediaFilenames[path.Join(folder, f)] })
+		usedMediaFilenames[path.Join(folder, filename)] = true
+		relocated[item.Href] = path.Join(contentFolderName, folder, filename)
+		resources = append(resources, readResource{item: item, media: media, filename: filename})
+	}
+	coverHref := ""
+	for _, r := range resources {
+		data, err := read(r.item.Href)
+		if err != nil {
+			return nil, err
+		}
+		switch r.item.MediaType {
+		case mediaTypeCSS:
+			data = []byte(relocateCSSReferences(string(data), r.item.Href, relocated))
+		case mediaTypeSmil:
+			data = []byte(relocateReferences(string(data), r.item.Href, relocated))
+		}
+		// The media type is sniffed again when the file is written; the data
+		// URLs can't have font/* media types
+		r.media[r.filename] = dataurl.New(data, "application/octet-stream").String()
+		if r.item.ID == coverID || hasProperty(r.item.Properties, coverImageProperties) {
+			e.cover.imageFilename = r.filename
+			coverHref = r.item.Href
+		}
+	}
+
+	// Add the sections, with their references to the relocated files
+	// rewritten
+	var stack []struct {
+		section *epubSection
+		depth   int
+	}
+	for _, item := range spineItems {
+		data, err := read(item.Href)
+		if err != nil {
+			return nil, err
+		}
+		x, err := newRawXhtml(relocateReferences(string(data), item.Href, relocated))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", item.Href, err)
+		}
+		section := &epubSection{
+			filename:   sectionFilenames[item.Href],
+			xhtml:      x,
+			properties: withoutProperties(item.Properties, tocNavItemProperties, coverImageProperties),
+		}
+
+		// Nest the section following the table of contents: a section is
+		// the child of the last section that is less deep in the TOC
+		depth := 1
+		for _, entry := range toc {
+			if entry.href == item.Href {
+				x.xml.Head.Title.Value = entry.title
+				depth = entry.depth
+				break
+			}
+		}
+		for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			e.sections = append(e.sections, section)
+		} else {
+			parent := stack[len(stack)-1].section
+			parent.children = append(parent.children, section)
+		}
+		stack = append(stack, struct {
+			section *epubSection
+			depth   int
+		}{section, depth})
+	}
+
+	e.checkTOCOrder(toc, spine, nonLinear, sectionFilenames)
+
+	if coverHref != "" && len(p.Spine.Itemrefs) > 0 {
+		e.cover.xhtmlFilename = readCoverPage(items[p.Spine.Itemrefs[0].Idref].Href, coverHref, toc, sectionFilenames, read)
+	}
+	for _, ref := range p.Guide {
+		if ref.Type == "cover" {
+			e.cover.xhtmlFilename = sectionFilenames[resolve(pkgPath, ref.Href)]
+		}
+	}
+
+	return e, nil
+}
+
+// Return the filename of the section of the first document of the spine, at
+// href, if it is the cover page of an EPUB being read, i.e. if it isn't in the
+// table of contents and shows the cover image; otherwise return "". The cover
+// entry of the guide, if any, takes precedence.
+func readCoverPage(href string, coverImageHref string, toc []readTOCEntry, sectionFilenames map[string]string, read func(string) ([]byte, error)) string {
+	filename, ok := sectionFilenames[href]
+	if !ok {
+		return ""
+	}
+	for _, entry := range toc {
+		if entry.href == href {
+			return ""
+		}
+	}
+	data, err := read(href)
+	if err != nil {
+		return ""
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "img" && start.Name.Local != "image" {
+			continue
+		}
+		for _, a := range start.Attr {
+			if (a.Name.Local == "src" || a.Name.Local == "href") && path.Join(path.Dir(href), a.Value) == coverImageHref {
+				return filename
+			}
+		}
+	}
+}
+
+// Set the metadata of a freshly created Epub from the package file
+func (e *Epub) readMetadata(p *readPackage) {
+	unique := 0
+	for i, id := range p.Metadata.Identifiers {
+		if id.ID == p.UniqueIdentifier {
+			unique = i
+		}
+	}
+	schemes := make(map[string]string)
+	for _, m := range p.Metadata.Metas {
+		if m.Property == pkgIdentifierTypeProperty {
+			schemes[strings.TrimPrefix(m.Refines, "#")] = readIdentifierScheme(m)
+		}
+	}
+	if len(p.Metadata.Identifiers) > 0 {
+		e.setIdentifier(strings.TrimSpace(p.Metadata.Identifiers[unique].Value))
+	}
+	for _, id := range p.Metadata.Identifiers {
+		if value := strings.TrimSpace(id.Value); value != "" {
+			e.addIdentifier(value, schemes[id.ID])
+		}
+	}
+	e.customIdentifier = true
+	e.readTitles(p)
+	e.readSubjects(p)
+	for i, lang := range p.Metadata.Languages {
+		if i == 0 {
+			e.SetLang(strings.TrimSpace(lang))
+		} else {
+			e.AddLang(strings.TrimSpace(lang))
+		}
+	}
+	if len(p.Metadata.Creators) > 0 {
+		e.SetAuthorLang(strings.TrimSpace(p.Metadata.Creators[0].Lang))
+		e.SetAuthor(strings.TrimSpace(p.Metadata.Creators[0].Value))
+	}
+	e.readContributors(p)
+	if p.Metadata.Description != "" {
+		e.SetDescription(p.Metadata.Description)
+	}
+	for _, m := range []struct {
+		value string
+		set   func(string)
+	}{
+		{p.Metadata.Publisher, e.SetPublisher},
+		{p.Metadata.Rights, e.SetRights},
+		{p.Metadata.Date, e.SetDate},
+		{p.Metadata.Source, e.SetSource},
+		{p.Metadata.Type, e.SetType},
+		{p.Metadata.Coverage, e.SetCoverage},
+		{p.Metadata.Relation, e.SetRelation},
+	} {
+		if value := strings.TrimSpace(m.value); value != "" {
+			m.set(value)
+		}
+	}
+	if name, position := readSeries(p); name != "" {
+		e.SetSeries(name, position)
+	}
+	e.SetAccessibility(readAccessibility(p))
+	e.readAudience(p)
+	if opts := readAppleBooksOptions(p); opts != nil {
+		// Unknown scroll axes are left out
+		if err := e.SetAppleBooksOptions(opts); err != nil {
+			opts.ScrollAxis = ""
+			e.SetAppleBooksOptions(opts)
+		}
+	}
+	if p.Spine.Ppd != "" {
+		e.SetPpd(p.Spine.Ppd)
+	}
+}
+
+// Return the map of the media of the Epub resources of the media type belong
+// to and the folder they are written to, or nil if the media type isn't
+// handled
+func (e *Epub) readMediaMap(mediaType string) (map[string]string, string) {
+	switch {
+	case mediaType == mediaTypeCSS:
+		return e.css, CSSFolderName
+	case mediaType == mediaTypeJavascript || mediaType == "application/javascript":
+		return e.scripts, ScriptFolderName
+	case mediaType == mediaTypeSmil:
+		return e.overlays, MediaOverlayFolderName
+	case mediaType == mediaTypeVTT:
+		return e.captions, CaptionsFolderName
+	case strings.HasPrefix(mediaType, "image/"):
+		return e.images, ImageFolderName
+	case strings.HasPrefix(mediaType, "audio/"):
+		return e.audios, AudioFolderName
+	case strings.HasPrefix(mediaType, "video/"):
+		return e.videos, VideoFolderName
+	case strings.HasPrefix(mediaType, "font/") || strings.HasPrefix(mediaType, "application/font-") ||
+		strings.HasPrefix(mediaType, "application/x-font-") || mediaType == "application/vnd.ms-opentype":
+		return e.fonts, FontFolderName
+	}
+	return nil, ""
+}
+
+// The href and src attributes of a start tag, whatever their namespace (e.g.
+// xlink:href)
+var refAttrRegexp = regexp.MustCompile(`(?i)(\s(?:[\w.-]+:)?(?:href|src)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// Rewrite the references of an XML document (a section or a media overlay)
+// at href in the EPUB being read to the files it was moved along with, see
+// relocated in OpenReader. The rest of the document is kept verbatim.
+func relocateReferences(doc string, href string, relocated map[string]string) string {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	last := 0
+	for {
+		start := int(d.InputOffset())
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		if _, ok := tok.(xml.StartElement); !ok {
+			continue
+		}
+		end := int(d.InputOffset())
+		tag := doc[start:end]
+		for _, m := range refAttrRegexp.FindAllStringSubmatchIndex(tag, -1) {
+			value := tag[m[4]+1 : m[5]-1]
+			ref, ok := relocateReference(html.UnescapeString(value), href, relocated)
+			if !ok {
+				continue
+			}
+			b.WriteString(doc[last : start+m[4]])
+			b.WriteString(`"` + html.EscapeString(ref) + `"`)
+			last = start + m[5]
+		}
+	}
+	if last == 0 {
+		return doc
+	}
+	b.WriteString(doc[last:])
+	return b.String()
+}
+
+// Rewrite the url() references of a CSS file at href in the EPUB being read,
+// see relocateReferences
+func relocateCSSReferences(css string, href string, relocated map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range cssURLRegexp.FindAllStringSubmatchIndex(css, -1) {
+		for i := 2; i < len(m); i += 2 {
+			if m[i] < 0 {
+				continue
+			}
+			ref, ok := relocateReference(css[m[i]:m[i+1]], href, relocated)
+			if !ok {
+				continue
+			}
+			b.WriteString(css[last:m[i]])
+			b.WriteString(ref)
+			last = m[i+1]
+		}
+	}
+	if last == 0 {
+		return css
+	}
+	b.WriteString(css[last:])
+	return b.String()
+}
+
+// Return the reference ref of the file at href in the EPUB being read,
+// rewritten relative to the new path of the file if it points to a relocated
+// file; return false if it doesn't need to be rewritten
+func relocateReference(ref string, href string, relocated map[string]string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || path.IsAbs(u.Path) {
+		return "", false
+	}
+	target, ok := relocated[path.Join(path.Dir(href), u.Path)]
+	if !ok {
+		return "", false
+	}
+	newPath, ok := relocated[href]
+	if !ok {
+		return "", false
+	}
+	rel := relativePath(path.Dir(newPath), target)
+	if rel == u.Path {
+		return "", false
+	}
+	u.Path, u.RawPath = rel, ""
+	return u.String(), true
+}
+
+// Return the path of target relative to the directory dir, both being clean
+// slash-separated paths
+func relativePath(dir string, target string) string {
+	dirParts := strings.Split(dir, "/")
+	targetParts := strings.Split(target, "/")
+	common := 0
+	for common < len(dirParts) && common < len(targetParts)-1 && dirParts[common] == targetParts[common] {
+		common++
+	}
+	parts := make([]string, 0, len(dirParts)-common+len(targetParts)-common)
+	for range dirParts[common:] {
+		parts = append(parts, "..")
+	}
+	return strings.Join(append(parts, targetParts[common:]...), "/")
+}
+
+// Parse the table of contents of a nav document
+func readNavTOC(doc []byte, resolve func(string) string) []readTOCEntry {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "nav" {
+			continue
+		}
+		isTOC := false
+		for _, a := range start.Attr {
+			if a.Name.Local == "type" && hasProperty(a.Value, "toc") {
+				isTOC = true
+			}
+		}
+		if !isTOC {
+			continue
+		}
+		var nav struct {
+			List readNavList `xml:"ol"`
+		}
+		if err := d.DecodeElement(&nav, &start); err != nil {
+			return nil
+		}
+		var entries []readTOCEntry
+		var walk func(l *readNavList, depth int)
+		walk = func(l *readNavList, depth int) {
+			for _, item := range l.Items {
+				if item.A.Href != "" {
+					entries = append(entries, readTOCEntry{
+						title: textContent(item.A.XML),
+						href:  resolve(item.A.Href),
+						depth: depth,
+					})
+				}
+				if item.List != nil {
+					walk(item.List, depth+1)
+				}
+			}
+		}
+		walk(&nav.List, 1)
+		return entries
+	}
+}
+
+// Parse the table of contents of an NCX document
+func readNcxTOC(doc []byte, resolve func(string) string) []readTOCEntry {
+	var ncx struct {
+		NavPoints []readNcxNavPoint `xml:"navMap>navPoint"`
+	}
+	if err := xml.Unmarshal(doc, &ncx); err != nil {
+		return nil
+	}
+	var entries []readTOCEntry
+	var walk func(points []readNcxNavPoint, depth int)
+	walk = func(points []readNcxNavPoint, depth int) {
+		for _, np := range points {
+			entries = append(entries, readTOCEntry{
+				title: strings.TrimSpace(np.Label),
+				href:  resolve(np.Content.Src),
+				depth: depth,
+			})
+			walk(np.Children, depth+1)
+		}
+	}
+	walk(ncx.NavPoints, 1)
+	return entries
+}
+
+// Return the text of an XML fragment, with whitespace collapsed
+func textContent(fragment string) string {
+	d := xml.NewDecoder(strings.NewReader("<x>" + fragment + "</x>"))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	var b strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			// The bylines of the nav documents written in articles mode
+			// aren't part of the titles
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "class" && attr.Value == tocNavBylineClass {
+					d.Skip()
+				}
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Report whether a space-separated list of properties contains property
+func hasProperty(properties string, property string) bool {
+	for _, p := range strings.Fields(properties) {
+		if p == property {
+			return true
+		}
+	}
+	return false
+}
+
+// Return a space-separated list of properties without the given ones
+func withoutProperties(properties string, remove ...string) string {
+	var kept []string
+	for _, p := range strings.Fields(properties) {
+		if !hasProperty(strings.Join(remove, " "), p) {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Return filename, or filename with a numeric suffix (-2, -3...) before its
+// extension if it is already used
+func uniqueFilename(filename string, used func(string) bool) string {
+	if !used(filename) {
+		return filename
+	}
+	ext := path.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if !used(candidate) {
+			return candidate
+		}
+	}
+}
+
+// TOC returns the table of contents of the EPUB, i.e. the sections and their
+// subsections with their titles. The cover page isn't listed.
+func (e *Epub) TOC() []TOCEntry {
+	e.Lock()
+	defer e.Unlock()
+	var entries func(sections []*epubSection) []TOCEntry
+	entries = func(sections []*epubSection) []TOCEntry {
+		var list []TOCEntry
+		for _, s := range sections {
+			if s.filename == e.cover.xhtmlFilename {
+				continue
+			}
+			list = append(list, TOCEntry{
+				Title:    s.xhtml.Title(),
+				Filename: s.filename,
+				Metadata: s.metadata,
+				Children: entries(s.children),
+			})
+		}
+		return list
+	}
+	return entries(e.sections)
+}
+
+// SectionContent returns the XHTML document of a section, identified by its
+// internal filename, as it will be written.
+func (e *Epub) SectionContent(sectionFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return "", &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	content, err := section.xhtml.content()
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
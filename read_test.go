@@ -0,0 +1,292 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Zip files into an EPUB, in order
+func zipTestEpub(t *testing.T, files [][2]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	for _, f := range files {
+		w, err := z.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAuthor(testEpubAuthor)
+	e.SetLang(testEpubLang)
+	e.SetDescription(testEpubDescription)
+	e.SetIdentifier(testEpubIdentifier)
+	e.SetPpd(testEpubPpd)
+	cssPath, err := e.AddCSS(testCoverCSSSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCover(imagePath, cssPath); err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := e.AddSection("<p>Chapter one</p>", "Chapter 1", "chapter1.xhtml", cssPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(chapter, "<p>Part A</p>", "Part A", "part-a.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>Chapter two</p>", "Chapter 2", "chapter2.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	epubPath := filepath.Join(t.TempDir(), testEpubFilename)
+	if err := e.Write(epubPath); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open(epubPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opened.Title() != testEpubTitle || opened.Author() != testEpubAuthor || opened.Lang() != testEpubLang ||
+		opened.Description() != testEpubDescription || opened.Identifier() != testEpubIdentifier || opened.Ppd() != testEpubPpd {
+		t.Errorf("Unexpected metadata: %q %q %q %q %q %q", opened.Title(), opened.Author(), opened.Lang(),
+			opened.Description(), opened.Identifier(), opened.Ppd())
+	}
+
+	if opened.cover.xhtmlFilename != "cover.xhtml" {
+		t.Errorf("Expected the cover page to be found, got %q", opened.cover.xhtmlFilename)
+	}
+	toc := opened.TOC()
+	if len(toc) != 2 || toc[0].Title != "Chapter 1" || toc[0].Filename != "chapter1.xhtml" ||
+		len(toc[0].Children) != 1 || toc[0].Children[0].Title != "Part A" || toc[1].Title != "Chapter 2" {
+		t.Errorf("Unexpected table of contents: %+v", toc)
+	}
+	content, err := opened.SectionContent("part-a.xhtml")
+	if err != nil || !strings.Contains(content, "<p>Part A</p>") {
+		t.Errorf("Unexpected section content: %q (%v)", content, err)
+	}
+	if _, err := opened.SectionContent("missing.xhtml"); err == nil {
+		t.Error("Expected an error for a missing section")
+	}
+
+	if len(opened.css) != 1 || len(opened.images) != 1 || opened.cover.imageFilename != "cover.png" {
+		t.Errorf("Unexpected resources: css %v, images %d, cover %q", opened.css, len(opened.images), opened.cover.imageFilename)
+	}
+
+	// The opened EPUB can be written again
+	var b bytes.Buffer
+	if _, err := opened.WriteTo(&b); err != nil {
+		t.Fatalf("Unexpected error writing the opened EPUB: %v", err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if !strings.Contains(files["EPUB/xhtml/chapter2.xhtml"], "<p>Chapter two</p>") {
+		t.Error("Expected the sections to be written again")
+	}
+}
+
+func TestOpenNCX(t *testing.T) {
+	data := zipTestEpub(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", testContainerContents},
+		{"EPUB/package.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Old book</dc:title>
+    <dc:identifier id="isbn">9780000000000</dc:identifier>
+    <dc:identifier id="bookid">urn:uuid:d1a2c3b4-0000-4000-8000-000000000000</dc:identifier>
+    <dc:language>en</dc:language>
+    <meta name="cover" content="img"/>
+  </metadata>
+  <manifest>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="c1" href="text/one.html" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/two%20b.html" media-type="application/xhtml+xml"/>
+    <item id="img" href="images/cover.jpg" media-type="image/jpeg"/>
+    <item id="doc" href="extra.pdf" media-type="application/pdf"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="c1"/>
+    <itemref idref="c2"/>
+  </spine>
+</package>`},
+		{"EPUB/toc.ncx", `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <navMap>
+    <navPoint id="p1"><navLabel><text>One</text></navLabel><content src="text/one.html"/>
+      <navPoint id="p2"><navLabel><text>Two</text></navLabel><content src="text/two%20b.html#start"/></navPoint>
+    </navPoint>
+  </navMap>
+</ncx>`},
+		{"EPUB/text/one.html", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>1</title></head><body><p>One</p></body></html>`},
+		{"EPUB/text/two b.html", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>2</title></head><body><p>Two</p></body></html>`},
+		{"EPUB/images/cover.jpg", "jpeg"},
+		{"EPUB/extra.pdf", "pdf"},
+	})
+
+	e, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.Title() != "Old book" || e.Identifier() != "urn:uuid:d1a2c3b4-0000-4000-8000-000000000000" || e.Lang() != "en" {
+		t.Errorf("Unexpected metadata: %q %q %q", e.Title(), e.Identifier(), e.Lang())
+	}
+	toc := e.TOC()
+	if len(toc) != 1 || toc[0].Title != "One" || len(toc[0].Children) != 1 ||
+		toc[0].Children[0].Title != "Two" || toc[0].Children[0].Filename != "two b.html" {
+		t.Errorf("Unexpected table of contents: %+v", toc)
+	}
+	if e.cover.imageFilename != "cover.jpg" {
+		t.Errorf("Expected the cover image to be cover.jpg, got %q", e.cover.imageFilename)
+	}
+	report := e.Validate()
+	found := false
+	for _, w := range report.Issues {
+		if w.Rule == RuleSkippedResource && w.Filename == "EPUB/extra.pdf" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the PDF file to be reported, got %v", report.Issues)
+	}
+}
+
+// Zip an EPUB with the folder layout of other tools (OEBPS/Text, OEBPS/Images,
+// OEBPS/Styles...) rather than the one of this package
+func foreignLayoutTestEpub(t *testing.T) []byte {
+	t.Helper()
+	png, err := os.ReadFile(testImageFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zipTestEpub(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml" />
+  </rootfiles>
+</container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Foreign book</dc:title>
+    <dc:identifier id="bookid">urn:uuid:d1a2c3b4-0000-4000-8000-000000000001</dc:identifier>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="nav" href="Text/nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch2" href="Text/Part/ch2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="pic" href="Images/pic.png" media-type="image/png"/>
+    <item id="bg" href="Images/bg.png" media-type="image/png"/>
+    <item id="css" href="Styles/s.css" media-type="text/css"/>
+    <item id="font" href="Fonts/f.ttf" media-type="font/ttf"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+    <itemref idref="ch2"/>
+  </spine>
+</package>`},
+		{"OEBPS/Text/nav.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Nav</title></head><body>
+<nav epub:type="toc"><ol><li><a href="ch1.xhtml">One</a></li><li><a href="Part/ch2.xhtml#two">Two</a></li></ol></nav></body></html>`},
+		{"OEBPS/Text/ch1.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>1</title><link rel="stylesheet" type="text/css" href="../Styles/s.css"/></head>
+<body><p>One <img src="../Images/pic.png" alt=""/> <a href="Part/ch2.xhtml#two">next</a> <a href="https://example.com/">site</a></p></body></html>`},
+		{"OEBPS/Text/Part/ch2.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>2</title><link rel="stylesheet" type="text/css" href="../../Styles/s.css"/></head>
+<body><p id="two">Two <a href="../ch1.xhtml">back</a></p></body></html>`},
+		{"OEBPS/Images/pic.png", string(png)},
+		{"OEBPS/Images/bg.png", string(png)},
+		{"OEBPS/Styles/s.css", `@font-face { font-family: F; src: url(../Fonts/f.ttf); }
+body { background: url("../Images/bg.png"); }`},
+		{"OEBPS/Fonts/f.ttf", "\x00\x01\x00\x00"},
+	})
+}
+
+// Check that the written EPUB doesn't have broken links
+func checkNoBrokenLinks(t *testing.T, e *Epub) {
+	t.Helper()
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleBrokenLink {
+			t.Errorf("Unexpected broken link: %v", w)
+		}
+	}
+}
+
+func TestOpenForeignLayout(t *testing.T) {
+	data := foreignLayoutTestEpub(t)
+	e, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	toc := e.TOC()
+	if len(toc) != 2 || toc[0].Filename != "ch1.xhtml" || toc[1].Title != "Two" {
+		t.Errorf("Unexpected table of contents: %+v", toc)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatalf("Unexpected error writing the opened EPUB: %v", err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	for filename, expected := range map[string][]string{
+		"EPUB/xhtml/ch1.xhtml": {`href="../css/s.css"`, `src="../images/pic.png"`, `href="ch2.xhtml#two"`, `href="https://example.com/"`},
+		"EPUB/xhtml/ch2.xhtml": {`href="../css/s.css"`, `href="ch1.xhtml"`},
+		"EPUB/css/s.css":       {`url(../fonts/f.ttf)`, `url("../images/bg.png")`},
+	} {
+		for _, s := range expected {
+			if !strings.Contains(files[filename], s) {
+				t.Errorf("Expected %s in %s, got:\n%s", s, filename, files[filename])
+			}
+		}
+	}
+	for _, filename := range []string{"EPUB/images/pic.png", "EPUB/images/bg.png", "EPUB/fonts/f.ttf"} {
+		if _, ok := files[filename]; !ok {
+			t.Errorf("Expected %s to be written", filename)
+		}
+	}
+	checkNoBrokenLinks(t, e)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	tests := map[string][][2]string{
+		"NoContainer": {{"mimetype", "application/epub+zip"}},
+		"UnsafeEntry": {{"META-INF/container.xml", testContainerContents}, {"../evil.opf", ""}},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := zipTestEpub(t, files)
+			_, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+			var invalid *InvalidEpubError
+			var unsafe *UnsafeEntryError
+			if !errors.As(err, &invalid) && !errors.As(err, &unsafe) {
+				t.Errorf("Expected an error, got %v", err)
+			}
+		})
+	}
+
+	if _, err := OpenReader(bytes.NewReader([]byte("not a zip")), 9); err == nil {
+		t.Errorf("Expected an error for a file that isn't a zip file, got %v", err)
+	}
+}
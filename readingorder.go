@@ -0,0 +1,156 @@
+package epub
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// ReadingOrderItem describes a section of the EPUB in the reading order
+// returned by ReadingOrder.
+type ReadingOrderItem struct {
+	// Path of the section relative to the package file, as used in the spine,
+	// e.g. xhtml/section0001.xhtml
+	Href  string `json:"href"`
+	Title string `json:"title"`
+	// Number of words in the body of the section
+	WordCount int `json:"wordCount"`
+	// Ids of the elements of the section, in document order, which can be
+	// used as fragment identifiers (href#id)
+	Anchors []string `json:"anchors,omitempty"`
+}
+
+// ReadingOrder returns the final reading order of the EPUB, i.e. the sections
+// in the order of the spine starting with the cover page if there is one, for
+// companion apps (progress sync, audiobook alignment...).
+func (e *Epub) ReadingOrder() ([]ReadingOrderItem, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.readingOrder()
+}
+
+// WriteReadingOrder writes the reading order returned by ReadingOrder to w as
+// JSON.
+func (e *Epub) WriteReadingOrder(w io.Writer) error {
+	e.Lock()
+	defer e.Unlock()
+
+	items, err := e.readingOrder()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(items); err != nil {
+		return fmt.Errorf("unable to write reading order: %w", err)
+	}
+	return nil
+}
+
+func (e *Epub) readingOrder() ([]ReadingOrderItem, error) {
+	// The body of the list of figures is only generated at write time
+	e.writeListOfFigures()
+
+	var items []ReadingOrderItem
+	for _, s := range e.spineSections() {
+		title := s.xhtml.Title()
+		if s.filename == e.cover.xhtmlFilename {
+			title = e.title
+		}
+		words, anchors, err := scanSectionBody(s.xhtml.xml.Body.XML)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read section %s: %w", s.filename, err)
+		}
+		items = append(items, ReadingOrderItem{
+			Href:      path.Join(xhtmlFolderName, s.filename),
+			Title:     title,
+			WordCount: words,
+			Anchors:   anchors,
+		})
+	}
+	return items, nil
+}
+
+// Return the sections in the order of the spine: the cover page first if
+// there is one, then the other sections depth-first, as written by
+// writeSections
+func (e *Epub) spineSections() []*epubSection {
+	var sections []*epubSection
+	if cover, ok := e.findSection(e.cover.xhtmlFilename); e.cover.xhtmlFilename != "" && ok {
+		sections = append(sections, cover)
+	}
+	var walk func([]*epubSection)
+	walk = func(children []*epubSection) {
+		for _, s := range children {
+			if s.filename != e.cover.xhtmlFilename {
+				sections = append(sections, s)
+			}
+			walk(s.children)
+		}
+	}
+	walk(e.sections)
+	return sections
+}
+
+// Count the words of the text of a section body and list the ids of its
+// elements. The body is parsed leniently, since it may contain HTML entities or
+// unclosed elements.
+func scanSectionBody(body string) (int, []string, error) {
+	d := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	words := 0
+	var anchors []string
+	// Words can be split across elements, e.g. <b>wo</b>rd
+	inWord := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if a.Name.Local == "id" && a.Value != "" {
+					anchors = append(anchors, a.Value)
+				}
+			}
+			if isBlockElement(t.Name.Local) {
+				inWord = false
+			}
+		case xml.EndElement:
+			if isBlockElement(t.Name.Local) {
+				inWord = false
+			}
+		case xml.CharData:
+			for _, r := range string(t) {
+				space := unicode.IsSpace(r)
+				if !space && !inWord {
+					words++
+				}
+				inWord = !space
+			}
+		}
+	}
+	return words, anchors, nil
+}
+
+// Report whether the element separates words, i.e. isn't an inline element
+func isBlockElement(name string) bool {
+	switch strings.ToLower(name) {
+	case "a", "abbr", "b", "bdi", "bdo", "cite", "code", "data", "dfn", "em", "i",
+		"kbd", "mark", "q", "rp", "rt", "ruby", "s", "samp", "small", "span",
+		"strong", "sub", "sup", "time", "u", "var":
+		return false
+	}
+	return true
+}
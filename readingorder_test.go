@@ -0,0 +1,77 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestReadingOrder(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetCover(imagePath, ""); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := e.AddSection(`<h1 id="ch1">Chapter one</h1><p id="p1">Some <b>bold</b>ly written&nbsp;text.</p><p>Two words</p>`, "Chapter 1", "ch1.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(parent, `<p>Sub</p>`, "Section 1.1", "ch1-1.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p>End</p>`, "Chapter 2", "ch2.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := e.ReadingOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hrefs []string
+	for _, item := range items {
+		hrefs = append(hrefs, item.Href)
+	}
+	expected := []string{"xhtml/cover.xhtml", "xhtml/ch1.xhtml", "xhtml/ch1-1.xhtml", "xhtml/ch2.xhtml"}
+	if !reflect.DeepEqual(hrefs, expected) {
+		t.Errorf("Unexpected reading order %v, expected %v", hrefs, expected)
+	}
+	if items[0].Title != testEpubTitle {
+		t.Errorf("Unexpected cover title %q", items[0].Title)
+	}
+	ch1 := items[1]
+	if ch1.Title != "Chapter 1" || ch1.WordCount != 8 || !reflect.DeepEqual(ch1.Anchors, []string{"ch1", "p1"}) {
+		t.Errorf("Unexpected reading order item %+v", ch1)
+	}
+
+	var b bytes.Buffer
+	if err := e.WriteReadingOrder(&b); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ReadingOrderItem
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("Unexpected JSON reading order:\n%s", b.String())
+	}
+}
+
+func TestScanSectionBody(t *testing.T) {
+	words, anchors, err := scanSectionBody(`<p>One<br/>two <span id="x">thr</span>ee</p><p>four</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words != 4 {
+		t.Errorf("Expected 4 words, got %d", words)
+	}
+	if !reflect.DeepEqual(anchors, []string{"x"}) {
+		t.Errorf("Unexpected anchors %v", anchors)
+	}
+}
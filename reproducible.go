@@ -0,0 +1,140 @@
+package epub
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// sourceDateEpochEnv is the environment variable giving the timestamp of
+// reproducible builds, see https://reproducible-builds.org/specs/source-date-epoch/
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// SetReproducible enables or disables the reproducible mode, in which writing
+// the same content twice yields byte-identical EPUBs, e.g. for caching,
+// content-addressed storage or golden tests:
+//   - the dcterms:modified date of the package file, the timestamps of the zip
+//     entries and the build time of the provenance statement are the time
+//     given by the SOURCE_DATE_EPOCH environment variable (a Unix timestamp),
+//     or 1980-01-01T00:00:00Z if it isn't set
+//   - unless an identifier was set with SetIdentifier or SetIDGenerator, the
+//     identifier written is a UUID derived from all the metadata, the sections
+//     and the media sources rather than the random one, which Identifier keeps
+//     returning
+//
+// The files are always written in the same order. Resource encrypters with a
+// random output (see SetResourceEncrypter) make the output differ all the
+// same.
+func (e *Epub) SetReproducible(reproducible bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.reproducible = reproducible
+}
+
+// SetModified sets the dcterms:modified date of the package file, which is the
+// time the EPUB is written by default, e.g. to keep the modification date of
+// the original book when it is regenerated. The timestamps of the zip entries
+// and the build time of the provenance statement use the same date. It takes
+// precedence over the fixed time of the reproducible mode; a zero time
+// restores the default.
+func (e *Epub) SetModified(t time.Time) {
+	e.Lock()
+	defer e.Unlock()
+	e.modified = t
+}
+
+// Modified returns the modification date set with SetModified, or the zero
+// time if none was set.
+func (e *Epub) Modified() time.Time {
+	return e.modified
+}
+
+// Return the time of a write: the date set with SetModified, the current
+// time, or the fixed time of the reproducible mode
+func (e *Epub) buildTime() time.Time {
+	if !e.modified.IsZero() {
+		return e.modified.UTC()
+	}
+	if !e.reproducible {
+		return time.Now()
+	}
+	if epoch, err := strconv.ParseInt(os.Getenv(sourceDateEpochEnv), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return canonicalModTime
+}
+
+// Return the identifier of the reproducible mode, derived from the metadata
+// and the content of the EPUB, or "" if the identifier was set by the user
+func (e *Epub) reproducibleIdentifier() (string, error) {
+	if !e.reproducible || e.customIdentifier || e.idGenerator != nil {
+		return "", nil
+	}
+	h := sha256.New()
+	write := func(s string) {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	// All the metadata of the package file but the random unique identifier
+	// and the metas produced by the writes
+	metadata := e.pkg.xml.Metadata
+	metadata.Identifiers = nil
+	metadata.Meta = nil
+	for _, m := range e.pkg.xml.Metadata.Meta {
+		switch {
+		case m.Property == pkgModifiedProperty, m.Property == pkgMediaDurationProperty,
+			m.Property == pkgIdentifierTypeProperty, m.Name == "cover":
+			continue
+		}
+		metadata.Meta = append(metadata.Meta, m)
+	}
+	b, err := xml.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	h.Write(b)
+	for _, identifier := range e.otherIdentifiers {
+		write(identifier.Value)
+		write(identifier.Scheme)
+	}
+	var walk func([]*epubSection)
+	walk = func(sections []*epubSection) {
+		for _, s := range sections {
+			write(s.filename)
+			write(s.xhtml.Title())
+			write(s.xhtml.raw)
+			write(s.xhtml.xml.Body.XML)
+			walk(s.children)
+		}
+	}
+	walk(e.sections)
+	for _, media := range []map[string]string{e.css, e.fonts, e.images, e.videos, e.audios, e.overlays, e.scripts, e.fontLicenses, e.captions, e.fileSources()} {
+		filenames := make([]string, 0, len(media))
+		for filename := range media {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			write(filename)
+			write(media[filename])
+		}
+	}
+	namespace := uuid.NewV5(uuid.NamespaceURL, "github.com/quailyquaily/go-epub")
+	return urnUUIDPrefix + uuid.NewV5(namespace, string(h.Sum(nil))).String(), nil
+}
+
+// Restore the identifiers replaced by the identifier of the reproducible mode
+// for a write
+func (e *Epub) restoreIdentifiers(identifier string, scheme string, others []Identifier) {
+	e.identifier = identifier
+	e.identifierScheme = scheme
+	e.otherIdentifiers = others
+	e.pkg.setIdentifiers(e.identifiers())
+	e.toc.setIdentifier(identifier)
+}
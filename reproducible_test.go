@@ -0,0 +1,125 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetReproducible(t *testing.T) {
+	build := func(reproducible bool, description string) ([]byte, string) {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetReproducible(reproducible)
+		e.SetDescription(description)
+		if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddCSS(testCoverCSSSource, ""); err != nil {
+			t.Fatal(err)
+		}
+		parent, err := e.AddSection(testSectionBody, "One", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSubSection(parent, testSectionBody, "Two", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		identifier := e.Identifier()
+		var b bytes.Buffer
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if e.Identifier() != identifier {
+			t.Errorf("Expected the identifier %s to be kept after the write, got %s", identifier, e.Identifier())
+		}
+		written := readPackageIdentifier(t, b.Bytes())
+		b.Reset()
+		if _, err := e.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if again := readPackageIdentifier(t, b.Bytes()); again != written {
+			t.Errorf("Expected the same identifier when writing again, got %s and %s", written, again)
+		}
+		return b.Bytes(), written
+	}
+
+	t.Setenv(sourceDateEpochEnv, "")
+	first, firstID := build(true, "")
+	second, secondID := build(true, "")
+	if !bytes.Equal(first, second) {
+		t.Error("Expected byte-identical EPUBs in reproducible mode")
+	}
+	if firstID != secondID || !strings.HasPrefix(firstID, urnUUIDPrefix) {
+		t.Errorf("Expected the same derived identifier, got %s and %s", firstID, secondID)
+	}
+	if pkg := readZipFiles(t, first)["EPUB/package.opf"]; !strings.Contains(pkg, ">1980-01-01T00:00:00Z</meta>") {
+		t.Errorf("Expected the fixed modified date, got:\n%s", pkg)
+	}
+
+	if _, id := build(true, "A description"); id == firstID {
+		t.Error("Expected the derived identifier to depend on the description")
+	}
+	if _, id := build(false, ""); id == firstID {
+		t.Error("Expected a random identifier outside of the reproducible mode")
+	}
+
+	t.Setenv(sourceDateEpochEnv, "1700000000")
+	epoch, _ := build(true, "")
+	if pkg := readZipFiles(t, epoch)["EPUB/package.opf"]; !strings.Contains(pkg, ">2023-11-14T22:13:20Z</meta>") {
+		t.Errorf("Expected the modified date of SOURCE_DATE_EPOCH, got:\n%s", pkg)
+	}
+}
+
+// Return the unique identifier written to the package file of an EPUB
+func readPackageIdentifier(t *testing.T, b []byte) string {
+	t.Helper()
+	m := regexp.MustCompile(`<dc:identifier id="pub-id">([^<]*)</dc:identifier>`).FindStringSubmatch(readZipFiles(t, b)["EPUB/package.opf"])
+	if m == nil {
+		t.Fatal("Expected a unique identifier in the package file")
+	}
+	return m[1]
+}
+
+func TestSetModified(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetReproducible(true)
+	modified := time.Date(2019, 6, 14, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	e.SetModified(modified)
+	if !e.Modified().Equal(modified) {
+		t.Errorf("Expected modification date %v, got %v", modified, e.Modified())
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if expected := `<meta property="dcterms:modified">2019-06-14T08:30:00Z</meta>`; !strings.Contains(opf, expected) {
+		t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := z.File[0].Modified; !got.Equal(time.Date(2019, 6, 14, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected the zip entries to have the modification date, got %v", got)
+	}
+
+	// A zero time writes the current date again
+	e.SetModified(time.Time{})
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(readZipFiles(t, b.Bytes())["EPUB/package.opf"], "2019-06-14") {
+		t.Error("Expected the modification date to be reset")
+	}
+}
@@ -0,0 +1,117 @@
+package epub
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryStatusCodes are the HTTP status codes retried by a RetryPolicy
+// that doesn't set its own: timeouts, rate limiting and server errors that are
+// usually transient.
+var DefaultRetryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy defines how the requests to remote media sources are retried
+// after a network error or a transient error response, see SetRetryPolicy.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one; 0 or 1 doesn't
+	// retry
+	Attempts int
+	// Delay before the first retry, doubled for each following one
+	Backoff time.Duration
+	// Maximum delay between two attempts, including the delay asked by the
+	// Retry-After header of the response; 0 doesn't limit it
+	MaxBackoff time.Duration
+	// HTTP status codes that are retried; if nil, DefaultRetryStatusCodes
+	StatusCodes []int
+}
+
+// SetRetryPolicy sets how the requests to remote media sources are retried,
+// both when they are added and when the EPUB is written, so that flaky servers
+// don't make a build fail. By default, requests aren't retried. The retries
+// stop when the context of the request is done, see WriteToContext.
+func (e *Epub) SetRetryPolicy(policy RetryPolicy) {
+	e.Lock()
+	defer e.Unlock()
+	e.retryPolicy = policy
+}
+
+// Report whether a response with the status code is retried
+func (p RetryPolicy) retryable(statusCode int) bool {
+	codes := p.StatusCodes
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the delay before the given retry (1 for the first one), honoring the
+// Retry-After header of the failed response if any
+func (p RetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			d = time.Duration(seconds) * time.Second
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Send a request for a remote source, waiting for the fetch limiter and
+// retrying according to the retry policy. The returned function releases the
+// limiter slot and must be called once the response body is closed.
+func (g grabber) send(req *http.Request) (*http.Response, func(), error) {
+	ctx := req.Context()
+	attempts := g.retry.Attempts
+	// Only remote URLs are worth retrying, local sources are also tried as
+	// URLs before they are found
+	if attempts < 1 || detectMediaType(req.URL.String()) != "URL" {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		release, err := g.limiter.acquire(ctx, req.URL.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := g.Do(req.Clone(ctx))
+		if attempt == attempts || ctx.Err() != nil || err == nil && !g.retry.retryable(resp.StatusCode) {
+			if err != nil {
+				release()
+				return nil, nil, err
+			}
+			return resp, release, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		release()
+
+		wait := g.retry.delay(attempt, resp)
+		logDebug(g.logger, "retrying media", "source", req.URL.String(), "attempt", attempt+1, "delay", wait)
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, nil, ctx.Err()
+		}
+	}
+}
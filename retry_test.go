@@ -0,0 +1,89 @@
+package epub
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Return a server failing the first failures requests of each method with a
+// 503 response before serving the test data
+func newFlakyServer(t *testing.T, failures int) *httptest.Server {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	fs := http.FileServer(http.Dir("./testdata/"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method]++
+		n := requests[r.Method]
+		mu.Unlock()
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	server := newFlakyServer(t, 2)
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetRetryPolicy(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err != nil {
+		t.Fatalf("Expected the check to be retried, got %v", err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatalf("Expected the download to be retried, got %v", err)
+	}
+
+	// Not enough attempts
+	server = newFlakyServer(t, 2)
+	e, err = NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetRetryPolicy(RetryPolicy{Attempts: 2, Backoff: time.Millisecond})
+	if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err == nil {
+		t.Error("Expected an error once the attempts are exhausted")
+	}
+
+	// Status codes that aren't retryable
+	server = newFlakyServer(t, 1)
+	e, err = NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetRetryPolicy(RetryPolicy{Attempts: 3, Backoff: time.Millisecond, StatusCodes: []int{http.StatusBadGateway}})
+	if _, err := e.AddImage(server.URL+"/gophercolor16x16.png", ""); err == nil {
+		t.Error("Expected a 503 response not to be retried")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, expected := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+	} {
+		if d := p.delay(retry, nil); d != expected {
+			t.Errorf("delay(%d) = %v, expected %v", retry, d, expected)
+		}
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"30"}}}
+	if d := p.delay(1, resp); d != time.Second {
+		t.Errorf("Expected Retry-After to be capped, got %v", d)
+	}
+	if d := (RetryPolicy{}).delay(1, resp); d != 30*time.Second {
+		t.Errorf("Expected Retry-After to be honored, got %v", d)
+	}
+}
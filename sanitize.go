@@ -0,0 +1,146 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFilenameLength is the maximum length in bytes of an internal filename
+	MaxFilenameLength = 255
+	// MaxSectionDepth is the maximum nesting depth of subsections
+	MaxSectionDepth = 32
+	// MaxElementDepth is the maximum nesting depth of the elements of a
+	// section body
+	MaxElementDepth = 256
+)
+
+// InvalidFilenameError is thrown by AddCSS, AddFont, AddImage, AddSection, etc.
+// if an internal filename can't be used safely inside the EPUB, e.g. because it
+// contains a path separator.
+type InvalidFilenameError struct {
+	Filename string // Filename that caused the error
+	Reason   string // Why the filename was rejected
+}
+
+func (e *InvalidFilenameError) Error() string {
+	return fmt.Sprintf("Invalid filename %q: %s", e.Filename, e.Reason)
+}
+
+// NestingTooDeepError is thrown by AddSection and AddSubSection if a section
+// body nests elements deeper than MaxElementDepth, or if a subsection would be
+// nested deeper than MaxSectionDepth.
+type NestingTooDeepError struct {
+	Filename string // Filename of the section (or of the parent section)
+	Limit    int    // The limit that was exceeded
+}
+
+func (e *NestingTooDeepError) Error() string {
+	return fmt.Sprintf("Nesting too deep in %s: more than %d levels", e.Filename, e.Limit)
+}
+
+// SanitizeText removes the characters that aren't allowed in XML documents
+// (control characters other than tab and line breaks, surrogates and the
+// non-characters U+FFFE and U+FFFF) from s and replaces invalid
+// UTF-8 sequences with the Unicode replacement character. It is applied to
+// the metadata (titles, authors, descriptions, identifiers, subjects...) when
+// it is set and to the section bodies and the texts added to them; text that
+// is changed is reported by Warnings.
+func SanitizeText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToValidUTF8(s, string(utf8.RuneError)) {
+		if isXMLChar(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Report whether r is a character allowed in XML 1.0
+//
+// Spec: https://www.w3.org/TR/xml/#charsets
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// ValidateFilename checks that an internal filename can be used safely inside
+// the EPUB: it must be valid UTF-8, at most MaxFilenameLength bytes long, and
+// it must not contain path separators, control characters or be a relative
+// path element such as "..". It returns an InvalidFilenameError otherwise.
+func ValidateFilename(filename string) error {
+	reason := ""
+	switch {
+	case filename == "":
+		reason = "empty filename"
+	case len(filename) > MaxFilenameLength:
+		reason = fmt.Sprintf("longer than %d bytes", MaxFilenameLength)
+	case !utf8.ValidString(filename):
+		reason = "not valid UTF-8"
+	case strings.ContainsAny(filename, `/\`):
+		reason = "contains a path separator"
+	case filename == "." || filename == "..":
+		reason = "relative path element"
+	case strings.IndexFunc(filename, func(r rune) bool { return r < 0x20 || r == 0x7F || !isXMLChar(r) }) >= 0:
+		reason = "contains a control character"
+	case !fs.ValidPath(filename):
+		reason = "not a valid path"
+	default:
+		return nil
+	}
+	return &InvalidFilenameError{Filename: filename, Reason: reason}
+}
+
+// Sanitize a text and report a warning if it was changed
+func (e *Epub) sanitize(filename string, field string, s string) string {
+	sanitized := SanitizeText(s)
+	if sanitized != s {
+		e.warnings.add(SeverityWarning, RuleSanitized, filename, "invalid characters were removed from the %s", field)
+	}
+	return sanitized
+}
+
+// Return the depth of the deepest element of a section body. The body is parsed
+// leniently, and parsing stops as soon as the depth exceeds limit.
+func elementDepth(body string, limit int) int {
+	d := xml.NewDecoder(strings.NewReader(body))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	depth, deepest := 0, 0
+	for deepest <= limit {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			deepest = max(deepest, depth)
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return deepest
+}
+
+// Return the depth of a section, 1 for the sections at the root, or 0 if
+// it doesn't exist
+func sectionDepth(sections []*epubSection, filename string) int {
+	for _, s := range sections {
+		if s.filename == filename {
+			return 1
+		}
+		if d := sectionDepth(s.children, filename); d > 0 {
+			return d + 1
+		}
+	}
+	return 0
+}
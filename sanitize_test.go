@@ -0,0 +1,219 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := map[string]string{
+		"My title":           "My title",
+		"My\x00 ti\x1btle":   "My title",
+		"tab\tnewline\n":     "tab\tnewline\n",
+		"bad \xff utf-8":     "bad � utf-8",
+		"non\uFFFEcharacter": "noncharacter",
+		"emoji 😀":            "emoji 😀",
+	}
+	for input, want := range tests {
+		if got := SanitizeText(input); got != want {
+			t.Errorf("SanitizeText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestValidateFilename(t *testing.T) {
+	for _, filename := range []string{"image.png", "my image.png", "ⓐ.xhtml", "..png"} {
+		if err := ValidateFilename(filename); err != nil {
+			t.Errorf("Unexpected error for %q: %v", filename, err)
+		}
+	}
+	for _, filename := range []string{"", ".", "..", "../evil.png", "/etc/passwd", `..\evil.png`, "a\x00.png", "a\nb.png", "bad\xff.png", strings.Repeat("a", MaxFilenameLength+1)} {
+		var invalid *InvalidFilenameError
+		if err := ValidateFilename(filename); !errors.As(err, &invalid) {
+			t.Errorf("Expected an InvalidFilenameError for %q, got %v", filename, err)
+		}
+	}
+}
+
+func TestAddMediaInvalidFilename(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var invalid *InvalidFilenameError
+	if _, err := e.AddImage(testImageFromFileSource, "../../evil.png"); !errors.As(err, &invalid) {
+		t.Errorf("Expected an InvalidFilenameError, got %v", err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "../evil", ""); !errors.As(err, &invalid) {
+		t.Errorf("Expected an InvalidFilenameError, got %v", err)
+	}
+}
+
+func TestNestingTooDeep(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tooDeep *NestingTooDeepError
+	body := strings.Repeat("<div>", MaxElementDepth+1) + strings.Repeat("</div>", MaxElementDepth+1)
+	if _, err := e.AddSection(body, testSectionTitle, "", ""); !errors.As(err, &tooDeep) {
+		t.Errorf("Expected a NestingTooDeepError for the body, got %v", err)
+	}
+
+	parent, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < MaxSectionDepth; i++ {
+		if parent, err = e.AddSubSection(parent, testSectionBody, testSectionTitle, "", ""); err != nil {
+			t.Fatalf("Unexpected error at depth %d: %v", i+1, err)
+		}
+	}
+	if _, err := e.AddSubSection(parent, testSectionBody, testSectionTitle, "", ""); !errors.As(err, &tooDeep) {
+		t.Errorf("Expected a NestingTooDeepError for the subsection, got %v", err)
+	}
+}
+
+func TestSanitizedWarnings(t *testing.T) {
+	e, err := NewEpub("My\x00 title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Title() != "My title" {
+		t.Errorf("Expected the title to be sanitized, got %q", e.Title())
+	}
+	if _, err := e.AddSection("<p>Text\x07</p>", testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	w := e.Warnings()
+	if len(w) != 2 || w[0].Rule != RuleSanitized || w[1].Rule != RuleSanitized {
+		t.Errorf("Expected 2 sanitized warnings, got %v", w)
+	}
+}
+
+func TestSanitizedMetadata(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagePath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetIdentifier("urn:isbn:9780000000002\x01")
+	e.AddIdentifier("12345", "Publisher\x02")
+	e.AddCodedSubject("Fantasy", "BISAC\x03", "FIC009020\x04")
+	e.AddContributor("Jane Doe", "trl\x05")
+	e.AddTranslatedTitle("Titre\x06", "fr")
+	if err := e.SetNavLogo(imagePath, "Logo\x0b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionMetadata(sectionPath, SectionMetadata{Date: "2024\x0c"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddFigure(sectionPath, testImageFromFileSource, "Caption\x0e", "Alt\x0f"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddTable(sectionPath, [][]string{{"Cell\x10"}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddEpigraph(sectionPath, "Quote\x11", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	sanitized := 0
+	for _, w := range e.Warnings() {
+		if w.Rule == RuleSanitized {
+			sanitized++
+		}
+	}
+	if sanitized != 12 {
+		t.Errorf("Expected 12 sanitized warnings, got %v", e.Warnings())
+	}
+	for name, content := range readZipFiles(t, b.Bytes()) {
+		if path.Ext(name) != ".opf" && path.Ext(name) != ".xhtml" && path.Ext(name) != ".ncx" {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(content))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("Expected %s to be well-formed, got %v", name, err)
+				break
+			}
+		}
+	}
+}
+
+func FuzzSanitizeText(f *testing.F) {
+	for _, seed := range []string{"My title", "\x00\x01\x1f", "\xff\xfe", "\uFFFE\uFFFF", "\uD7FF"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		sanitized := SanitizeText(s)
+		if !utf8.ValidString(sanitized) {
+			t.Fatalf("SanitizeText(%q) is not valid UTF-8", s)
+		}
+		for _, r := range sanitized {
+			if !isXMLChar(r) {
+				t.Fatalf("SanitizeText(%q) contains %U", s, r)
+			}
+		}
+		if again := SanitizeText(sanitized); again != sanitized {
+			t.Fatalf("SanitizeText isn't idempotent for %q", s)
+		}
+	})
+}
+
+func FuzzValidateFilename(f *testing.F) {
+	for _, seed := range []string{"image.png", "../evil.png", "/abs", `a\b`, "a\x00b", ".", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, filename string) {
+		if ValidateFilename(filename) != nil {
+			return
+		}
+		if !fs.ValidPath(filename) || strings.ContainsAny(filename, `/\`) || filename == ".." {
+			t.Fatalf("ValidateFilename accepted %q", filename)
+		}
+	})
+}
+
+func FuzzAddSection(f *testing.F) {
+	f.Add("<p>Body</p>", "Title", "section.xhtml")
+	f.Add("<p>\x00</p>", "\x1b[31mTitle", "../section")
+	f.Add("<div><div><div>", "", "")
+	f.Fuzz(func(t *testing.T, body string, title string, filename string) {
+		e, err := NewEpub(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSection(body, title, filename, ""); err != nil {
+			return
+		}
+		if _, err := e.WriteTo(io.Discard); err != nil {
+			t.Fatalf("Unable to write EPUB: %v", err)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// AddScript adds a JavaScript file to the EPUB and returns a relative path to
+// the script file that can be used in EPUB sections in the format:
+// ../ScriptFolderName/internalFilename
+//
+// The script source should either be a URL, a path to a local file, or an
+// embedded data URL, like the source of AddCSS. The script can then be
+// attached to sections with AddSectionScript.
+//
+// The internal filename will be used when storing the script file in the
+// EPUB and must be unique among all script files. If the same filename is
+// used more than once, FilenameAlreadyUsedError will be returned. The internal
+// filename is optional; if no filename is provided, one will be generated.
+func (e *Epub) AddScript(source string, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return addMedia(e.grabber(), source, internalFilename, scriptFileFormat, ScriptFolderName, e.scripts)
+}
+
+// AddSectionScript attaches a script to a section: a script element is added
+// to the head of the section, after the scripts already attached, and the
+// section is marked as scripted in the manifest as EPUB 3 requires. Attaching
+// a script twice has no effect.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection) and the internal path to an already-added script file (as
+// returned by AddScript) is required. The head of complete documents, such as
+// the sections added with AddRawSection, is left as is, but they are marked as
+// scripted.
+func (e *Epub) AddSectionScript(sectionFilename string, internalScriptPath string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	filename := path.Base(internalScriptPath)
+	if _, ok := e.scripts[filename]; !ok {
+		return fmt.Errorf("script file %s has not been added", internalScriptPath)
+	}
+	scriptPath := path.Join("..", ScriptFolderName, filename)
+	for _, script := range section.xhtml.xml.Head.Scripts {
+		if script.Src == scriptPath {
+			return nil
+		}
+	}
+	section.xhtml.addScript(scriptPath)
+	section.properties = addProperty(section.properties, sectionPropertyScripted)
+	return nil
+}
+
+// Add a property to a space-separated list of properties, if it isn't
+// already in it
+func addProperty(properties string, property string) string {
+	if hasProperty(properties, property) {
+		return properties
+	}
+	return strings.TrimSpace(properties + " " + property)
+}
@@ -0,0 +1,63 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestAddSectionScript(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptPath, err := e.AddScript(dataurl.EncodeBytes([]byte("document.body.classList.add('ready');")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scriptPath != "../scripts/script0001.js" {
+		t.Errorf("Unexpected script path %s", scriptPath)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "static", ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := e.AddSectionScript(sectionPath, scriptPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var notFound *SectionDoesNotExistError
+	if err := e.AddSectionScript("missing.xhtml", scriptPath); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError, got %v", err)
+	}
+	if err := e.AddSectionScript(sectionPath, "../scripts/missing.js"); err == nil {
+		t.Error("Expected an error for a script that hasn't been added")
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	section := files["EPUB/xhtml/section0001.xhtml"]
+	if n := strings.Count(section, `<script type="text/javascript" src="../scripts/script0001.js"></script>`); n != 1 {
+		t.Errorf("Expected the script once in the section, got:\n%s", section)
+	}
+	opf := files["EPUB/package.opf"]
+	for _, expected := range []string{
+		`href="scripts/script0001.js" media-type="text/javascript"></item>`,
+		`<item id="section0001.xhtml" href="xhtml/section0001.xhtml" media-type="application/xhtml+xml" properties="scripted"></item>`,
+		`<item id="static.xhtml" href="xhtml/static.xhtml" media-type="application/xhtml+xml"></item>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package epub
+
+const (
+	// Dublin Core terms in HTML metadata (DC-HTML)
+	xhtmlDCTermsSchemaRel  = "schema.dcterms"
+	xhtmlDCTermsSchemaHref = "http://purl.org/dc/terms/"
+	xhtmlDCTermsCreator    = "dcterms.creator"
+	xhtmlDCTermsDate       = "dcterms.date"
+	xhtmlDCTermsSource     = "dcterms.source"
+)
+
+// SectionMetadata is the metadata of a section of its own, e.g. a story of an
+// anthology or a collected blog post, see SetSectionMetadata.
+type SectionMetadata struct {
+	Author string
+	// Publication date, in the W3C Date and Time Formats like SetDate
+	Date string
+	// Resource the section is derived from, e.g. the URL of the blog post
+	Source string
+}
+
+// The <meta> element of the head of a section
+// Ex: <meta name="dcterms.creator" content="Jane Doe" />
+type xhtmlMeta struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
+}
+
+// SetSectionMetadata sets the author, publication date and source of a
+// section, for anthologies and collected works whose parts have their own. They
+// are written to the head of the section as Dublin Core terms
+// (dcterms.creator, dcterms.date and dcterms.source meta elements), and are
+// available to the chapter opener template (ChapterOpenerData) and in the
+// entries returned by TOC. Empty fields are left out. Dates that don't follow
+// the W3C Date and Time Formats are reported by Validate.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection). RawSectionError is returned for the complete documents, such as
+// the sections of an EPUB read with Open, whose head is written as is.
+func (e *Epub) SetSectionMetadata(sectionFilename string, m SectionMetadata) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	if section.xhtml.raw != "" {
+		return &RawSectionError{Filename: sectionFilename}
+	}
+	m.Author = e.sanitize(section.filename, "section author", m.Author)
+	m.Source = e.sanitize(section.filename, "section source", m.Source)
+	m.Date = e.sanitize(section.filename, "section date", m.Date)
+	section.metadata = m
+	section.xhtml.setMetadata(m)
+	return nil
+}
+
+// Set the Dublin Core meta elements of the document, replacing any previous
+// ones
+func (x *xhtml) setMetadata(m SectionMetadata) {
+	x.xml.Head.Metas = nil
+	for _, meta := range []xhtmlMeta{
+		{Name: xhtmlDCTermsCreator, Content: m.Author},
+		{Name: xhtmlDCTermsDate, Content: m.Date},
+		{Name: xhtmlDCTermsSource, Content: m.Source},
+	} {
+		if meta.Content != "" {
+			x.xml.Head.Metas = append(x.xml.Head.Metas, meta)
+		}
+	}
+	var links []xhtmlLink
+	for _, l := range x.xml.Head.Links {
+		if l.Rel != xhtmlDCTermsSchemaRel {
+			links = append(links, l)
+		}
+	}
+	if len(x.xml.Head.Metas) > 0 {
+		links = append(links, xhtmlLink{Rel: xhtmlDCTermsSchemaRel, Href: xhtmlDCTermsSchemaHref})
+	}
+	x.xml.Head.Links = links
+}
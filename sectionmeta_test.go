@@ -0,0 +1,95 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetSectionMetadata(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "Story", "story.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "Post", "post.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	story := SectionMetadata{Author: "Ann Author", Date: "2023-04-01", Source: "https://example.com/story"}
+	if err := e.SetSectionMetadata("story.xhtml", story); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionMetadata("post.xhtml", SectionMetadata{Date: "April 2023"}); err != nil {
+		t.Fatal(err)
+	}
+	var notFound *SectionDoesNotExistError
+	if err := e.SetSectionMetadata("missing.xhtml", story); !errors.As(err, &notFound) {
+		t.Errorf("Expected SectionDoesNotExistError, got %v", err)
+	}
+
+	if toc := e.TOC(); toc[0].Metadata != story {
+		t.Errorf("Expected the metadata in the TOC entry, got %+v", toc[0].Metadata)
+	}
+	content, err := e.SectionContent("story.xhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, element := range []string{
+		`<meta name="dcterms.creator" content="Ann Author"></meta>`,
+		`<meta name="dcterms.date" content="2023-04-01"></meta>`,
+		`<meta name="dcterms.source" content="https://example.com/story"></meta>`,
+		`<link rel="schema.dcterms" href="http://purl.org/dc/terms/"></link>`,
+	} {
+		if !strings.Contains(content, element) {
+			t.Errorf("Expected %s in the section head, got:\n%s", element, content)
+		}
+	}
+
+	var invalidDate []string
+	for _, issue := range e.Validate().Issues {
+		if issue.Rule == RuleInvalidDate {
+			invalidDate = append(invalidDate, issue.Filename)
+		}
+	}
+	if len(invalidDate) != 1 || invalidDate[0] != "post.xhtml" {
+		t.Errorf("Expected the date of post.xhtml to be reported, got %v", invalidDate)
+	}
+
+	// Clearing the metadata removes the elements
+	if err := e.SetSectionMetadata("story.xhtml", SectionMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if written := readZipFiles(t, b.Bytes())["EPUB/xhtml/story.xhtml"]; strings.Contains(written, "dcterms") {
+		t.Errorf("Expected no metadata in the section, got:\n%s", written)
+	}
+}
+
+func TestChapterOpenerSectionMetadata(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>Once upon a time</p>", "Story", "story.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionMetadata("story.xhtml", SectionMetadata{Author: "Ann Author"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChapterOpeners(`<h1>{{.Title}}</h1><p class="byline">{{.Metadata.Author}}</p>`); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if written := readZipFiles(t, b.Bytes())["EPUB/xhtml/story.xhtml"]; !strings.Contains(written, `<p class="byline">Ann Author</p>`) {
+		t.Errorf("Expected the author in the chapter opener, got:\n%s", written)
+	}
+}
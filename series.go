@@ -0,0 +1,112 @@
+package epub
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	pkgCollectionProperty     = "belongs-to-collection"
+	pkgCollectionTypeProperty = "collection-type"
+	pkgGroupPositionProperty  = "group-position"
+	pkgSeriesID               = "series"
+	pkgSeriesRefines          = "#series"
+	pkgCollectionTypeSeries   = "series"
+	// Legacy metas of calibre, which many reading systems still rely on
+	pkgCalibreSeriesName  = "calibre:series"
+	pkgCalibreSeriesIndex = "calibre:series_index"
+)
+
+// Series returns the series the EPUB belongs to and its position in the
+// series, see SetSeries.
+func (e *Epub) Series() (string, float64) {
+	return e.seriesName, e.seriesPosition
+}
+
+// SetSeries sets the series the EPUB belongs to and its position in the
+// series, e.g. 2 for the second book or 2.5 for a novella published between
+// the second and the third one. A position of 0 or less leaves the position
+// out, and an empty name removes the series.
+//
+// The series is written to the package file both as an EPUB 3 collection
+// (belongs-to-collection, refined by collection-type and group-position) and
+// as the legacy calibre:series and calibre:series_index metas, for the
+// reading systems that only know the latter.
+func (e *Epub) SetSeries(name string, position float64) {
+	e.Lock()
+	defer e.Unlock()
+	name = strings.TrimSpace(e.sanitize("", "series", name))
+	if name == "" {
+		position = 0
+	}
+	e.seriesName = name
+	e.seriesPosition = position
+	pos := ""
+	if position > 0 {
+		pos = strconv.FormatFloat(position, 'f', -1, 64)
+	}
+	e.pkg.setSeries(name, pos)
+}
+
+// Set the series metas, replacing any previous ones. The position is omitted
+// if empty, and the series is removed if name is empty.
+func (p *pkg) setSeries(name string, position string) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.ID == pkgSeriesID || m.Refines == pkgSeriesRefines ||
+			m.Name == pkgCalibreSeriesName || m.Name == pkgCalibreSeriesIndex {
+			continue
+		}
+		metas = append(metas, m)
+	}
+	if name != "" {
+		metas = append(metas,
+			pkgMeta{Property: pkgCollectionProperty, ID: pkgSeriesID, Data: name},
+			pkgMeta{Refines: pkgSeriesRefines, Property: pkgCollectionTypeProperty, Data: pkgCollectionTypeSeries},
+		)
+		if position != "" {
+			metas = append(metas, pkgMeta{Refines: pkgSeriesRefines, Property: pkgGroupPositionProperty, Data: position})
+		}
+		metas = append(metas, pkgMeta{Name: pkgCalibreSeriesName, Content: name})
+		if position != "" {
+			metas = append(metas, pkgMeta{Name: pkgCalibreSeriesIndex, Content: position})
+		}
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Return the series of an EPUB read with Open and its position, from its
+// first collection of type series or, failing that, from the calibre metas
+func readSeries(p *readPackage) (string, float64) {
+	metas := p.Metadata.Metas
+	for _, collection := range metas {
+		if collection.Property != pkgCollectionProperty || collection.ID == "" {
+			continue
+		}
+		series, position := false, 0.0
+		for _, m := range metas {
+			if m.Refines != "#"+collection.ID {
+				continue
+			}
+			switch m.Property {
+			case pkgCollectionTypeProperty:
+				series = strings.TrimSpace(m.Value) == pkgCollectionTypeSeries
+			case pkgGroupPositionProperty:
+				position, _ = strconv.ParseFloat(strings.TrimSpace(m.Value), 64)
+			}
+		}
+		if series {
+			return strings.TrimSpace(collection.Value), position
+		}
+	}
+	name, position := "", 0.0
+	for _, m := range metas {
+		switch m.Name {
+		case pkgCalibreSeriesName:
+			name = strings.TrimSpace(m.Content)
+		case pkgCalibreSeriesIndex:
+			position, _ = strconv.ParseFloat(strings.TrimSpace(m.Content), 64)
+		}
+	}
+	return name, position
+}
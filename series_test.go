@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetSeries(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetSeries("The First Series", 1)
+	// Setting the series again replaces it
+	e.SetSeries("The Expanse", 2.5)
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`<meta property="belongs-to-collection" id="series">The Expanse</meta>`,
+		`<meta refines="#series" property="collection-type">series</meta>`,
+		`<meta refines="#series" property="group-position">2.5</meta>`,
+		`<meta name="calibre:series" content="The Expanse"></meta>`,
+		`<meta name="calibre:series_index" content="2.5"></meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+	if strings.Contains(opf, "The First Series") {
+		t.Error("Expected the previous series to be replaced")
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, position := opened.Series(); name != "The Expanse" || position != 2.5 {
+		t.Errorf("Expected the series to be read back, got %q %v", name, position)
+	}
+
+	e.SetSeries("", 3)
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf = readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if strings.Contains(opf, "series") {
+		t.Errorf("Expected the series to be removed, got:\n%s", opf)
+	}
+}
+
+func TestReadSeriesCalibre(t *testing.T) {
+	var p readPackage
+	p.Metadata.Metas = []readPackageMeta{
+		{Name: pkgCalibreSeriesName, Content: "Discworld"},
+		{Name: pkgCalibreSeriesIndex, Content: "4"},
+	}
+	if name, position := readSeries(&p); name != "Discworld" || position != 4 {
+		t.Errorf("Expected the calibre series, got %q %v", name, position)
+	}
+}
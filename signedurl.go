@@ -0,0 +1,107 @@
+package epub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Signed URLs expiring within this delay are refreshed before they are
+// requested, so that they don't expire while the request is being sent
+const signedURLExpiryMargin = 10 * time.Second
+
+// SignedURLRefresher returns a new URL for a media source whose signature has
+// expired, e.g. by presigning the object of an object storage again, see
+// SetSignedURLRefresher.
+type SignedURLRefresher func(ctx context.Context, source string) (string, error)
+
+// SetSignedURLRefresher sets the function called when a media source is a
+// signed URL that has expired by the time it is retrieved, which is common
+// for large EPUBs whose media are added long before the EPUB is written. The
+// new URL is requested instead of the expired one; the source of the media
+// doesn't change, so it is refreshed again on the next write if needed.
+//
+// The expiry is read from the query of the URL for the signatures of Amazon
+// S3 (X-Amz-Date and X-Amz-Expires, or Expires with Signature), Google Cloud
+// Storage (X-Goog-Date and X-Goog-Expires), Amazon CloudFront (Expires with
+// Signature) and Azure Blob Storage (se with sig). A signed URL denied with
+// 403 Forbidden is refreshed as well, since the clock of the server may
+// differ. Setting nil removes the refresher; expired URLs then fail like any
+// other request.
+func (e *Epub) SetSignedURLRefresher(refresh SignedURLRefresher) {
+	e.Lock()
+	defer e.Unlock()
+	e.signedURLRefresher = refresh
+}
+
+// Return the expiry of a signed URL, if the URL is signed with a known
+// signature that gives one
+func signedURLExpiry(source string) (expiry time.Time, ok bool) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return time.Time{}, false
+	}
+	q := u.Query()
+	for _, v := range [][2]string{{"X-Amz-Date", "X-Amz-Expires"}, {"X-Goog-Date", "X-Goog-Expires"}} {
+		date, expires := q.Get(v[0]), q.Get(v[1])
+		if date == "" || expires == "" {
+			continue
+		}
+		signed, err := time.Parse("20060102T150405Z", date)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds, err := strconv.Atoi(expires)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return signed.Add(time.Duration(seconds) * time.Second), true
+	}
+	if expires := q.Get("Expires"); expires != "" && q.Get("Signature") != "" {
+		seconds, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(seconds, 0), true
+	}
+	if se := q.Get("se"); se != "" && q.Get("sig") != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z", "2006-01-02"} {
+			if t, err := time.Parse(layout, se); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Return the URL to request for a source: a refreshed URL if the source is a
+// signed URL that has expired (or if force is set and the source is signed),
+// the source itself otherwise
+func (g grabber) signedURL(mediaSource string, force bool) (string, error) {
+	if g.refreshSigned == nil {
+		return mediaSource, nil
+	}
+	expiry, ok := signedURLExpiry(mediaSource)
+	if !ok || !force && time.Until(expiry) > signedURLExpiryMargin {
+		return mediaSource, nil
+	}
+	logDebug(g.logger, "refreshing signed URL", "source", mediaSource, "expiry", expiry)
+	refreshed, err := g.refreshSigned(g.context(), mediaSource)
+	if err != nil {
+		return "", fmt.Errorf("unable to refresh signed URL: %w", err)
+	}
+	return refreshed, nil
+}
+
+// Report whether a response to a signed URL denies it, which may mean that
+// the URL has expired according to the server
+func (g grabber) signedURLDenied(mediaSource string, resp *http.Response) bool {
+	if g.refreshSigned == nil || resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	_, ok := signedURLExpiry(mediaSource)
+	return ok
+}
@@ -0,0 +1,84 @@
+package epub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURLExpiry(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		expiry time.Time
+		signed bool
+	}{
+		{"https://bucket.s3.amazonaws.com/a.png?X-Amz-Date=20240501T120000Z&X-Amz-Expires=3600&X-Amz-Signature=abc", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC), true},
+		{"https://storage.googleapis.com/b/a.png?X-Goog-Date=20240501T120000Z&X-Goog-Expires=60&X-Goog-Signature=abc", time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC), true},
+		{"https://d111.cloudfront.net/a.png?Expires=1714564800&Signature=abc&Key-Pair-Id=K", time.Unix(1714564800, 0), true},
+		{"https://account.blob.core.windows.net/c/a.png?sv=2022-11-02&se=2024-05-01T12:00:00Z&sig=abc", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), true},
+		{"https://example.com/a.png?Expires=1714564800", time.Time{}, false},
+		{"https://example.com/a.png", time.Time{}, false},
+	} {
+		expiry, signed := signedURLExpiry(test.source)
+		if signed != test.signed || !expiry.Equal(test.expiry) {
+			t.Errorf("%s: expected %v %v, got %v %v", test.source, test.expiry, test.signed, expiry, signed)
+		}
+	}
+}
+
+func TestSetSignedURLRefresher(t *testing.T) {
+	files := http.FileServer(http.Dir("testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expires, _ := time.Parse("20060102T150405Z", r.URL.Query().Get("X-Amz-Date"))
+		if time.Now().After(expires.Add(time.Minute)) || r.URL.Query().Get("X-Amz-Signature") == "revoked" {
+			http.Error(w, "Request has expired", http.StatusForbidden)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	sign := func(date time.Time) string {
+		return fmt.Sprintf("%s/gophercolor16x16.png?X-Amz-Date=%s&X-Amz-Expires=60&X-Amz-Signature=abc", server.URL, date.UTC().Format("20060102T150405Z"))
+	}
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := sign(time.Now())
+	if _, err := e.AddImage(source, "image.png"); err != nil {
+		t.Fatal(err)
+	}
+	// The image source expires before the EPUB is written
+	e.images["image.png"] = sign(time.Now().Add(-time.Hour))
+	if _, err := e.WriteTo(io.Discard); err == nil {
+		t.Fatal("Expected the expired URL to fail without refresher")
+	}
+
+	var refreshed []string
+	e.SetSignedURLRefresher(func(ctx context.Context, source string) (string, error) {
+		refreshed = append(refreshed, source)
+		return sign(time.Now()), nil
+	})
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 1 || refreshed[0] != e.images["image.png"] {
+		t.Errorf("Expected the expired source to be refreshed once, got %v", refreshed)
+	}
+
+	// A signed URL denied by the server is refreshed as well
+	refreshed = nil
+	e.images["image.png"] = strings.Replace(sign(time.Now()), "X-Amz-Signature=abc", "X-Amz-Signature=revoked", 1)
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 1 || !strings.Contains(refreshed[0], "revoked") {
+		t.Errorf("Expected the denied source to be refreshed once, got %v", refreshed)
+	}
+}
@@ -0,0 +1,85 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+)
+
+// SetSectionStylesheets sets the stylesheets of a section, replacing the one
+// given to AddSection, so that themes can be layered (e.g. a base stylesheet
+// followed by a chapter-specific one). The stylesheets are linked in the given
+// order, after the default stylesheets (see SetDefaultStylesheets). Setting no
+// stylesheet removes them.
+//
+// The section is identified by its internal filename (as returned by
+// AddSection) and the internal paths to already-added CSS files (as returned
+// by AddCSS) are required. The head of complete documents, such as the
+// sections added with AddRawSection, is left as is.
+func (e *Epub) SetSectionStylesheets(sectionFilename string, internalCSSPaths ...string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	paths, err := e.stylesheetPaths(internalCSSPaths)
+	if err != nil {
+		return err
+	}
+	section.xhtml.setStylesheets(paths)
+	return nil
+}
+
+// SetDefaultStylesheets sets the stylesheets linked to all the sections when
+// the EPUB is written, before their own stylesheets, e.g. the base stylesheet
+// of a theme. The cover page and the complete documents, such as the sections
+// added with AddRawSection, are left as is. Setting no stylesheet removes
+// them.
+//
+// The internal paths to already-added CSS files (as returned by AddCSS) are
+// required.
+func (e *Epub) SetDefaultStylesheets(internalCSSPaths ...string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	paths, err := e.stylesheetPaths(internalCSSPaths)
+	if err != nil {
+		return err
+	}
+	e.defaultCSS = paths
+	return nil
+}
+
+// Return the paths of CSS files relative to the sections, checking that the
+// files have been added
+func (e *Epub) stylesheetPaths(internalCSSPaths []string) ([]string, error) {
+	var paths []string
+	for _, p := range internalCSSPaths {
+		filename := path.Base(p)
+		if _, ok := e.css[filename]; !ok {
+			return nil, fmt.Errorf("CSS file %s has not been added", p)
+		}
+		paths = append(paths, path.Join("..", CSSFolderName, filename))
+	}
+	return paths, nil
+}
+
+// Link the default stylesheets to a section document, before its own
+// stylesheets. The stylesheets the section already links are left out.
+func (e *Epub) linkDefaultStylesheets(root *xhtmlRoot) {
+	linked := make(map[string]bool)
+	for _, l := range root.Head.Links {
+		if l.Rel == xhtmlLinkRel {
+			linked[l.Href] = true
+		}
+	}
+	var links []xhtmlLink
+	for _, p := range e.defaultCSS {
+		if !linked[p] {
+			linked[p] = true
+			links = append(links, xhtmlLink{Rel: xhtmlLinkRel, Type: mediaTypeCSS, Href: p})
+		}
+	}
+	root.Head.Links = append(links, root.Head.Links...)
+}
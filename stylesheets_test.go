@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+var stylesheetLinkRegex = regexp.MustCompile(`<link rel="stylesheet" type="text/css" href="([^"]+)"`)
+
+func TestSetSectionStylesheets(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, filename := range []string{"base.css", "chapter.css", "theme.css"} {
+		p, err := e.AddCSS(dataurl.EncodeBytes([]byte("p {}")), filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	basePath, chapterPath, themePath := paths[0], paths[1], paths[2]
+	layered, err := e.AddSection(testSectionBody, "Layered", "", basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, "Default", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSectionStylesheets(layered, themePath, chapterPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetDefaultStylesheets(basePath, themePath); err != nil {
+		t.Fatal(err)
+	}
+
+	var notFound *SectionDoesNotExistError
+	if err := e.SetSectionStylesheets("missing.xhtml", basePath); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError, got %v", err)
+	}
+	if err := e.SetDefaultStylesheets("../css/missing.css"); err == nil {
+		t.Error("Expected an error for a CSS file that hasn't been added")
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	for filename, expected := range map[string][]string{
+		// The stylesheets of the section aren't linked twice
+		"EPUB/xhtml/section0001.xhtml": {"../css/base.css", "../css/theme.css", "../css/chapter.css"},
+		"EPUB/xhtml/section0002.xhtml": {"../css/base.css", "../css/theme.css"},
+	} {
+		var hrefs []string
+		for _, m := range stylesheetLinkRegex.FindAllStringSubmatch(files[filename], -1) {
+			hrefs = append(hrefs, m[1])
+		}
+		if strings.Join(hrefs, " ") != strings.Join(expected, " ") {
+			t.Errorf("Expected the stylesheets %v in %s, got %v", expected, filename, hrefs)
+		}
+	}
+}
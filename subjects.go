@@ -0,0 +1,119 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Authorities of the subject codes, see AddCodedSubject
+const (
+	SubjectAuthorityBISAC = "BISAC"
+	SubjectAuthorityTHEMA = "THEMA"
+)
+
+const (
+	pkgSubjectIDFormat          = "subject-%d"
+	pkgSubjectAuthorityProperty = "authority"
+	pkgSubjectTermProperty      = "term"
+)
+
+// Subject is a subject of the EPUB (dc:subject).
+type Subject struct {
+	// Term of the subject, e.g. "FICTION / Fantasy / Epic" or a keyword
+	Term string `json:"term"`
+	// Authority of the code, e.g. SubjectAuthorityBISAC, and code of the
+	// subject in this authority, e.g. FIC009020; empty for a keyword
+	Authority string `json:"authority,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// The <dc:subject> element
+type pkgSubject struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Data string `xml:",chardata"`
+}
+
+// Subjects returns the subjects of the EPUB, in the order they were added.
+func (e *Epub) Subjects() []Subject {
+	e.Lock()
+	defer e.Unlock()
+	return append([]Subject(nil), e.subjects...)
+}
+
+// AddSubject adds a subject or keyword to the EPUB.
+func (e *Epub) AddSubject(term string) {
+	e.Lock()
+	defer e.Unlock()
+	e.addSubject(Subject{Term: term})
+}
+
+// AddCodedSubject adds a subject of a classification to the EPUB, such as
+// the BISAC or THEMA classification stores require: the term is written as
+// the dc:subject element, refined by the authority and the code of the
+// subject, e.g.
+//
+//	e.AddCodedSubject("FICTION / Fantasy / Epic", SubjectAuthorityBISAC, "FIC009020")
+func (e *Epub) AddCodedSubject(term string, authority string, code string) {
+	e.Lock()
+	defer e.Unlock()
+	e.addSubject(Subject{Term: term, Authority: authority, Code: code})
+}
+
+func (e *Epub) addSubject(s Subject) {
+	s.Term = e.sanitize("", "subject", s.Term)
+	s.Authority = e.sanitize("", "subject authority", s.Authority)
+	s.Code = e.sanitize("", "subject code", s.Code)
+	e.subjects = append(e.subjects, s)
+	e.pkg.setSubjects(e.subjects)
+}
+
+// Set the dc:subject elements and the authority and term refinements of the
+// coded subjects
+func (p *pkg) setSubjects(subjects []Subject) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if !(strings.HasPrefix(m.Refines, "#subject-") &&
+			(m.Property == pkgSubjectAuthorityProperty || m.Property == pkgSubjectTermProperty)) {
+			metas = append(metas, m)
+		}
+	}
+	p.xml.Metadata.Subjects = nil
+	for i, s := range subjects {
+		subject := pkgSubject{Data: s.Term}
+		if s.Authority != "" || s.Code != "" {
+			subject.ID = fmt.Sprintf(pkgSubjectIDFormat, i+1)
+			if s.Authority != "" {
+				metas = append(metas, pkgMeta{Refines: "#" + subject.ID, Property: pkgSubjectAuthorityProperty, Data: s.Authority})
+			}
+			if s.Code != "" {
+				metas = append(metas, pkgMeta{Refines: "#" + subject.ID, Property: pkgSubjectTermProperty, Data: s.Code})
+			}
+		}
+		p.xml.Metadata.Subjects = append(p.xml.Metadata.Subjects, subject)
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Set the subjects of a freshly created Epub from the package file
+func (e *Epub) readSubjects(p *readPackage) {
+	authorities := make(map[string]string)
+	codes := make(map[string]string)
+	for _, m := range p.Metadata.Metas {
+		id := strings.TrimPrefix(m.Refines, "#")
+		switch m.Property {
+		case pkgSubjectAuthorityProperty:
+			authorities[id] = strings.TrimSpace(m.Value)
+		case pkgSubjectTermProperty:
+			codes[id] = strings.TrimSpace(m.Value)
+		}
+	}
+	for _, s := range p.Metadata.Subjects {
+		subject := Subject{Term: strings.TrimSpace(s.Value)}
+		if s.ID != "" {
+			subject.Authority, subject.Code = authorities[s.ID], codes[s.ID]
+		}
+		if subject.Term != "" {
+			e.addSubject(subject)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddSubject(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.AddSubject("dragons")
+	e.AddCodedSubject("FICTION / Fantasy / Epic", SubjectAuthorityBISAC, "FIC009020")
+	e.AddCodedSubject("Epic fantasy", SubjectAuthorityTHEMA, "FMB")
+	expected := []Subject{
+		{Term: "dragons"},
+		{Term: "FICTION / Fantasy / Epic", Authority: SubjectAuthorityBISAC, Code: "FIC009020"},
+		{Term: "Epic fantasy", Authority: SubjectAuthorityTHEMA, Code: "FMB"},
+	}
+	if !reflect.DeepEqual(e.Subjects(), expected) {
+		t.Errorf("Unexpected subjects %v", e.Subjects())
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, element := range []string{
+		`<dc:subject>dragons</dc:subject>`,
+		`<dc:subject id="subject-2">FICTION / Fantasy / Epic</dc:subject>`,
+		`<dc:subject id="subject-3">Epic fantasy</dc:subject>`,
+		`<meta refines="#subject-2" property="authority">BISAC</meta>`,
+		`<meta refines="#subject-2" property="term">FIC009020</meta>`,
+		`<meta refines="#subject-3" property="authority">THEMA</meta>`,
+		`<meta refines="#subject-3" property="term">FMB</meta>`,
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened.Subjects(), expected) {
+		t.Errorf("Subjects not read back: %v", opened.Subjects())
+	}
+}
@@ -0,0 +1,147 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultCoverSVGFilename = "cover.svg"
+	defaultCoverSVGFallback = "cover.png"
+	// Cover page wrapping the cover image in an SVG element scaled with its
+	// viewBox, so that it fills the page whatever its size
+	defaultCoverSVGBody = `<div class="epub-cover"><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="100%%" height="100%%" viewBox="0 0 %s %s" preserveAspectRatio="xMidYMid meet"><image width="%s" height="%s" xlink:href="%s"/></svg></div>`
+	sectionPropertySVG  = "svg"
+)
+
+// SVGRasterizer renders SVG images, see SetSVGRasterizer.
+type SVGRasterizer interface {
+	// Rasterize returns the PNG image of an SVG image, rendered at the given
+	// size in pixels.
+	Rasterize(svg []byte, width int, height int) ([]byte, error)
+}
+
+// SetSVGRasterizer sets the rasterizer of the SVG covers set with SetCoverSVG,
+// which generates the raster cover image EPUB 2 reading systems need. Setting
+// a nil rasterizer disables the raster cover image.
+func (e *Epub) SetSVGRasterizer(rasterizer SVGRasterizer) {
+	e.Lock()
+	defer e.Unlock()
+	e.svgRasterizer = rasterizer
+}
+
+// SetCoverSVG sets the cover page for the EPUB using an SVG image read from r
+// and optional CSS, like SetCover, so that the cover stays crisp at any size.
+// The cover page wraps the image in an SVG element scaled with the viewBox of
+// the image, the standard cover page of fixed-ratio covers.
+//
+// If an SVG rasterizer is set (see SetSVGRasterizer), a PNG image of the cover
+// fitting within CoverMaxWidth x CoverMaxHeight is generated and referenced by
+// the EPUB 2 cover meta element, since EPUB 2 reading systems don't support
+// SVG images. It returns the relative paths to the SVG cover image and to the
+// PNG image, or "" if there is none.
+func (e *Epub) SetCoverSVG(r io.Reader, internalCSSPath string) (string, string, error) {
+	svg, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read SVG cover image: %w", err)
+	}
+	width, height, err := svgSize(svg)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read SVG cover image: %w", err)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	var fallback []byte
+	if e.svgRasterizer != nil {
+		scale := math.Min(CoverMaxWidth/width, CoverMaxHeight/height)
+		fallback, err = e.svgRasterizer.Rasterize(svg, int(math.Round(width*scale)), int(math.Round(height*scale)))
+		if err != nil {
+			return "", "", fmt.Errorf("unable to rasterize SVG cover image: %w", err)
+		}
+	}
+	coverPath, err := e.addGeneratedImage(svg, defaultCoverSVGFilename)
+	if err != nil {
+		return "", "", err
+	}
+	fallbackPath := ""
+	if fallback != nil {
+		if fallbackPath, err = e.addGeneratedImage(fallback, defaultCoverSVGFallback); err != nil {
+			return "", "", err
+		}
+	}
+
+	w, h := svgNumber(width), svgNumber(height)
+	if err := e.setCoverPage(coverPath, internalCSSPath, fmt.Sprintf(defaultCoverSVGBody, w, h, w, h, coverPath)); err != nil {
+		return "", "", err
+	}
+	if section, ok := e.findSection(e.cover.xhtmlFilename); ok {
+		section.properties = sectionPropertySVG
+	}
+	if fallbackPath != "" {
+		e.cover.fallbackFilename = path.Base(fallbackPath)
+	}
+	return coverPath, fallbackPath, nil
+}
+
+// Return the size of an SVG image: the size of its viewBox, or its width and
+// height if it has no viewBox
+func svgSize(svg []byte) (float64, float64, error) {
+	d := xml.NewDecoder(strings.NewReader(string(svg)))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("no svg element: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return 0, 0, fmt.Errorf("the root element is %s, not svg", start.Name.Local)
+		}
+		var width, height float64
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "viewBox":
+				fields := strings.FieldsFunc(attr.Value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+				if len(fields) == 4 {
+					w, errW := strconv.ParseFloat(fields[2], 64)
+					h, errH := strconv.ParseFloat(fields[3], 64)
+					if errW == nil && errH == nil && w > 0 && h > 0 {
+						return w, h, nil
+					}
+				}
+			case "width":
+				width = svgLength(attr.Value)
+			case "height":
+				height = svgLength(attr.Value)
+			}
+		}
+		if width > 0 && height > 0 {
+			return width, height, nil
+		}
+		return 0, 0, fmt.Errorf("the svg element has neither a viewBox nor a width and a height")
+	}
+}
+
+// Return an SVG length in user units (px), or 0 if it is relative (e.g. 100%)
+// or invalid
+func svgLength(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Format a number of an SVG attribute
+func svgNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
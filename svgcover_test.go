@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testCoverSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="100%" height="100%" viewBox="0 0 600 900"><rect width="600" height="900" fill="navy"/></svg>`
+
+// SVG rasterizer rendering blank images
+type testRasterizer struct {
+	width, height int
+}
+
+func (r *testRasterizer) Rasterize(svg []byte, width int, height int) ([]byte, error) {
+	r.width, r.height = width, height
+	var b bytes.Buffer
+	err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return b.Bytes(), err
+}
+
+func TestSetCoverSVG(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rasterizer := &testRasterizer{}
+	e.SetSVGRasterizer(rasterizer)
+	coverPath, fallbackPath, err := e.SetCoverSVG(strings.NewReader(testCoverSVG), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverPath != "../images/cover.svg" || fallbackPath != "../images/cover.png" {
+		t.Errorf("Unexpected cover paths %s and %s", coverPath, fallbackPath)
+	}
+	// Scaled to fit within the maximum cover size
+	if rasterizer.width != 1600 || rasterizer.height != 2400 {
+		t.Errorf("Expected the cover to be rasterized at 1600x2400, got %dx%d", rasterizer.width, rasterizer.height)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if !strings.Contains(files["EPUB/xhtml/cover.xhtml"], `viewBox="0 0 600 900" preserveAspectRatio="xMidYMid meet"><image width="600" height="900" xlink:href="../images/cover.svg"`) {
+		t.Errorf("Expected an SVG wrapper cover page, got:\n%s", files["EPUB/xhtml/cover.xhtml"])
+	}
+	opf := files["EPUB/package.opf"]
+	m := regexp.MustCompile(`<item id="([^"]+)" href="images/cover.png" media-type="image/png">`).FindStringSubmatch(opf)
+	if m == nil {
+		t.Fatalf("Expected the PNG cover image in the manifest, got:\n%s", opf)
+	}
+	for _, element := range []string{
+		`href="images/cover.svg" media-type="image/svg+xml" properties="cover-image"></item>`,
+		`<item id="cover.xhtml" href="xhtml/cover.xhtml" media-type="application/xhtml+xml" properties="svg"></item>`,
+		`<meta name="cover" content="` + m[1] + `"></meta>`,
+	} {
+		if !strings.Contains(opf, element) {
+			t.Errorf("Expected %s in the package file, got:\n%s", element, opf)
+		}
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	for svg, expected := range map[string][2]float64{
+		`<svg viewBox="0,0,600,900"/>`:                        {600, 900},
+		`<svg width="300px" height="450"/>`:                   {300, 450},
+		`<svg width="100%" height="100%" viewBox="0 0 2 3"/>`: {2, 3},
+	} {
+		w, h, err := svgSize([]byte(svg))
+		if err != nil || w != expected[0] || h != expected[1] {
+			t.Errorf("%s: expected %v, got %vx%v (%v)", svg, expected, w, h, err)
+		}
+	}
+	for _, svg := range []string{`<svg width="100%"/>`, `<html/>`, ``} {
+		if _, _, err := svgSize([]byte(svg)); err == nil {
+			t.Errorf("%s: expected an error", svg)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package epub
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const (
+	tableIDFormat       = "table%d"
+	tableWrapperStyle   = "overflow-x: auto; max-width: 100%;"
+	tableStyle          = "border-collapse: collapse; margin: 1em 0;"
+	tableCellStyle      = "border: 1px solid; padding: 0.25em 0.5em; text-align: left; vertical-align: top;"
+	tableCaptionStyle   = "caption-side: top; font-weight: bold; padding-bottom: 0.5em;"
+	tableWrapperClass   = "epub-table"
+	tableWrapperAriaFmt = `role="region" aria-labelledby="%s-caption" tabindex="0"`
+)
+
+// AddTable appends a table to the end of the body of an existing section and
+// returns a relative path to the table (the section filename followed by the
+// table id as a fragment) that can be used for links.
+//
+// The first row is used as the table header; every other row is a row of
+// data. Cells are plain text and will be escaped. The caption is optional.
+//
+// The table is wrapped in a scrollable container so that wide tables don't
+// overflow the page on small screens.
+func (e *Epub) AddTable(sectionFilename string, rows [][]string, caption string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.addTable(sectionFilename, rows, caption)
+}
+
+// AddTableCSV is like AddTable, but reads the rows from CSV data.
+func (e *Epub) AddTableCSV(sectionFilename string, r io.Reader, caption string) (string, error) {
+	cr := csv.NewReader(r)
+	// Allow rows with a different number of cells, the table will be padded
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("unable to read CSV table: %w", err)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	return e.addTable(sectionFilename, rows, caption)
+}
+
+func (e *Epub) addTable(sectionFilename string, rows [][]string, caption string) (string, error) {
+	section, ok := e.findSection(sectionFilename)
+	if !ok {
+		return "", &SectionDoesNotExistError{Filename: sectionFilename}
+	}
+	if section.xhtml.raw != "" {
+		return "", &RawSectionError{Filename: sectionFilename}
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("can't add a table without rows")
+	}
+
+	e.tableCount++
+	id := fmt.Sprintf(tableIDFormat, e.tableCount)
+	section.xhtml.xml.Body.XML += e.sanitize(sectionFilename, "table", renderTable(id, rows, caption))
+
+	return sectionFilename + "#" + id, nil
+}
+
+// Render the table markup. Every row is padded to the width of the widest row.
+func renderTable(id string, rows [][]string, caption string) string {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	cell := func(row []string, i int) string {
+		if i < len(row) {
+			return html.EscapeString(row[i])
+		}
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<div class=\"%s\" style=\"%s\"", tableWrapperClass, tableWrapperStyle)
+	if caption != "" {
+		b.WriteString(" ")
+		fmt.Fprintf(&b, tableWrapperAriaFmt, id)
+	}
+	b.WriteString(">\n")
+	fmt.Fprintf(&b, "<table id=\"%s\" style=\"%s\">\n", id, tableStyle)
+	if caption != "" {
+		fmt.Fprintf(&b, "<caption id=\"%s-caption\" style=\"%s\">%s</caption>\n", id, tableCaptionStyle, html.EscapeString(caption))
+	}
+	b.WriteString("<thead>\n<tr>")
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&b, "<th scope=\"col\" style=\"%s\">%s</th>", tableCellStyle, cell(rows[0], i))
+	}
+	b.WriteString("</tr>\n</thead>\n")
+	if len(rows) > 1 {
+		b.WriteString("<tbody>\n")
+		for _, row := range rows[1:] {
+			b.WriteString("<tr>")
+			for i := 0; i < columns; i++ {
+				fmt.Fprintf(&b, "<td style=\"%s\">%s</td>", tableCellStyle, cell(row, i))
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n</div>\n")
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package epub
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddTable(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(testSectionBody, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tablePath, err := e.AddTable(sectionPath, [][]string{
+		{"Name", "Score"},
+		{"Ada", "10"},
+		{"<Bob>"},
+	}, "Scores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tablePath != sectionPath+"#table1" {
+		t.Errorf("Unexpected table path: %s", tablePath)
+	}
+
+	csvPath, err := e.AddTableCSV(sectionPath, strings.NewReader("a,b\n1,2\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csvPath != sectionPath+"#table2" {
+		t.Errorf("Unexpected table path: %s", csvPath)
+	}
+
+	var notFound *SectionDoesNotExistError
+	if _, err := e.AddTable("doesnotexist.xhtml", [][]string{{"a"}}, ""); !errors.As(err, &notFound) {
+		t.Errorf("Expected a SectionDoesNotExistError when adding a table to a section that doesn't exist, got %v", err)
+	}
+
+	body := e.sections[0].xhtml.xml.Body.XML
+	for _, expected := range []string{
+		`<caption id="table1-caption"`,
+		`<th scope="col" style="` + tableCellStyle + `">Score</th>`,
+		`<td style="` + tableCellStyle + `">&lt;Bob&gt;</td><td style="` + tableCellStyle + `"></td>`,
+		`<table id="table2"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Section body doesn't contain %s\nGot: %s", expected, body)
+		}
+	}
+	if err := xml.Unmarshal([]byte("<div>"+body+"</div>"), new(struct{})); err != nil {
+		t.Errorf("Table markup isn't well-formed: %s", err)
+	}
+}
@@ -0,0 +1,40 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// SetTemplateData makes the section bodies Go templates (see html/template),
+// executed against data when the EPUB is written, e.g. to fill in variables or
+// to include content conditionally, so that several variants of a book can be
+// written from the same sections:
+//
+//	e.SetTemplateData(map[string]interface{}{"Edition": "teacher"})
+//	e.AddSection(`<p>Answer: {{if eq .Edition "teacher"}}42{{else}}…{{end}}</p>`, "Quiz", "", "")
+//
+// Values are escaped for their context; use template.HTML for markup. As with
+// any html/template, comments are removed from the bodies. A key
+// missing from data is an error, as is a body that isn't a valid template.
+// Complete documents, such as a cover page set with SetCoverXHTML or the
+// sections of an EPUB read with Open, aren't templates. Setting nil data, the
+// default, writes the bodies as they are.
+func (e *Epub) SetTemplateData(data map[string]interface{}) {
+	e.Lock()
+	defer e.Unlock()
+	e.templateData = data
+}
+
+// Execute the body of a section as a template against data
+func executeSectionTemplate(filename string, body string, data map[string]interface{}) (string, error) {
+	t, err := template.New(filename).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the template of %s: %w", filename, err)
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("unable to execute the template of %s: %w", filename, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,57 @@
+package epub
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestSetTemplateData(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p>{{.Name}} {{if eq .Edition "teacher"}}<em>42</em>{{else}}?{{end}}</p>{{.Note}}`, testSectionTitle, "quiz.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetTemplateData(map[string]interface{}{
+		"Name":    "A & B",
+		"Edition": "teacher",
+		"Note":    template.HTML(`<aside>Note</aside>`),
+	})
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	section := readZipFiles(t, b.Bytes())["EPUB/xhtml/quiz.xhtml"]
+	if !strings.Contains(section, `<p>A &amp; B <em>42</em></p><aside>Note</aside>`) {
+		t.Errorf("Unexpected section:\n%s", section)
+	}
+
+	// Another variant from the same sections
+	e.SetTemplateData(map[string]interface{}{"Name": "C", "Edition": "student", "Note": ""})
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if section := readZipFiles(t, b.Bytes())["EPUB/xhtml/quiz.xhtml"]; !strings.Contains(section, `<p>C ?</p>`) {
+		t.Errorf("Unexpected section:\n%s", section)
+	}
+
+	// Missing keys are errors
+	e.SetTemplateData(map[string]interface{}{"Edition": "student"})
+	if _, err := e.WriteTo(&b); err == nil || !strings.Contains(err.Error(), "quiz.xhtml") {
+		t.Errorf("Expected an error for the missing key, got %v", err)
+	}
+
+	// Without data, the body is written as is
+	e.SetTemplateData(nil)
+	b.Reset()
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if section := readZipFiles(t, b.Bytes())["EPUB/xhtml/quiz.xhtml"]; !strings.Contains(section, `{{.Name}}`) {
+		t.Errorf("Expected the body to be written as is:\n%s", section)
+	}
+}
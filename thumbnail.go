@@ -0,0 +1,208 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"path"
+	"strings"
+)
+
+const (
+	// Maximum size of the chapter thumbnails, see SetChapterThumbnails
+	ChapterThumbnailMaxWidth  = 160
+	ChapterThumbnailMaxHeight = 256
+
+	// Where the chapter thumbnails and their sidecar file are stored in the
+	// container
+	thumbnailsFolderName = "thumbnails"
+	thumbnailsFilename   = "thumbnails.json"
+	thumbnailFileFormat  = "%s%s"
+)
+
+// Sources of chapter thumbnails
+const (
+	// The thumbnail is the first image of the chapter
+	ThumbnailSourceImage = "image"
+	// The chapter has no image, so the thumbnail is a generated title card
+	ThumbnailSourceTitleCard = "title-card"
+)
+
+// ChapterThumbnail is a thumbnail of a chapter of the EPUB, see
+// SetChapterThumbnails.
+type ChapterThumbnail struct {
+	// Internal filename of the chapter section, e.g. section0001.xhtml
+	Section string `json:"section"`
+	Title   string `json:"title"`
+	// Path of the thumbnail in the container, relative to META-INF
+	Href      string `json:"href"`
+	MediaType string `json:"mediaType"`
+	// ThumbnailSourceImage or ThumbnailSourceTitleCard
+	Source string `json:"source"`
+	Data   []byte `json:"-"`
+}
+
+// SetChapterThumbnails sets whether a thumbnail is rendered for every chapter
+// (top-level section) when the EPUB is written, for store and library UIs. The
+// thumbnail is the first image of the chapter or of its subsections, scaled
+// down to fit within ChapterThumbnailMaxWidth x ChapterThumbnailMaxHeight, or
+// a title card colored after the chapter title if there is no image; title
+// cards carry no text, UIs are expected to display the title over them.
+//
+// The thumbnails are stored in META-INF/thumbnails, which reading systems
+// ignore, and listed in the META-INF/thumbnails.json sidecar file. They are
+// also returned by ChapterThumbnails.
+func (e *Epub) SetChapterThumbnails(enabled bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.chapterThumbnails = enabled
+}
+
+// ChapterThumbnails returns the chapter thumbnails rendered by the last write,
+// or nil if the EPUB hasn't been written or SetChapterThumbnails isn't set.
+func (e *Epub) ChapterThumbnails() []ChapterThumbnail {
+	e.Lock()
+	defer e.Unlock()
+	return e.thumbnails
+}
+
+// Find the images the chapter thumbnails are rendered from, so that the
+// archive keeps them when they are added
+//
+// Must be called before the images are written
+func (e *Epub) keepThumbnailImages(a *archive) {
+	if !e.chapterThumbnails {
+		return
+	}
+	for _, s := range e.sections {
+		if filename := e.firstChapterImage(s); filename != "" {
+			a.images[filename] = nil
+		}
+	}
+}
+
+// Render the chapter thumbnails and write them to the archive
+//
+// Must be called after the images have been written
+func (e *Epub) writeChapterThumbnails(a *archive) error {
+	e.thumbnails = nil
+	if !e.chapterThumbnails {
+		return nil
+	}
+	thumbnails := []ChapterThumbnail{}
+	for _, s := range e.sections {
+		if s.filename == e.cover.xhtmlFilename || s == e.placeholder {
+			continue
+		}
+		t, err := renderChapterThumbnail(s, a.images[e.firstChapterImage(s)])
+		if err != nil {
+			return err
+		}
+		thumbnails = append(thumbnails, t)
+	}
+
+	for _, t := range thumbnails {
+		if err := a.add(path.Join(metaInfFolderName, t.Href), t.Data); err != nil {
+			return fmt.Errorf("unable to write thumbnail of %s: %w", t.Section, err)
+		}
+	}
+	sidecar, err := json.MarshalIndent(struct {
+		Thumbnails []ChapterThumbnail `json:"thumbnails"`
+	}{thumbnails}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %w", thumbnailsFilename, err)
+	}
+	if err := a.add(path.Join(metaInfFolderName, thumbnailsFilename), append(sidecar, '\n')); err != nil {
+		return fmt.Errorf("unable to write %s: %w", thumbnailsFilename, err)
+	}
+	e.thumbnails = thumbnails
+	return nil
+}
+
+// Render the thumbnail of a chapter from its first image, falling back to a
+// title card if there is none
+func renderChapterThumbnail(s *epubSection, imageData []byte) (ChapterThumbnail, error) {
+	base := strings.TrimSuffix(s.filename, path.Ext(s.filename))
+	t := ChapterThumbnail{
+		Section: s.filename,
+		Title:   s.xhtml.Title(),
+		Source:  ThumbnailSourceTitleCard,
+	}
+	var img image.Image
+	format := "png"
+	if imageData != nil {
+		if decoded, f, err := image.Decode(bytes.NewReader(imageData)); err == nil {
+			img, format = decoded, f
+			t.Source = ThumbnailSourceImage
+		}
+	}
+	if img == nil {
+		img = titleCard(t.Title)
+	}
+	data, ext, err := encodeImage(downscaleImage(img, ChapterThumbnailMaxWidth, ChapterThumbnailMaxHeight), format)
+	if err != nil {
+		return t, fmt.Errorf("unable to render thumbnail of %s: %w", s.filename, err)
+	}
+	t.Data = data
+	t.Href = path.Join(thumbnailsFolderName, fmt.Sprintf(thumbnailFileFormat, base, ext))
+	t.MediaType = "image/png"
+	if ext == ".jpg" {
+		t.MediaType = mediaTypeJpeg
+	}
+	return t, nil
+}
+
+// Return the filename of the first image of a section or of its subsections
+// that was added to the EPUB, or "" if there is none
+func (e *Epub) firstChapterImage(s *epubSection) string {
+	d := xml.NewDecoder(strings.NewReader("<body>" + s.xhtml.xml.Body.XML + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || strings.ToLower(start.Name.Local) != "img" {
+			continue
+		}
+		for _, a := range start.Attr {
+			if a.Name.Local != "src" {
+				continue
+			}
+			filename := path.Base(a.Value)
+			if _, ok := e.images[filename]; ok && path.Base(path.Dir(a.Value)) == ImageFolderName {
+				return filename
+			}
+		}
+	}
+	for _, child := range s.children {
+		if filename := e.firstChapterImage(child); filename != "" {
+			return filename
+		}
+	}
+	return ""
+}
+
+// Generate a title card: a background colored after the title with a darker
+// band where UIs can display the title
+func titleCard(title string) image.Image {
+	h := fnv.New32a()
+	h.Write([]byte(title))
+	sum := h.Sum32()
+	background := color.RGBA{R: uint8(sum>>16) | 0x80, G: uint8(sum>>8) | 0x80, B: uint8(sum) | 0x80, A: 0xff}
+	band := color.RGBA{R: background.R / 2, G: background.G / 2, B: background.B / 2, A: 0xff}
+
+	img := image.NewRGBA(image.Rect(0, 0, ChapterThumbnailMaxWidth, ChapterThumbnailMaxHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	bandRect := image.Rect(0, ChapterThumbnailMaxHeight*3/8, ChapterThumbnailMaxWidth, ChapterThumbnailMaxHeight*5/8)
+	draw.Draw(img, bandRect, &image.Uniform{C: band}, image.Point{}, draw.Src)
+	return img
+}
@@ -0,0 +1,66 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestChapterThumbnails(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetChapterThumbnails(true)
+	imagePath, err := e.AddImage(testImageFromFileSource, "gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection("<p>No image</p>", "Chapter 1", "chapter1.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	chapter2, err := e.AddSection("<p>Image in a subsection</p>", "Chapter 2", "chapter2.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(chapter2, `<img src="`+imagePath+`" alt="Gopher"/>`, "Part A", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	thumbnails := e.ChapterThumbnails()
+	if len(thumbnails) != 2 {
+		t.Fatalf("Expected 2 thumbnails, got %d", len(thumbnails))
+	}
+	if thumbnails[0].Source != ThumbnailSourceTitleCard || thumbnails[1].Source != ThumbnailSourceImage {
+		t.Errorf("Expected a title card then an image, got %s and %s", thumbnails[0].Source, thumbnails[1].Source)
+	}
+
+	files := readZipFiles(t, b.Bytes())
+	var sidecar struct {
+		Thumbnails []ChapterThumbnail `json:"thumbnails"`
+	}
+	if err := json.Unmarshal([]byte(files["META-INF/thumbnails.json"]), &sidecar); err != nil {
+		t.Fatalf("Unable to parse the sidecar file: %v", err)
+	}
+	if len(sidecar.Thumbnails) != 2 || sidecar.Thumbnails[1].Section != "chapter2.xhtml" || sidecar.Thumbnails[1].Title != "Chapter 2" {
+		t.Fatalf("Unexpected sidecar file: %+v", sidecar)
+	}
+	for _, thumbnail := range sidecar.Thumbnails {
+		config, _, err := image.DecodeConfig(strings.NewReader(files["META-INF/"+thumbnail.Href]))
+		if err != nil {
+			t.Fatalf("Unable to decode %s: %v", thumbnail.Href, err)
+		}
+		if config.Width > ChapterThumbnailMaxWidth || config.Height > ChapterThumbnailMaxHeight {
+			t.Errorf("Expected %s to fit within the maximum size, got %dx%d", thumbnail.Href, config.Width, config.Height)
+		}
+	}
+	if strings.Contains(files["EPUB/package.opf"], "thumbnails/") {
+		t.Error("Expected the thumbnails not to be listed in the manifest")
+	}
+}
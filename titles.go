@@ -0,0 +1,192 @@
+package epub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Types of the titles of the EPUB, see AddTitle
+const (
+	TitleMain       = "main"
+	TitleSubtitle   = "subtitle"
+	TitleShort      = "short"
+	TitleCollection = "collection"
+	TitleEdition    = "edition"
+	TitleExpanded   = "expanded"
+)
+
+const (
+	pkgTitleID                 = "title"
+	pkgTitleIDFormat           = pkgTitleID + "-%d"
+	pkgTitleTypeProperty       = "title-type"
+	pkgTitleDisplaySeqProperty = "display-seq"
+)
+
+// A title of the EPUB besides its main title, see AddTitle
+type epubTitle struct {
+	text       string
+	titleType  string
+	displaySeq int
+	lang       string
+}
+
+// The <dc:title> element
+type pkgTitle struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Data string `xml:",chardata"`
+}
+
+// Subtitle returns the first subtitle of the EPUB, see SetSubtitle.
+func (e *Epub) Subtitle() string {
+	for _, t := range e.titles {
+		if t.titleType == TitleSubtitle {
+			return t.text
+		}
+	}
+	return ""
+}
+
+// SetSubtitle sets the subtitle of the EPUB, replacing any subtitle added
+// with AddTitle. An empty subtitle removes it. Reading systems that don't
+// support title types show the main title only.
+func (e *Epub) SetSubtitle(subtitle string) {
+	e.Lock()
+	defer e.Unlock()
+	titles := e.titles[:0]
+	for _, t := range e.titles {
+		if t.titleType != TitleSubtitle {
+			titles = append(titles, t)
+		}
+	}
+	e.titles = titles
+	if subtitle != "" {
+		e.addTitle(subtitle, TitleSubtitle, 0, "")
+	}
+	e.pkg.setTitles(e.mainTitle(), e.titles)
+}
+
+// AddTitle adds a title to the EPUB besides its main title (see SetTitle),
+// e.g. a subtitle or the title of the collection the EPUB belongs to, so that
+// reading systems can display each of them rather than a single title
+// cramming them all. titleType is one of the title types (TitleSubtitle,
+// TitleCollection, etc); it may be empty. displaySeq is the position of the
+// title when the titles are displayed together, starting at 1, or 0 if
+// unspecified; a title of type TitleMain sets the position of the main title
+// rather than adding another title.
+func (e *Epub) AddTitle(title string, titleType string, displaySeq int) {
+	e.Lock()
+	defer e.Unlock()
+	if titleType == TitleMain {
+		e.mainDisplaySeq = displaySeq
+	} else {
+		e.addTitle(title, titleType, displaySeq, "")
+	}
+	e.pkg.setTitles(e.mainTitle(), e.titles)
+}
+
+func (e *Epub) addTitle(title string, titleType string, displaySeq int, lang string) {
+	e.titles = append(e.titles, epubTitle{
+		text:       e.sanitize("", "title", title),
+		titleType:  titleType,
+		displaySeq: displaySeq,
+		lang:       lang,
+	})
+}
+
+// Return the main title of the EPUB
+func (e *Epub) mainTitle() epubTitle {
+	return epubTitle{
+		text:       e.title,
+		titleType:  TitleMain,
+		displaySeq: e.mainDisplaySeq,
+		lang:       e.titleLang,
+	}
+}
+
+// Set the dc:title elements: the main title, then the other titles. The
+// titles only get ids and title-type and display-seq refinements if there
+// are other titles.
+func (p *pkg) setTitles(main epubTitle, titles []epubTitle) {
+	metas := p.xml.Metadata.Meta[:0]
+	for _, m := range p.xml.Metadata.Meta {
+		if m.Property != pkgTitleTypeProperty && m.Property != pkgTitleDisplaySeqProperty {
+			metas = append(metas, m)
+		}
+	}
+	p.xml.Metadata.Titles = []pkgTitle{{Lang: main.lang, Data: main.text}}
+	if len(titles) > 0 || main.displaySeq > 0 {
+		all := append([]epubTitle{main}, titles...)
+		p.xml.Metadata.Titles = p.xml.Metadata.Titles[:0]
+		for i, t := range all {
+			id := pkgTitleID
+			if i > 0 {
+				id = fmt.Sprintf(pkgTitleIDFormat, i+1)
+			}
+			p.xml.Metadata.Titles = append(p.xml.Metadata.Titles, pkgTitle{ID: id, Lang: t.lang, Data: t.text})
+			if t.titleType != "" {
+				metas = append(metas, pkgMeta{Refines: "#" + id, Property: pkgTitleTypeProperty, Data: t.titleType})
+			}
+			if t.displaySeq > 0 {
+				metas = append(metas, pkgMeta{Refines: "#" + id, Property: pkgTitleDisplaySeqProperty, Data: strconv.Itoa(t.displaySeq)})
+			}
+		}
+	}
+	p.xml.Metadata.Meta = metas
+}
+
+// Return the title-type and display-seq refinements of the titles of a
+// package file, by title id
+func readTitleRefinements(p *readPackage) (types map[string]string, seqs map[string]int) {
+	types = make(map[string]string)
+	seqs = make(map[string]int)
+	for _, m := range p.Metadata.Metas {
+		id := strings.TrimPrefix(m.Refines, "#")
+		switch m.Property {
+		case pkgTitleTypeProperty:
+			types[id] = strings.TrimSpace(m.Value)
+		case pkgTitleDisplaySeqProperty:
+			seqs[id], _ = strconv.Atoi(strings.TrimSpace(m.Value))
+		}
+	}
+	return types, seqs
+}
+
+// Return the main title of a package file: the title of type main, or the
+// first one
+func readMainTitle(p *readPackage) (index int, title string) {
+	if len(p.Metadata.Titles) == 0 {
+		return -1, ""
+	}
+	types, _ := readTitleRefinements(p)
+	for i, t := range p.Metadata.Titles {
+		if t.ID != "" && types[t.ID] == TitleMain {
+			return i, t.Value
+		}
+	}
+	return 0, p.Metadata.Titles[0].Value
+}
+
+// Set the other titles of a freshly created Epub from the package file
+func (e *Epub) readTitles(p *readPackage) {
+	types, seqs := readTitleRefinements(p)
+	main, _ := readMainTitle(p)
+	for i, t := range p.Metadata.Titles {
+		lang := strings.TrimSpace(t.Lang)
+		switch {
+		case i == main:
+			e.titleLang = lang
+			if t.ID != "" {
+				e.mainDisplaySeq = seqs[t.ID]
+			}
+		case t.ID != "":
+			e.addTitle(strings.TrimSpace(t.Value), types[t.ID], seqs[t.ID], lang)
+		default:
+			e.addTitle(strings.TrimSpace(t.Value), "", 0, lang)
+		}
+	}
+	if len(p.Metadata.Titles) > 1 || e.mainDisplaySeq > 0 || e.titleLang != "" {
+		e.pkg.setTitles(e.mainTitle(), e.titles)
+	}
+}
@@ -0,0 +1,79 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAddTitle(t *testing.T) {
+	e, err := NewEpub("The Lord of the Rings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	e.SetSubtitle("A first subtitle")
+	e.SetSubtitle("The Fellowship of the Ring")
+	e.AddTitle("The Lord of the Rings", TitleMain, 1)
+	e.AddTitle("Middle-earth Classics", TitleCollection, 3)
+	if e.Subtitle() != "The Fellowship of the Ring" {
+		t.Errorf("Unexpected subtitle %q", e.Subtitle())
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	for _, expected := range []string{
+		`<dc:title id="title">The Lord of the Rings</dc:title>`,
+		`<dc:title id="title-2">The Fellowship of the Ring</dc:title>`,
+		`<dc:title id="title-3">Middle-earth Classics</dc:title>`,
+		`<meta refines="#title" property="title-type">main</meta>`,
+		`<meta refines="#title" property="display-seq">1</meta>`,
+		`<meta refines="#title-2" property="title-type">subtitle</meta>`,
+		`<meta refines="#title-3" property="title-type">collection</meta>`,
+		`<meta refines="#title-3" property="display-seq">3</meta>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("Expected %s in the package file, got:\n%s", expected, opf)
+		}
+	}
+	if strings.Contains(opf, "A first subtitle") || strings.Count(opf, "<dc:title") != 3 {
+		t.Errorf("Expected the first subtitle to be replaced, got:\n%s", opf)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Title() != "The Lord of the Rings" || opened.Subtitle() != "The Fellowship of the Ring" {
+		t.Errorf("Titles not read back: %q %q", opened.Title(), opened.Subtitle())
+	}
+	b.Reset()
+	if _, err := opened.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if reopf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]; !strings.Contains(reopf, `<meta refines="#title-3" property="display-seq">3</meta>`) {
+		t.Errorf("Expected the titles to be written back, got:\n%s", reopf)
+	}
+}
+
+func TestSetSubtitleRemove(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetSubtitle("Subtitle")
+	e.SetSubtitle("")
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	opf := readZipFiles(t, b.Bytes())["EPUB/package.opf"]
+	if !strings.Contains(opf, "<dc:title>"+testEpubTitle+"</dc:title>") || strings.Contains(opf, "title-type") {
+		t.Errorf("Expected the main title only, got:\n%s", opf)
+	}
+}
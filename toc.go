@@ -2,11 +2,14 @@ package epub
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
+	"html"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -40,6 +43,15 @@ const (
   </navMap>
 </ncx>`
 
+	tocNavLogoFormat = `
+    <div class="nav-logo"><img src="%s" alt="%s"/></div>`
+
+	// Class and style of the bylines of the entries, see SetNavArticles. The
+	// content of a nav list item is limited to its link, so the byline is
+	// laid out under the title from inside it.
+	tocNavBylineClass = "toc-byline"
+	tocNavBylineStyle = "display: block"
+
 	xmlnsEpub = "http://www.idpf.org/2007/ops"
 )
 
@@ -59,6 +71,17 @@ type toc struct {
 
 	title  string // EPUB title
 	author string // EPUB author
+
+	// Stylesheet and logo of the nav document, relative to it
+	navCSS     string
+	navLogo    string
+	navLogoAlt string
+	// Show the byline and date of the sections under their entries, see
+	// SetNavArticles
+	articles bool
+
+	// Pages of the page lists, see SetPageLabel
+	pageTargets []*tocNavItem
 }
 
 type tocNavBody struct {
@@ -74,18 +97,26 @@ type tocNavItem struct {
 }
 
 type tocNavLink struct {
-	XMLName xml.Name `xml:"a"`
-	Href    string   `xml:"href,attr"`
-	Data    string   `xml:",chardata"`
+	XMLName xml.Name      `xml:"a"`
+	Href    string        `xml:"href,attr"`
+	Data    string        `xml:",chardata"`
+	Byline  *tocNavByline `xml:"span,omitempty"`
+}
+
+type tocNavByline struct {
+	Class string `xml:"class,attr"`
+	Style string `xml:"style,attr"`
+	Text  string `xml:",chardata"`
 }
 
 type tocNcxRoot struct {
-	XMLName xml.Name          `xml:"http://www.daisy.org/z3986/2005/ncx/ ncx"`
-	Version string            `xml:"version,attr"`
-	Meta    tocNcxMeta        `xml:"head>meta"`
-	Title   string            `xml:"docTitle>text"`
-	Author  string            `xml:"docAuthor>text"`
-	NavMap  []*tocNcxNavPoint `xml:"navMap>navPoint"`
+	XMLName  xml.Name          `xml:"http://www.daisy.org/z3986/2005/ncx/ ncx"`
+	Version  string            `xml:"version,attr"`
+	Meta     tocNcxMeta        `xml:"head>meta"`
+	Title    string            `xml:"docTitle>text"`
+	Author   string            `xml:"docAuthor>text"`
+	NavMap   []*tocNcxNavPoint `xml:"navMap>navPoint"`
+	PageList *tocNcxPageList   `xml:"pageList,omitempty"`
 }
 
 type tocNcxContent struct {
@@ -148,16 +179,25 @@ func newTocNcxXML() (*tocNcxRoot, error) {
 	return n, nil
 }
 
+// Remove the TOC entries, which are added each time the EPUB is written
+func (t *toc) resetEntries() {
+	t.navXML.Links = nil
+	t.ncxXML.NavMap = nil
+	t.pageTargets = nil
+}
+
 // TODO: user should not add -1 as filename
-// Add a section to the TOC (navXML as well as ncxXML)
-func (t *toc) addSubSection(parent string, index int, title string, relativePath string) {
+// Add a section to the TOC (navXML as well as ncxXML), with the metadata of
+// the section for its byline
+func (t *toc) addSubSection(parent string, index int, title string, metadata SectionMetadata, relativePath string) error {
 	relativePath = filepath.ToSlash(relativePath)
 	if parent == "-1" {
 
 		l := &tocNavItem{
 			A: tocNavLink{
-				Href: relativePath,
-				Data: title,
+				Href:   relativePath,
+				Data:   title,
+				Byline: t.byline(metadata),
 			},
 			Children: nil,
 		}
@@ -178,8 +218,9 @@ func (t *toc) addSubSection(parent string, index int, title string, relativePath
 
 		l := &tocNavItem{
 			A: tocNavLink{
-				Href: relativePath,
-				Data: title,
+				Href:   relativePath,
+				Data:   title,
+				Byline: t.byline(metadata),
 			},
 		}
 		np := &tocNcxNavPoint{
@@ -190,15 +231,30 @@ func (t *toc) addSubSection(parent string, index int, title string, relativePath
 			},
 			Children: nil,
 		}
-		err := navAppender(t.navXML.Links, parentRelativePath, l)
-		if err != nil {
-			log.Println(err)
-		}
-		err = ncxAppender(t.ncxXML.NavMap, parentRelativePath, np)
-		if err != nil {
-			log.Println(err)
+		return errors.Join(
+			navAppender(t.navXML.Links, parentRelativePath, l),
+			ncxAppender(t.ncxXML.NavMap, parentRelativePath, np),
+		)
+	}
+	return nil
+}
+
+// Return the byline of the nav entry of a section: its author and date, in
+// articles mode only. nil is returned if there is none.
+func (t *toc) byline(m SectionMetadata) *tocNavByline {
+	if !t.articles {
+		return nil
+	}
+	var parts []string
+	for _, part := range []string{m.Author, m.Date} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
 		}
 	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return &tocNavByline{Class: tocNavBylineClass, Style: tocNavBylineStyle, Text: strings.Join(parts, ", ")}
 }
 
 func (t *toc) setIdentifier(identifier string) {
@@ -209,25 +265,17 @@ func (t *toc) setTitle(title string) {
 	t.title = title
 }
 
-// Write the TOC files
-func (t *toc) write(tempDir string) error {
-	err := t.writeNavDoc(tempDir)
+// Write the EPUB v3 TOC file (nav.xhtml) to the archive
+func (t *toc) writeNavDoc(a *archive) error {
+	navBodyContent, err := xml.MarshalIndent(t.navXML, "    ", "  ")
 	if err != nil {
-		return err
+		return fmt.Errorf("Error marshalling XML for EPUB v3 TOC file: %w\n"+"\tXML=%#v", err, t.navXML)
 	}
-	err = t.writeNcxDoc(tempDir)
+	pageList, err := t.pageListNav()
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// Write the the EPUB v3 TOC file (nav.xhtml) to the temporary directory
-func (t *toc) writeNavDoc(tempDir string) error {
-	navBodyContent, err := xml.MarshalIndent(t.navXML, "    ", "  ")
-	if err != nil {
-		return fmt.Errorf("Error marshalling XML for EPUB v3 TOC file: %w\n"+"\tXML=%#v", err, t.navXML)
-	}
+	navBodyContent = append(navBodyContent, pageList...)
 
 	// subsection without children itself left an empty tag <ol></ol>
 	// that not acceptable for epub v3
@@ -240,21 +288,29 @@ func (t *toc) writeNavDoc(tempDir string) error {
 	if err != nil {
 		return fmt.Errorf("can't create xhtml for TOC file: %w", err)
 	}
+	if t.navLogo != "" {
+		// The nav element may only contain a heading and a list, so the logo
+		// goes before it
+		n.setBody(fmt.Sprintf(tocNavLogoFormat, html.EscapeString(t.navLogo), html.EscapeString(t.navLogoAlt)) + bodyWithoutEmptyTags)
+	}
+	if t.navCSS != "" {
+		n.setCSS(t.navCSS)
+	}
 	n.setXmlnsEpub(xmlnsEpub)
 	n.setTitle(t.title)
 
-	navFilePath := filepath.Join(tempDir, contentFolderName, tocNavFilename)
-	err = n.write(navFilePath)
+	err = n.writeTransformed(a, path.Join(contentFolderName, tocNavFilename), nil)
 	if err != nil {
 		return fmt.Errorf("can't write TOC file: %w", err)
 	}
 	return nil
 }
 
-// Write the EPUB v2 TOC file (toc.ncx) to the temporary directory
-func (t *toc) writeNcxDoc(tempDir string) error {
+// Write the EPUB v2 TOC file (toc.ncx) to the archive
+func (t *toc) writeNcxDoc(a *archive) error {
 	t.ncxXML.Title = t.title
 	t.ncxXML.Author = t.author
+	t.ncxXML.PageList = t.ncxPageList()
 
 	ncxFileContent, err := xml.MarshalIndent(t.ncxXML, "", "  ")
 	if err != nil {
@@ -266,8 +322,7 @@ func (t *toc) writeNcxDoc(tempDir string) error {
 	// It's generally nice to have files end with a newline
 	ncxFileContent = append(ncxFileContent, "\n"...)
 
-	ncxFilePath := filepath.Join(tempDir, contentFolderName, tocNcxFilename)
-	if err := filesystem.WriteFile(ncxFilePath, []byte(ncxFileContent), filePermissions); err != nil {
+	if err := a.add(path.Join(contentFolderName, tocNcxFilename), ncxFileContent); err != nil {
 		return fmt.Errorf("Error writing EPUB v2 TOC file: %w", err)
 	}
 	return nil
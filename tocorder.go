@@ -0,0 +1,37 @@
+package epub
+
+// Report the entries of the table of contents of an EPUB being read that
+// don't follow the reading order: entries pointing to a document before the
+// one of a previous entry, or to a document that isn't in the spine. The
+// documents of the spine marked as non-linear (linear="no"), e.g. footnotes
+// read out of the flow, are exceptions. The hrefs are paths in the container;
+// sectionFilenames maps them to the filenames of the sections.
+func (e *Epub) checkTOCOrder(toc []readTOCEntry, spine []string, nonLinear map[string]bool, sectionFilenames map[string]string) {
+	index := make(map[string]int, len(spine))
+	for i, href := range spine {
+		index[href] = i
+	}
+	filename := func(href string) string {
+		if f, ok := sectionFilenames[href]; ok {
+			return f
+		}
+		return href
+	}
+
+	last, lastHref := -1, ""
+	for _, entry := range toc {
+		if nonLinear[entry.href] {
+			continue
+		}
+		i, ok := index[entry.href]
+		if !ok {
+			e.warnings.add(SeverityWarning, RuleTOCOrder, entry.href, "TOC entry %q points to a document that isn't in the reading order", entry.title)
+			continue
+		}
+		if i < last {
+			e.warnings.add(SeverityWarning, RuleTOCOrder, filename(entry.href), "TOC entry %q comes after %s in the TOC but before it in the reading order", entry.title, filename(lastHref))
+			continue
+		}
+		last, lastHref = i, entry.href
+	}
+}
@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOpenTOCOrder(t *testing.T) {
+	data := zipTestEpub(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", testContainerContents},
+		{"EPUB/package.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Shuffled</dc:title>
+    <dc:identifier id="bookid">urn:uuid:d1a2c3b4-0000-4000-8000-000000000001</dc:identifier>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="c1" href="one.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="two.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c3" href="three.xhtml" media-type="application/xhtml+xml"/>
+    <item id="notes" href="notes.xhtml" media-type="application/xhtml+xml"/>
+    <item id="extra" href="extra.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="c1"/>
+    <itemref idref="c2"/>
+    <itemref idref="c3"/>
+    <itemref idref="notes" linear="no"/>
+  </spine>
+</package>`},
+		{"EPUB/nav.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="toc"><ol>
+  <li><a href="notes.xhtml">Notes</a></li>
+  <li><a href="one.xhtml">One</a></li>
+  <li><a href="three.xhtml">Three</a></li>
+  <li><a href="two.xhtml">Two</a></li>
+  <li><a href="three.xhtml#end">Three, end</a></li>
+  <li><a href="extra.xhtml">Extra</a></li>
+</ol></nav></body></html>`},
+		{"EPUB/one.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>1</title></head><body><p>One</p></body></html>`},
+		{"EPUB/two.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>2</title></head><body><p>Two</p></body></html>`},
+		{"EPUB/three.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>3</title></head><body><p id="end">Three</p></body></html>`},
+		{"EPUB/notes.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>N</title></head><body><p>Notes</p></body></html>`},
+		{"EPUB/extra.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>E</title></head><body><p>Extra</p></body></html>`},
+	})
+
+	e, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []string
+	for _, i := range e.Validate().Issues {
+		if i.Rule == RuleTOCOrder {
+			issues = append(issues, i.Filename+": "+i.Message)
+		}
+	}
+	// The notes aren't in the linear reading order, they are an exception
+	expected := []string{
+		`two.xhtml: TOC entry "Two" comes after three.xhtml in the TOC but before it in the reading order`,
+		`EPUB/extra.xhtml: TOC entry "Extra" points to a document that isn't in the reading order`,
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s", strings.Join(issues, "\n"))
+	}
+}
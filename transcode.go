@@ -0,0 +1,192 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// AudioTranscoder converts audio files when the EPUB is written, e.g. to turn
+// OGG or FLAC sources into one of the EPUB core media types (MP3 or AAC in
+// MP4) or to normalize their bitrate. Transcoders that can be cancelled with
+// the write also implement ContextTranscoder.
+type AudioTranscoder interface {
+	// Transcode returns the converted content of the audio file and its media
+	// type. The filename is the internal filename of the file (e.g.
+	// audio0001.ogg) and the media type is the detected type of data. The data
+	// may be returned as is.
+	Transcode(filename string, mediaType string, data []byte) ([]byte, string, error)
+}
+
+// SetAudioTranscoder sets the transcoder applied to every audio file when the
+// EPUB is written, before the media type policy is enforced. Setting a nil
+// transcoder disables transcoding.
+func (e *Epub) SetAudioTranscoder(transcoder AudioTranscoder) {
+	e.Lock()
+	defer e.Unlock()
+	e.audioTranscoder = transcoder
+}
+
+// VideoTranscoder converts video files when the EPUB is written, e.g. to
+// downscale them or to re-encode them to H.264 in MP4, the most widely
+// supported format.
+type VideoTranscoder interface {
+	// Transcode returns the converted content of the video file and its media
+	// type, like AudioTranscoder.Transcode.
+	Transcode(filename string, mediaType string, data []byte) ([]byte, string, error)
+}
+
+// SetVideoTranscoder sets the transcoder applied to every video file when the
+// EPUB is written. Setting a nil transcoder disables transcoding.
+func (e *Epub) SetVideoTranscoder(transcoder VideoTranscoder) {
+	e.Lock()
+	defer e.Unlock()
+	e.videoTranscoder = transcoder
+}
+
+// ContextTranscoder is implemented by the audio and video transcoders that
+// stop when the write of the EPUB is cancelled (see WriteToContext), such as
+// FFmpegTranscoder. TranscodeContext is called instead of Transcode.
+type ContextTranscoder interface {
+	TranscodeContext(ctx context.Context, filename string, mediaType string, data []byte) ([]byte, string, error)
+}
+
+// An AudioTranscoder or a VideoTranscoder
+type transcoder interface {
+	Transcode(filename string, mediaType string, data []byte) ([]byte, string, error)
+}
+
+// Transcode the content of a media file and return it with its media type
+func transcodeMedia(ctx context.Context, t transcoder, mediaFilename string, mediaType string, data []byte) ([]byte, string, error) {
+	var err error
+	if ct, ok := t.(ContextTranscoder); ok {
+		data, mediaType, err = ct.TranscodeContext(ctx, mediaFilename, mediaType, data)
+	} else {
+		data, mediaType, err = t.Transcode(mediaFilename, mediaType, data)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to transcode %s: %w", mediaFilename, err)
+	}
+	return data, mediaType, nil
+}
+
+// FFmpegTranscoder is an AudioTranscoder running ffmpeg, which must be
+// installed. It converts every audio file to MP3, or to AAC in an MP4
+// container if AAC is true.
+type FFmpegTranscoder struct {
+	// Path of the ffmpeg executable, "ffmpeg" if empty
+	Path string
+	// Convert to AAC in MP4 rather than MP3
+	AAC bool
+	// Bitrate of the converted files as understood by ffmpeg, e.g. "128k";
+	// if empty, ffmpeg picks one
+	Bitrate string
+}
+
+// Transcode implements AudioTranscoder.
+func (t FFmpegTranscoder) Transcode(filename string, mediaType string, data []byte) ([]byte, string, error) {
+	return t.TranscodeContext(context.Background(), filename, mediaType, data)
+}
+
+// TranscodeContext implements ContextTranscoder: ffmpeg is killed when ctx is
+// done.
+func (t FFmpegTranscoder) TranscodeContext(ctx context.Context, filename string, mediaType string, data []byte) ([]byte, string, error) {
+	args := []string{"-vn"}
+	if t.Bitrate != "" {
+		args = append(args, "-b:a", t.Bitrate)
+	}
+	outputType := "audio/mpeg"
+	if t.AAC {
+		// A fragmented MP4 can be written to a pipe
+		args = append(args, "-c:a", "aac", "-f", "mp4", "-movflags", "frag_keyframe+empty_moov")
+		outputType = "audio/mp4"
+	} else {
+		args = append(args, "-c:a", "libmp3lame", "-f", "mp3")
+	}
+	args = append(args, "pipe:1")
+
+	var stdout bytes.Buffer
+	if err := runFFmpeg(ctx, t.Path, filename, data, args, &stdout); err != nil {
+		return nil, "", err
+	}
+	return stdout.Bytes(), outputType, nil
+}
+
+// FFmpegVideoTranscoder is a VideoTranscoder running ffmpeg, which must be
+// installed. It converts every video file to H.264 and AAC in an MP4
+// container, downscaling it to MaxHeight if it is higher.
+type FFmpegVideoTranscoder struct {
+	// Path of the ffmpeg executable, "ffmpeg" if empty
+	Path string
+	// Maximum height of the converted videos in pixels, keeping their aspect
+	// ratio; if 0, videos keep their size
+	MaxHeight int
+	// Constant rate factor of the H.264 encoder, from 0 (lossless) to 51;
+	// if 0, ffmpeg's default (23) is used
+	CRF int
+}
+
+// Transcode implements VideoTranscoder.
+func (t FFmpegVideoTranscoder) Transcode(filename string, mediaType string, data []byte) ([]byte, string, error) {
+	return t.TranscodeContext(context.Background(), filename, mediaType, data)
+}
+
+// TranscodeContext implements ContextTranscoder: ffmpeg is killed when ctx is
+// done.
+func (t FFmpegVideoTranscoder) TranscodeContext(ctx context.Context, filename string, mediaType string, data []byte) ([]byte, string, error) {
+	args := []string{"-c:v", "libx264", "-c:a", "aac"}
+	if t.MaxHeight > 0 {
+		// Both dimensions of H.264 videos must be even
+		args = append(args, "-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", t.MaxHeight))
+	}
+	if t.CRF > 0 {
+		args = append(args, "-crf", strconv.Itoa(t.CRF))
+	}
+	args = append(args, "-f", "mp4", "-movflags", "frag_keyframe+empty_moov", "pipe:1")
+
+	var stdout bytes.Buffer
+	if err := runFFmpeg(ctx, t.Path, filename, data, args, &stdout); err != nil {
+		return nil, "", err
+	}
+	return stdout.Bytes(), "video/mp4", nil
+}
+
+// Run ffmpeg with data as its input and the output options in args, until ctx
+// is done. The input is written to a temporary file rather than piped: ffmpeg
+// can't seek in a pipe, so it fails on MP4 files whose moov atom is at the end.
+func runFFmpeg(ctx context.Context, path string, filename string, data []byte, args []string, stdout io.Writer) error {
+	if path == "" {
+		path = "ffmpeg"
+	}
+	// Keep the extension, ffmpeg uses it to probe the format
+	input, err := os.CreateTemp("", "go-epub-ffmpeg-*"+filepath.Ext(filename))
+	if err != nil {
+		return fmt.Errorf("unable to create the ffmpeg input file: %w", err)
+	}
+	defer os.Remove(input.Name())
+	_, err = input.Write(data)
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write the ffmpeg input file: %w", err)
+	}
+
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-i", input.Name()}, args...)
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
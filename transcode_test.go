@@ -0,0 +1,208 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Transcoder recording the files it is called with and returning a fixed MP3
+type testTranscoder struct {
+	calls []string
+	err   error
+}
+
+func (t *testTranscoder) Transcode(filename string, mediaType string, data []byte) ([]byte, string, error) {
+	t.calls = append(t.calls, filename+" "+mediaType)
+	return []byte("ID3transcoded"), "audio/mpeg", t.err
+}
+
+func TestAudioTranscoder(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetMediaTypePolicy(MediaTypesCoreOnly)
+	transcoder := &testTranscoder{}
+	e.SetAudioTranscoder(transcoder)
+	if _, err := e.AddAudio("testdata/sample_audio.wav", "audio.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcoder.calls) != 1 || !strings.HasPrefix(transcoder.calls[0], "audio.wav audio/") {
+		t.Errorf("Expected the audio file to be transcoded once, got %v", transcoder.calls)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/audios/audio.wav"] != "ID3transcoded" {
+		t.Errorf("Expected the transcoded content, got %q", files["EPUB/audios/audio.wav"])
+	}
+	if !strings.Contains(files["EPUB/package.opf"], `href="audios/audio.wav" media-type="audio/mpeg"`) {
+		t.Error("Expected the transcoded audio file to be listed as audio/mpeg")
+	}
+
+	transcoder.err = errors.New("transcoding failed")
+	if _, err := e.WriteTo(io.Discard); err == nil || !strings.Contains(err.Error(), "transcoding failed") {
+		t.Errorf("Expected the transcoding error, got %v", err)
+	}
+}
+
+func TestFFmpegTranscoderContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	// A fake ffmpeg that never finishes
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAudioTranscoder(FFmpegTranscoder{Path: script})
+	if _, err := e.AddAudio("testdata/sample_audio.wav", "audio.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := e.WriteToContext(ctx, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected ffmpeg to be killed when the deadline is exceeded, the write took %s", elapsed)
+	}
+}
+
+// Write a fake ffmpeg copying its input file to its output and recording the
+// path of the input in the returned log file
+func fakeFFmpeg(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ffmpeg")
+	log := filepath.Join(dir, "input.log")
+	content := "#!/bin/sh\nprintf '%s' \"$5\" > " + log + "\n[ \"$4\" = -i ] && [ -f \"$5\" ] && exec cat \"$5\"\nexit 1\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script, log
+}
+
+// Check that the fake ffmpeg read a regular file, removed after the call
+func checkFFmpegInput(t *testing.T, log string, ext string) {
+	t.Helper()
+	input, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(string(input)) != ext {
+		t.Errorf("Expected the ffmpeg input to keep the %s extension, got %s", ext, input)
+	}
+	if _, err := os.Stat(string(input)); !os.IsNotExist(err) {
+		t.Errorf("Expected the ffmpeg input file to be removed, got %v", err)
+	}
+}
+
+func TestFFmpegTranscoderInputFile(t *testing.T) {
+	script, log := fakeFFmpeg(t)
+	converted, _, err := FFmpegTranscoder{Path: script}.Transcode("audio.m4a", "audio/mp4", []byte("audio"))
+	if err != nil {
+		t.Fatalf("Expected ffmpeg to read a seekable input file, got %v", err)
+	}
+	if string(converted) != "audio" {
+		t.Errorf("Expected the input content, got %q", converted)
+	}
+	checkFFmpegInput(t, log, ".m4a")
+}
+
+func TestFFmpegTranscoder(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg isn't installed")
+	}
+	data, err := os.ReadFile("testdata/sample_audio.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, transcoder := range []FFmpegTranscoder{{Bitrate: "64k"}, {AAC: true}} {
+		converted, mediaType, err := transcoder.Transcode("sample_audio.wav", "audio/wav", data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(converted) == 0 || !coreMediaTypes[mediaType] {
+			t.Errorf("Expected a core media type, got %s (%d bytes)", mediaType, len(converted))
+		}
+	}
+}
+
+func TestVideoTranscoder(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcoder := &testTranscoder{}
+	e.SetVideoTranscoder(transcoder)
+	if _, err := e.AddVideo(testVideoFromFileSource, "video.mp4"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcoder.calls) != 1 || !strings.HasPrefix(transcoder.calls[0], "video.mp4 video/") {
+		t.Errorf("Expected the video file to be transcoded once, got %v", transcoder.calls)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if files["EPUB/videos/video.mp4"] != "ID3transcoded" {
+		t.Errorf("Expected the transcoded content, got %d bytes", len(files["EPUB/videos/video.mp4"]))
+	}
+}
+
+func TestFFmpegVideoTranscoder(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg isn't installed")
+	}
+	data, err := os.ReadFile(testVideoFromFileSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, mediaType, err := FFmpegVideoTranscoder{MaxHeight: 180, CRF: 30}.Transcode("sample.mp4", "video/mp4", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if mediaType != "video/mp4" || len(converted) == 0 || len(converted) >= len(data) {
+		t.Errorf("Expected a smaller MP4 file, got %s (%d bytes)", mediaType, len(converted))
+	}
+}
+
+func TestFFmpegVideoTranscoderInputFile(t *testing.T) {
+	script, log := fakeFFmpeg(t)
+	// A MOV file whose moov atom is at the end can't be read from a pipe
+	converted, mediaType, err := FFmpegVideoTranscoder{Path: script, MaxHeight: 180}.Transcode("video.mov", "video/quicktime", []byte("video"))
+	if err != nil {
+		t.Fatalf("Expected ffmpeg to read a seekable input file, got %v", err)
+	}
+	if string(converted) != "video" || mediaType != "video/mp4" {
+		t.Errorf("Expected the input content as video/mp4, got %q (%s)", converted, mediaType)
+	}
+	checkFFmpegInput(t, log, ".mov")
+}
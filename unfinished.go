@@ -0,0 +1,59 @@
+package epub
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+)
+
+// Markers of unfinished content in the text of the sections
+var unfinishedMarkerRegex = regexp.MustCompile(`\b(?:TODO|FIXME|TBD|XXX)\b|(?i:\blorem ipsum\b)`)
+
+// Elements that are content of a section even without text
+var contentElements = map[string]bool{
+	"audio":  true,
+	"canvas": true,
+	"embed":  true,
+	"iframe": true,
+	"img":    true,
+	"math":   true,
+	"object": true,
+	"svg":    true,
+	"video":  true,
+}
+
+// Return the markers of unfinished content found in the text of a section body
+// (TODO, FIXME, TBD, XXX, lorem ipsum), in order and without duplicates, and
+// whether the body has no content at all
+func unfinishedContent(body string) (markers []string, empty bool) {
+	text := proofText(body)
+	if strings.TrimSpace(text) == "" && !hasContentElement(body) {
+		return nil, true
+	}
+	seen := make(map[string]bool)
+	for _, m := range unfinishedMarkerRegex.FindAllString(text, -1) {
+		if key := strings.ToLower(m); !seen[key] {
+			seen[key] = true
+			markers = append(markers, m)
+		}
+	}
+	return markers, false
+}
+
+// Report whether a section body has an element that is content without text,
+// e.g. an image
+func hasContentElement(body string) bool {
+	d := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok && contentElements[strings.ToLower(start.Name.Local)] {
+			return true
+		}
+	}
+}
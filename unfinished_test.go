@@ -0,0 +1,55 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnfinishedContent(t *testing.T) {
+	for body, expected := range map[string]string{
+		`<p>Finished chapter.</p>`:                           ``,
+		`<p>TODO: write this. Lorem ipsum dolor. TODO</p>`:   `TODO, Lorem ipsum`,
+		`<p>FIXME</p><p>Details TBD, see XXX.</p>`:           `FIXME, TBD, XXX`,
+		`<p>A todo list, mastodon and textbooks.</p>`:        ``,
+		`<!-- TODO: only in a comment --><p>Done</p>`:        ``,
+		"<p> </p>\n<div></div>":                              `(empty)`,
+		`<p>empty body</p>`:                                  ``,
+		`<img src="../images/cover.png" alt="Cover"/>`:       ``,
+		`<script>var todo = "TODO";</script><p>Written.</p>`: ``,
+	} {
+		markers, empty := unfinishedContent(body)
+		got := strings.Join(markers, ", ")
+		if empty {
+			got = "(empty)"
+		}
+		if got != expected {
+			t.Errorf("Unexpected issues for %s: %q, expected %q", body, got, expected)
+		}
+	}
+}
+
+func TestUnfinishedContentWarnings(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(`<p>FIXME</p>`, "One", "one.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(``, "Two", "two.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var issues []string
+	for _, i := range e.Validate().Issues {
+		if i.Rule == RuleUnfinishedContent {
+			issues = append(issues, i.Filename+": "+i.Message)
+		}
+	}
+	expected := []string{
+		`one.xhtml: the section contains "FIXME"`,
+		`two.xhtml: the section has an empty body`,
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s", strings.Join(issues, "\n"))
+	}
+}
@@ -0,0 +1,303 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// BuildMode defines how issues found while building the EPUB are handled.
+type BuildMode int
+
+const (
+	// BuildModeDefault reports issues without failing, except media that
+	// can't be retrieved and files that can't be written, which make the
+	// build fail
+	BuildModeDefault BuildMode = iota
+	// BuildModeStrict fails the build on any issue
+	BuildModeStrict
+	// BuildModeLenient reports issues and continues; media that can't be
+	// retrieved, sections, table of contents and package file that can't be
+	// written are left out of the EPUB (RuleWriteFailure)
+	BuildModeLenient
+)
+
+// Severity is the severity of a ValidationIssue.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rules checked by the built-in validator
+const (
+	// The language of the EPUB isn't a well-formed BCP 47 language tag
+	RuleInvalidLang = "invalid-lang"
+	// An image has no alt attribute
+	RuleMissingAlt = "missing-alt"
+	// A media source couldn't be retrieved when the EPUB was written
+	RuleFetchFailure = "fetch-failure"
+	// The EPUB has no sections, so its spine is empty
+	RuleNoSections = "no-sections"
+	// A media file was given a generated filename because its own filename
+	// was invalid or already used
+	RuleRenamedFile = "renamed-file"
+	// EmbedImages couldn't embed an image, or an image referenced by a
+	// section couldn't be added automatically (see SetAutoAddImages); the
+	// image was left as is
+	RuleEmbedImage = "embed-image"
+	// A file was added with a filename already used and was either replaced
+	// or left out, see SetDuplicatePolicy
+	RuleDuplicateFile = "duplicate-file"
+	// A file of the EPUB couldn't be written
+	RuleWriteFailure = "write-failure"
+	// Characters that aren't allowed in XML were removed from a text, see
+	// SanitizeText
+	RuleSanitized = "sanitized"
+	// An image was converted to a core media type, see SetMediaTypePolicy
+	RuleConvertedMedia = "converted-media"
+	// A resource of an EPUB read with Open wasn't kept because its media type
+	// isn't handled
+	RuleSkippedResource = "skipped-resource"
+	// A media file is among the largest ones of an EPUB whose media exceed
+	// the size budget, see SetMediaSizeBudget
+	RuleMediaBudget = "media-budget"
+	// A CSS file uses a property that the reading systems of the
+	// compatibility profile don't support, see SetCompatProfile
+	RuleCSSCompat = "css-compat"
+	// A link or reference of a section, or a text reference of a media
+	// overlay, doesn't resolve to a file of the EPUB or to an element of the
+	// linked section
+	RuleBrokenLink = "broken-link"
+	// Several elements of a section have the same id, so links to this id are
+	// ambiguous
+	RuleDuplicateID = "duplicate-id"
+	// A heading of a section is more than one level below the heading it
+	// follows, see SetNormalizeHeadings
+	RuleSkippedHeading = "skipped-heading"
+	// A proof checker found an issue in the text of a section, see
+	// AddProofChecker
+	RuleProofing = "proofing"
+	// A section uses a banned word, see BannedWords
+	RuleBannedWord = "banned-word"
+	// A section has an empty body or contains a marker of unfinished content
+	// such as TODO, FIXME or lorem ipsum
+	RuleUnfinishedContent = "unfinished-content"
+	// An entry of the table of contents of an EPUB read with Open doesn't
+	// follow the reading order (the spine), which confuses the progress
+	// indicators of reading systems
+	RuleTOCOrder = "toc-order"
+	// The publication date of the EPUB or of a section doesn't follow the W3C
+	// Date and Time Formats, see SetDate and SetSectionMetadata
+	RuleInvalidDate = "invalid-date"
+	// An embedded font isn't used by any @font-face rule, or a font family
+	// used by the CSS isn't declared by any @font-face rule with an embedded
+	// font
+	RuleFontUsage = "font-usage"
+	// The duration of a media overlay couldn't be determined, as an audio
+	// clip without clipEnd plays a file whose duration is unknown (not WAV,
+	// converted or left out), so it is left out of the package file
+	RuleMediaDuration = "media-duration"
+)
+
+// ValidationIssue is an issue found in the EPUB.
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	// Identifier of the rule that found the issue, e.g. RuleMissingAlt
+	Rule string `json:"rule"`
+	// Internal filename of the file with the issue (e.g. section0001.xhtml),
+	// if the issue is specific to a file
+	Filename string `json:"filename,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	if i.Filename != "" {
+		return fmt.Sprintf("%s: %s: %s (%s)", i.Severity, i.Filename, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s: %s (%s)", i.Severity, i.Message, i.Rule)
+}
+
+// ValidationReport lists the issues found in the EPUB.
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+	// Logger of the issues added to the report, may be nil
+	logger *slog.Logger
+}
+
+// HasErrors reports whether the report contains issues with the error
+// severity.
+func (r *ValidationReport) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings returns the issues with the warning severity.
+func (r *ValidationReport) Warnings() []ValidationIssue {
+	var warnings []ValidationIssue
+	for _, i := range r.Issues {
+		if i.Severity == SeverityWarning {
+			warnings = append(warnings, i)
+		}
+	}
+	return warnings
+}
+
+func (r *ValidationReport) add(severity Severity, rule string, filename string, format string, a ...interface{}) {
+	issue := ValidationIssue{
+		Severity: severity,
+		Rule:     rule,
+		Filename: filename,
+		Message:  fmt.Sprintf(format, a...),
+	}
+	r.Issues = append(r.Issues, issue)
+	r.log(issue)
+}
+
+// StrictModeError is returned by Write and WriteTo in strict mode if any issue
+// was found in the EPUB.
+type StrictModeError struct {
+	Report *ValidationReport
+}
+
+func (e *StrictModeError) Error() string {
+	issues := make([]string, len(e.Report.Issues))
+	for i, issue := range e.Report.Issues {
+		issues[i] = issue.String()
+	}
+	return fmt.Sprintf("EPUB has %d issue(s) in strict mode:\n%s", len(issues), strings.Join(issues, "\n"))
+}
+
+// Well-formed BCP 47 language tags, simplified: a primary language subtag
+// followed by subtags, or a private use tag
+var langTagRegex = regexp.MustCompile(`^(?:[a-zA-Z]{2,8}(?:-[a-zA-Z0-9]{1,8})*|[xX](?:-[a-zA-Z0-9]{1,8})+)$`)
+
+// SetBuildMode sets how issues found when the EPUB is written are handled. See
+// BuildMode.
+func (e *Epub) SetBuildMode(mode BuildMode) {
+	e.Lock()
+	defer e.Unlock()
+	e.buildMode = mode
+}
+
+// Warnings returns the non-fatal issues found by the last call to Write or
+// WriteTo, e.g. media left out in lenient mode, media files that were renamed
+// or images that EmbedImages couldn't embed. It returns nil if the EPUB hasn't
+// been written yet.
+func (e *Epub) Warnings() []ValidationIssue {
+	e.Lock()
+	defer e.Unlock()
+	if e.report == nil {
+		return nil
+	}
+	return e.report.Warnings()
+}
+
+// Validate checks the EPUB with the built-in validator and returns the issues
+// found. Issues that can only be found when the EPUB is written (e.g. media
+// that can't be retrieved) are reported by WriteToWithReport.
+func (e *Epub) Validate() *ValidationReport {
+	e.Lock()
+	defer e.Unlock()
+	return e.validate()
+}
+
+func (e *Epub) validate() *ValidationReport {
+	// Start with the issues found when the EPUB was built
+	r := &ValidationReport{Issues: append([]ValidationIssue(nil), e.warnings.Issues...)}
+	if !langTagRegex.MatchString(e.lang) {
+		r.add(SeverityWarning, RuleInvalidLang, "", "language %q isn't a valid BCP 47 language tag", e.lang)
+	}
+	langs := append([]string(nil), e.otherLangs...)
+	for _, l := range []string{e.titleLang, e.authorLang} {
+		if l != "" {
+			langs = append(langs, l)
+		}
+	}
+	for _, t := range e.titles {
+		if t.lang != "" {
+			langs = append(langs, t.lang)
+		}
+	}
+	for _, l := range langs {
+		if !langTagRegex.MatchString(l) {
+			r.add(SeverityWarning, RuleInvalidLang, "", "language %q of the metadata isn't a valid BCP 47 language tag", l)
+		}
+	}
+	if e.date != "" && !w3cdtfRegex.MatchString(e.date) {
+		r.add(SeverityWarning, RuleInvalidDate, "", "date %q doesn't follow the W3C Date and Time Formats", e.date)
+	}
+	// A metadata-only EPUB gets a placeholder section when it is written
+	if len(e.sections) == 0 && !e.metadataOnly {
+		r.add(SeverityWarning, RuleNoSections, "", "the EPUB has no sections")
+	}
+	var walk func([]*epubSection)
+	walk = func(sections []*epubSection) {
+		for _, s := range sections {
+			// Raw documents (e.g. a custom cover page) are the caller's
+			// responsibility, but their body is checked all the same
+			for _, src := range imagesWithoutAlt(s.xhtml.xml.Body.XML) {
+				r.add(SeverityWarning, RuleMissingAlt, s.filename, "image %s has no alt attribute", src)
+			}
+			markers, empty := unfinishedContent(s.xhtml.xml.Body.XML)
+			if empty {
+				r.add(SeverityWarning, RuleUnfinishedContent, s.filename, "the section has an empty body")
+			}
+			for _, marker := range markers {
+				r.add(SeverityWarning, RuleUnfinishedContent, s.filename, "the section contains %q", marker)
+			}
+			if s.metadata.Date != "" && !w3cdtfRegex.MatchString(s.metadata.Date) {
+				r.add(SeverityWarning, RuleInvalidDate, s.filename, "date %q of the section doesn't follow the W3C Date and Time Formats", s.metadata.Date)
+			}
+			if !e.normalizeHeadings || s.xhtml.raw != "" {
+				for _, levels := range skippedHeadingLevels(s.xhtml.xml.Body.XML) {
+					r.add(SeverityWarning, RuleSkippedHeading, s.filename, "h%d follows h%d, skipping heading levels", levels[1], levels[0])
+				}
+			}
+			walk(s.children)
+		}
+	}
+	walk(e.sections)
+	e.proofread(r)
+	return r
+}
+
+// Return the sources of the images of a section body that have no alt
+// attribute
+func imagesWithoutAlt(body string) []string {
+	d := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var sources []string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return sources
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || strings.ToLower(start.Name.Local) != "img" {
+			continue
+		}
+		src, hasAlt := "", false
+		for _, a := range start.Attr {
+			switch a.Name.Local {
+			case "src":
+				src = a.Value
+			case "alt":
+				hasAlt = true
+			}
+		}
+		if !hasAlt {
+			sources = append(sources, src)
+		}
+	}
+}
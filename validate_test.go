@@ -0,0 +1,166 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := e.Validate().Issues; len(issues) != 1 || issues[0].Rule != RuleNoSections {
+		t.Errorf("Unexpected issues for an EPUB without sections: %v", issues)
+	}
+	e.SetMetadataOnly(true)
+	if issues := e.Validate().Issues; len(issues) != 0 {
+		t.Errorf("Unexpected issues for a metadata-only EPUB: %v", issues)
+	}
+
+	e.SetLang("not a language")
+	sectionPath, err := e.AddSection(`<p><img src="../images/a.png" alt=""/><img src="../images/b.png"/></p>`, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := e.Validate()
+	if len(r.Issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %v", r.Issues)
+	}
+	if r.Issues[0].Rule != RuleInvalidLang {
+		t.Errorf("Expected an invalid language issue, got %v", r.Issues[0])
+	}
+	if got := r.Issues[1]; got.Rule != RuleMissingAlt || got.Filename != sectionPath || !strings.Contains(got.Message, "../images/b.png") {
+		t.Errorf("Expected a missing alt issue for ../images/b.png, got %v", got)
+	}
+	if r.HasErrors() {
+		t.Error("Expected the report to only contain warnings")
+	}
+	if len(r.Warnings()) != 2 {
+		t.Errorf("Expected 2 warnings, got %d", len(r.Warnings()))
+	}
+}
+
+func TestBuildModeStrict(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetBuildMode(BuildModeStrict)
+	if _, err := e.AddSection(`<p><img src="../images/a.png"/></p>`, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.WriteTo(io.Discard)
+	var strictErr *StrictModeError
+	if !errors.As(err, &strictErr) {
+		t.Fatalf("Expected a StrictModeError, got %v", err)
+	}
+	if len(strictErr.Report.Issues) != 1 || strictErr.Report.Issues[0].Rule != RuleMissingAlt {
+		t.Errorf("Unexpected issues: %v", strictErr.Report.Issues)
+	}
+}
+
+func TestBuildModeFetchFailure(t *testing.T) {
+	newEpub := func(mode BuildMode) *Epub {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetBuildMode(mode)
+		if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddImage(testImageFromFileSource, "kept.png"); err != nil {
+			t.Fatal(err)
+		}
+		// Add a file that no longer exists when the EPUB is written
+		temp, err := os.CreateTemp("", "temp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		temp.Close()
+		if _, err := e.AddImage(temp.Name(), "missing.png"); err != nil {
+			t.Fatal(err)
+		}
+		os.Remove(temp.Name())
+		return e
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		if _, err := newEpub(BuildModeDefault).WriteTo(io.Discard); err == nil {
+			t.Error("Expected an error for a missing image")
+		}
+	})
+	t.Run("Strict", func(t *testing.T) {
+		_, err := newEpub(BuildModeStrict).WriteTo(io.Discard)
+		var strictErr *StrictModeError
+		if !errors.As(err, &strictErr) {
+			t.Fatalf("Expected a StrictModeError, got %v", err)
+		}
+	})
+	t.Run("Lenient", func(t *testing.T) {
+		var b bytes.Buffer
+		_, r, err := newEpub(BuildModeLenient).WriteToWithReport(&b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(r.Issues) != 1 || r.Issues[0].Rule != RuleFetchFailure || r.Issues[0].Filename != "missing.png" {
+			t.Errorf("Unexpected issues: %v", r.Issues)
+		}
+		files := readZipFiles(t, b.Bytes())
+		if _, ok := files[filepath.Join(contentFolderName, ImageFolderName, "missing.png")]; ok {
+			t.Error("Expected the missing image to be left out")
+		}
+		if _, ok := files[filepath.Join(contentFolderName, ImageFolderName, "kept.png")]; !ok {
+			t.Error("Expected the other image to be written")
+		}
+		if strings.Contains(files[filepath.Join(contentFolderName, pkgFilename)], "missing.png") {
+			t.Error("Expected the missing image to be left out of the manifest")
+		}
+	})
+}
+
+func TestWarnings(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionPath, err := e.AddSection(`<p><img src="testdata/missing.png" alt=""/></p>`, testSectionTitle, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EmbedImages()
+	if _, err := e.AddImage(testImageFromFileSource, ""); err != nil {
+		t.Fatal(err)
+	}
+	renamedPath, err := e.AddImage(testImageFromFileSource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := e.Warnings(); w != nil {
+		t.Errorf("Expected no warnings before the EPUB is written, got %v", w)
+	}
+
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	w := e.Warnings()
+	if len(w) != 3 {
+		t.Fatalf("Expected 3 warnings, got %v", w)
+	}
+	if w[0].Rule != RuleEmbedImage || w[0].Filename != sectionPath {
+		t.Errorf("Expected an embed-image warning for %s, got %v", sectionPath, w[0])
+	}
+	if w[1].Rule != RuleRenamedFile || w[1].Filename != filepath.Base(renamedPath) {
+		t.Errorf("Expected a renamed-file warning for %s, got %v", renamedPath, w[1])
+	}
+	// The image that couldn't be embedded is missing from the EPUB
+	if w[2].Rule != RuleBrokenLink || w[2].Filename != sectionPath {
+		t.Errorf("Expected a broken-link warning for %s, got %v", sectionPath, w[2])
+	}
+}
@@ -0,0 +1,232 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const (
+	sectionPropertyScripted = "scripted"
+
+	widgetsCSSFilename = "widgets.css"
+	widgetsJSFilename  = "widgets.js"
+	widgetsCSSContent  = `.epub-quiz-question, .epub-flashcard {
+  margin: 1em 0;
+  page-break-inside: avoid;
+}
+.epub-quiz-choices {
+  list-style-type: upper-alpha;
+}
+.epub-quiz-choice {
+  font: inherit;
+  text-align: left;
+}
+.epub-quiz-choice.correct {
+  font-weight: bold;
+}
+.epub-quiz-choice.incorrect {
+  text-decoration: line-through;
+}
+.epub-flashcard details {
+  border: 1px solid;
+  border-radius: 0.3em;
+  padding: 0.5em;
+}
+.epub-flashcard summary {
+  font-weight: bold;
+}
+`
+	// The script only enhances the markup: without it, the answers are still
+	// available in <details> elements
+	widgetsJSContent = `(function () {
+  "use strict";
+
+  function ready(fn) {
+    if (document.readyState !== "loading") {
+      fn();
+    } else {
+      document.addEventListener("DOMContentLoaded", fn);
+    }
+  }
+
+  function setupQuestion(question) {
+    var feedback = question.querySelector(".epub-quiz-feedback");
+    var choices = question.querySelectorAll(".epub-quiz-choice");
+    for (var i = 0; i < choices.length; i++) {
+      choices[i].addEventListener("click", function (event) {
+        var choice = event.currentTarget;
+        var correct = choice.getAttribute("data-correct") === "true";
+        choice.className += correct ? " correct" : " incorrect";
+        choice.setAttribute("aria-pressed", "true");
+        if (feedback) {
+          feedback.textContent = correct ? "Correct!" : "Not quite, try again.";
+        }
+      });
+    }
+  }
+
+  function setupFlashcards(deck) {
+    var toggle = deck.querySelector(".epub-flashcards-toggle");
+    if (!toggle) {
+      return;
+    }
+    toggle.removeAttribute("hidden");
+    toggle.addEventListener("click", function () {
+      var open = toggle.getAttribute("aria-pressed") !== "true";
+      var cards = deck.querySelectorAll("details");
+      for (var i = 0; i < cards.length; i++) {
+        cards[i].open = open;
+      }
+      toggle.setAttribute("aria-pressed", open ? "true" : "false");
+    });
+  }
+
+  ready(function () {
+    var questions = document.querySelectorAll(".epub-quiz-question");
+    for (var i = 0; i < questions.length; i++) {
+      setupQuestion(questions[i]);
+    }
+    var decks = document.querySelectorAll(".epub-flashcards");
+    for (var j = 0; j < decks.length; j++) {
+      setupFlashcards(decks[j]);
+    }
+  });
+})();
+`
+)
+
+// QuizQuestion is a multiple-choice question used by AddQuiz. All strings are
+// plain text and will be escaped.
+type QuizQuestion struct {
+	Prompt  string
+	Choices []string
+	// Index in Choices of the correct answer
+	Answer int
+	// Optional explanation shown along with the answer
+	Explanation string
+}
+
+// Flashcard is a single card used by AddFlashcards. Both sides are plain text
+// and will be escaped.
+type Flashcard struct {
+	Front string
+	Back  string
+}
+
+type epubWidgets struct {
+	cssPath string
+	jsPath  string
+}
+
+// AddQuiz adds a section containing a multiple-choice quiz and returns a
+// relative path to the section, like AddSection.
+//
+// The section links to a bundled script that gives immediate feedback when a
+// choice is selected and its manifest item is marked as scripted. Reading
+// systems without scripting support can still reveal each answer.
+//
+// The title and internal filename follow the same rules as AddSection.
+func (e *Epub) AddQuiz(sectionTitle string, questions []QuizQuestion, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	var body strings.Builder
+	body.WriteString("<section class=\"epub-quiz\">\n")
+	if sectionTitle != "" {
+		fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(sectionTitle))
+	}
+	for i, q := range questions {
+		if q.Answer < 0 || q.Answer >= len(q.Choices) {
+			return "", fmt.Errorf("answer %d of question %d is not one of its choices", q.Answer, i+1)
+		}
+		id := fmt.Sprintf("question%d", i+1)
+		fmt.Fprintf(&body, "<div class=\"epub-quiz-question\" id=\"%s\">\n", id)
+		fmt.Fprintf(&body, "<p class=\"epub-quiz-prompt\" id=\"%s-prompt\">%s</p>\n", id, html.EscapeString(q.Prompt))
+		fmt.Fprintf(&body, "<ol class=\"epub-quiz-choices\" aria-labelledby=\"%s-prompt\">\n", id)
+		for j, choice := range q.Choices {
+			fmt.Fprintf(&body, "<li><button type=\"button\" class=\"epub-quiz-choice\" data-correct=\"%t\">%s</button></li>\n", j == q.Answer, html.EscapeString(choice))
+		}
+		body.WriteString("</ol>\n")
+		body.WriteString("<p class=\"epub-quiz-feedback\" role=\"status\" aria-live=\"polite\"></p>\n")
+		fmt.Fprintf(&body, "<details class=\"epub-quiz-answer\">\n<summary>Show answer</summary>\n<p>%c. %s</p>\n", 'A'+q.Answer, html.EscapeString(q.Choices[q.Answer]))
+		if q.Explanation != "" {
+			fmt.Fprintf(&body, "<p>%s</p>\n", html.EscapeString(q.Explanation))
+		}
+		body.WriteString("</details>\n</div>\n")
+	}
+	body.WriteString("</section>")
+
+	return e.addWidgetSection(body.String(), sectionTitle, internalFilename)
+}
+
+// AddFlashcards adds a section containing a deck of flashcards and returns a
+// relative path to the section, like AddSection.
+//
+// Each card is rendered as a <details> element so it can be flipped without
+// scripting; the bundled script adds a control to flip all the cards at once.
+//
+// The title and internal filename follow the same rules as AddSection.
+func (e *Epub) AddFlashcards(sectionTitle string, cards []Flashcard, internalFilename string) (string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	var body strings.Builder
+	body.WriteString("<section class=\"epub-flashcards\">\n")
+	if sectionTitle != "" {
+		fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(sectionTitle))
+	}
+	body.WriteString("<button type=\"button\" class=\"epub-flashcards-toggle\" aria-pressed=\"false\" hidden=\"hidden\">Flip all cards</button>\n")
+	for _, card := range cards {
+		fmt.Fprintf(&body, "<div class=\"epub-flashcard\">\n<details>\n<summary>%s</summary>\n<p>%s</p>\n</details>\n</div>\n",
+			html.EscapeString(card.Front),
+			html.EscapeString(card.Back))
+	}
+	body.WriteString("</section>")
+
+	return e.addWidgetSection(body.String(), sectionTitle, internalFilename)
+}
+
+// Add a section linked to the widget stylesheet and script
+func (e *Epub) addWidgetSection(body string, sectionTitle string, internalFilename string) (string, error) {
+	if err := e.addWidgetResources(); err != nil {
+		return "", err
+	}
+	sectionPath, err := e.addSection("", body, sectionTitle, internalFilename, e.widgets.cssPath)
+	if err != nil {
+		return "", err
+	}
+	s, _ := e.findSection(sectionPath)
+	s.xhtml.addScript(e.widgets.jsPath)
+	s.properties = addProperty(s.properties, sectionPropertyScripted)
+	return sectionPath, nil
+}
+
+// Add the widget stylesheet and script to the EPUB the first time a widget is
+// used
+func (e *Epub) addWidgetResources() error {
+	if e.widgets != nil {
+		return nil
+	}
+	cssPath, err := addMedia(e.generatedGrabber(), dataurl.EncodeBytes([]byte(widgetsCSSContent)), widgetsCSSFilename, cssFileFormat, CSSFolderName, e.css)
+	if _, ok := err.(*FilenameAlreadyUsedError); ok {
+		cssPath, err = addMedia(e.generatedGrabber(), dataurl.EncodeBytes([]byte(widgetsCSSContent)), fmt.Sprintf(cssFileFormat, len(e.css)+1, ".css"), cssFileFormat, CSSFolderName, e.css)
+	}
+	if err != nil {
+		return fmt.Errorf("Error adding widget CSS file: %w", err)
+	}
+	jsPath, err := addMedia(e.generatedGrabber(), dataurl.EncodeBytes([]byte(widgetsJSContent)), widgetsJSFilename, scriptFileFormat, ScriptFolderName, e.scripts)
+	if _, ok := err.(*FilenameAlreadyUsedError); ok {
+		jsPath, err = addMedia(e.generatedGrabber(), dataurl.EncodeBytes([]byte(widgetsJSContent)), fmt.Sprintf(scriptFileFormat, len(e.scripts)+1, ".js"), scriptFileFormat, ScriptFolderName, e.scripts)
+	}
+	if err != nil {
+		return fmt.Errorf("Error adding widget script file: %w", err)
+	}
+	e.widgets = &epubWidgets{
+		cssPath: cssPath,
+		jsPath:  jsPath,
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package epub
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/go-epub/internal/storage"
+)
+
+func TestAddQuiz(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quizPath, err := e.AddQuiz("Quiz", []QuizQuestion{
+		{
+			Prompt:      "Which gopher is the mascot of Go?",
+			Choices:     []string{"Gordon", "The Go gopher", "Gary"},
+			Answer:      1,
+			Explanation: "It was designed by Renée French.",
+		},
+	}, "quiz.xhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.AddFlashcards("Cards", []Flashcard{{Front: "1 + 1", Back: "2"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.AddQuiz("Broken", []QuizQuestion{{Prompt: "?", Choices: []string{"a"}, Answer: 2}}, "")
+	if err == nil {
+		t.Error("Expected an error for an answer that isn't one of the choices")
+	}
+
+	tempDir := writeAndExtractEpub(t, e, testEpubFilename)
+	defer cleanup(testEpubFilename, tempDir)
+
+	contents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, xhtmlFolderName, quizPath))
+	if err != nil {
+		t.Fatalf("Unexpected error reading section file: %s", err)
+	}
+	for _, expected := range []string{
+		`<script type="text/javascript" src="../scripts/widgets.js"></script>`,
+		`<link rel="stylesheet" type="text/css" href="../css/widgets.css"></link>`,
+		`<button type="button" class="epub-quiz-choice" data-correct="true">The Go gopher</button>`,
+		`<summary>Show answer</summary>`,
+	} {
+		if !strings.Contains(string(contents), expected) {
+			t.Errorf("Quiz section doesn't contain %s\nGot: %s", expected, contents)
+		}
+	}
+
+	pkgContents, err := storage.ReadFile(filesystem, filepath.Join(tempDir, contentFolderName, pkgFilename))
+	if err != nil {
+		t.Fatalf("Unexpected error reading package file: %s", err)
+	}
+	for _, expected := range []string{
+		fmt.Sprintf(`<item id="%s" href="xhtml/%s" media-type="application/xhtml+xml" properties="scripted"></item>`, quizPath, quizPath),
+		`href="scripts/widgets.js" media-type="text/javascript"`,
+	} {
+		if !strings.Contains(string(pkgContents), expected) {
+			t.Errorf("Package file doesn't contain %s\nGot: %s", expected, pkgContents)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package epub
 
 import (
-	"archive/zip"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"io/fs"
-	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -38,111 +40,247 @@ const (
 	// Permissions for any new directories we create
 	dirPermissions = 0755
 	// Permissions for any new files we create
-	filePermissions   = 0644
-	mediaTypeCSS      = "text/css"
-	mediaTypeEpub     = "application/epub+zip"
-	mediaTypeJpeg     = "image/jpeg"
-	mediaTypeNcx      = "application/x-dtbncx+xml"
-	mediaTypeXhtml    = "application/xhtml+xml"
-	metaInfFolderName = "META-INF"
-	mimetypeFilename  = "mimetype"
-	pkgFilename       = "package.opf"
-	tempDirPrefix     = "go-epub"
-	xhtmlFolderName   = "xhtml"
+	filePermissions     = 0644
+	mediaTypeCSS        = "text/css"
+	mediaTypeEpub       = "application/epub+zip"
+	mediaTypeJavascript = "text/javascript"
+	mediaTypeJpeg       = "image/jpeg"
+	mediaTypeNcx        = "application/x-dtbncx+xml"
+	mediaTypeVTT        = "text/vtt"
+	mediaTypeXhtml      = "application/xhtml+xml"
+	metaInfFolderName   = "META-INF"
+	mimetypeFilename    = "mimetype"
+	pkgFilename         = "package.opf"
+	tempDirPrefix       = "go-epub"
+	xhtmlFolderName     = "xhtml"
 )
 
 // WriteTo the dest io.Writer. The return value is the number of bytes written. Any error encountered during the write is also returned.
 func (e *Epub) WriteTo(dst io.Writer) (int64, error) {
 	e.Lock()
 	defer e.Unlock()
-	tempDir := uuid.Must(uuid.NewV4()).String()
+	return e.writeTo(context.Background(), dst)
+}
 
-	err := filesystem.Mkdir(tempDir, dirPermissions)
-	if err != nil {
-		return 0, fmt.Errorf("Error creating temp directory: %w", err)
+// WriteToContext is like WriteTo, but the write is cancelled when ctx is done,
+// e.g. when its deadline passes while a remote media source is being
+// retrieved. The error is then ctx.Err(), possibly wrapped, and dst may hold a
+// partial EPUB.
+func (e *Epub) WriteToContext(ctx context.Context, dst io.Writer) (int64, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.writeTo(ctx, dst)
+}
+
+// WriteToWithReport is like WriteTo, but also returns the issues found while
+// writing the EPUB (see SetBuildMode). The report is returned even if the
+// write fails.
+func (e *Epub) WriteToWithReport(dst io.Writer) (int64, *ValidationReport, error) {
+	e.Lock()
+	defer e.Unlock()
+	n, err := e.writeTo(context.Background(), dst)
+	return n, e.report, err
+}
 
+func (e *Epub) writeTo(ctx context.Context, dst io.Writer) (int64, error) {
+	e.report = e.validate()
+	// The issues found outside of writes have been logged already
+	e.report.logger = e.logger
+	for _, issue := range e.report.Issues[len(e.warnings.Issues):] {
+		e.report.log(issue)
+	}
+	e.skippedMedia = make(map[string]bool)
+	if e.buildMode == BuildModeStrict && len(e.report.Issues) > 0 {
+		return 0, &StrictModeError{Report: e.report}
 	}
-	defer func() {
-		if err := filesystem.RemoveAll(tempDir); err != nil {
-			log.Print("Error removing temp directory: %w", err)
+
+	if e.needsPlaceholder() {
+		placeholder, err := e.placeholderSection()
+		if err != nil {
+			return 0, err
 		}
-	}()
-	err = writeMimetype(tempDir)
+		e.sections = []*epubSection{placeholder}
+		e.placeholder = placeholder
+		defer func() {
+			e.sections = nil
+			e.placeholder = nil
+		}()
+	}
+
+	// Start from an empty manifest, spine and TOC in case the EPUB has already
+	// been written
+	e.pkg.resetItems()
+	e.toc.resetEntries()
+	e.assignSlugIDs()
+	identifier, err := e.reproducibleIdentifier()
 	if err != nil {
 		return 0, err
 	}
-	err = createEpubFolders(tempDir)
+	if identifier != "" {
+		defer e.restoreIdentifiers(e.identifier, e.identifierScheme, slices.Clone(e.otherIdentifiers))
+		e.setIdentifier(identifier)
+	}
+
+	counter := &writeCounter{progress: e.progress}
+	a := newArchive(ctx, e, io.MultiWriter(counter, dst))
+	if err := e.writeArchive(a); err != nil {
+		a.abort()
+		return counter.Total, err
+	}
+	err = a.close()
+	if err == nil {
+		e.removeCheckpoints()
+	}
+	return counter.Total, err
+}
+
+// Write the files of the EPUB into the archive, in the order they must be
+// produced
+func (e *Epub) writeArchive(a *archive) error {
+	// Must be called first
+	err := writeMimetype(a)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	// Must be called after:
-	// createEpubFolders()
-	err = writeContainerFile(tempDir)
+	err = writeContainerFile(a)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	err = e.writeAppleBooksDisplayOptions(a)
+	if err != nil {
+		return err
+	}
+
+	err = e.writeCSSFiles(a)
+	if err != nil {
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	err = e.writeCSSFiles(tempDir)
+	// writeCSSFiles()
+	if e.pruneMedia {
+		e.pruneUnusedMedia(a)
+	}
+
+	err = e.writeFonts(a)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	err = e.writeFonts(tempDir)
+	// writeFonts()
+	err = e.writeFontLicenses(a)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	e.keepThumbnailImages(a)
+	err = e.writeImages(a)
+	if err != nil {
+		return err
+	}
+
+	err = e.writeVideos(a)
+	if err != nil {
+		return err
+	}
+
+	err = e.writeAudios(a)
+	if err != nil {
+		return err
+	}
+
+	err = e.writeCaptions(a)
+	if err != nil {
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	err = e.writeImages(tempDir)
+	// writeAudios()
+	err = e.writeMediaOverlays(a)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	err = e.writeScripts(a)
+	if err != nil {
+		return err
+	}
+
+	err = e.writeFiles(a)
+	if err != nil {
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	err = e.writeVideos(tempDir)
+	// writeImages()
+	// writeVideos()
+	// writeAudios()
+	err = e.checkMediaBudget(a)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	err = e.writeAudios(tempDir)
+	// writeImages()
+	err = e.writeChapterThumbnails(a)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	if err := a.ctx.Err(); err != nil {
+		return err
+	}
+	err = e.writeSections(a)
+	if err != nil {
+		return err
 	}
 
 	// Must be called after:
-	// createEpubFolders()
-	e.writeSections(tempDir)
+	// writeCSSFiles()
+	// writeImages()
+	// writeVideos()
+	// writeAudios()
+	// writeMediaOverlays()
+	// writeScripts()
+	// writeSections()
+	e.checkLinks(a)
+
+	// Must be called after:
+	// writeCSSFiles()
+	// writeFonts()
+	// writeSections()
+	e.checkFontUsage(a)
+
+	// Must be called after:
+	// writeSections()
+	err = e.writeToc(a)
+	if err != nil {
+		return err
+	}
 
 	// Must be called after:
-	// createEpubFolders()
 	// writeSections()
-	e.writeToc(tempDir)
+	e.writeGuide()
 
 	// Must be called after:
-	// createEpubFolders()
 	// writeCSSFiles()
 	// writeImages()
 	// writeVideos()
 	// writeAudios()
+	// writeMediaOverlays()
+	// writeScripts()
 	// writeSections()
 	// writeToc()
-	e.writePackageFile(tempDir)
-	// Must be called last
-	return e.writeEpub(tempDir, dst)
+	return e.writePackageFile(a)
 }
 
 // Write writes the EPUB file. The destination path must be the full path to
 // the resulting file, including filename and extension.
-// The result is always writen to the local filesystem even if the underlying storage is in memory.
+// The result is always written to the local filesystem, whatever the storage selected with Use.
+// See WriteToDestination to write the EPUB elsewhere, e.g. to an object storage.
 func (e *Epub) Write(destFilePath string) error {
 
 	f, err := os.Create(destFilePath)
@@ -157,47 +295,13 @@ func (e *Epub) Write(destFilePath string) error {
 	return err
 }
 
-// Create the EPUB folder structure in a temp directory
-func createEpubFolders(rootEpubDir string) error {
-	if err := filesystem.Mkdir(
-		filepath.Join(
-			rootEpubDir,
-			contentFolderName,
-		),
-		dirPermissions); err != nil {
-		// No reason this should happen if tempDir creation was successful
-		return fmt.Errorf("Error creating EPUB subdirectory: %w", err)
-	}
-
-	if err := filesystem.Mkdir(
-		filepath.Join(
-			rootEpubDir,
-			contentFolderName,
-			xhtmlFolderName,
-		),
-		dirPermissions); err != nil {
-		return fmt.Errorf("Error creating xhtml subdirectory: %w", err)
-	}
-
-	if err := filesystem.Mkdir(
-		filepath.Join(
-			rootEpubDir,
-			metaInfFolderName,
-		),
-		dirPermissions); err != nil {
-		return fmt.Errorf("Error creating META-INF subdirectory: %w", err)
-	}
-	return nil
-}
-
 // Write the contatiner file (container.xml), which mostly just points to the
 // package file (package.opf)
 //
 // Spec: http://www.idpf.org/epub/301/spec/epub-ocf.html#sec-container-metainf-container.xml
-func writeContainerFile(rootEpubDir string) error {
-	containerFilePath := filepath.Join(rootEpubDir, metaInfFolderName, containerFilename)
-	if err := filesystem.WriteFile(
-		containerFilePath,
+func writeContainerFile(a *archive) error {
+	if err := a.add(
+		path.Join(metaInfFolderName, containerFilename),
 		[]byte(
 			fmt.Sprintf(
 				containerFileTemplate,
@@ -205,17 +309,15 @@ func writeContainerFile(rootEpubDir string) error {
 				pkgFilename,
 			),
 		),
-		filePermissions,
 	); err != nil {
 		return fmt.Errorf("Error writing container file: %w", err)
 	}
 	return nil
 }
 
-// Write the CSS files to the temporary directory and add them to the package
-// file
-func (e *Epub) writeCSSFiles(rootEpubDir string) error {
-	err := e.writeMedia(rootEpubDir, e.css, CSSFolderName)
+// Write the CSS files to the archive and add them to the package file
+func (e *Epub) writeCSSFiles(a *archive) error {
+	err := e.writeMedia(a, e.css, CSSFolderName)
 	if err != nil {
 		return err
 	}
@@ -229,6 +331,8 @@ func (e *Epub) writeCSSFiles(rootEpubDir string) error {
 // writeCounter counts the number of bytes written to it.
 type writeCounter struct {
 	Total int64 // Total # of bytes written
+	// Reports the bytes written, may be nil
+	progress func(stage string, done, total int)
 }
 
 // Write implements the io.Writer interface.
@@ -236,156 +340,206 @@ type writeCounter struct {
 func (wc *writeCounter) Write(p []byte) (int, error) {
 	n := len(p)
 	wc.Total += int64(n)
+	if wc.progress != nil {
+		wc.progress(ProgressBytes, int(wc.Total), 0)
+	}
 	return n, nil
 }
 
-// Write the EPUB file itself by zipping up everything from a temp directory
-// The return value is the number of bytes written. Any error encountered during the write is also returned.
-func (e *Epub) writeEpub(rootEpubDir string, dst io.Writer) (int64, error) {
-	counter := &writeCounter{}
-	teeWriter := io.MultiWriter(counter, dst)
+// Get fonts from their source and write them to the archive
+func (e *Epub) writeFonts(a *archive) error {
+	return e.writeMedia(a, e.fonts, FontFolderName)
+}
 
-	z := zip.NewWriter(teeWriter)
+// Get images from their source and write them to the archive
+func (e *Epub) writeImages(a *archive) error {
+	return e.writeMedia(a, e.images, ImageFolderName)
+}
 
-	skipMimetypeFile := false
+// Get videos from their source and write them to the archive
+func (e *Epub) writeVideos(a *archive) error {
+	return e.writeMedia(a, e.videos, VideoFolderName)
+}
 
-	// addFileToZip adds the file present at path to the zip archive. The path is relative to the rootEpubDir
-	addFileToZip := func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// Get audios from their source and write them to the archive
+func (e *Epub) writeAudios(a *archive) error {
+	return e.writeMedia(a, e.audios, AudioFolderName)
+}
 
-		// Get the path of the file relative to the folder we're zipping
-		relativePath, err := filepath.Rel(rootEpubDir, path)
-		if err != nil {
-			// tempDir and path are both internal, so we shouldn't get here
-			return err
-		}
-		relativePath = filepath.ToSlash(relativePath)
+// Get scripts from their source and write them to the archive
+func (e *Epub) writeScripts(a *archive) error {
+	return e.writeMedia(a, e.scripts, ScriptFolderName)
+}
 
-		// Only include regular files, not directories
-		info, err := d.Info()
-		if err != nil {
+// Get media from their source and write them to the archive. The media are
+// streamed to the archive, unless their content is needed to transform or
+// inspect them, see needsMediaData.
+func (e *Epub) writeMedia(a *archive, mediaMap map[string]string, mediaFolderName string) error {
+	// Add the files in filename order rather than in map order so the
+	// manifest is the same from one build to the next
+	mediaFilenames := make([]string, 0, len(mediaMap))
+	for mediaFilename := range mediaMap {
+		mediaFilenames = append(mediaFilenames, mediaFilename)
+	}
+	sort.Strings(mediaFilenames)
+
+	for _, mediaFilename := range mediaFilenames {
+		if err := a.ctx.Err(); err != nil {
 			return err
 		}
-		if !info.Mode().IsRegular() {
-			return nil
+		mediaSource := mediaMap[mediaFilename]
+		stream, mediaType, err := e.grabber().withContext(a.ctx).openMediaStream(mediaSource, mediaFilename)
+		if err != nil {
+			if err := e.mediaFailure(a, mediaFilename, mediaSource, err); err != nil {
+				return err
+			}
+			continue
 		}
-
-		var w io.Writer
-		if filepath.FromSlash(path) == filepath.Join(rootEpubDir, mimetypeFilename) {
-			// Skip the mimetype file if it's already been written
-			if skipMimetypeFile {
-				return nil
+		mediaPath := path.Join(mediaFolderName, mediaFilename)
+		if e.needsMediaData(a, mediaFolderName, mediaFilename, mediaType) {
+			data, err := io.ReadAll(stream)
+			stream.Close()
+			if err != nil {
+				if err := e.mediaFailure(a, mediaFilename, mediaSource, &FileRetrievalError{Source: mediaSource, Err: err}); err != nil {
+					return err
+				}
+				continue
+			}
+			if mediaType, err = e.writeMediaData(a, mediaFolderName, mediaFilename, mediaType, data); err != nil {
+				return err
 			}
-			// The mimetype file must be uncompressed according to the EPUB spec
-			w, err = z.CreateHeader(&zip.FileHeader{
-				Name:   relativePath,
-				Method: zip.Store,
-			})
 		} else {
-			w, err = z.Create(relativePath)
-		}
-		if err != nil {
-			return fmt.Errorf("error creating zip writer: %w", err)
-		}
-
-		r, err := filesystem.Open(path)
-		if err != nil {
-			return fmt.Errorf("error opening file %v being added to EPUB: %w", path, err)
-		}
-		defer func() {
-			if err := r.Close(); err != nil {
-				log.Println(err)
+			if mediaFolderName == AudioFolderName {
+				// The header of a WAV file is enough to compute its duration
+				head, _ := stream.Peek(mediaStreamPeekSize)
+				if d, err := wavDuration(bytes.NewReader(head), mediaFilename); err == nil {
+					a.wavDurations[mediaPath] = d
+				}
 			}
-		}()
+			err := a.addMediaStream(path.Join(contentFolderName, mediaPath), mediaType, stream)
+			stream.Close()
+			if err != nil {
+				return err
+			}
+		}
 
-		_, err = io.Copy(w, r)
+		// Add the file to the OPF manifest
+		xmlId, err := e.mediaID(mediaFilename)
 		if err != nil {
-			return fmt.Errorf("error copying contents of file being added EPUB: %w", err)
+			return fmt.Errorf("error creating xml id: %w", err)
 		}
-		return nil
-	}
 
-	// Add the mimetype file first
-	mimetypeFilePath := filepath.Join(rootEpubDir, mimetypeFilename)
-	mimetypeInfo, err := fs.Stat(filesystem, mimetypeFilePath)
-	if err != nil {
-		if err := z.Close(); err != nil {
-			log.Println(err)
+		// The cover image has a special value for the properties attribute
+		mediaProperties := ""
+		if mediaFilename == e.cover.imageFilename {
+			mediaProperties = coverImageProperties
 		}
-		return counter.Total, fmt.Errorf("unable to get FileInfo for mimetype file: %w", err)
-	}
-	err = addFileToZip(mimetypeFilePath, fileInfoToDirEntry(mimetypeInfo), nil)
-	if err != nil {
-		if err := z.Close(); err != nil {
-			log.Println(err)
+		// EPUB 2 reading systems expect a raster image, see SetCoverSVG
+		coverMetaFilename := e.cover.imageFilename
+		if e.cover.fallbackFilename != "" {
+			coverMetaFilename = e.cover.fallbackFilename
 		}
-		return counter.Total, fmt.Errorf("unable to add mimetype file to EPUB: %w", err)
-	}
-
-	skipMimetypeFile = true
-
-	err = fs.WalkDir(filesystem, rootEpubDir, addFileToZip)
-	if err != nil {
-		if err := z.Close(); err != nil {
-			log.Println(err)
+		if mediaFilename == coverMetaFilename && e.compat.CoverMeta {
+			e.pkg.setCover(xmlId)
 		}
-		return counter.Total, fmt.Errorf("unable to add file to EPUB: %w", err)
+		e.pkg.addToManifest(xmlId, mediaPath, mediaType, mediaProperties)
+		a.mediaWritten()
 	}
-
-	err = z.Close()
-	return counter.Total, err
-}
-
-// Get fonts from their source and save them in the temporary directory
-func (e *Epub) writeFonts(rootEpubDir string) error {
-	return e.writeMedia(rootEpubDir, e.fonts, FontFolderName)
-}
-
-// Get images from their source and save them in the temporary directory
-func (e *Epub) writeImages(rootEpubDir string) error {
-	return e.writeMedia(rootEpubDir, e.images, ImageFolderName)
+	return nil
 }
 
-// Get videos from their source and save them in the temporary directory
-func (e *Epub) writeVideos(rootEpubDir string) error {
-	return e.writeMedia(rootEpubDir, e.videos, VideoFolderName)
+// Handle the failure to retrieve a media file: in lenient mode it is reported
+// and left out, in strict mode it is reported and the build fails, otherwise
+// err is returned
+func (e *Epub) mediaFailure(a *archive, mediaFilename string, mediaSource string, err error) error {
+	// A cancelled write doesn't leave the media out
+	if a.ctx.Err() != nil || e.buildMode == BuildModeDefault {
+		return err
+	}
+	e.report.add(SeverityWarning, RuleFetchFailure, mediaFilename, "unable to retrieve %s: %v", mediaSource, err)
+	if e.buildMode == BuildModeStrict {
+		return &StrictModeError{Report: e.report}
+	}
+	// Leave the media out
+	e.skippedMedia[mediaFilename] = true
+	a.mediaWritten()
+	return nil
 }
 
-// Get audios from their source and save them in the temporary directory
-func (e *Epub) writeAudios(rootEpubDir string) error {
-	return e.writeMedia(rootEpubDir, e.audios, AudioFolderName)
+// Report whether the content of a media file must be held in memory to be
+// written: to be encrypted, transcoded, converted or stripped, or because the
+// write needs it (CSS files, media overlays and chapter thumbnails). The other
+// files are streamed to the archive.
+func (e *Epub) needsMediaData(a *archive, mediaFolderName string, mediaFilename string, mediaType string) bool {
+	if e.shouldEncrypt(path.Join(contentFolderName, mediaFolderName, mediaFilename)) {
+		return true
+	}
+	policy := e.mediaTypePolicy != MediaTypesAny && !coreMediaTypes[mediaType]
+	switch mediaFolderName {
+	case CSSFolderName, MediaOverlayFolderName:
+		return true
+	case AudioFolderName:
+		return e.audioTranscoder != nil || policy
+	case VideoFolderName:
+		return e.videoTranscoder != nil
+	case ImageFolderName:
+		_, thumbnail := a.images[mediaFilename]
+		return thumbnail || policy || e.stripImageMetadata || e.normalizeColorProfiles
+	}
+	return false
 }
 
-// Get media from their source and save them in the temporary directory
-func (e *Epub) writeMedia(rootEpubDir string, mediaMap map[string]string, mediaFolderName string) error {
-	if len(mediaMap) > 0 {
-		mediaFolderPath := filepath.Join(rootEpubDir, contentFolderName, mediaFolderName)
-		if err := filesystem.Mkdir(mediaFolderPath, dirPermissions); err != nil {
-			return fmt.Errorf("unable to create directory: %s", err)
+// Transform the content of a media file as needed and write it to the
+// archive, returning its final media type
+func (e *Epub) writeMediaData(a *archive, mediaFolderName string, mediaFilename string, mediaType string, data []byte) (string, error) {
+	var err error
+	if mediaFolderName == AudioFolderName && e.audioTranscoder != nil {
+		data, mediaType, err = transcodeMedia(a.ctx, e.audioTranscoder, mediaFilename, mediaType, data)
+		if err != nil {
+			return "", err
 		}
+	}
+	if mediaFolderName == VideoFolderName && e.videoTranscoder != nil {
+		data, mediaType, err = transcodeMedia(a.ctx, e.videoTranscoder, mediaFilename, mediaType, data)
+		if err != nil {
+			return "", err
+		}
+	}
+	data, mediaType, err = e.enforceMediaTypePolicy(mediaFolderName, mediaFilename, mediaType, data)
+	if err != nil {
+		return "", err
+	}
+	// Before the color profiles, whose conversions drop the EXIF
+	// orientation
+	if mediaFolderName == ImageFolderName && e.stripImageMetadata {
+		data = e.stripMetadata(mediaFilename, mediaType, data)
+	}
+	if mediaFolderName == ImageFolderName && e.normalizeColorProfiles {
+		data = e.normalizeColorProfile(mediaFilename, mediaType, data)
+	}
 
-		for mediaFilename, mediaSource := range mediaMap {
-			mediaType, err := grabber{(e.Client)}.fetchMedia(mediaSource, mediaFolderPath, mediaFilename)
-			if err != nil {
-				return err
-			}
-			// The cover image has a special value for the properties attribute
-			mediaProperties := ""
-			if mediaFilename == e.cover.imageFilename {
-				mediaProperties = coverImageProperties
-			}
-
-			// Add the file to the OPF manifest
-			xmlId, err := fixXMLId(mediaFilename)
-			if err != nil {
-				return fmt.Errorf("error creating xml id: %w", err)
-			}
-			e.pkg.addToManifest(xmlId, filepath.Join(mediaFolderName, mediaFilename), mediaType, mediaProperties)
+	mediaPath := path.Join(mediaFolderName, mediaFilename)
+	switch mediaFolderName {
+	case CSSFolderName:
+		e.checkCSSCompat(mediaFilename, data)
+		a.css[mediaFilename] = data
+	case AudioFolderName:
+		// Media overlays need the duration of the WAV files they play
+		// until the end
+		if d, err := wavDuration(bytes.NewReader(data), mediaFilename); err == nil {
+			a.wavDurations[mediaPath] = d
 		}
+	case ImageFolderName:
+		if _, ok := a.images[mediaFilename]; ok {
+			a.images[mediaFilename] = data
+		}
+	case MediaOverlayFolderName:
+		a.overlays[mediaFilename] = data
 	}
-	return nil
+	if err := a.addMedia(path.Join(contentFolderName, mediaPath), mediaType, data); err != nil {
+		return "", err
+	}
+	return mediaType, nil
 }
 
 // fixXMLId takes a string and returns an XML id compatible string.
@@ -404,51 +558,68 @@ func fixXMLId(id string) (string, error) {
 // Write the mimetype file
 //
 // Spec: http://www.idpf.org/epub/301/spec/epub-ocf.html#sec-zip-container-mime
-func writeMimetype(rootEpubDir string) error {
-	mimetypeFilePath := filepath.Join(rootEpubDir, mimetypeFilename)
-
-	if err := filesystem.WriteFile(mimetypeFilePath, []byte(mediaTypeEpub), filePermissions); err != nil {
+func writeMimetype(a *archive) error {
+	if err := a.add(mimetypeFilename, []byte(mediaTypeEpub)); err != nil {
 		return fmt.Errorf("Error writing mimetype file: %w", err)
 	}
 	return nil
 }
 
-func (e *Epub) writePackageFile(rootEpubDir string) {
-	err := e.pkg.write(rootEpubDir)
-	if err != nil {
-		log.Println(err)
+func (e *Epub) writePackageFile(a *archive) error {
+	return e.writeFailure(pkgFilename, e.pkg.write(a))
+}
+
+// Handle the failure to write a file of the EPUB: in lenient mode the failure
+// is reported and the write goes on without the file, otherwise err is
+// returned. A nil err is returned as is.
+func (e *Epub) writeFailure(filename string, err error) error {
+	if err == nil || e.buildMode != BuildModeLenient {
+		return err
 	}
+	e.report.add(SeverityWarning, RuleWriteFailure, filename, "%v", err)
+	return nil
 }
 
-// Write the section files to the temporary directory and add the sections to
-// the TOC and package files
-func (e *Epub) writeSections(rootEpubDir string) {
+// Write the section files to the archive and add the sections to the TOC and
+// package files
+func (e *Epub) writeSections(a *archive) error {
+	e.writeListOfFigures()
+	e.writePrefetchHints()
+	a.sectionsTotal = sectionCount(e.sections)
 	filenamelist := getFilenames(e.sections)
 	parentlist := getParents(e.sections, "-1")
 	if len(e.sections) > 0 {
 		// If a cover was set, add it to the package spine first so it shows up
 		// first in the reading order
 		if e.cover.xhtmlFilename != "" {
-			e.pkg.addToSpine(e.cover.xhtmlFilename)
+			coverID, err := e.sectionID(e.cover.xhtmlFilename)
+			if err != nil {
+				return err
+			}
+			e.pkg.addToSpine(coverID)
 		}
-		err := writeSections(rootEpubDir, e, e.sections, parentlist, filenamelist)
+		err := writeSections(a, e, e.sections, parentlist, filenamelist)
 		if err != nil {
-			log.Println(err)
+			return err
 		}
 	}
+	return e.writeSectionAccess()
 }
 
-// Write the TOC file to the temporary directory and add the TOC entries to the
-// package file
-func (e *Epub) writeToc(rootEpubDir string) {
+// Write the TOC files to the archive and add the TOC entries to the package
+// file
+func (e *Epub) writeToc(a *archive) error {
 	e.pkg.addToManifest(tocNavItemID, tocNavFilename, mediaTypeXhtml, tocNavItemProperties)
-	e.pkg.addToManifest(tocNcxItemID, tocNcxFilename, mediaTypeNcx, "")
-
-	err := e.toc.write(rootEpubDir)
-	if err != nil {
-		log.Println(err)
+	if e.compat.NCX {
+		e.pkg.addToManifest(tocNcxItemID, tocNcxFilename, mediaTypeNcx, "")
 	}
+	e.pkg.setNcx(e.compat.NCX)
 
+	err := e.toc.writeNavDoc(a)
+	if err == nil && e.compat.NCX {
+		err = e.toc.writeNcxDoc(a)
+	}
+	return e.writeFailure(tocNavFilename, err)
 }
 
 // Create a list of sections and their parents.
@@ -467,7 +638,75 @@ func getParents(sections []*epubSection, root string) map[string]string {
 	return fileparent
 }
 
-func writeSections(rootEpubDir string, e *Epub, sections []*epubSection, parentfilename map[string]string, filenamelist map[string]int) error {
+// Return the XHTML document of a section to write, with its body executed as
+// a template (see SetTemplateData), filtered by the build tags (see
+// SetBuildTags), with its external links rewritten (see
+// SetExternalLinkPolicy), its headings normalized (see SetNormalizeHeadings),
+// its break hints (see SetBreakHints) and the default stylesheets (see
+// SetDefaultStylesheets)
+func (e *Epub) sectionDocument(s *epubSection) (*xhtml, error) {
+	if s.xhtml.raw != "" || s == e.placeholder {
+		return s.xhtml, nil
+	}
+	body := s.xhtml.xml.Body.XML
+	if e.templateData != nil {
+		var err error
+		body, err = executeSectionTemplate(s.filename, body, e.templateData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.chapterOpeners != nil {
+		if number := e.chapterNumber(s); number > 0 {
+			opener, err := e.chapterOpener(s, number)
+			if err != nil {
+				return nil, err
+			}
+			body = opener + body
+		}
+	}
+	if e.buildTags != nil {
+		body = filterBuildTags(body, e.buildTags)
+	}
+	if e.linkPolicy != nil {
+		body = rewriteExternalLinks(s.filename, body, e.linkPolicy)
+	}
+	if e.normalizeHeadings {
+		body = normalizeHeadings(body)
+	}
+	if e.breakHints {
+		body = wrapBreakAvoidBlocks(body)
+	}
+	defaultCSS := len(e.defaultCSS) > 0 && s.filename != e.cover.xhtmlFilename
+	if body == s.xhtml.xml.Body.XML && !e.breakHints && !defaultCSS {
+		return s.xhtml, nil
+	}
+	root := *s.xhtml.xml
+	root.Body.XML = body
+	if defaultCSS {
+		e.linkDefaultStylesheets(&root)
+	}
+	if e.breakHints {
+		e.linkBreakHints(&root)
+	}
+	return &xhtml{xml: &root}, nil
+}
+
+// Return the parent of a section in the table of contents: its closest
+// ancestor with a title that has been written, as the sections without one
+// aren't listed, or "-1" for the root
+func (e *Epub) tocParent(a *archive, parentfilename map[string]string, filename string) string {
+	parent := parentfilename[filename]
+	for parent != "-1" && parent != "" {
+		if s, ok := e.findSection(parent); ok && s.xhtml.Title() != "" && !a.skippedSections[parent] {
+			return parent
+		}
+		parent = parentfilename[parent]
+	}
+	return "-1"
+}
+
+func writeSections(a *archive, e *Epub, sections []*epubSection, parentfilename map[string]string, filenamelist map[string]int) error {
 	for _, section := range sections {
 
 		// Set the title of the cover page XHTML to the title of the EPUB
@@ -475,33 +714,82 @@ func writeSections(rootEpubDir string, e *Epub, sections []*epubSection, parentf
 			section.xhtml.setTitle(e.Title())
 		}
 
-		sectionFilePath := filepath.Join(rootEpubDir, contentFolderName, xhtmlFolderName, section.filename)
-		err := section.xhtml.write(sectionFilePath)
-		if err != nil {
-			log.Println(err)
-		}
-
-		relativePath := filepath.Join(xhtmlFolderName, section.filename)
-		if section.filename != e.cover.xhtmlFilename {
-			e.pkg.addToSpine(section.filename)
-		}
-		e.pkg.addToManifest(section.filename, relativePath, mediaTypeXhtml, "")
-		if parentfilename[section.filename] == "-1" && section.filename != e.cover.xhtmlFilename {
-			j := filenamelist[section.filename]
-			e.toc.addSubSection("-1", j, section.xhtml.Title(), relativePath)
-		}
-		if parentfilename[section.filename] != "-1" && section.filename != e.cover.xhtmlFilename {
-			j := filenamelist[section.filename]
-			parentfilenameis := parentfilename[section.filename]
-			e.toc.addSubSection(parentfilenameis, j, section.xhtml.Title(), relativePath)
+		if err := e.writeSection(a, section); err != nil {
+			// In lenient mode, the section is left out of the EPUB
+			if err := e.writeFailure(section.filename, err); err != nil {
+				return err
+			}
+			a.skippedSections[section.filename] = true
+			if section.filename == e.cover.xhtmlFilename {
+				coverID, err := e.sectionID(section.filename)
+				if err != nil {
+					return err
+				}
+				e.pkg.removeFromSpine(coverID)
+			}
+		} else if err := e.addSectionItems(a, section, parentfilename, filenamelist); err != nil {
+			return err
 		}
+		a.sectionWritten()
 		if section.children != nil {
-			err = writeSections(rootEpubDir, e, section.children, parentfilename, filenamelist)
+			err := writeSections(a, e, section.children, parentfilename, filenamelist)
 			if err != nil {
-				log.Println(err)
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// Write the XHTML document of a section to the archive
+func (e *Epub) writeSection(a *archive, section *epubSection) error {
+	x, err := e.sectionDocument(section)
+	if err != nil {
+		return err
+	}
+	sectionFilePath := path.Join(contentFolderName, xhtmlFolderName, section.filename)
+	if err := x.writeTransformed(a, sectionFilePath, e.sectionTransform()); err != nil {
+		return err
+	}
+	if section != e.placeholder {
+		if content, err := x.content(); err == nil {
+			a.sections[section.filename] = string(content)
+		}
+	}
+	return nil
+}
+
+// Add a written section to the manifest, the spine and the TOC
+func (e *Epub) addSectionItems(a *archive, section *epubSection, parentfilename map[string]string, filenamelist map[string]int) error {
+	relativePath := filepath.Join(xhtmlFolderName, section.filename)
+	sectionID, err := e.sectionID(section.filename)
+	if err != nil {
+		return err
+	}
+	if section.filename != e.cover.xhtmlFilename {
+		e.pkg.addToSpine(sectionID)
+	}
+	e.pkg.addToManifest(sectionID, relativePath, mediaTypeXhtml, sectionProperties(section, a.sections[section.filename]))
+	if overlay, ok := e.sectionOverlays[section.filename]; ok && !e.skippedMedia[overlay] {
+		overlayID, err := e.mediaID(overlay)
+		if err != nil {
+			return fmt.Errorf("error creating xml id: %w", err)
+		}
+		e.pkg.setMediaOverlay(sectionID, overlayID)
+	}
+	// The sections without a title are left out of the table of contents
+	if title := section.xhtml.Title(); title != "" && section.filename != e.cover.xhtmlFilename {
+		j := filenamelist[section.filename]
+		parentfilenameis := e.tocParent(a, parentfilename, section.filename)
+		if err := e.toc.addSubSection(parentfilenameis, j, title, section.metadata, relativePath); err != nil {
+			if err := e.writeFailure(section.filename, fmt.Errorf("unable to add section to the TOC: %w", err)); err != nil {
+				return err
+			}
+		}
+	}
+	if section.pageLabel != "" {
+		e.toc.addPageTarget(section.pageLabel, relativePath)
+	}
+	return nil
+}
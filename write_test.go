@@ -1,11 +1,21 @@
 package epub
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEpubWriteTo(t *testing.T) {
@@ -23,6 +33,116 @@ func TestEpubWriteTo(t *testing.T) {
 	}
 }
 
+func TestEpubWriteToTwice(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "section.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	first := writeAndReadPackage(t, e)
+	second := writeAndReadPackage(t, e)
+	if n := strings.Count(second, `href="xhtml/section.xhtml"`); n != 1 {
+		t.Errorf("Expected the section to be in the manifest once, got %d times:\n%s", n, second)
+	}
+	if len(first) != len(second) {
+		t.Errorf("Package files differ between writes:\n%s\n%s", first, second)
+	}
+}
+
+func TestWriteManifestOrder(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"c.png", "a.png", "d.png", "b.png"} {
+		if _, err := e.AddImage(testImageFromFileSource, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opf := writeAndReadPackage(t, e)
+	var hrefs []string
+	for _, line := range strings.Split(opf, "\n") {
+		if i := strings.Index(line, `href="images/`); i >= 0 {
+			hrefs = append(hrefs, line[i:])
+		}
+	}
+	if len(hrefs) != 4 {
+		t.Fatalf("Expected 4 images in the manifest, got %d\n%s", len(hrefs), opf)
+	}
+	if !sort.StringsAreSorted(hrefs) {
+		t.Errorf("Manifest items aren't sorted: %v", hrefs)
+	}
+}
+
+func TestWriteUntitledSections(t *testing.T) {
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := e.AddSection("<p>Part</p>", "Part", "part.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	untitled, err := e.AddSubSection(part, "<p>Interlude</p>", "", "interlude.xhtml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSubSection(untitled, "<p>Chapter</p>", "Chapter", "chapter.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	files := readZipFiles(t, b.Bytes())
+	nav := files["EPUB/nav.xhtml"]
+	if strings.Contains(nav, "interlude.xhtml") || strings.Contains(files["EPUB/toc.ncx"], "interlude.xhtml") {
+		t.Errorf("Expected the untitled section to be left out of the table of contents, got:\n%s", nav)
+	}
+	// The subsection of the untitled section is listed under its parent
+	chapter := strings.Index(nav, `<a href="xhtml/chapter.xhtml">Chapter</a>`)
+	if part := strings.Index(nav, `<a href="xhtml/part.xhtml">Part</a>`); part < 0 || chapter < part || !strings.Contains(nav[part:chapter], "<ol>") {
+		t.Errorf("Expected the chapter under the part in the nav document, got:\n%s", nav)
+	}
+}
+
+func TestWriteToContext(t *testing.T) {
+	// The server answers the checks made when the image is added, but never
+	// sends the image itself
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage(server.URL+"/image.png", "image.png"); err != nil {
+		t.Fatal(err)
+	}
+	// Leaving out the media doesn't hide the cancellation
+	e.SetBuildMode(BuildModeLenient)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var b bytes.Buffer
+	if _, err := e.WriteToContext(ctx, &b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.AddImageContext(ctx, server.URL+"/other.png", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context to be cancelled, got %v", err)
+	}
+}
+
 func TestWriteToErrors(t *testing.T) {
 	t.Run("CSS", func(t *testing.T) {
 		e, err := NewEpub(testEpubTitle)
@@ -92,3 +212,171 @@ func testWriteToErrors(t *testing.T, e *Epub, adder func(string, string) (string
 		t.Fatal("Expected error")
 	}
 }
+
+// Return the content of the files of a zip archive, keyed by their name
+// A writer failing every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteToWriteFailure(t *testing.T) {
+	// A section large enough to be flushed to the writer while the sections
+	// are written
+	r := rand.New(rand.NewSource(1))
+	text := make([]byte, 20000)
+	for i := range text {
+		text[i] = byte('a' + r.Intn(26))
+	}
+	newEpub := func() *Epub {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSection("<p>"+string(text)+"</p>", testSectionTitle, "section.xhtml", ""); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	if _, err := newEpub().WriteTo(failingWriter{}); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the write failure to be returned, got %v", err)
+	}
+
+	// In lenient mode, the failure is reported and the write goes on
+	e := newEpub()
+	e.SetBuildMode(BuildModeLenient)
+	_, report, _ := e.WriteToWithReport(failingWriter{})
+	found := false
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleWriteFailure && issue.Filename == pkgFilename {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the package file write failure to be reported, got %v", report.Issues)
+	}
+}
+
+func TestWriteLenientSectionFailure(t *testing.T) {
+	newEpub := func() *Epub {
+		e, err := NewEpub(testEpubTitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetTemplateData(map[string]interface{}{"Name": "World"})
+		if _, err := e.AddSection("<p>Hello {{.Name}}</p>", "Hello", "hello.xhtml", ""); err != nil {
+			t.Fatal(err)
+		}
+		// The template refers to missing data and can't be executed
+		broken, err := e.AddSection("<p>{{.Missing}}</p>", "Broken", "broken.xhtml", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.AddSubSection(broken, "<p>Child</p>", "Child", "child.xhtml", ""); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	if _, err := newEpub().WriteTo(io.Discard); err == nil {
+		t.Error("Expected the section failure to be returned")
+	}
+
+	e := newEpub()
+	e.SetBuildMode(BuildModeLenient)
+	var b bytes.Buffer
+	_, report, err := e.WriteToWithReport(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleWriteFailure && issue.Filename == "broken.xhtml" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the section failure to be reported, got %v", report.Issues)
+	}
+	files := readZipFiles(t, b.Bytes())
+	if _, ok := files["EPUB/xhtml/broken.xhtml"]; ok {
+		t.Error("Expected the section to be left out of the archive")
+	}
+	for _, name := range []string{"EPUB/package.opf", "EPUB/nav.xhtml", "EPUB/toc.ncx"} {
+		if strings.Contains(files[name], "broken.xhtml") {
+			t.Errorf("Expected the section to be left out of %s, got:\n%s", name, files[name])
+		}
+	}
+	// The subsection of the section is still written, at the root of the
+	// table of contents
+	if _, ok := files["EPUB/xhtml/child.xhtml"]; !ok {
+		t.Error("Expected the subsection in the archive")
+	}
+	if nav := files["EPUB/nav.xhtml"]; !strings.Contains(nav, `<a href="xhtml/child.xhtml">Child</a>`) {
+		t.Errorf("Expected the subsection in the nav document, got:\n%s", nav)
+	}
+}
+
+func TestWriteMediaStreamed(t *testing.T) {
+	const size = 32 << 20
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		if r.Method == http.MethodHead {
+			return
+		}
+		chunk := make([]byte, 64<<10)
+		for written := 0; written < size; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	e, err := NewEpub(testEpubTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddSection(testSectionBody, testSectionTitle, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddAudio(server.URL+"/audiobook.mp3", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The audio file is copied to the archive rather than held in memory
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if _, err := e.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/2 {
+		t.Errorf("Expected the audio file to be streamed, %d bytes were allocated for a %d bytes file", allocated, size)
+	}
+}
+
+func readZipFiles(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(z.File))
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
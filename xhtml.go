@@ -8,8 +8,9 @@ import (
 const (
 	xhtmlDoctype = `<!DOCTYPE html>
 `
-	xhtmlLinkRel  = "stylesheet"
-	xhtmlTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+	xhtmlLinkRel         = "stylesheet"
+	xhtmlLinkRelPrefetch = "prefetch"
+	xhtmlTemplate        = `<?xml version="1.0" encoding="UTF-8"?>
 <!DOCTYPE html>
 <html xmlns="http://www.w3.org/1999/xhtml">
   <head>
@@ -23,6 +24,9 @@ const (
 // xhtml implements an XHTML document
 type xhtml struct {
 	xml *xhtmlRoot
+	// A complete document provided by the user, written verbatim instead of
+	// the generated one
+	raw string
 }
 
 // This holds the actual XHTML content
@@ -35,7 +39,10 @@ type xhtmlRoot struct {
 
 type xhtmlHead struct {
 	Title xhtmlTitle `xml:"title"`
-	Link  *xhtmlLink
+	// Metadata of the section, see SetSectionMetadata
+	Metas   []xhtmlMeta `xml:"meta"`
+	Links   []xhtmlLink
+	Scripts []xhtmlScript `xml:"script"`
 }
 
 type xhtmlTitle struct {
@@ -51,6 +58,14 @@ type xhtmlLink struct {
 	Rel     string   `xml:"rel,attr,omitempty"`
 	Type    string   `xml:"type,attr,omitempty"`
 	Href    string   `xml:"href,attr,omitempty"`
+	Title   string   `xml:"title,attr,omitempty"`
+}
+
+// The <script> element, used to link to scripts
+// Ex: <script type="text/javascript" src="../scripts/widgets.js"></script>
+type xhtmlScript struct {
+	Type string `xml:"type,attr,omitempty"`
+	Src  string `xml:"src,attr"`
 }
 
 // This holds the content of the XHTML document between the <body> tags. It is
@@ -59,6 +74,8 @@ type xhtmlLink struct {
 type xhtmlInnerxml struct {
 	XML string `xml:",innerxml"`
 	Dir string `xml:"dir,attr,omitempty"`
+	// Access of the section for partial access, see SetSectionAccess
+	Access string `xml:"data-access,attr,omitempty"`
 }
 
 // Constructor for xhtml
@@ -75,6 +92,19 @@ func newXhtml(body string) (*xhtml, error) {
 	return x, nil
 }
 
+// Constructor for an xhtml written verbatim from a complete XHTML document.
+// The document is parsed to make sure it is well-formed and to get its title.
+func newRawXhtml(doc string) (*xhtml, error) {
+	r := &xhtmlRoot{}
+	if err := xml.Unmarshal([]byte(doc), r); err != nil {
+		return nil, fmt.Errorf("can't parse XHTML document: %w", err)
+	}
+	return &xhtml{
+		xml: r,
+		raw: doc,
+	}, nil
+}
+
 // Constructor for xhtmlRoot
 func newXhtmlRoot() (*xhtmlRoot, error) {
 	r := &xhtmlRoot{
@@ -94,11 +124,61 @@ func (x *xhtml) setBody(body string) {
 }
 
 func (x *xhtml) setCSS(path string) {
-	x.xml.Head.Link = &xhtmlLink{
-		Rel:  xhtmlLinkRel,
-		Type: mediaTypeCSS,
-		Href: path,
+	x.setStylesheets([]string{path})
+}
+
+// Set the stylesheets of the document, replacing any previous ones. The
+// stylesheets come first, in the given order.
+func (x *xhtml) setStylesheets(paths []string) {
+	var links []xhtmlLink
+	for _, p := range paths {
+		links = append(links, xhtmlLink{
+			Rel:  xhtmlLinkRel,
+			Type: mediaTypeCSS,
+			Href: p,
+		})
+	}
+	for _, l := range x.xml.Head.Links {
+		if l.Rel != xhtmlLinkRel {
+			links = append(links, l)
+		}
+	}
+	x.xml.Head.Links = links
+}
+
+// Return the paths of the stylesheets of the document
+func (x *xhtml) stylesheets() []string {
+	var paths []string
+	for _, l := range x.xml.Head.Links {
+		if l.Rel == xhtmlLinkRel {
+			paths = append(paths, l.Href)
+		}
+	}
+	return paths
+}
+
+// Set the resources to prefetch, replacing any previous ones
+func (x *xhtml) setPrefetchLinks(paths []string) {
+	var links []xhtmlLink
+	for _, l := range x.xml.Head.Links {
+		if l.Rel != xhtmlLinkRelPrefetch {
+			links = append(links, l)
+		}
+	}
+	for _, p := range paths {
+		links = append(links, xhtmlLink{
+			Rel:  xhtmlLinkRelPrefetch,
+			Href: p,
+		})
 	}
+	x.xml.Head.Links = links
+}
+
+func (x *xhtml) addScript(path string) {
+	x.xml.Head.Scripts = append(x.xml.Head.Scripts, xhtmlScript{
+		Type: mediaTypeJavascript,
+		Src:  path,
+	})
 }
 
 func (x *xhtml) setTitle(title string) {
@@ -116,11 +196,14 @@ func (x *xhtml) Title() string {
 	return x.xml.Head.Title.Value
 }
 
-// Write the XHTML file to the specified path
-func (x *xhtml) write(xhtmlFilePath string) error {
+// Render the complete XHTML document, including the XML header and doctype
+func (x *xhtml) content() ([]byte, error) {
+	if x.raw != "" {
+		return []byte(x.raw), nil
+	}
 	xhtmlFileContent, err := xml.MarshalIndent(x.xml, "", "  ")
 	if err != nil {
-		return fmt.Errorf("Error marshalling XML for XHTML file: %w\n"+"\tXML=%v", err, x.xml)
+		return nil, fmt.Errorf("Error marshalling XML for XHTML file: %w\n"+"\tXML=%v", err, x.xml)
 	}
 
 	// Add the doctype declaration to the output
@@ -130,7 +213,21 @@ func (x *xhtml) write(xhtmlFilePath string) error {
 	// It's generally nice to have files end with a newline
 	xhtmlFileContent = append(xhtmlFileContent, "\n"...)
 
-	if err := filesystem.WriteFile(xhtmlFilePath, []byte(xhtmlFileContent), filePermissions); err != nil {
+	return xhtmlFileContent, nil
+}
+
+// Write the XHTML file to the archive at the specified path, passing its
+// content through transform first if it isn't nil
+func (x *xhtml) writeTransformed(a *archive, xhtmlFilePath string, transform func([]byte) []byte) error {
+	xhtmlFileContent, err := x.content()
+	if err != nil {
+		return err
+	}
+	if transform != nil {
+		xhtmlFileContent = transform(xhtmlFileContent)
+	}
+
+	if err := a.add(xhtmlFilePath, xhtmlFileContent); err != nil {
 		return fmt.Errorf("Error writing XHTML file: %w", err)
 	}
 	return nil